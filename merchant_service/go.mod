@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/golang/protobuf v1.4.1
 	github.com/hashicorp/consul/api v1.5.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	google.golang.org/grpc v1.30.0
 	google.golang.org/protobuf v1.25.0
 )
@@ -0,0 +1,318 @@
+package ledger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be positive")
+	ErrOrderLockNotFound   = errors.New("order locked balance not found")
+	ErrOrderLockMismatch   = errors.New("merchant or amount not match the locked balance of order")
+	ErrOrderLockApproved   = errors.New("order locked balance already approved")
+	ErrOrderLockCanceled   = errors.New("order locked balance already canceled")
+	ErrConcurrentUpdate    = errors.New("merchant balance changed by other request")
+	ErrInsufficientBalance = errors.New("merchant locked balance not enough")
+)
+
+/**
+ * 商户账本. 订单的金额先锁定在商户的锁定余额中，订单完成时转入可用余额，订单取消时释放
+ */
+type Ledger struct {
+	store Store
+}
+
+func NewLedger(store Store) *Ledger {
+	return &Ledger{store: store}
+}
+
+/**
+ * 在一个存储事务中执行fn，fn返回错误时回滚
+ */
+func (l *Ledger) inTx(ctx context.Context, fn func(tx StoreTx) error) (err error) {
+	tx, err := l.store.BeginTx(ctx)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+			return
+		}
+		err = tx.Commit()
+	}()
+	err = fn(tx)
+	return
+}
+
+/**
+ * 修改商户余额，商户不存在时创建
+ */
+func changeMerchantBalance(ctx context.Context, tx StoreTx, merchantName string,
+	balanceDelta int64, lockedBalanceDelta int64) (err error) {
+	now := time.Now()
+	record, err := tx.FindMerchantBalance(ctx, merchantName)
+	if err != nil {
+		return
+	}
+	if record == nil {
+		record = &MerchantBalanceEntity{
+			MerchantName: merchantName,
+			UpdatedAt:    now,
+		}
+		if err = tx.InsertMerchantBalance(ctx, record); err != nil {
+			return
+		}
+	}
+	if record.LockedBalance+lockedBalanceDelta < 0 {
+		err = ErrInsufficientBalance
+		return
+	}
+	oldVersion := record.Version
+	record.Balance += balanceDelta
+	record.LockedBalance += lockedBalanceDelta
+	record.UpdatedAt = now
+	rowsChanged, err := tx.UpdateMerchantBalance(ctx, record, oldVersion)
+	if err != nil {
+		return
+	}
+	if rowsChanged < 1 {
+		err = ErrConcurrentUpdate
+		return
+	}
+	return
+}
+
+func checkOrderLockMatch(lock *OrderLockEntity, merchantName string, amount int64) error {
+	if (len(merchantName) > 0 && merchantName != lock.MerchantName) || (amount != 0 && amount != lock.Amount) {
+		return ErrOrderLockMismatch
+	}
+	return nil
+}
+
+/**
+ * 执行一次订单操作，并在同一个存储事务中记录操作日志
+ * apply返回OperationResultRefused时err是拒绝的原因，这时账本没有修改，仍然记录日志并提交
+ * 其他情况下apply返回err表示执行出错，回滚并且不记录日志
+ */
+func (l *Ledger) runOrderOperation(ctx context.Context, op *OrderOperationEntity,
+	apply func(tx StoreTx) (result string, err error)) (err error) {
+	var refusedErr error
+	err = l.inTx(ctx, func(tx StoreTx) (err error) {
+		result, err := apply(tx)
+		if result == OperationResultRefused {
+			refusedErr = err
+			op.Message = err.Error()
+		} else if err != nil {
+			return
+		}
+		op.Result = result
+		op.CreatedAt = time.Now()
+		return tx.InsertOrderOperation(ctx, op)
+	})
+	if err != nil {
+		return
+	}
+	return refusedErr
+}
+
+/**
+ * 把订单的金额锁定到商户的锁定余额
+ * 重复的锁定请求不做修改，订单已经取消(包括空回滚)后迟到的锁定请求被拒绝，避免锁定金额悬挂
+ */
+func (l *Ledger) AddLockedBalance(ctx context.Context, orderId string, merchantName string, amount int64) error {
+	if amount <= 0 {
+		return ErrInvalidAmount
+	}
+	op := &OrderOperationEntity{
+		OrderId:      orderId,
+		MerchantName: merchantName,
+		Operation:    OperationAddLockedBalance,
+		Amount:       amount,
+	}
+	return l.runOrderOperation(ctx, op, func(tx StoreTx) (result string, err error) {
+		lock, err := tx.FindOrderLock(ctx, orderId)
+		if err != nil {
+			return
+		}
+		if lock != nil {
+			result = OperationResultRefused
+			switch {
+			case lock.State == OrderLockCanceled || lock.State == OrderLockEmptyRollback:
+				err = ErrOrderLockCanceled
+			case checkOrderLockMatch(lock, merchantName, amount) != nil:
+				err = ErrOrderLockMismatch
+			default:
+				result = OperationResultDuplicate
+			}
+			return
+		}
+		now := time.Now()
+		err = tx.InsertOrderLock(ctx, &OrderLockEntity{
+			OrderId:      orderId,
+			MerchantName: merchantName,
+			Amount:       amount,
+			State:        OrderLockLocked,
+			CreatedAt:    now,
+			UpdatedAt:    now,
+		})
+		if err != nil {
+			return
+		}
+		result = OperationResultApplied
+		err = changeMerchantBalance(ctx, tx, merchantName, 0, amount)
+		return
+	})
+}
+
+/**
+ * 订单完成，把锁定金额转入商户的可用余额. merchantName和amount不为空时需要和锁定时一致
+ */
+func (l *Ledger) ApproveLockedBalance(ctx context.Context, orderId string, merchantName string, amount int64) error {
+	op := &OrderOperationEntity{
+		OrderId:      orderId,
+		MerchantName: merchantName,
+		Operation:    OperationApproveLockedBalance,
+		Amount:       amount,
+	}
+	return l.runOrderOperation(ctx, op, func(tx StoreTx) (result string, err error) {
+		lock, err := tx.FindOrderLock(ctx, orderId)
+		if err != nil {
+			return
+		}
+		result = OperationResultRefused
+		if lock == nil {
+			err = ErrOrderLockNotFound
+			return
+		}
+		if err = checkOrderLockMatch(lock, merchantName, amount); err != nil {
+			return
+		}
+		switch lock.State {
+		case OrderLockApproved:
+			result = OperationResultDuplicate
+			return
+		case OrderLockCanceled, OrderLockEmptyRollback:
+			err = ErrOrderLockCanceled
+			return
+		}
+		result = OperationResultApplied
+		if err = updateOrderLockState(ctx, tx, lock, OrderLockApproved); err != nil {
+			return
+		}
+		err = changeMerchantBalance(ctx, tx, lock.MerchantName, lock.Amount, -lock.Amount)
+		return
+	})
+}
+
+/**
+ * 订单取消，释放锁定金额. 重复的取消请求不做修改
+ * 订单还没有锁定过金额时记录一条空回滚，之后这个订单的锁定请求会被拒绝
+ */
+func (l *Ledger) CancelAddLockedBalance(ctx context.Context, orderId string, merchantName string, amount int64) error {
+	op := &OrderOperationEntity{
+		OrderId:      orderId,
+		MerchantName: merchantName,
+		Operation:    OperationCancelAddLockedBalance,
+		Amount:       amount,
+	}
+	return l.runOrderOperation(ctx, op, func(tx StoreTx) (result string, err error) {
+		lock, err := tx.FindOrderLock(ctx, orderId)
+		if err != nil {
+			return
+		}
+		if lock == nil {
+			now := time.Now()
+			result = OperationResultEmptyRollback
+			err = tx.InsertOrderLock(ctx, &OrderLockEntity{
+				OrderId:      orderId,
+				MerchantName: merchantName,
+				Amount:       amount,
+				State:        OrderLockEmptyRollback,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			})
+			return
+		}
+		result = OperationResultRefused
+		if err = checkOrderLockMatch(lock, merchantName, amount); err != nil {
+			return
+		}
+		switch lock.State {
+		case OrderLockCanceled, OrderLockEmptyRollback:
+			result = OperationResultDuplicate
+			return
+		case OrderLockApproved:
+			err = ErrOrderLockApproved
+			return
+		}
+		result = OperationResultApplied
+		if err = updateOrderLockState(ctx, tx, lock, OrderLockCanceled); err != nil {
+			return
+		}
+		err = changeMerchantBalance(ctx, tx, lock.MerchantName, 0, -lock.Amount)
+		return
+	})
+}
+
+func updateOrderLockState(ctx context.Context, tx StoreTx, lock *OrderLockEntity, state OrderLockState) (err error) {
+	rowsChanged, err := tx.UpdateOrderLockState(ctx, lock.OrderId, lock.State, state)
+	if err != nil {
+		return
+	}
+	if rowsChanged < 1 {
+		err = fmt.Errorf("order %s state changed by other request", lock.OrderId)
+		return
+	}
+	lock.State = state
+	return
+}
+
+/**
+ * 查询商户余额，商户不存在时返回余额为0的记录
+ */
+func (l *Ledger) GetMerchantBalance(ctx context.Context, merchantName string) (record *MerchantBalanceEntity, err error) {
+	err = l.inTx(ctx, func(tx StoreTx) (err error) {
+		record, err = tx.FindMerchantBalance(ctx, merchantName)
+		return
+	})
+	if err != nil {
+		return
+	}
+	if record == nil {
+		record = &MerchantBalanceEntity{MerchantName: merchantName}
+	}
+	return
+}
+
+/**
+ * 查询订单的锁定记录，不存在时返回nil
+ */
+func (l *Ledger) GetOrderLock(ctx context.Context, orderId string) (record *OrderLockEntity, err error) {
+	err = l.inTx(ctx, func(tx StoreTx) (err error) {
+		record, err = tx.FindOrderLock(ctx, orderId)
+		return
+	})
+	return
+}
+
+/**
+ * 按先后顺序返回订单的操作日志
+ */
+func (l *Ledger) ListOrderOperations(ctx context.Context, orderId string) ([]*OrderOperationEntity, error) {
+	return l.ListOperations(ctx, "", orderId, 0, 0)
+}
+
+/**
+ * 按id升序分页查询操作日志，merchantName和orderId为空时不过滤
+ */
+func (l *Ledger) ListOperations(ctx context.Context, merchantName string, orderId string,
+	afterId uint64, limit int32) (records []*OrderOperationEntity, err error) {
+	err = l.inTx(ctx, func(tx StoreTx) (err error) {
+		records, err = tx.FindOrderOperations(ctx, merchantName, orderId, afterId, limit)
+		return
+	})
+	return
+}
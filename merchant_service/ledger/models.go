@@ -0,0 +1,79 @@
+package ledger
+
+import "time"
+
+/**
+ * 订单锁定余额的状态
+ */
+type OrderLockState int
+
+const (
+	OrderLockLocked   OrderLockState = 1 // 已锁定，等待订单完成或者取消
+	OrderLockApproved OrderLockState = 2 // 锁定金额已转入商户可用余额
+	OrderLockCanceled OrderLockState = 3 // 锁定金额已释放
+	// 空回滚: 取消时订单还没有锁定过金额，留下这条记录使之后迟到的锁定请求被拒绝
+	OrderLockEmptyRollback OrderLockState = 4
+)
+
+func (s OrderLockState) String() string {
+	switch s {
+	case OrderLockLocked:
+		return "LOCKED"
+	case OrderLockApproved:
+		return "APPROVED"
+	case OrderLockCanceled:
+		return "CANCELED"
+	case OrderLockEmptyRollback:
+		return "EMPTY_ROLLBACK"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+/**
+ * 商户的可用余额和锁定中余额
+ */
+type MerchantBalanceEntity struct {
+	MerchantName  string
+	Balance       int64
+	LockedBalance int64
+	Version       int32
+	UpdatedAt     time.Time
+}
+
+/**
+ * 一个订单在商户上锁定的金额，以orderId为主键
+ */
+type OrderLockEntity struct {
+	OrderId      string
+	MerchantName string
+	Amount       int64
+	State        OrderLockState
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+const (
+	OperationAddLockedBalance       = "ADD_LOCKED_BALANCE"
+	OperationApproveLockedBalance   = "APPROVE_LOCKED_BALANCE"
+	OperationCancelAddLockedBalance = "CANCEL_ADD_LOCKED_BALANCE"
+
+	OperationResultApplied       = "APPLIED"        // 修改了账本
+	OperationResultDuplicate     = "DUPLICATE"      // 重复请求，账本不变
+	OperationResultEmptyRollback = "EMPTY_ROLLBACK" // 取消时订单还没有锁定，记录了空回滚
+	OperationResultRefused       = "REFUSED"        // 请求被拒绝，账本不变
+)
+
+/**
+ * 对订单的每次账本操作请求的记录
+ */
+type OrderOperationEntity struct {
+	Id           uint64
+	OrderId      string
+	MerchantName string
+	Operation    string
+	Amount       int64
+	Result       string
+	Message      string
+	CreatedAt    time.Time
+}
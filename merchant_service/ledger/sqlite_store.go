@@ -0,0 +1,235 @@
+package ledger
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+	"time"
+)
+
+var sqliteSchema = []string{
+	"create table if not exists merchant_balances (" +
+		" merchant_name varchar(200) not null primary key," +
+		" balance bigint not null default 0," +
+		" locked_balance bigint not null default 0," +
+		" version int not null default 0," +
+		" updated_at datetime not null)",
+	"create table if not exists order_locks (" +
+		" order_id varchar(100) not null primary key," +
+		" merchant_name varchar(200) not null," +
+		" amount bigint not null," +
+		" state int not null," +
+		" created_at datetime not null," +
+		" updated_at datetime not null)",
+	"create index if not exists idx_order_locks_merchant_name on order_locks (merchant_name)",
+	"create table if not exists order_operations (" +
+		" id integer primary key autoincrement," +
+		" order_id varchar(100) not null," +
+		" merchant_name varchar(200) not null," +
+		" operation varchar(50) not null," +
+		" amount bigint not null," +
+		" result varchar(50) not null," +
+		" message text not null," +
+		" created_at datetime not null)",
+	"create index if not exists idx_order_operations_order_id on order_operations (order_id)",
+	"create index if not exists idx_order_operations_merchant_name on order_operations (merchant_name)",
+}
+
+/**
+ * sql.DB和sql.Tx的公共方法
+ */
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+type sqlDaos struct {
+	exec sqlExecutor
+}
+
+func (d *sqlDaos) FindMerchantBalance(ctx context.Context, merchantName string) (record *MerchantBalanceEntity, err error) {
+	r := &MerchantBalanceEntity{}
+	err = d.exec.QueryRowContext(ctx, "select merchant_name, balance, locked_balance, version, updated_at"+
+		" from merchant_balances where merchant_name=?", merchantName).
+		Scan(&r.MerchantName, &r.Balance, &r.LockedBalance, &r.Version, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	record = r
+	return
+}
+
+func (d *sqlDaos) InsertMerchantBalance(ctx context.Context, record *MerchantBalanceEntity) (err error) {
+	_, err = d.exec.ExecContext(ctx, "insert into merchant_balances"+
+		" (merchant_name, balance, locked_balance, version, updated_at) values (?,?,?,?,?)",
+		record.MerchantName, record.Balance, record.LockedBalance, record.Version, record.UpdatedAt.UTC())
+	return
+}
+
+func (d *sqlDaos) UpdateMerchantBalance(ctx context.Context, record *MerchantBalanceEntity,
+	oldVersion int32) (rowsChanged int64, err error) {
+	result, err := d.exec.ExecContext(ctx, "update merchant_balances"+
+		" set balance=?, locked_balance=?, version=version+1, updated_at=?"+
+		" where merchant_name=? and version=?",
+		record.Balance, record.LockedBalance, record.UpdatedAt.UTC(), record.MerchantName, oldVersion)
+	if err != nil {
+		return
+	}
+	return result.RowsAffected()
+}
+
+func (d *sqlDaos) FindOrderLock(ctx context.Context, orderId string) (record *OrderLockEntity, err error) {
+	r := &OrderLockEntity{}
+	err = d.exec.QueryRowContext(ctx, "select order_id, merchant_name, amount, state, created_at, updated_at"+
+		" from order_locks where order_id=?", orderId).
+		Scan(&r.OrderId, &r.MerchantName, &r.Amount, &r.State, &r.CreatedAt, &r.UpdatedAt)
+	if err == sql.ErrNoRows {
+		err = nil
+		return
+	}
+	if err != nil {
+		return
+	}
+	record = r
+	return
+}
+
+func (d *sqlDaos) InsertOrderLock(ctx context.Context, record *OrderLockEntity) (err error) {
+	_, err = d.exec.ExecContext(ctx, "insert into order_locks"+
+		" (order_id, merchant_name, amount, state, created_at, updated_at) values (?,?,?,?,?,?)",
+		record.OrderId, record.MerchantName, record.Amount, record.State,
+		record.CreatedAt.UTC(), record.UpdatedAt.UTC())
+	return
+}
+
+func (d *sqlDaos) UpdateOrderLockState(ctx context.Context, orderId string,
+	oldState OrderLockState, state OrderLockState) (rowsChanged int64, err error) {
+	result, err := d.exec.ExecContext(ctx, "update order_locks set state=?, updated_at=?"+
+		" where order_id=? and state=?", state, time.Now().UTC(), orderId, oldState)
+	if err != nil {
+		return
+	}
+	return result.RowsAffected()
+}
+
+const orderOperationColumns = "id, order_id, merchant_name, operation, amount, result, message, created_at"
+
+func (d *sqlDaos) InsertOrderOperation(ctx context.Context, record *OrderOperationEntity) (err error) {
+	result, err := d.exec.ExecContext(ctx, "insert into order_operations"+
+		" (order_id, merchant_name, operation, amount, result, message, created_at) values (?,?,?,?,?,?,?)",
+		record.OrderId, record.MerchantName, record.Operation, record.Amount, record.Result,
+		record.Message, record.CreatedAt.UTC())
+	if err != nil {
+		return
+	}
+	id, err := result.LastInsertId()
+	if err != nil {
+		return
+	}
+	record.Id = uint64(id)
+	return
+}
+
+func (d *sqlDaos) queryOrderOperations(ctx context.Context, query string,
+	args ...interface{}) (records []*OrderOperationEntity, err error) {
+	rows, err := d.exec.QueryContext(ctx, query, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		r := &OrderOperationEntity{}
+		err = rows.Scan(&r.Id, &r.OrderId, &r.MerchantName, &r.Operation, &r.Amount,
+			&r.Result, &r.Message, &r.CreatedAt)
+		if err != nil {
+			return
+		}
+		records = append(records, r)
+	}
+	err = rows.Err()
+	return
+}
+
+func (d *sqlDaos) FindOrderOperations(ctx context.Context, merchantName string, orderId string,
+	afterId uint64, limit int32) ([]*OrderOperationEntity, error) {
+	query := "select " + orderOperationColumns + " from order_operations where id > ?"
+	args := []interface{}{afterId}
+	if len(merchantName) > 0 {
+		query += " and merchant_name=?"
+		args = append(args, merchantName)
+	}
+	if len(orderId) > 0 {
+		query += " and order_id=?"
+		args = append(args, orderId)
+	}
+	query += " order by id asc"
+	if limit > 0 {
+		query += " limit ?"
+		args = append(args, limit)
+	}
+	return d.queryOrderOperations(ctx, query, args...)
+}
+
+type sqlStoreTx struct {
+	sqlDaos
+	tx *sql.Tx
+}
+
+func (t *sqlStoreTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlStoreTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+/**
+ * 基于sqlite的账本存储，表结构在打开时自动创建
+ */
+type SqliteStore struct {
+	db *sql.DB
+}
+
+func NewSqliteStore(path string) (store *SqliteStore, err error) {
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&_busy_timeout=5000"
+	} else {
+		dsn += "?_busy_timeout=5000"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return
+	}
+	// sqlite同一时间只允许一个写连接，限制为单连接避免database is locked错误
+	db.SetMaxOpenConns(1)
+	for _, stmt := range sqliteSchema {
+		if _, err = db.Exec(stmt); err != nil {
+			_ = db.Close()
+			return
+		}
+	}
+	store = &SqliteStore{db: db}
+	return
+}
+
+func (s *SqliteStore) BeginTx(ctx context.Context) (StoreTx, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &sqlStoreTx{
+		sqlDaos: sqlDaos{exec: tx},
+		tx:      tx,
+	}, nil
+}
+
+func (s *SqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,36 @@
+package ledger
+
+import "context"
+
+type StoreOps interface {
+	// 商户不存在时返回nil
+	FindMerchantBalance(ctx context.Context, merchantName string) (*MerchantBalanceEntity, error)
+	InsertMerchantBalance(ctx context.Context, record *MerchantBalanceEntity) error
+	// 按版本号乐观更新余额，返回修改的行数
+	UpdateMerchantBalance(ctx context.Context, record *MerchantBalanceEntity, oldVersion int32) (int64, error)
+
+	// 订单不存在时返回nil
+	FindOrderLock(ctx context.Context, orderId string) (*OrderLockEntity, error)
+	InsertOrderLock(ctx context.Context, record *OrderLockEntity) error
+	// 只在状态为oldState时修改，返回修改的行数
+	UpdateOrderLockState(ctx context.Context, orderId string, oldState OrderLockState, state OrderLockState) (int64, error)
+
+	InsertOrderOperation(ctx context.Context, record *OrderOperationEntity) error
+	// 按id升序返回id大于afterId的操作记录，merchantName和orderId为空时不过滤，limit<=0时不限制数量
+	FindOrderOperations(ctx context.Context, merchantName string, orderId string,
+		afterId uint64, limit int32) ([]*OrderOperationEntity, error)
+}
+
+type StoreTx interface {
+	StoreOps
+	Commit() error
+	Rollback() error
+}
+
+/**
+ * 商户账本的持久化存储
+ */
+type Store interface {
+	BeginTx(ctx context.Context) (StoreTx, error)
+	Close() error
+}
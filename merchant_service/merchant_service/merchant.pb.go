@@ -18,7 +18,7 @@ import (
 	sync "sync"
 )
 
-var (
+const (
 	// Verify that this generated code is sufficiently up-to-date.
 	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
 	// Verify that runtime/protoimpl is sufficiently up-to-date.
@@ -383,6 +383,385 @@ func (x *CancelAddLockedBalanceReply) GetMessage() string {
 	return ""
 }
 
+type GetMerchantBalanceRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MerchantName string `protobuf:"bytes,1,opt,name=merchantName,proto3" json:"merchantName,omitempty"`
+}
+
+func (x *GetMerchantBalanceRequest) Reset() {
+	*x = GetMerchantBalanceRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_merchant_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetMerchantBalanceRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMerchantBalanceRequest) ProtoMessage() {}
+
+func (x *GetMerchantBalanceRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_merchant_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMerchantBalanceRequest.ProtoReflect.Descriptor instead.
+func (*GetMerchantBalanceRequest) Descriptor() ([]byte, []int) {
+	return file_merchant_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetMerchantBalanceRequest) GetMerchantName() string {
+	if x != nil {
+		return x.MerchantName
+	}
+	return ""
+}
+
+type GetMerchantBalanceReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success       bool   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message       string `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	MerchantName  string `protobuf:"bytes,3,opt,name=merchantName,proto3" json:"merchantName,omitempty"`
+	Balance       int64  `protobuf:"varint,4,opt,name=balance,proto3" json:"balance,omitempty"`             // 可用余额
+	LockedBalance int64  `protobuf:"varint,5,opt,name=lockedBalance,proto3" json:"lockedBalance,omitempty"` // 锁定中的余额
+}
+
+func (x *GetMerchantBalanceReply) Reset() {
+	*x = GetMerchantBalanceReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_merchant_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *GetMerchantBalanceReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetMerchantBalanceReply) ProtoMessage() {}
+
+func (x *GetMerchantBalanceReply) ProtoReflect() protoreflect.Message {
+	mi := &file_merchant_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetMerchantBalanceReply.ProtoReflect.Descriptor instead.
+func (*GetMerchantBalanceReply) Descriptor() ([]byte, []int) {
+	return file_merchant_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetMerchantBalanceReply) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *GetMerchantBalanceReply) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *GetMerchantBalanceReply) GetMerchantName() string {
+	if x != nil {
+		return x.MerchantName
+	}
+	return ""
+}
+
+func (x *GetMerchantBalanceReply) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *GetMerchantBalanceReply) GetLockedBalance() int64 {
+	if x != nil {
+		return x.LockedBalance
+	}
+	return 0
+}
+
+type MerchantOperation struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Id           uint64 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	OrderId      string `protobuf:"bytes,2,opt,name=orderId,proto3" json:"orderId,omitempty"`
+	MerchantName string `protobuf:"bytes,3,opt,name=merchantName,proto3" json:"merchantName,omitempty"`
+	Operation    string `protobuf:"bytes,4,opt,name=operation,proto3" json:"operation,omitempty"` // ADD_LOCKED_BALANCE, APPROVE_LOCKED_BALANCE, CANCEL_ADD_LOCKED_BALANCE
+	Amount       int64  `protobuf:"varint,5,opt,name=amount,proto3" json:"amount,omitempty"`
+	Result       string `protobuf:"bytes,6,opt,name=result,proto3" json:"result,omitempty"`        // APPLIED, DUPLICATE, EMPTY_ROLLBACK, REFUSED
+	Message      string `protobuf:"bytes,7,opt,name=message,proto3" json:"message,omitempty"`      // 请求被拒绝的原因
+	CreatedAt    int64  `protobuf:"varint,8,opt,name=createdAt,proto3" json:"createdAt,omitempty"` // unix毫秒时间
+}
+
+func (x *MerchantOperation) Reset() {
+	*x = MerchantOperation{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_merchant_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MerchantOperation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MerchantOperation) ProtoMessage() {}
+
+func (x *MerchantOperation) ProtoReflect() protoreflect.Message {
+	mi := &file_merchant_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MerchantOperation.ProtoReflect.Descriptor instead.
+func (*MerchantOperation) Descriptor() ([]byte, []int) {
+	return file_merchant_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *MerchantOperation) GetId() uint64 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *MerchantOperation) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *MerchantOperation) GetMerchantName() string {
+	if x != nil {
+		return x.MerchantName
+	}
+	return ""
+}
+
+func (x *MerchantOperation) GetOperation() string {
+	if x != nil {
+		return x.Operation
+	}
+	return ""
+}
+
+func (x *MerchantOperation) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *MerchantOperation) GetResult() string {
+	if x != nil {
+		return x.Result
+	}
+	return ""
+}
+
+func (x *MerchantOperation) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *MerchantOperation) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+type ListMerchantOperationsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MerchantName string `protobuf:"bytes,1,opt,name=merchantName,proto3" json:"merchantName,omitempty"` // 为空时不按商户过滤
+	OrderId      string `protobuf:"bytes,2,opt,name=orderId,proto3" json:"orderId,omitempty"`           // 为空时不按订单过滤
+	AfterId      uint64 `protobuf:"varint,3,opt,name=afterId,proto3" json:"afterId,omitempty"`          // 只返回id大于afterId的记录，第一页传0，之后传上一页的nextAfterId
+	Limit        int32  `protobuf:"varint,4,opt,name=limit,proto3" json:"limit,omitempty"`              // 每页数量，0表示使用默认值
+}
+
+func (x *ListMerchantOperationsRequest) Reset() {
+	*x = ListMerchantOperationsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_merchant_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMerchantOperationsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMerchantOperationsRequest) ProtoMessage() {}
+
+func (x *ListMerchantOperationsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_merchant_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMerchantOperationsRequest.ProtoReflect.Descriptor instead.
+func (*ListMerchantOperationsRequest) Descriptor() ([]byte, []int) {
+	return file_merchant_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListMerchantOperationsRequest) GetMerchantName() string {
+	if x != nil {
+		return x.MerchantName
+	}
+	return ""
+}
+
+func (x *ListMerchantOperationsRequest) GetOrderId() string {
+	if x != nil {
+		return x.OrderId
+	}
+	return ""
+}
+
+func (x *ListMerchantOperationsRequest) GetAfterId() uint64 {
+	if x != nil {
+		return x.AfterId
+	}
+	return 0
+}
+
+func (x *ListMerchantOperationsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListMerchantOperationsReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Success     bool                 `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	Message     string               `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	Operations  []*MerchantOperation `protobuf:"bytes,3,rep,name=operations,proto3" json:"operations,omitempty"` // 按id升序
+	NextAfterId uint64               `protobuf:"varint,4,opt,name=nextAfterId,proto3" json:"nextAfterId,omitempty"`
+	HasMore     bool                 `protobuf:"varint,5,opt,name=hasMore,proto3" json:"hasMore,omitempty"`
+}
+
+func (x *ListMerchantOperationsReply) Reset() {
+	*x = ListMerchantOperationsReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_merchant_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ListMerchantOperationsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMerchantOperationsReply) ProtoMessage() {}
+
+func (x *ListMerchantOperationsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_merchant_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMerchantOperationsReply.ProtoReflect.Descriptor instead.
+func (*ListMerchantOperationsReply) Descriptor() ([]byte, []int) {
+	return file_merchant_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListMerchantOperationsReply) GetSuccess() bool {
+	if x != nil {
+		return x.Success
+	}
+	return false
+}
+
+func (x *ListMerchantOperationsReply) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+func (x *ListMerchantOperationsReply) GetOperations() []*MerchantOperation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+func (x *ListMerchantOperationsReply) GetNextAfterId() uint64 {
+	if x != nil {
+		return x.NextAfterId
+	}
+	return 0
+}
+
+func (x *ListMerchantOperationsReply) GetHasMore() bool {
+	if x != nil {
+		return x.HasMore
+	}
+	return false
+}
+
 var File_merchant_proto protoreflect.FileDescriptor
 
 var file_merchant_proto_rawDesc = []byte{
@@ -424,29 +803,93 @@ var file_merchant_proto_rawDesc = []byte{
 	0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x18,
 	0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12, 0x18,
 	0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x32, 0xb0, 0x02, 0x0a, 0x08, 0x4d, 0x65, 0x72,
-	0x63, 0x68, 0x61, 0x6e, 0x74, 0x12, 0x56, 0x0a, 0x10, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b,
-	0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x21, 0x2e, 0x6d, 0x65, 0x72, 0x63,
-	0x68, 0x61, 0x6e, 0x74, 0x2e, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61,
-	0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x6d,
-	0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65,
-	0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x62, 0x0a,
-	0x14, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61,
-	0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x25, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74,
-	0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61,
-	0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x6d,
-	0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c,
-	0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x12, 0x68, 0x0a, 0x16, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x41, 0x64, 0x64, 0x4c, 0x6f,
-	0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x27, 0x2e, 0x6d, 0x65,
-	0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x41, 0x64, 0x64,
-	0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e,
-	0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42,
-	0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x27, 0x5a, 0x12, 0x2e,
-	0x3b, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63,
-	0x65, 0xaa, 0x02, 0x10, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x5f, 0x73, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x3f, 0x0a, 0x19, 0x47, 0x65, 0x74, 0x4d,
+	0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e,
+	0x74, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6d, 0x65, 0x72,
+	0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x22, 0xb1, 0x01, 0x0a, 0x17, 0x47, 0x65,
+	0x74, 0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73, 0x73, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x22, 0x0a, 0x0c, 0x6d, 0x65, 0x72,
+	0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a,
+	0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07,
+	0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x6c, 0x6f, 0x63, 0x6b, 0x65,
+	0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d,
+	0x6c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x22, 0xe7, 0x01,
+	0x0a, 0x11, 0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x0e, 0x0a, 0x02, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x04, 0x52,
+	0x02, 0x69, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x12, 0x22, 0x0a,
+	0x0c, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x0c, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d,
+	0x65, 0x12, 0x1c, 0x0a, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x6f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x16, 0x0a, 0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x06, 0x61, 0x6d, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c,
+	0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x72, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12,
+	0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x72, 0x65,
+	0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72,
+	0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x22, 0x8d, 0x01, 0x0a, 0x1d, 0x4c, 0x69, 0x73, 0x74,
+	0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x0c, 0x6d, 0x65, 0x72,
+	0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x0c, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4e, 0x61, 0x6d, 0x65, 0x12, 0x18, 0x0a,
+	0x07, 0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07,
+	0x6f, 0x72, 0x64, 0x65, 0x72, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x66, 0x74, 0x65, 0x72,
+	0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x61, 0x66, 0x74, 0x65, 0x72, 0x49,
+	0x64, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x22, 0xca, 0x01, 0x0a, 0x1b, 0x4c, 0x69, 0x73, 0x74,
+	0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65,
+	0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x73, 0x75, 0x63, 0x63, 0x65, 0x73,
+	0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x12, 0x3b, 0x0a, 0x0a, 0x6f,
+	0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1b, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x4d, 0x65, 0x72, 0x63, 0x68,
+	0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x0a, 0x6f, 0x70,
+	0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x20, 0x0a, 0x0b, 0x6e, 0x65, 0x78, 0x74,
+	0x41, 0x66, 0x74, 0x65, 0x72, 0x49, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6e,
+	0x65, 0x78, 0x74, 0x41, 0x66, 0x74, 0x65, 0x72, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07, 0x68, 0x61,
+	0x73, 0x4d, 0x6f, 0x72, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07, 0x68, 0x61, 0x73,
+	0x4d, 0x6f, 0x72, 0x65, 0x32, 0xf8, 0x03, 0x0a, 0x08, 0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e,
+	0x74, 0x12, 0x56, 0x0a, 0x10, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61,
+	0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x21, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74,
+	0x2e, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1f, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68,
+	0x61, 0x6e, 0x74, 0x2e, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c,
+	0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x62, 0x0a, 0x14, 0x41, 0x70, 0x70,
+	0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63,
+	0x65, 0x12, 0x25, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x41, 0x70, 0x70,
+	0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63,
+	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x23, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68,
+	0x61, 0x6e, 0x74, 0x2e, 0x41, 0x70, 0x70, 0x72, 0x6f, 0x76, 0x65, 0x4c, 0x6f, 0x63, 0x6b, 0x65,
+	0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x68, 0x0a,
+	0x16, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64,
+	0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x27, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61,
+	0x6e, 0x74, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b,
+	0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x25, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x43, 0x61, 0x6e, 0x63,
+	0x65, 0x6c, 0x41, 0x64, 0x64, 0x4c, 0x6f, 0x63, 0x6b, 0x65, 0x64, 0x42, 0x61, 0x6c, 0x61, 0x6e,
+	0x63, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x5c, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x4d, 0x65,
+	0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x23, 0x2e,
+	0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x47, 0x65, 0x74, 0x4d, 0x65, 0x72, 0x63,
+	0x68, 0x61, 0x6e, 0x74, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x21, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x47, 0x65,
+	0x74, 0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x42, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x68, 0x0a, 0x16, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x72,
+	0x63, 0x68, 0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12,
+	0x27, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d,
+	0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x25, 0x2e, 0x6d, 0x65, 0x72, 0x63, 0x68,
+	0x61, 0x6e, 0x74, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x4d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74,
+	0x4f, 0x70, 0x65, 0x72, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42,
+	0x27, 0x5a, 0x12, 0x2e, 0x3b, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74, 0x5f, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0xaa, 0x02, 0x10, 0x6d, 0x65, 0x72, 0x63, 0x68, 0x61, 0x6e, 0x74,
+	0x5f, 0x73, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -461,7 +904,7 @@ func file_merchant_proto_rawDescGZIP() []byte {
 	return file_merchant_proto_rawDescData
 }
 
-var file_merchant_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_merchant_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_merchant_proto_goTypes = []interface{}{
 	(*AddLockedBalanceRequest)(nil),       // 0: merchant.AddLockedBalanceRequest
 	(*AddLockedBalanceReply)(nil),         // 1: merchant.AddLockedBalanceReply
@@ -469,19 +912,29 @@ var file_merchant_proto_goTypes = []interface{}{
 	(*ApproveLockedBalanceReply)(nil),     // 3: merchant.ApproveLockedBalanceReply
 	(*CancelAddLockedBalanceRequest)(nil), // 4: merchant.CancelAddLockedBalanceRequest
 	(*CancelAddLockedBalanceReply)(nil),   // 5: merchant.CancelAddLockedBalanceReply
+	(*GetMerchantBalanceRequest)(nil),     // 6: merchant.GetMerchantBalanceRequest
+	(*GetMerchantBalanceReply)(nil),       // 7: merchant.GetMerchantBalanceReply
+	(*MerchantOperation)(nil),             // 8: merchant.MerchantOperation
+	(*ListMerchantOperationsRequest)(nil), // 9: merchant.ListMerchantOperationsRequest
+	(*ListMerchantOperationsReply)(nil),   // 10: merchant.ListMerchantOperationsReply
 }
 var file_merchant_proto_depIdxs = []int32{
-	0, // 0: merchant.Merchant.AddLockedBalance:input_type -> merchant.AddLockedBalanceRequest
-	2, // 1: merchant.Merchant.ApproveLockedBalance:input_type -> merchant.ApproveLockedBalanceRequest
-	4, // 2: merchant.Merchant.CancelAddLockedBalance:input_type -> merchant.CancelAddLockedBalanceRequest
-	1, // 3: merchant.Merchant.AddLockedBalance:output_type -> merchant.AddLockedBalanceReply
-	3, // 4: merchant.Merchant.ApproveLockedBalance:output_type -> merchant.ApproveLockedBalanceReply
-	5, // 5: merchant.Merchant.CancelAddLockedBalance:output_type -> merchant.CancelAddLockedBalanceReply
-	3, // [3:6] is the sub-list for method output_type
-	0, // [0:3] is the sub-list for method input_type
-	0, // [0:0] is the sub-list for extension type_name
-	0, // [0:0] is the sub-list for extension extendee
-	0, // [0:0] is the sub-list for field type_name
+	8,  // 0: merchant.ListMerchantOperationsReply.operations:type_name -> merchant.MerchantOperation
+	0,  // 1: merchant.Merchant.AddLockedBalance:input_type -> merchant.AddLockedBalanceRequest
+	2,  // 2: merchant.Merchant.ApproveLockedBalance:input_type -> merchant.ApproveLockedBalanceRequest
+	4,  // 3: merchant.Merchant.CancelAddLockedBalance:input_type -> merchant.CancelAddLockedBalanceRequest
+	6,  // 4: merchant.Merchant.GetMerchantBalance:input_type -> merchant.GetMerchantBalanceRequest
+	9,  // 5: merchant.Merchant.ListMerchantOperations:input_type -> merchant.ListMerchantOperationsRequest
+	1,  // 6: merchant.Merchant.AddLockedBalance:output_type -> merchant.AddLockedBalanceReply
+	3,  // 7: merchant.Merchant.ApproveLockedBalance:output_type -> merchant.ApproveLockedBalanceReply
+	5,  // 8: merchant.Merchant.CancelAddLockedBalance:output_type -> merchant.CancelAddLockedBalanceReply
+	7,  // 9: merchant.Merchant.GetMerchantBalance:output_type -> merchant.GetMerchantBalanceReply
+	10, // 10: merchant.Merchant.ListMerchantOperations:output_type -> merchant.ListMerchantOperationsReply
+	6,  // [6:11] is the sub-list for method output_type
+	1,  // [1:6] is the sub-list for method input_type
+	1,  // [1:1] is the sub-list for extension type_name
+	1,  // [1:1] is the sub-list for extension extendee
+	0,  // [0:1] is the sub-list for field type_name
 }
 
 func init() { file_merchant_proto_init() }
@@ -562,6 +1015,66 @@ func file_merchant_proto_init() {
 				return nil
 			}
 		}
+		file_merchant_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetMerchantBalanceRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_merchant_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetMerchantBalanceReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_merchant_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MerchantOperation); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_merchant_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMerchantOperationsRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_merchant_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListMerchantOperationsReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -569,7 +1082,7 @@ func file_merchant_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_merchant_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   6,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -598,6 +1111,10 @@ type MerchantClient interface {
 	AddLockedBalance(ctx context.Context, in *AddLockedBalanceRequest, opts ...grpc.CallOption) (*AddLockedBalanceReply, error)
 	ApproveLockedBalance(ctx context.Context, in *ApproveLockedBalanceRequest, opts ...grpc.CallOption) (*ApproveLockedBalanceReply, error)
 	CancelAddLockedBalance(ctx context.Context, in *CancelAddLockedBalanceRequest, opts ...grpc.CallOption) (*CancelAddLockedBalanceReply, error)
+	// 查询商户的可用余额和锁定余额，用于saga失败后的对账
+	GetMerchantBalance(ctx context.Context, in *GetMerchantBalanceRequest, opts ...grpc.CallOption) (*GetMerchantBalanceReply, error)
+	// 分页查询商户账本的操作记录
+	ListMerchantOperations(ctx context.Context, in *ListMerchantOperationsRequest, opts ...grpc.CallOption) (*ListMerchantOperationsReply, error)
 }
 
 type merchantClient struct {
@@ -635,11 +1152,33 @@ func (c *merchantClient) CancelAddLockedBalance(ctx context.Context, in *CancelA
 	return out, nil
 }
 
+func (c *merchantClient) GetMerchantBalance(ctx context.Context, in *GetMerchantBalanceRequest, opts ...grpc.CallOption) (*GetMerchantBalanceReply, error) {
+	out := new(GetMerchantBalanceReply)
+	err := c.cc.Invoke(ctx, "/merchant.Merchant/GetMerchantBalance", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *merchantClient) ListMerchantOperations(ctx context.Context, in *ListMerchantOperationsRequest, opts ...grpc.CallOption) (*ListMerchantOperationsReply, error) {
+	out := new(ListMerchantOperationsReply)
+	err := c.cc.Invoke(ctx, "/merchant.Merchant/ListMerchantOperations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // MerchantServer is the server API for Merchant service.
 type MerchantServer interface {
 	AddLockedBalance(context.Context, *AddLockedBalanceRequest) (*AddLockedBalanceReply, error)
 	ApproveLockedBalance(context.Context, *ApproveLockedBalanceRequest) (*ApproveLockedBalanceReply, error)
 	CancelAddLockedBalance(context.Context, *CancelAddLockedBalanceRequest) (*CancelAddLockedBalanceReply, error)
+	// 查询商户的可用余额和锁定余额，用于saga失败后的对账
+	GetMerchantBalance(context.Context, *GetMerchantBalanceRequest) (*GetMerchantBalanceReply, error)
+	// 分页查询商户账本的操作记录
+	ListMerchantOperations(context.Context, *ListMerchantOperationsRequest) (*ListMerchantOperationsReply, error)
 }
 
 // UnimplementedMerchantServer can be embedded to have forward compatible implementations.
@@ -655,6 +1194,12 @@ func (*UnimplementedMerchantServer) ApproveLockedBalance(context.Context, *Appro
 func (*UnimplementedMerchantServer) CancelAddLockedBalance(context.Context, *CancelAddLockedBalanceRequest) (*CancelAddLockedBalanceReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CancelAddLockedBalance not implemented")
 }
+func (*UnimplementedMerchantServer) GetMerchantBalance(context.Context, *GetMerchantBalanceRequest) (*GetMerchantBalanceReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetMerchantBalance not implemented")
+}
+func (*UnimplementedMerchantServer) ListMerchantOperations(context.Context, *ListMerchantOperationsRequest) (*ListMerchantOperationsReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMerchantOperations not implemented")
+}
 
 func RegisterMerchantServer(s *grpc.Server, srv MerchantServer) {
 	s.RegisterService(&_Merchant_serviceDesc, srv)
@@ -714,6 +1259,42 @@ func _Merchant_CancelAddLockedBalance_Handler(srv interface{}, ctx context.Conte
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Merchant_GetMerchantBalance_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMerchantBalanceRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerchantServer).GetMerchantBalance(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/merchant.Merchant/GetMerchantBalance",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerchantServer).GetMerchantBalance(ctx, req.(*GetMerchantBalanceRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Merchant_ListMerchantOperations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMerchantOperationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MerchantServer).ListMerchantOperations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/merchant.Merchant/ListMerchantOperations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MerchantServer).ListMerchantOperations(ctx, req.(*ListMerchantOperationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _Merchant_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "merchant.Merchant",
 	HandlerType: (*MerchantServer)(nil),
@@ -730,6 +1311,14 @@ var _Merchant_serviceDesc = grpc.ServiceDesc{
 			MethodName: "CancelAddLockedBalance",
 			Handler:    _Merchant_CancelAddLockedBalance_Handler,
 		},
+		{
+			MethodName: "GetMerchantBalance",
+			Handler:    _Merchant_GetMerchantBalance_Handler,
+		},
+		{
+			MethodName: "ListMerchantOperations",
+			Handler:    _Merchant_ListMerchantOperations_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "merchant.proto",
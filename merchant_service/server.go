@@ -6,46 +6,132 @@ import (
 	consulapi "github.com/hashicorp/consul/api"
 	grpc "google.golang.org/grpc"
 	"log"
+	"merchant_server/ledger"
 	pb "merchant_server/merchant_service"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
+	"time"
 )
 
 const (
 	address = ":5003"
 	port    = 5003
 	network = "tcp"
+
+	defaultDbPath = "merchant_service.db"
+
+	defaultOperationsPageSize = 100
+	maxOperationsPageSize     = 1000
 )
 
 type MerchantService struct {
 	pb.UnimplementedMerchantServer
+	ledger *ledger.Ledger
+}
+
+func NewMerchantService(merchantLedger *ledger.Ledger) *MerchantService {
+	return &MerchantService{ledger: merchantLedger}
 }
 
 func (s *MerchantService) AddLockedBalance(ctx context.Context,
 	req *pb.AddLockedBalanceRequest) (res *pb.AddLockedBalanceReply, err error) {
-	// TODO
-	log.Println("AddLockedBalance")
+	log.Printf("AddLockedBalance order %s merchant %s amount %d\n", req.OrderId, req.MerchantName, req.Amount)
+	lockErr := s.ledger.AddLockedBalance(ctx, req.OrderId, req.MerchantName, req.Amount)
+	if lockErr != nil {
+		res = &pb.AddLockedBalanceReply{Success: false, Message: lockErr.Error()}
+		return
+	}
 	res = &pb.AddLockedBalanceReply{Success: true, Message: "success"}
 	return
 }
 
 func (s *MerchantService) ApproveLockedBalance(ctx context.Context,
 	req *pb.ApproveLockedBalanceRequest) (res *pb.ApproveLockedBalanceReply, err error) {
-	// TODO
-	log.Println("ApproveLockedBalance")
+	log.Printf("ApproveLockedBalance order %s merchant %s amount %d\n", req.OrderId, req.MerchantName, req.Amount)
+	approveErr := s.ledger.ApproveLockedBalance(ctx, req.OrderId, req.MerchantName, req.Amount)
+	if approveErr != nil {
+		res = &pb.ApproveLockedBalanceReply{Success: false, Message: approveErr.Error()}
+		return
+	}
 	res = &pb.ApproveLockedBalanceReply{Success: true, Message: "done"}
 	return
 }
 
 func (s *MerchantService) CancelAddLockedBalance(ctx context.Context,
 	req *pb.CancelAddLockedBalanceRequest) (res *pb.CancelAddLockedBalanceReply, err error) {
-	// TODO
-	log.Println("CancelAddLockedBalance")
+	log.Printf("CancelAddLockedBalance order %s merchant %s amount %d\n", req.OrderId, req.MerchantName, req.Amount)
+	cancelErr := s.ledger.CancelAddLockedBalance(ctx, req.OrderId, req.MerchantName, req.Amount)
+	if cancelErr != nil {
+		res = &pb.CancelAddLockedBalanceReply{Success: false, Message: cancelErr.Error()}
+		return
+	}
 	res = &pb.CancelAddLockedBalanceReply{Success: true, Message: "cancel done"}
 	return
 }
 
+func (s *MerchantService) GetMerchantBalance(ctx context.Context,
+	req *pb.GetMerchantBalanceRequest) (res *pb.GetMerchantBalanceReply, err error) {
+	if len(req.MerchantName) < 1 {
+		res = &pb.GetMerchantBalanceReply{Success: false, Message: "merchantName can't be empty"}
+		return
+	}
+	balance, queryErr := s.ledger.GetMerchantBalance(ctx, req.MerchantName)
+	if queryErr != nil {
+		res = &pb.GetMerchantBalanceReply{Success: false, Message: queryErr.Error()}
+		return
+	}
+	res = &pb.GetMerchantBalanceReply{
+		Success:       true,
+		Message:       "success",
+		MerchantName:  balance.MerchantName,
+		Balance:       balance.Balance,
+		LockedBalance: balance.LockedBalance,
+	}
+	return
+}
+
+func (s *MerchantService) ListMerchantOperations(ctx context.Context,
+	req *pb.ListMerchantOperationsRequest) (res *pb.ListMerchantOperationsReply, err error) {
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultOperationsPageSize
+	}
+	if limit > maxOperationsPageSize {
+		limit = maxOperationsPageSize
+	}
+	// 多查一条用来判断是否还有下一页
+	records, queryErr := s.ledger.ListOperations(ctx, req.MerchantName, req.OrderId, req.AfterId, limit+1)
+	if queryErr != nil {
+		res = &pb.ListMerchantOperationsReply{Success: false, Message: queryErr.Error()}
+		return
+	}
+	res = &pb.ListMerchantOperationsReply{
+		Success:     true,
+		Message:     "success",
+		NextAfterId: req.AfterId,
+	}
+	if int32(len(records)) > limit {
+		records = records[:limit]
+		res.HasMore = true
+	}
+	for _, record := range records {
+		res.Operations = append(res.Operations, &pb.MerchantOperation{
+			Id:           record.Id,
+			OrderId:      record.OrderId,
+			MerchantName: record.MerchantName,
+			Operation:    record.Operation,
+			Amount:       record.Amount,
+			Result:       record.Result,
+			Message:      record.Message,
+			CreatedAt:    record.CreatedAt.UnixNano() / int64(time.Millisecond),
+		})
+		res.NextAfterId = record.Id
+	}
+	return
+}
+
 var count int64
 
 // consul 服务端会自己发送请求，来进行健康检查
@@ -114,14 +200,29 @@ func localIP() string {
 	return ""
 }
 
+// 账本sqlite文件路径，可以通过环境变量MERCHANT_DB_PATH配置
+func getDbPath() string {
+	dbPath := os.Getenv("MERCHANT_DB_PATH")
+	if len(dbPath) > 0 {
+		return dbPath
+	}
+	return defaultDbPath
+}
+
 func main() {
+	store, err := ledger.NewSqliteStore(getDbPath())
+	if err != nil {
+		log.Fatalf("open ledger store err: %v", err)
+	}
+	defer store.Close()
+
 	listener, err := net.Listen(network, address)
 	if err != nil {
 		log.Fatalf("net.Listen err: %v", err)
 	}
 	log.Println(address + " net.Listing...")
 	grpcServer := grpc.NewServer()
-	pb.RegisterMerchantServer(grpcServer, &MerchantService{})
+	pb.RegisterMerchantServer(grpcServer, NewMerchantService(ledger.NewLedger(store)))
 
 	registerServer()
 
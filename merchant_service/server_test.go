@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"io/ioutil"
+	"log"
+	"merchant_server/ledger"
+	pb "merchant_server/merchant_service"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var (
+	// 测试用的进程内merchant service地址和账本文件
+	testAddress string
+	testDbPath  string
+)
+
+// 在进程内启动使用临时sqlite文件的merchant service，不注册consul
+func TestMain(m *testing.M) {
+	dir, err := ioutil.TempDir("", "merchant_service_test")
+	if err != nil {
+		log.Fatalf("create temp dir err: %v", err)
+	}
+	testDbPath = filepath.Join(dir, "merchant_service.db")
+	store, err := ledger.NewSqliteStore(testDbPath)
+	if err != nil {
+		log.Fatalf("open ledger store err: %v", err)
+	}
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("net.Listen err: %v", err)
+	}
+	testAddress = listener.Addr().String()
+	grpcServer := grpc.NewServer()
+	pb.RegisterMerchantServer(grpcServer, NewMerchantService(ledger.NewLedger(store)))
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	code := m.Run()
+	grpcServer.Stop()
+	_ = store.Close()
+	_ = os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newTestMerchantClient(t *testing.T) (client pb.MerchantClient, closeFn func()) {
+	conn, err := grpc.Dial(testAddress, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	client = pb.NewMerchantClient(conn)
+	closeFn = func() {
+		_ = conn.Close()
+	}
+	return
+}
+
+// 直接打开同一个sqlite文件读取账本，验证数据已经持久化
+func openTestLedger(t *testing.T) (merchantLedger *ledger.Ledger, closeFn func()) {
+	store, err := ledger.NewSqliteStore(testDbPath)
+	if err != nil {
+		t.Fatalf("open ledger store err: %v", err)
+	}
+	merchantLedger = ledger.NewLedger(store)
+	closeFn = func() {
+		_ = store.Close()
+	}
+	return
+}
+
+func queryTestMerchantBalance(t *testing.T, merchantName string) *ledger.MerchantBalanceEntity {
+	merchantLedger, closeFn := openTestLedger(t)
+	defer closeFn()
+	balance, err := merchantLedger.GetMerchantBalance(context.Background(), merchantName)
+	if err != nil {
+		t.Fatalf("GetMerchantBalance err: %v", err)
+	}
+	return balance
+}
+
+func assertTestMerchantBalance(t *testing.T, merchantName string, balance int64, lockedBalance int64) {
+	record := queryTestMerchantBalance(t, merchantName)
+	if record.Balance != balance || record.LockedBalance != lockedBalance {
+		t.Errorf("merchant %s balance should be %d locked %d but got %d locked %d",
+			merchantName, balance, lockedBalance, record.Balance, record.LockedBalance)
+	}
+}
+
+func TestOrderApprovedLifecycle(t *testing.T) {
+	client, closeFn := newTestMerchantClient(t)
+	defer closeFn()
+	ctx := context.Background()
+	merchantName := "approveMerchant"
+
+	addReply, err := client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+		OrderId: "order1", MerchantName: merchantName, Amount: 100,
+	})
+	if err != nil || !addReply.Success {
+		t.Fatalf("AddLockedBalance failed %v %v", addReply, err)
+	}
+	assertTestMerchantBalance(t, merchantName, 0, 100)
+
+	addReply, err = client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+		OrderId: "order2", MerchantName: merchantName, Amount: 30,
+	})
+	if err != nil || !addReply.Success {
+		t.Fatalf("AddLockedBalance failed %v %v", addReply, err)
+	}
+	assertTestMerchantBalance(t, merchantName, 0, 130)
+
+	approveReply, err := client.ApproveLockedBalance(ctx, &pb.ApproveLockedBalanceRequest{
+		OrderId: "order1", MerchantName: merchantName, Amount: 100,
+	})
+	if err != nil || !approveReply.Success {
+		t.Fatalf("ApproveLockedBalance failed %v %v", approveReply, err)
+	}
+	assertTestMerchantBalance(t, merchantName, 100, 30)
+
+	cancelReply, err := client.CancelAddLockedBalance(ctx, &pb.CancelAddLockedBalanceRequest{
+		OrderId: "order1", MerchantName: merchantName, Amount: 100,
+	})
+	if err != nil {
+		t.Fatalf("CancelAddLockedBalance err: %v", err)
+	}
+	if cancelReply.Success {
+		t.Errorf("cancel approved order should fail")
+	}
+	assertTestMerchantBalance(t, merchantName, 100, 30)
+}
+
+func TestOrderCanceledLifecycle(t *testing.T) {
+	client, closeFn := newTestMerchantClient(t)
+	defer closeFn()
+	ctx := context.Background()
+	merchantName := "cancelMerchant"
+
+	addReply, err := client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+		OrderId: "order3", MerchantName: merchantName, Amount: 50,
+	})
+	if err != nil || !addReply.Success {
+		t.Fatalf("AddLockedBalance failed %v %v", addReply, err)
+	}
+	addReply, err = client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+		OrderId: "order3", MerchantName: merchantName, Amount: 50,
+	})
+	if err != nil || !addReply.Success {
+		t.Fatalf("repeated AddLockedBalance should succeed %v %v", addReply, err)
+	}
+	// 重复的锁定请求不会重复锁定金额
+	assertTestMerchantBalance(t, merchantName, 0, 50)
+
+	cancelReply, err := client.CancelAddLockedBalance(ctx, &pb.CancelAddLockedBalanceRequest{
+		OrderId: "order3", MerchantName: merchantName, Amount: 50,
+	})
+	if err != nil || !cancelReply.Success {
+		t.Fatalf("CancelAddLockedBalance failed %v %v", cancelReply, err)
+	}
+	assertTestMerchantBalance(t, merchantName, 0, 0)
+
+	approveReply, err := client.ApproveLockedBalance(ctx, &pb.ApproveLockedBalanceRequest{
+		OrderId: "order3", MerchantName: merchantName, Amount: 50,
+	})
+	if err != nil {
+		t.Fatalf("ApproveLockedBalance err: %v", err)
+	}
+	if approveReply.Success {
+		t.Errorf("approve canceled order should fail")
+	}
+	assertTestMerchantBalance(t, merchantName, 0, 0)
+}
+
+// 补偿先于锁定请求到达时记录空回滚，迟到的锁定请求被拒绝
+func TestOrderEmptyRollbackRefusesLateLock(t *testing.T) {
+	client, closeFn := newTestMerchantClient(t)
+	defer closeFn()
+	ctx := context.Background()
+	merchantName := "emptyRollbackMerchant"
+
+	for i := 0; i < 2; i++ {
+		cancelReply, err := client.CancelAddLockedBalance(ctx, &pb.CancelAddLockedBalanceRequest{
+			OrderId: "order4", MerchantName: merchantName, Amount: 20,
+		})
+		if err != nil || !cancelReply.Success {
+			t.Fatalf("CancelAddLockedBalance failed %v %v", cancelReply, err)
+		}
+	}
+	addReply, err := client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+		OrderId: "order4", MerchantName: merchantName, Amount: 20,
+	})
+	if err != nil {
+		t.Fatalf("AddLockedBalance err: %v", err)
+	}
+	if addReply.Success {
+		t.Errorf("lock balance after empty rollback should be refused")
+	}
+	assertTestMerchantBalance(t, merchantName, 0, 0)
+
+	merchantLedger, closeLedger := openTestLedger(t)
+	defer closeLedger()
+	lock, err := merchantLedger.GetOrderLock(ctx, "order4")
+	if err != nil {
+		t.Fatalf("GetOrderLock err: %v", err)
+	}
+	if lock == nil || lock.State != ledger.OrderLockEmptyRollback {
+		t.Errorf("order should have an empty rollback record but got %v", lock)
+	}
+	operations, err := merchantLedger.ListOrderOperations(ctx, "order4")
+	if err != nil {
+		t.Fatalf("ListOrderOperations err: %v", err)
+	}
+	expectedResults := []string{
+		ledger.OperationResultEmptyRollback, ledger.OperationResultDuplicate, ledger.OperationResultRefused,
+	}
+	if len(operations) != len(expectedResults) {
+		t.Fatalf("order should have %d operations but got %d", len(expectedResults), len(operations))
+	}
+	for i, operation := range operations {
+		if operation.Result != expectedResults[i] {
+			t.Errorf("operation %d result should be %s but got %s", i, expectedResults[i], operation.Result)
+		}
+	}
+	if operations[2].Operation != ledger.OperationAddLockedBalance || len(operations[2].Message) < 1 {
+		t.Errorf("unexpected refused operation %v", operations[2])
+	}
+}
+
+func TestMerchantBalanceAndOperationsQuery(t *testing.T) {
+	client, closeFn := newTestMerchantClient(t)
+	defer closeFn()
+	ctx := context.Background()
+	merchantName := "queryMerchant"
+
+	for _, orderId := range []string{"order5", "order6"} {
+		addReply, err := client.AddLockedBalance(ctx, &pb.AddLockedBalanceRequest{
+			OrderId: orderId, MerchantName: merchantName, Amount: 40,
+		})
+		if err != nil || !addReply.Success {
+			t.Fatalf("AddLockedBalance failed %v %v", addReply, err)
+		}
+	}
+	approveReply, err := client.ApproveLockedBalance(ctx, &pb.ApproveLockedBalanceRequest{
+		OrderId: "order5", MerchantName: merchantName, Amount: 40,
+	})
+	if err != nil || !approveReply.Success {
+		t.Fatalf("ApproveLockedBalance failed %v %v", approveReply, err)
+	}
+	cancelReply, err := client.CancelAddLockedBalance(ctx, &pb.CancelAddLockedBalanceRequest{
+		OrderId: "order6", MerchantName: merchantName, Amount: 40,
+	})
+	if err != nil || !cancelReply.Success {
+		t.Fatalf("CancelAddLockedBalance failed %v %v", cancelReply, err)
+	}
+
+	balanceReply, err := client.GetMerchantBalance(ctx, &pb.GetMerchantBalanceRequest{MerchantName: merchantName})
+	if err != nil || !balanceReply.Success {
+		t.Fatalf("GetMerchantBalance failed %v %v", balanceReply, err)
+	}
+	if balanceReply.Balance != 40 || balanceReply.LockedBalance != 0 {
+		t.Errorf("unexpected merchant balance %v", balanceReply)
+	}
+	balanceReply, err = client.GetMerchantBalance(ctx, &pb.GetMerchantBalanceRequest{})
+	if err != nil {
+		t.Fatalf("GetMerchantBalance err: %v", err)
+	}
+	if balanceReply.Success {
+		t.Errorf("query balance without merchantName should fail")
+	}
+
+	// 按商户分页查询，每页2条
+	var operations []*pb.MerchantOperation
+	var afterId uint64
+	for page := 0; ; page++ {
+		listReply, err := client.ListMerchantOperations(ctx, &pb.ListMerchantOperationsRequest{
+			MerchantName: merchantName,
+			AfterId:      afterId,
+			Limit:        2,
+		})
+		if err != nil || !listReply.Success {
+			t.Fatalf("ListMerchantOperations failed %v %v", listReply, err)
+		}
+		if len(listReply.Operations) > 2 {
+			t.Fatalf("page size should be at most 2 but got %d", len(listReply.Operations))
+		}
+		operations = append(operations, listReply.Operations...)
+		afterId = listReply.NextAfterId
+		if !listReply.HasMore {
+			break
+		}
+		if page > 10 {
+			t.Fatalf("too many pages")
+		}
+	}
+	if len(operations) != 4 {
+		t.Fatalf("merchant should have 4 operations but got %d", len(operations))
+	}
+	for i := 1; i < len(operations); i++ {
+		if operations[i].Id <= operations[i-1].Id {
+			t.Errorf("operations should be ordered by id")
+		}
+	}
+
+	listReply, err := client.ListMerchantOperations(ctx, &pb.ListMerchantOperationsRequest{
+		MerchantName: merchantName,
+		OrderId:      "order6",
+	})
+	if err != nil || !listReply.Success {
+		t.Fatalf("ListMerchantOperations failed %v %v", listReply, err)
+	}
+	if len(listReply.Operations) != 2 || listReply.HasMore ||
+		listReply.Operations[0].Operation != ledger.OperationAddLockedBalance ||
+		listReply.Operations[1].Operation != ledger.OperationCancelAddLockedBalance {
+		t.Errorf("unexpected operations of order6 %v", listReply.Operations)
+	}
+}
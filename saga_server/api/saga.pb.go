@@ -38,6 +38,9 @@ const (
 	TxState_COMPENSATION_ERROR TxState = 3 // 补偿任务某次执行失败
 	TxState_COMPENSATION_DONE  TxState = 4 // 补偿任务执行完成
 	TxState_COMPENSATION_FAIL  TxState = 5 // 补偿任务多次执行过程整体失败
+	TxState_RETRYING           TxState = 6 // 向前恢复的分支执行失败，等待重试直到COMMITTED
+	TxState_CONFIRMING         TxState = 7 // TCC模式下全局事务提交后，等待分支confirm(confirm失败会重试直到成功)
+	TxState_CONFIRMED          TxState = 8 // TCC模式下分支confirm成功
 )
 
 // Enum value maps for TxState.
@@ -49,6 +52,9 @@ var (
 		3: "COMPENSATION_ERROR",
 		4: "COMPENSATION_DONE",
 		5: "COMPENSATION_FAIL",
+		6: "RETRYING",
+		7: "CONFIRMING",
+		8: "CONFIRMED",
 	}
 	TxState_value = map[string]int32{
 		"PROCESSING":         0,
@@ -57,6 +63,9 @@ var (
 		"COMPENSATION_ERROR": 3,
 		"COMPENSATION_DONE":  4,
 		"COMPENSATION_FAIL":  5,
+		"RETRYING":           6,
+		"CONFIRMING":         7,
+		"CONFIRMED":          8,
 	}
 )
 
@@ -87,6 +96,150 @@ func (TxState) EnumDescriptor() ([]byte, []int) {
 	return file_protos_saga_proto_rawDescGZIP(), []int{0}
 }
 
+// 全局事务的模式
+type TransactionMode int32
+
+const (
+	TransactionMode_SAGA TransactionMode = 0 // 分支执行成功即生效，失败时补偿
+	TransactionMode_TCC  TransactionMode = 1 // 分支执行的是try，全局事务提交时再对每个分支执行confirm，回滚时执行cancel(补偿)
+)
+
+// Enum value maps for TransactionMode.
+var (
+	TransactionMode_name = map[int32]string{
+		0: "SAGA",
+		1: "TCC",
+	}
+	TransactionMode_value = map[string]int32{
+		"SAGA": 0,
+		"TCC":  1,
+	}
+)
+
+func (x TransactionMode) Enum() *TransactionMode {
+	p := new(TransactionMode)
+	*p = x
+	return p
+}
+
+func (x TransactionMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (TransactionMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_saga_proto_enumTypes[1].Descriptor()
+}
+
+func (TransactionMode) Type() protoreflect.EnumType {
+	return &file_protos_saga_proto_enumTypes[1]
+}
+
+func (x TransactionMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use TransactionMode.Descriptor instead.
+func (TransactionMode) EnumDescriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{1}
+}
+
+// 分支失败时的恢复方式
+type RecoveryMode int32
+
+const (
+	RecoveryMode_BACKWARD RecoveryMode = 0 // 向后恢复，分支失败时回滚全局事务并补偿
+	RecoveryMode_FORWARD  RecoveryMode = 1 // 向前恢复，分支失败时按重试策略的间隔重试直到成功，不回滚全局事务
+)
+
+// Enum value maps for RecoveryMode.
+var (
+	RecoveryMode_name = map[int32]string{
+		0: "BACKWARD",
+		1: "FORWARD",
+	}
+	RecoveryMode_value = map[string]int32{
+		"BACKWARD": 0,
+		"FORWARD":  1,
+	}
+)
+
+func (x RecoveryMode) Enum() *RecoveryMode {
+	p := new(RecoveryMode)
+	*p = x
+	return p
+}
+
+func (x RecoveryMode) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (RecoveryMode) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_saga_proto_enumTypes[2].Descriptor()
+}
+
+func (RecoveryMode) Type() protoreflect.EnumType {
+	return &file_protos_saga_proto_enumTypes[2]
+}
+
+func (x RecoveryMode) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use RecoveryMode.Descriptor instead.
+func (RecoveryMode) EnumDescriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{2}
+}
+
+// 参与方通过租约领取的任务类型
+type WorkType int32
+
+const (
+	WorkType_COMPENSATION_WORK  WorkType = 0 // 补偿待补偿的分支
+	WorkType_FORWARD_RETRY_WORK WorkType = 1 // 重试等待重试的向前恢复分支
+	WorkType_CONFIRM_WORK       WorkType = 2 // 执行TCC全局事务中等待confirm的分支
+)
+
+// Enum value maps for WorkType.
+var (
+	WorkType_name = map[int32]string{
+		0: "COMPENSATION_WORK",
+		1: "FORWARD_RETRY_WORK",
+		2: "CONFIRM_WORK",
+	}
+	WorkType_value = map[string]int32{
+		"COMPENSATION_WORK":  0,
+		"FORWARD_RETRY_WORK": 1,
+		"CONFIRM_WORK":       2,
+	}
+)
+
+func (x WorkType) Enum() *WorkType {
+	p := new(WorkType)
+	*p = x
+	return p
+}
+
+func (x WorkType) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (WorkType) Descriptor() protoreflect.EnumDescriptor {
+	return file_protos_saga_proto_enumTypes[3].Descriptor()
+}
+
+func (WorkType) Type() protoreflect.EnumType {
+	return &file_protos_saga_proto_enumTypes[3]
+}
+
+func (x WorkType) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use WorkType.Descriptor instead.
+func (WorkType) EnumDescriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{3}
+}
+
 type NodeInfo struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -150,20 +303,94 @@ func (x *NodeInfo) GetInstanceId() string {
 	return ""
 }
 
+// 分支补偿失败后的重试策略，字段为0时使用上一级(全局事务或者server默认)的配置
+type RetryPolicy struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	MaxAttempts    int32   `protobuf:"varint,1,opt,name=maxAttempts,proto3" json:"maxAttempts,omitempty"`       // 补偿最多执行的次数，达到后分支标记为COMPENSATION_FAIL
+	InitialDelayMs int64   `protobuf:"varint,2,opt,name=initialDelayMs,proto3" json:"initialDelayMs,omitempty"` // 第一次补偿失败后等待多少毫秒再重试
+	Multiplier     float64 `protobuf:"fixed64,3,opt,name=multiplier,proto3" json:"multiplier,omitempty"`        // 之后每次失败等待时间的增长倍数
+	MaxDelayMs     int64   `protobuf:"varint,4,opt,name=maxDelayMs,proto3" json:"maxDelayMs,omitempty"`         // 等待时间的上限毫秒数
+}
+
+func (x *RetryPolicy) Reset() {
+	*x = RetryPolicy{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RetryPolicy) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RetryPolicy) ProtoMessage() {}
+
+func (x *RetryPolicy) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RetryPolicy.ProtoReflect.Descriptor instead.
+func (*RetryPolicy) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *RetryPolicy) GetMaxAttempts() int32 {
+	if x != nil {
+		return x.MaxAttempts
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetInitialDelayMs() int64 {
+	if x != nil {
+		return x.InitialDelayMs
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMultiplier() float64 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *RetryPolicy) GetMaxDelayMs() int64 {
+	if x != nil {
+		return x.MaxDelayMs
+	}
+	return 0
+}
+
 type CreateGlobalTransactionRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Node          *NodeInfo `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
-	ExpireSeconds int64     `protobuf:"varint,2,opt,name=expireSeconds,proto3" json:"expireSeconds,omitempty"` // tx expire after {expireSeconds} seconds
-	Extra         string    `protobuf:"bytes,3,opt,name=extra,proto3" json:"extra,omitempty"`                  // extra info
+	Node          *NodeInfo       `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	ExpireSeconds int64           `protobuf:"varint,2,opt,name=expireSeconds,proto3" json:"expireSeconds,omitempty"` // tx expire after {expireSeconds} seconds
+	Extra         string          `protobuf:"bytes,3,opt,name=extra,proto3" json:"extra,omitempty"`                  // extra info
+	RetryPolicy   *RetryPolicy    `protobuf:"bytes,4,opt,name=retryPolicy,proto3" json:"retryPolicy,omitempty"`      // 各分支默认的补偿重试策略
+	Mode          TransactionMode `protobuf:"varint,5,opt,name=mode,proto3,enum=saga.TransactionMode" json:"mode,omitempty"`
 }
 
 func (x *CreateGlobalTransactionRequest) Reset() {
 	*x = CreateGlobalTransactionRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[1]
+		mi := &file_protos_saga_proto_msgTypes[2]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -176,7 +403,7 @@ func (x *CreateGlobalTransactionRequest) String() string {
 func (*CreateGlobalTransactionRequest) ProtoMessage() {}
 
 func (x *CreateGlobalTransactionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[1]
+	mi := &file_protos_saga_proto_msgTypes[2]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -189,7 +416,7 @@ func (x *CreateGlobalTransactionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateGlobalTransactionRequest.ProtoReflect.Descriptor instead.
 func (*CreateGlobalTransactionRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{1}
+	return file_protos_saga_proto_rawDescGZIP(), []int{2}
 }
 
 func (x *CreateGlobalTransactionRequest) GetNode() *NodeInfo {
@@ -213,6 +440,20 @@ func (x *CreateGlobalTransactionRequest) GetExtra() string {
 	return ""
 }
 
+func (x *CreateGlobalTransactionRequest) GetRetryPolicy() *RetryPolicy {
+	if x != nil {
+		return x.RetryPolicy
+	}
+	return nil
+}
+
+func (x *CreateGlobalTransactionRequest) GetMode() TransactionMode {
+	if x != nil {
+		return x.Mode
+	}
+	return TransactionMode_SAGA
+}
+
 type CreateGlobalTransactionReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -226,7 +467,7 @@ type CreateGlobalTransactionReply struct {
 func (x *CreateGlobalTransactionReply) Reset() {
 	*x = CreateGlobalTransactionReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[2]
+		mi := &file_protos_saga_proto_msgTypes[3]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -239,7 +480,7 @@ func (x *CreateGlobalTransactionReply) String() string {
 func (*CreateGlobalTransactionReply) ProtoMessage() {}
 
 func (x *CreateGlobalTransactionReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[2]
+	mi := &file_protos_saga_proto_msgTypes[3]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -252,7 +493,7 @@ func (x *CreateGlobalTransactionReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateGlobalTransactionReply.ProtoReflect.Descriptor instead.
 func (*CreateGlobalTransactionReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{2}
+	return file_protos_saga_proto_rawDescGZIP(), []int{3}
 }
 
 func (x *CreateGlobalTransactionReply) GetCode() int32 {
@@ -281,16 +522,23 @@ type CreateBranchTransactionRequest struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Node                         *NodeInfo `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
-	BranchServiceKey             string    `protobuf:"bytes,2,opt,name=branchServiceKey,proto3" json:"branchServiceKey,omitempty"`
-	BranchCompensationServiceKey string    `protobuf:"bytes,3,opt,name=branchCompensationServiceKey,proto3" json:"branchCompensationServiceKey,omitempty"`
-	Xid                          string    `protobuf:"bytes,4,opt,name=xid,proto3" json:"xid,omitempty"`
+	Node                         *NodeInfo    `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	BranchServiceKey             string       `protobuf:"bytes,2,opt,name=branchServiceKey,proto3" json:"branchServiceKey,omitempty"`
+	BranchCompensationServiceKey string       `protobuf:"bytes,3,opt,name=branchCompensationServiceKey,proto3" json:"branchCompensationServiceKey,omitempty"`
+	Xid                          string       `protobuf:"bytes,4,opt,name=xid,proto3" json:"xid,omitempty"`
+	RetryPolicy                  *RetryPolicy `protobuf:"bytes,5,opt,name=retryPolicy,proto3" json:"retryPolicy,omitempty"`          // 覆盖全局事务的补偿重试策略
+	ParentBranchId               string       `protobuf:"bytes,6,opt,name=parentBranchId,proto3" json:"parentBranchId,omitempty"`    // 上级分支，子分支在上级分支之前补偿
+	BranchGroup                  string       `protobuf:"bytes,7,opt,name=branchGroup,proto3" json:"branchGroup,omitempty"`          // 并行执行的分支组，同一个全局事务中同名的分支属于同一组
+	BranchGroupSize              int32        `protobuf:"varint,8,opt,name=branchGroupSize,proto3" json:"branchGroupSize,omitempty"` // 分支组的分支总数，branchGroup不为空时必须大于0
+	RecoveryMode                 RecoveryMode `protobuf:"varint,9,opt,name=recoveryMode,proto3,enum=saga.RecoveryMode" json:"recoveryMode,omitempty"`
+	BranchConfirmServiceKey      string       `protobuf:"bytes,10,opt,name=branchConfirmServiceKey,proto3" json:"branchConfirmServiceKey,omitempty"`  // TCC模式下分支的confirm，为空表示不需要confirm
+	HeartbeatTimeoutSeconds      int32        `protobuf:"varint,11,opt,name=heartbeatTimeoutSeconds,proto3" json:"heartbeatTimeoutSeconds,omitempty"` // 执行中超过这个时间没有心跳时认为执行方已经崩溃，0表示不检查心跳
 }
 
 func (x *CreateBranchTransactionRequest) Reset() {
 	*x = CreateBranchTransactionRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[3]
+		mi := &file_protos_saga_proto_msgTypes[4]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -303,7 +551,7 @@ func (x *CreateBranchTransactionRequest) String() string {
 func (*CreateBranchTransactionRequest) ProtoMessage() {}
 
 func (x *CreateBranchTransactionRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[3]
+	mi := &file_protos_saga_proto_msgTypes[4]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -316,7 +564,7 @@ func (x *CreateBranchTransactionRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateBranchTransactionRequest.ProtoReflect.Descriptor instead.
 func (*CreateBranchTransactionRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{3}
+	return file_protos_saga_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *CreateBranchTransactionRequest) GetNode() *NodeInfo {
@@ -347,6 +595,55 @@ func (x *CreateBranchTransactionRequest) GetXid() string {
 	return ""
 }
 
+func (x *CreateBranchTransactionRequest) GetRetryPolicy() *RetryPolicy {
+	if x != nil {
+		return x.RetryPolicy
+	}
+	return nil
+}
+
+func (x *CreateBranchTransactionRequest) GetParentBranchId() string {
+	if x != nil {
+		return x.ParentBranchId
+	}
+	return ""
+}
+
+func (x *CreateBranchTransactionRequest) GetBranchGroup() string {
+	if x != nil {
+		return x.BranchGroup
+	}
+	return ""
+}
+
+func (x *CreateBranchTransactionRequest) GetBranchGroupSize() int32 {
+	if x != nil {
+		return x.BranchGroupSize
+	}
+	return 0
+}
+
+func (x *CreateBranchTransactionRequest) GetRecoveryMode() RecoveryMode {
+	if x != nil {
+		return x.RecoveryMode
+	}
+	return RecoveryMode_BACKWARD
+}
+
+func (x *CreateBranchTransactionRequest) GetBranchConfirmServiceKey() string {
+	if x != nil {
+		return x.BranchConfirmServiceKey
+	}
+	return ""
+}
+
+func (x *CreateBranchTransactionRequest) GetHeartbeatTimeoutSeconds() int32 {
+	if x != nil {
+		return x.HeartbeatTimeoutSeconds
+	}
+	return 0
+}
+
 type CreateBranchTransactionReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -360,7 +657,7 @@ type CreateBranchTransactionReply struct {
 func (x *CreateBranchTransactionReply) Reset() {
 	*x = CreateBranchTransactionReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[4]
+		mi := &file_protos_saga_proto_msgTypes[5]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -373,7 +670,7 @@ func (x *CreateBranchTransactionReply) String() string {
 func (*CreateBranchTransactionReply) ProtoMessage() {}
 
 func (x *CreateBranchTransactionReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[4]
+	mi := &file_protos_saga_proto_msgTypes[5]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -386,7 +683,7 @@ func (x *CreateBranchTransactionReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use CreateBranchTransactionReply.ProtoReflect.Descriptor instead.
 func (*CreateBranchTransactionReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{4}
+	return file_protos_saga_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *CreateBranchTransactionReply) GetCode() int32 {
@@ -421,7 +718,7 @@ type QueryGlobalTransactionDetailRequest struct {
 func (x *QueryGlobalTransactionDetailRequest) Reset() {
 	*x = QueryGlobalTransactionDetailRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[5]
+		mi := &file_protos_saga_proto_msgTypes[6]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -434,7 +731,7 @@ func (x *QueryGlobalTransactionDetailRequest) String() string {
 func (*QueryGlobalTransactionDetailRequest) ProtoMessage() {}
 
 func (x *QueryGlobalTransactionDetailRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[5]
+	mi := &file_protos_saga_proto_msgTypes[6]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -447,7 +744,7 @@ func (x *QueryGlobalTransactionDetailRequest) ProtoReflect() protoreflect.Messag
 
 // Deprecated: Use QueryGlobalTransactionDetailRequest.ProtoReflect.Descriptor instead.
 func (*QueryGlobalTransactionDetailRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{5}
+	return file_protos_saga_proto_rawDescGZIP(), []int{6}
 }
 
 func (x *QueryGlobalTransactionDetailRequest) GetXid() string {
@@ -462,19 +759,32 @@ type TransactionBranchDetail struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	BranchId                     string    `protobuf:"bytes,1,opt,name=branchId,proto3" json:"branchId,omitempty"`
-	Node                         *NodeInfo `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
-	State                        TxState   `protobuf:"varint,3,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"`
-	CompensationFailTimes        int32     `protobuf:"varint,4,opt,name=compensationFailTimes,proto3" json:"compensationFailTimes,omitempty"` // 本branch的补偿任务失败重试次数
-	BranchServiceKey             string    `protobuf:"bytes,5,opt,name=branchServiceKey,proto3" json:"branchServiceKey,omitempty"`
-	BranchCompensationServiceKey string    `protobuf:"bytes,6,opt,name=branchCompensationServiceKey,proto3" json:"branchCompensationServiceKey,omitempty"`
-	Version                      int32     `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	BranchId                     string                     `protobuf:"bytes,1,opt,name=branchId,proto3" json:"branchId,omitempty"`
+	Node                         *NodeInfo                  `protobuf:"bytes,2,opt,name=node,proto3" json:"node,omitempty"`
+	State                        TxState                    `protobuf:"varint,3,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"`
+	CompensationFailTimes        int32                      `protobuf:"varint,4,opt,name=compensationFailTimes,proto3" json:"compensationFailTimes,omitempty"` // 本branch的补偿任务失败重试次数
+	BranchServiceKey             string                     `protobuf:"bytes,5,opt,name=branchServiceKey,proto3" json:"branchServiceKey,omitempty"`
+	BranchCompensationServiceKey string                     `protobuf:"bytes,6,opt,name=branchCompensationServiceKey,proto3" json:"branchCompensationServiceKey,omitempty"`
+	Version                      int32                      `protobuf:"varint,7,opt,name=version,proto3" json:"version,omitempty"`
+	NextRetryAt                  int64                      `protobuf:"varint,8,opt,name=nextRetryAt,proto3" json:"nextRetryAt,omitempty"`      // 补偿失败后下次允许重试的unix毫秒时间，0表示不需要等待
+	ParentBranchId               string                     `protobuf:"bytes,9,opt,name=parentBranchId,proto3" json:"parentBranchId,omitempty"` // 上级分支，为空表示是顶层分支
+	Children                     []*TransactionBranchDetail `protobuf:"bytes,10,rep,name=children,proto3" json:"children,omitempty"`            // 只在branchTree中填充
+	BranchGroup                  string                     `protobuf:"bytes,11,opt,name=branchGroup,proto3" json:"branchGroup,omitempty"`
+	BranchGroupSize              int32                      `protobuf:"varint,12,opt,name=branchGroupSize,proto3" json:"branchGroupSize,omitempty"`
+	RecoveryMode                 RecoveryMode               `protobuf:"varint,13,opt,name=recoveryMode,proto3,enum=saga.RecoveryMode" json:"recoveryMode,omitempty"`
+	ForwardRetryTimes            int32                      `protobuf:"varint,14,opt,name=forwardRetryTimes,proto3" json:"forwardRetryTimes,omitempty"` // 向前恢复的分支执行失败或者TCC分支confirm失败的次数
+	BranchConfirmServiceKey      string                     `protobuf:"bytes,15,opt,name=branchConfirmServiceKey,proto3" json:"branchConfirmServiceKey,omitempty"`
+	LeaseOwner                   string                     `protobuf:"bytes,16,opt,name=leaseOwner,proto3" json:"leaseOwner,omitempty"`        // 领取了补偿任务的参与方实例
+	LeaseExpireAt                int64                      `protobuf:"varint,17,opt,name=leaseExpireAt,proto3" json:"leaseExpireAt,omitempty"` // 补偿任务租约到期的unix毫秒时间，0表示没有被领取
+	HeartbeatTimeoutSeconds      int32                      `protobuf:"varint,18,opt,name=heartbeatTimeoutSeconds,proto3" json:"heartbeatTimeoutSeconds,omitempty"`
+	LastHeartbeatAt              int64                      `protobuf:"varint,19,opt,name=lastHeartbeatAt,proto3" json:"lastHeartbeatAt,omitempty"` // 最近一次心跳的unix毫秒时间
+	OrphanedAt                   int64                      `protobuf:"varint,20,opt,name=orphanedAt,proto3" json:"orphanedAt,omitempty"`           // 被标记为失去心跳的unix毫秒时间，0表示没有被标记
 }
 
 func (x *TransactionBranchDetail) Reset() {
 	*x = TransactionBranchDetail{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[6]
+		mi := &file_protos_saga_proto_msgTypes[7]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -487,7 +797,7 @@ func (x *TransactionBranchDetail) String() string {
 func (*TransactionBranchDetail) ProtoMessage() {}
 
 func (x *TransactionBranchDetail) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[6]
+	mi := &file_protos_saga_proto_msgTypes[7]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -500,7 +810,7 @@ func (x *TransactionBranchDetail) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use TransactionBranchDetail.ProtoReflect.Descriptor instead.
 func (*TransactionBranchDetail) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{6}
+	return file_protos_saga_proto_rawDescGZIP(), []int{7}
 }
 
 func (x *TransactionBranchDetail) GetBranchId() string {
@@ -552,54 +862,245 @@ func (x *TransactionBranchDetail) GetVersion() int32 {
 	return 0
 }
 
-type QueryGlobalTransactionDetailReply struct {
-	state         protoimpl.MessageState
-	sizeCache     protoimpl.SizeCache
-	unknownFields protoimpl.UnknownFields
-
-	Code          int32                      `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
-	Error         string                     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
-	Xid           string                     `protobuf:"bytes,3,opt,name=xid,proto3" json:"xid,omitempty"`
-	Branches      []*TransactionBranchDetail `protobuf:"bytes,4,rep,name=branches,proto3" json:"branches,omitempty"`
-	StarterNode   *NodeInfo                  `protobuf:"bytes,5,opt,name=starterNode,proto3" json:"starterNode,omitempty"`
-	State         TxState                    `protobuf:"varint,6,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"`
-	EndBranches   bool                       `protobuf:"varint,7,opt,name=endBranches,proto3" json:"endBranches,omitempty"`
-	Version       int32                      `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
-	CreatedAt     int64                      `protobuf:"varint,9,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
-	UpdatedAt     int64                      `protobuf:"varint,10,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
-	ExpireSeconds int32                      `protobuf:"varint,11,opt,name=expireSeconds,proto3" json:"expireSeconds,omitempty"`
+func (x *TransactionBranchDetail) GetNextRetryAt() int64 {
+	if x != nil {
+		return x.NextRetryAt
+	}
+	return 0
 }
 
-func (x *QueryGlobalTransactionDetailReply) Reset() {
-	*x = QueryGlobalTransactionDetailReply{}
-	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[7]
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		ms.StoreMessageInfo(mi)
+func (x *TransactionBranchDetail) GetParentBranchId() string {
+	if x != nil {
+		return x.ParentBranchId
 	}
+	return ""
 }
 
-func (x *QueryGlobalTransactionDetailReply) String() string {
-	return protoimpl.X.MessageStringOf(x)
+func (x *TransactionBranchDetail) GetChildren() []*TransactionBranchDetail {
+	if x != nil {
+		return x.Children
+	}
+	return nil
 }
 
-func (*QueryGlobalTransactionDetailReply) ProtoMessage() {}
+func (x *TransactionBranchDetail) GetBranchGroup() string {
+	if x != nil {
+		return x.BranchGroup
+	}
+	return ""
+}
 
-func (x *QueryGlobalTransactionDetailReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[7]
-	if protoimpl.UnsafeEnabled && x != nil {
-		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
-		if ms.LoadMessageInfo() == nil {
-			ms.StoreMessageInfo(mi)
-		}
-		return ms
+func (x *TransactionBranchDetail) GetBranchGroupSize() int32 {
+	if x != nil {
+		return x.BranchGroupSize
 	}
-	return mi.MessageOf(x)
+	return 0
+}
+
+func (x *TransactionBranchDetail) GetRecoveryMode() RecoveryMode {
+	if x != nil {
+		return x.RecoveryMode
+	}
+	return RecoveryMode_BACKWARD
+}
+
+func (x *TransactionBranchDetail) GetForwardRetryTimes() int32 {
+	if x != nil {
+		return x.ForwardRetryTimes
+	}
+	return 0
+}
+
+func (x *TransactionBranchDetail) GetBranchConfirmServiceKey() string {
+	if x != nil {
+		return x.BranchConfirmServiceKey
+	}
+	return ""
+}
+
+func (x *TransactionBranchDetail) GetLeaseOwner() string {
+	if x != nil {
+		return x.LeaseOwner
+	}
+	return ""
+}
+
+func (x *TransactionBranchDetail) GetLeaseExpireAt() int64 {
+	if x != nil {
+		return x.LeaseExpireAt
+	}
+	return 0
+}
+
+func (x *TransactionBranchDetail) GetHeartbeatTimeoutSeconds() int32 {
+	if x != nil {
+		return x.HeartbeatTimeoutSeconds
+	}
+	return 0
+}
+
+func (x *TransactionBranchDetail) GetLastHeartbeatAt() int64 {
+	if x != nil {
+		return x.LastHeartbeatAt
+	}
+	return 0
+}
+
+func (x *TransactionBranchDetail) GetOrphanedAt() int64 {
+	if x != nil {
+		return x.OrphanedAt
+	}
+	return 0
+}
+
+// 分支组的完成情况
+type BranchGroupDetail struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BranchGroup           string `protobuf:"bytes,1,opt,name=branchGroup,proto3" json:"branchGroup,omitempty"`
+	Size                  int32  `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`               // 声明的分支总数
+	BranchCount           int32  `protobuf:"varint,3,opt,name=branchCount,proto3" json:"branchCount,omitempty"` // 已经创建的分支数
+	ProcessingCount       int32  `protobuf:"varint,4,opt,name=processingCount,proto3" json:"processingCount,omitempty"`
+	CommittedCount        int32  `protobuf:"varint,5,opt,name=committedCount,proto3" json:"committedCount,omitempty"`
+	CompensationDoneCount int32  `protobuf:"varint,6,opt,name=compensationDoneCount,proto3" json:"compensationDoneCount,omitempty"`
+	Settled               bool   `protobuf:"varint,7,opt,name=settled,proto3" json:"settled,omitempty"` // 所有分支都已经创建并且都不在PROCESSING状态
+}
+
+func (x *BranchGroupDetail) Reset() {
+	*x = BranchGroupDetail{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BranchGroupDetail) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BranchGroupDetail) ProtoMessage() {}
+
+func (x *BranchGroupDetail) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BranchGroupDetail.ProtoReflect.Descriptor instead.
+func (*BranchGroupDetail) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BranchGroupDetail) GetBranchGroup() string {
+	if x != nil {
+		return x.BranchGroup
+	}
+	return ""
+}
+
+func (x *BranchGroupDetail) GetSize() int32 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *BranchGroupDetail) GetBranchCount() int32 {
+	if x != nil {
+		return x.BranchCount
+	}
+	return 0
+}
+
+func (x *BranchGroupDetail) GetProcessingCount() int32 {
+	if x != nil {
+		return x.ProcessingCount
+	}
+	return 0
+}
+
+func (x *BranchGroupDetail) GetCommittedCount() int32 {
+	if x != nil {
+		return x.CommittedCount
+	}
+	return 0
+}
+
+func (x *BranchGroupDetail) GetCompensationDoneCount() int32 {
+	if x != nil {
+		return x.CompensationDoneCount
+	}
+	return 0
+}
+
+func (x *BranchGroupDetail) GetSettled() bool {
+	if x != nil {
+		return x.Settled
+	}
+	return false
+}
+
+type QueryGlobalTransactionDetailReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code          int32                      `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error         string                     `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Xid           string                     `protobuf:"bytes,3,opt,name=xid,proto3" json:"xid,omitempty"`
+	Branches      []*TransactionBranchDetail `protobuf:"bytes,4,rep,name=branches,proto3" json:"branches,omitempty"`
+	StarterNode   *NodeInfo                  `protobuf:"bytes,5,opt,name=starterNode,proto3" json:"starterNode,omitempty"`
+	State         TxState                    `protobuf:"varint,6,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"`
+	EndBranches   bool                       `protobuf:"varint,7,opt,name=endBranches,proto3" json:"endBranches,omitempty"`
+	Version       int32                      `protobuf:"varint,8,opt,name=version,proto3" json:"version,omitempty"`
+	CreatedAt     int64                      `protobuf:"varint,9,opt,name=createdAt,proto3" json:"createdAt,omitempty"`
+	UpdatedAt     int64                      `protobuf:"varint,10,opt,name=updatedAt,proto3" json:"updatedAt,omitempty"`
+	ExpireSeconds int32                      `protobuf:"varint,11,opt,name=expireSeconds,proto3" json:"expireSeconds,omitempty"`
+	BranchTree    []*TransactionBranchDetail `protobuf:"bytes,12,rep,name=branchTree,proto3" json:"branchTree,omitempty"` // 按parentBranchId组织的分支树，只包含顶层分支
+	Extra         string                     `protobuf:"bytes,13,opt,name=extra,proto3" json:"extra,omitempty"`           // 创建全局事务时传入的extra
+	BranchGroups  []*BranchGroupDetail       `protobuf:"bytes,14,rep,name=branchGroups,proto3" json:"branchGroups,omitempty"`
+	Mode          TransactionMode            `protobuf:"varint,15,opt,name=mode,proto3,enum=saga.TransactionMode" json:"mode,omitempty"`
+}
+
+func (x *QueryGlobalTransactionDetailReply) Reset() {
+	*x = QueryGlobalTransactionDetailReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[9]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *QueryGlobalTransactionDetailReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryGlobalTransactionDetailReply) ProtoMessage() {}
+
+func (x *QueryGlobalTransactionDetailReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[9]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
 }
 
 // Deprecated: Use QueryGlobalTransactionDetailReply.ProtoReflect.Descriptor instead.
 func (*QueryGlobalTransactionDetailReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{7}
+	return file_protos_saga_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *QueryGlobalTransactionDetailReply) GetCode() int32 {
@@ -679,6 +1180,34 @@ func (x *QueryGlobalTransactionDetailReply) GetExpireSeconds() int32 {
 	return 0
 }
 
+func (x *QueryGlobalTransactionDetailReply) GetBranchTree() []*TransactionBranchDetail {
+	if x != nil {
+		return x.BranchTree
+	}
+	return nil
+}
+
+func (x *QueryGlobalTransactionDetailReply) GetExtra() string {
+	if x != nil {
+		return x.Extra
+	}
+	return ""
+}
+
+func (x *QueryGlobalTransactionDetailReply) GetBranchGroups() []*BranchGroupDetail {
+	if x != nil {
+		return x.BranchGroups
+	}
+	return nil
+}
+
+func (x *QueryGlobalTransactionDetailReply) GetMode() TransactionMode {
+	if x != nil {
+		return x.Mode
+	}
+	return TransactionMode_SAGA
+}
+
 type QueryBranchTransactionDetailRequest struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
@@ -690,7 +1219,7 @@ type QueryBranchTransactionDetailRequest struct {
 func (x *QueryBranchTransactionDetailRequest) Reset() {
 	*x = QueryBranchTransactionDetailRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[8]
+		mi := &file_protos_saga_proto_msgTypes[10]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -703,7 +1232,7 @@ func (x *QueryBranchTransactionDetailRequest) String() string {
 func (*QueryBranchTransactionDetailRequest) ProtoMessage() {}
 
 func (x *QueryBranchTransactionDetailRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[8]
+	mi := &file_protos_saga_proto_msgTypes[10]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -716,7 +1245,7 @@ func (x *QueryBranchTransactionDetailRequest) ProtoReflect() protoreflect.Messag
 
 // Deprecated: Use QueryBranchTransactionDetailRequest.ProtoReflect.Descriptor instead.
 func (*QueryBranchTransactionDetailRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{8}
+	return file_protos_saga_proto_rawDescGZIP(), []int{10}
 }
 
 func (x *QueryBranchTransactionDetailRequest) GetBranchId() string {
@@ -741,7 +1270,7 @@ type QueryBranchTransactionDetailReply struct {
 func (x *QueryBranchTransactionDetailReply) Reset() {
 	*x = QueryBranchTransactionDetailReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[9]
+		mi := &file_protos_saga_proto_msgTypes[11]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -754,7 +1283,7 @@ func (x *QueryBranchTransactionDetailReply) String() string {
 func (*QueryBranchTransactionDetailReply) ProtoMessage() {}
 
 func (x *QueryBranchTransactionDetailReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[9]
+	mi := &file_protos_saga_proto_msgTypes[11]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -767,7 +1296,7 @@ func (x *QueryBranchTransactionDetailReply) ProtoReflect() protoreflect.Message
 
 // Deprecated: Use QueryBranchTransactionDetailReply.ProtoReflect.Descriptor instead.
 func (*QueryBranchTransactionDetailReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{9}
+	return file_protos_saga_proto_rawDescGZIP(), []int{11}
 }
 
 func (x *QueryBranchTransactionDetailReply) GetCode() int32 {
@@ -819,7 +1348,7 @@ type SubmitGlobalTransactionStateRequest struct {
 func (x *SubmitGlobalTransactionStateRequest) Reset() {
 	*x = SubmitGlobalTransactionStateRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[10]
+		mi := &file_protos_saga_proto_msgTypes[12]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -832,7 +1361,7 @@ func (x *SubmitGlobalTransactionStateRequest) String() string {
 func (*SubmitGlobalTransactionStateRequest) ProtoMessage() {}
 
 func (x *SubmitGlobalTransactionStateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[10]
+	mi := &file_protos_saga_proto_msgTypes[12]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -845,7 +1374,7 @@ func (x *SubmitGlobalTransactionStateRequest) ProtoReflect() protoreflect.Messag
 
 // Deprecated: Use SubmitGlobalTransactionStateRequest.ProtoReflect.Descriptor instead.
 func (*SubmitGlobalTransactionStateRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{10}
+	return file_protos_saga_proto_rawDescGZIP(), []int{12}
 }
 
 func (x *SubmitGlobalTransactionStateRequest) GetXid() string {
@@ -889,7 +1418,7 @@ type SubmitGlobalTransactionStateReply struct {
 func (x *SubmitGlobalTransactionStateReply) Reset() {
 	*x = SubmitGlobalTransactionStateReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[11]
+		mi := &file_protos_saga_proto_msgTypes[13]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -902,7 +1431,7 @@ func (x *SubmitGlobalTransactionStateReply) String() string {
 func (*SubmitGlobalTransactionStateReply) ProtoMessage() {}
 
 func (x *SubmitGlobalTransactionStateReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[11]
+	mi := &file_protos_saga_proto_msgTypes[13]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -915,7 +1444,7 @@ func (x *SubmitGlobalTransactionStateReply) ProtoReflect() protoreflect.Message
 
 // Deprecated: Use SubmitGlobalTransactionStateReply.ProtoReflect.Descriptor instead.
 func (*SubmitGlobalTransactionStateReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{11}
+	return file_protos_saga_proto_rawDescGZIP(), []int{13}
 }
 
 func (x *SubmitGlobalTransactionStateReply) GetCode() int32 {
@@ -952,12 +1481,13 @@ type SubmitBranchTransactionStateRequest struct {
 	JobId       string  `protobuf:"bytes,6,opt,name=jobId,proto3" json:"jobId,omitempty"`             // 每次分支执行每次任务或者补偿任务都有一个不同的jobId
 	ErrorReason string  `protobuf:"bytes,7,opt,name=errorReason,proto3" json:"errorReason,omitempty"` // 失败原因
 	SagaData    []byte  `protobuf:"bytes,8,opt,name=sagaData,proto3" json:"sagaData,omitempty"`
+	LeaseId     string  `protobuf:"bytes,9,opt,name=leaseId,proto3" json:"leaseId,omitempty"` // 领取了任务时带上租约id，租约期间只接受持有租约的参与方提交的结果
 }
 
 func (x *SubmitBranchTransactionStateRequest) Reset() {
 	*x = SubmitBranchTransactionStateRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[12]
+		mi := &file_protos_saga_proto_msgTypes[14]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -970,7 +1500,7 @@ func (x *SubmitBranchTransactionStateRequest) String() string {
 func (*SubmitBranchTransactionStateRequest) ProtoMessage() {}
 
 func (x *SubmitBranchTransactionStateRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[12]
+	mi := &file_protos_saga_proto_msgTypes[14]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -983,7 +1513,7 @@ func (x *SubmitBranchTransactionStateRequest) ProtoReflect() protoreflect.Messag
 
 // Deprecated: Use SubmitBranchTransactionStateRequest.ProtoReflect.Descriptor instead.
 func (*SubmitBranchTransactionStateRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{12}
+	return file_protos_saga_proto_rawDescGZIP(), []int{14}
 }
 
 func (x *SubmitBranchTransactionStateRequest) GetXid() string {
@@ -1042,12 +1572,19 @@ func (x *SubmitBranchTransactionStateRequest) GetSagaData() []byte {
 	return nil
 }
 
+func (x *SubmitBranchTransactionStateRequest) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
 type SubmitBranchTransactionStateReply struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Code  int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Code  int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success. 7表示分支的任务被其他参与方领取了
 	Error string  `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
 	State TxState `protobuf:"varint,3,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"` // 修改后的branch state
 }
@@ -1055,7 +1592,7 @@ type SubmitBranchTransactionStateReply struct {
 func (x *SubmitBranchTransactionStateReply) Reset() {
 	*x = SubmitBranchTransactionStateReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[13]
+		mi := &file_protos_saga_proto_msgTypes[15]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1068,7 +1605,7 @@ func (x *SubmitBranchTransactionStateReply) String() string {
 func (*SubmitBranchTransactionStateReply) ProtoMessage() {}
 
 func (x *SubmitBranchTransactionStateReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[13]
+	mi := &file_protos_saga_proto_msgTypes[15]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1081,7 +1618,7 @@ func (x *SubmitBranchTransactionStateReply) ProtoReflect() protoreflect.Message
 
 // Deprecated: Use SubmitBranchTransactionStateReply.ProtoReflect.Descriptor instead.
 func (*SubmitBranchTransactionStateReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{13}
+	return file_protos_saga_proto_rawDescGZIP(), []int{15}
 }
 
 func (x *SubmitBranchTransactionStateReply) GetCode() int32 {
@@ -1117,7 +1654,7 @@ type InitSagaDataRequest struct {
 func (x *InitSagaDataRequest) Reset() {
 	*x = InitSagaDataRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[14]
+		mi := &file_protos_saga_proto_msgTypes[16]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1130,7 +1667,7 @@ func (x *InitSagaDataRequest) String() string {
 func (*InitSagaDataRequest) ProtoMessage() {}
 
 func (x *InitSagaDataRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[14]
+	mi := &file_protos_saga_proto_msgTypes[16]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1143,7 +1680,7 @@ func (x *InitSagaDataRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InitSagaDataRequest.ProtoReflect.Descriptor instead.
 func (*InitSagaDataRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{14}
+	return file_protos_saga_proto_rawDescGZIP(), []int{16}
 }
 
 func (x *InitSagaDataRequest) GetXid() string {
@@ -1172,7 +1709,7 @@ type InitSagaDataReply struct {
 func (x *InitSagaDataReply) Reset() {
 	*x = InitSagaDataReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[15]
+		mi := &file_protos_saga_proto_msgTypes[17]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1185,7 +1722,7 @@ func (x *InitSagaDataReply) String() string {
 func (*InitSagaDataReply) ProtoMessage() {}
 
 func (x *InitSagaDataReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[15]
+	mi := &file_protos_saga_proto_msgTypes[17]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1198,7 +1735,7 @@ func (x *InitSagaDataReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use InitSagaDataReply.ProtoReflect.Descriptor instead.
 func (*InitSagaDataReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{15}
+	return file_protos_saga_proto_rawDescGZIP(), []int{17}
 }
 
 func (x *InitSagaDataReply) GetCode() int32 {
@@ -1226,7 +1763,7 @@ type GetSagaDataRequest struct {
 func (x *GetSagaDataRequest) Reset() {
 	*x = GetSagaDataRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[16]
+		mi := &file_protos_saga_proto_msgTypes[18]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1239,7 +1776,7 @@ func (x *GetSagaDataRequest) String() string {
 func (*GetSagaDataRequest) ProtoMessage() {}
 
 func (x *GetSagaDataRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[16]
+	mi := &file_protos_saga_proto_msgTypes[18]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1252,7 +1789,7 @@ func (x *GetSagaDataRequest) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSagaDataRequest.ProtoReflect.Descriptor instead.
 func (*GetSagaDataRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{16}
+	return file_protos_saga_proto_rawDescGZIP(), []int{18}
 }
 
 func (x *GetSagaDataRequest) GetXid() string {
@@ -1276,7 +1813,7 @@ type GetSagaDataReply struct {
 func (x *GetSagaDataReply) Reset() {
 	*x = GetSagaDataReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[17]
+		mi := &file_protos_saga_proto_msgTypes[19]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1289,7 +1826,7 @@ func (x *GetSagaDataReply) String() string {
 func (*GetSagaDataReply) ProtoMessage() {}
 
 func (x *GetSagaDataReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[17]
+	mi := &file_protos_saga_proto_msgTypes[19]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1302,7 +1839,7 @@ func (x *GetSagaDataReply) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetSagaDataReply.ProtoReflect.Descriptor instead.
 func (*GetSagaDataReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{17}
+	return file_protos_saga_proto_rawDescGZIP(), []int{19}
 }
 
 func (x *GetSagaDataReply) GetCode() int32 {
@@ -1345,7 +1882,7 @@ type ListGlobalTransactionsOfStatesRequest struct {
 func (x *ListGlobalTransactionsOfStatesRequest) Reset() {
 	*x = ListGlobalTransactionsOfStatesRequest{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[18]
+		mi := &file_protos_saga_proto_msgTypes[20]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1358,7 +1895,7 @@ func (x *ListGlobalTransactionsOfStatesRequest) String() string {
 func (*ListGlobalTransactionsOfStatesRequest) ProtoMessage() {}
 
 func (x *ListGlobalTransactionsOfStatesRequest) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[18]
+	mi := &file_protos_saga_proto_msgTypes[20]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1371,7 +1908,7 @@ func (x *ListGlobalTransactionsOfStatesRequest) ProtoReflect() protoreflect.Mess
 
 // Deprecated: Use ListGlobalTransactionsOfStatesRequest.ProtoReflect.Descriptor instead.
 func (*ListGlobalTransactionsOfStatesRequest) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{18}
+	return file_protos_saga_proto_rawDescGZIP(), []int{20}
 }
 
 func (x *ListGlobalTransactionsOfStatesRequest) GetStates() []TxState {
@@ -1401,7 +1938,7 @@ type ListGlobalTransactionsOfStatesReply struct {
 func (x *ListGlobalTransactionsOfStatesReply) Reset() {
 	*x = ListGlobalTransactionsOfStatesReply{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_protos_saga_proto_msgTypes[19]
+		mi := &file_protos_saga_proto_msgTypes[21]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -1414,7 +1951,7 @@ func (x *ListGlobalTransactionsOfStatesReply) String() string {
 func (*ListGlobalTransactionsOfStatesReply) ProtoMessage() {}
 
 func (x *ListGlobalTransactionsOfStatesReply) ProtoReflect() protoreflect.Message {
-	mi := &file_protos_saga_proto_msgTypes[19]
+	mi := &file_protos_saga_proto_msgTypes[21]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -1427,7 +1964,7 @@ func (x *ListGlobalTransactionsOfStatesReply) ProtoReflect() protoreflect.Messag
 
 // Deprecated: Use ListGlobalTransactionsOfStatesReply.ProtoReflect.Descriptor instead.
 func (*ListGlobalTransactionsOfStatesReply) Descriptor() ([]byte, []int) {
-	return file_protos_saga_proto_rawDescGZIP(), []int{19}
+	return file_protos_saga_proto_rawDescGZIP(), []int{21}
 }
 
 func (x *ListGlobalTransactionsOfStatesReply) GetCode() int32 {
@@ -1451,257 +1988,1834 @@ func (x *ListGlobalTransactionsOfStatesReply) GetXids() []string {
 	return nil
 }
 
-var File_protos_saga_proto protoreflect.FileDescriptor
+type BranchCompensationRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
 
-var file_protos_saga_proto_rawDesc = []byte{
-	0x0a, 0x11, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x70, 0x72,
-	0x6f, 0x74, 0x6f, 0x12, 0x04, 0x73, 0x61, 0x67, 0x61, 0x22, 0x5a, 0x0a, 0x08, 0x4e, 0x6f, 0x64,
-	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x01,
-	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x73,
-	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65,
-	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63,
-	0x65, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61,
-	0x6e, 0x63, 0x65, 0x49, 0x64, 0x22, 0x80, 0x01, 0x0a, 0x1e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65,
-	0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f,
-	0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x24, 0x0a, 0x0d,
-	0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x03, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e,
-	0x64, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x78, 0x74, 0x72, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x65, 0x78, 0x74, 0x72, 0x61, 0x22, 0x5a, 0x0a, 0x1c, 0x43, 0x72, 0x65, 0x61,
-	0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
-	0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x03, 0x78, 0x69, 0x64, 0x22, 0xc6, 0x01, 0x0a, 0x1e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42,
-	0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64,
-	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x2a, 0x0a, 0x10, 0x62,
-	0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18,
-	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x42, 0x0a, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63,
-	0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x1c, 0x62,
-	0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f,
-	0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x78,
-	0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0x64, 0x0a,
-	0x1c, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61,
-	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a,
+	Xid              string `protobuf:"bytes,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	BranchId         string `protobuf:"bytes,2,opt,name=branchId,proto3" json:"branchId,omitempty"`
+	BranchServiceKey string `protobuf:"bytes,3,opt,name=branchServiceKey,proto3" json:"branchServiceKey,omitempty"`
+	SagaData         []byte `protobuf:"bytes,4,opt,name=sagaData,proto3" json:"sagaData,omitempty"`
+	JobId            string `protobuf:"bytes,5,opt,name=jobId,proto3" json:"jobId,omitempty"` // 每次补偿调用都是一个不同的jobId
+}
+
+func (x *BranchCompensationRequest) Reset() {
+	*x = BranchCompensationRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[22]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BranchCompensationRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BranchCompensationRequest) ProtoMessage() {}
+
+func (x *BranchCompensationRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[22]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BranchCompensationRequest.ProtoReflect.Descriptor instead.
+func (*BranchCompensationRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *BranchCompensationRequest) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+func (x *BranchCompensationRequest) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+func (x *BranchCompensationRequest) GetBranchServiceKey() string {
+	if x != nil {
+		return x.BranchServiceKey
+	}
+	return ""
+}
+
+func (x *BranchCompensationRequest) GetSagaData() []byte {
+	if x != nil {
+		return x.SagaData
+	}
+	return nil
+}
+
+func (x *BranchCompensationRequest) GetJobId() string {
+	if x != nil {
+		return x.JobId
+	}
+	return ""
+}
+
+type BranchCompensationReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code     int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error    string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	SagaData []byte `protobuf:"bytes,3,opt,name=sagaData,proto3" json:"sagaData,omitempty"` // 补偿后修改过的saga data，为空表示不修改
+}
+
+func (x *BranchCompensationReply) Reset() {
+	*x = BranchCompensationReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[23]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BranchCompensationReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BranchCompensationReply) ProtoMessage() {}
+
+func (x *BranchCompensationReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[23]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BranchCompensationReply.ProtoReflect.Descriptor instead.
+func (*BranchCompensationReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *BranchCompensationReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *BranchCompensationReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *BranchCompensationReply) GetSagaData() []byte {
+	if x != nil {
+		return x.SagaData
+	}
+	return nil
+}
+
+// 发起方声明全局事务不再有新的分支，之后各分支都committed时全局事务自动committed
+type CloseGlobalTransactionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid string `protobuf:"bytes,1,opt,name=xid,proto3" json:"xid,omitempty"`
+}
+
+func (x *CloseGlobalTransactionRequest) Reset() {
+	*x = CloseGlobalTransactionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[24]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CloseGlobalTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseGlobalTransactionRequest) ProtoMessage() {}
+
+func (x *CloseGlobalTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[24]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseGlobalTransactionRequest.ProtoReflect.Descriptor instead.
+func (*CloseGlobalTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *CloseGlobalTransactionRequest) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+type CloseGlobalTransactionReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code  int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error string  `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	State TxState `protobuf:"varint,3,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"` // 关闭后的全局事务状态，各分支已经都committed时为COMMITTED
+}
+
+func (x *CloseGlobalTransactionReply) Reset() {
+	*x = CloseGlobalTransactionReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[25]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CloseGlobalTransactionReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CloseGlobalTransactionReply) ProtoMessage() {}
+
+func (x *CloseGlobalTransactionReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[25]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CloseGlobalTransactionReply.ProtoReflect.Descriptor instead.
+func (*CloseGlobalTransactionReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *CloseGlobalTransactionReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *CloseGlobalTransactionReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *CloseGlobalTransactionReply) GetState() TxState {
+	if x != nil {
+		return x.State
+	}
+	return TxState_PROCESSING
+}
+
+// 一个分支补偿任务的租约
+type CompensationWorkLease struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid           string `protobuf:"bytes,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	BranchId      string `protobuf:"bytes,2,opt,name=branchId,proto3" json:"branchId,omitempty"`
+	LeaseId       string `protobuf:"bytes,3,opt,name=leaseId,proto3" json:"leaseId,omitempty"`              // 续约和释放时需要提供
+	LeaseExpireAt int64  `protobuf:"varint,4,opt,name=leaseExpireAt,proto3" json:"leaseExpireAt,omitempty"` // unix毫秒时间
+}
+
+func (x *CompensationWorkLease) Reset() {
+	*x = CompensationWorkLease{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[26]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompensationWorkLease) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompensationWorkLease) ProtoMessage() {}
+
+func (x *CompensationWorkLease) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[26]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompensationWorkLease.ProtoReflect.Descriptor instead.
+func (*CompensationWorkLease) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *CompensationWorkLease) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+func (x *CompensationWorkLease) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+func (x *CompensationWorkLease) GetLeaseId() string {
+	if x != nil {
+		return x.LeaseId
+	}
+	return ""
+}
+
+func (x *CompensationWorkLease) GetLeaseExpireAt() int64 {
+	if x != nil {
+		return x.LeaseExpireAt
+	}
+	return 0
+}
+
+type ClaimCompensationWorkRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Node         *NodeInfo `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`                     // 只领取group和service相同的参与方创建的分支
+	LeaseSeconds int32     `protobuf:"varint,2,opt,name=leaseSeconds,proto3" json:"leaseSeconds,omitempty"`    // 租约时长，<=0时使用默认值
+	Limit        int32     `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`                  // 最多领取的分支数，<=0时使用默认值
+	Xid          string    `protobuf:"bytes,4,opt,name=xid,proto3" json:"xid,omitempty"`                       // 不为空时只领取这个全局事务中的分支
+	Work         WorkType  `protobuf:"varint,5,opt,name=work,proto3,enum=saga.WorkType" json:"work,omitempty"` // 领取的任务类型，默认是补偿任务
+}
+
+func (x *ClaimCompensationWorkRequest) Reset() {
+	*x = ClaimCompensationWorkRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[27]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimCompensationWorkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimCompensationWorkRequest) ProtoMessage() {}
+
+func (x *ClaimCompensationWorkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[27]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimCompensationWorkRequest.ProtoReflect.Descriptor instead.
+func (*ClaimCompensationWorkRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *ClaimCompensationWorkRequest) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *ClaimCompensationWorkRequest) GetLeaseSeconds() int32 {
+	if x != nil {
+		return x.LeaseSeconds
+	}
+	return 0
+}
+
+func (x *ClaimCompensationWorkRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ClaimCompensationWorkRequest) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+func (x *ClaimCompensationWorkRequest) GetWork() WorkType {
+	if x != nil {
+		return x.Work
+	}
+	return WorkType_COMPENSATION_WORK
+}
+
+type CompensationWorkItem struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Lease  *CompensationWorkLease   `protobuf:"bytes,1,opt,name=lease,proto3" json:"lease,omitempty"`
+	Branch *TransactionBranchDetail `protobuf:"bytes,2,opt,name=branch,proto3" json:"branch,omitempty"`
+}
+
+func (x *CompensationWorkItem) Reset() {
+	*x = CompensationWorkItem{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[28]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CompensationWorkItem) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CompensationWorkItem) ProtoMessage() {}
+
+func (x *CompensationWorkItem) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[28]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CompensationWorkItem.ProtoReflect.Descriptor instead.
+func (*CompensationWorkItem) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *CompensationWorkItem) GetLease() *CompensationWorkLease {
+	if x != nil {
+		return x.Lease
+	}
+	return nil
+}
+
+func (x *CompensationWorkItem) GetBranch() *TransactionBranchDetail {
+	if x != nil {
+		return x.Branch
+	}
+	return nil
+}
+
+type ClaimCompensationWorkReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code  int32                   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error string                  `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Items []*CompensationWorkItem `protobuf:"bytes,3,rep,name=items,proto3" json:"items,omitempty"` // 按可以补偿的顺序排列
+}
+
+func (x *ClaimCompensationWorkReply) Reset() {
+	*x = ClaimCompensationWorkReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[29]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ClaimCompensationWorkReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ClaimCompensationWorkReply) ProtoMessage() {}
+
+func (x *ClaimCompensationWorkReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[29]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ClaimCompensationWorkReply.ProtoReflect.Descriptor instead.
+func (*ClaimCompensationWorkReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{29}
+}
+
+func (x *ClaimCompensationWorkReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ClaimCompensationWorkReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ClaimCompensationWorkReply) GetItems() []*CompensationWorkItem {
+	if x != nil {
+		return x.Items
+	}
+	return nil
+}
+
+type RenewCompensationWorkRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Node         *NodeInfo                `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Leases       []*CompensationWorkLease `protobuf:"bytes,2,rep,name=leases,proto3" json:"leases,omitempty"`
+	LeaseSeconds int32                    `protobuf:"varint,3,opt,name=leaseSeconds,proto3" json:"leaseSeconds,omitempty"`
+}
+
+func (x *RenewCompensationWorkRequest) Reset() {
+	*x = RenewCompensationWorkRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[30]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RenewCompensationWorkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewCompensationWorkRequest) ProtoMessage() {}
+
+func (x *RenewCompensationWorkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[30]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewCompensationWorkRequest.ProtoReflect.Descriptor instead.
+func (*RenewCompensationWorkRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *RenewCompensationWorkRequest) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *RenewCompensationWorkRequest) GetLeases() []*CompensationWorkLease {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+func (x *RenewCompensationWorkRequest) GetLeaseSeconds() int32 {
+	if x != nil {
+		return x.LeaseSeconds
+	}
+	return 0
+}
+
+type RenewCompensationWorkReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code   int32                    `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error  string                   `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Leases []*CompensationWorkLease `protobuf:"bytes,3,rep,name=leases,proto3" json:"leases,omitempty"` // 续约成功的租约，已经被其他参与方领取的不返回
+}
+
+func (x *RenewCompensationWorkReply) Reset() {
+	*x = RenewCompensationWorkReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[31]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RenewCompensationWorkReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RenewCompensationWorkReply) ProtoMessage() {}
+
+func (x *RenewCompensationWorkReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[31]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RenewCompensationWorkReply.ProtoReflect.Descriptor instead.
+func (*RenewCompensationWorkReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{31}
+}
+
+func (x *RenewCompensationWorkReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *RenewCompensationWorkReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *RenewCompensationWorkReply) GetLeases() []*CompensationWorkLease {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+type ReleaseCompensationWorkRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Node   *NodeInfo                `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`
+	Leases []*CompensationWorkLease `protobuf:"bytes,2,rep,name=leases,proto3" json:"leases,omitempty"`
+}
+
+func (x *ReleaseCompensationWorkRequest) Reset() {
+	*x = ReleaseCompensationWorkRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[32]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseCompensationWorkRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseCompensationWorkRequest) ProtoMessage() {}
+
+func (x *ReleaseCompensationWorkRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[32]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseCompensationWorkRequest.ProtoReflect.Descriptor instead.
+func (*ReleaseCompensationWorkRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *ReleaseCompensationWorkRequest) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *ReleaseCompensationWorkRequest) GetLeases() []*CompensationWorkLease {
+	if x != nil {
+		return x.Leases
+	}
+	return nil
+}
+
+type ReleaseCompensationWorkReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code     int32  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error    string `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Released int32  `protobuf:"varint,3,opt,name=released,proto3" json:"released,omitempty"`
+}
+
+func (x *ReleaseCompensationWorkReply) Reset() {
+	*x = ReleaseCompensationWorkReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[33]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReleaseCompensationWorkReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReleaseCompensationWorkReply) ProtoMessage() {}
+
+func (x *ReleaseCompensationWorkReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[33]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReleaseCompensationWorkReply.ProtoReflect.Descriptor instead.
+func (*ReleaseCompensationWorkReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{33}
+}
+
+func (x *ReleaseCompensationWorkReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *ReleaseCompensationWorkReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *ReleaseCompensationWorkReply) GetReleased() int32 {
+	if x != nil {
+		return x.Released
+	}
+	return 0
+}
+
+type HeartbeatBranchRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Node     *NodeInfo `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"` // 提供instanceId时必须是创建分支的实例
+	Xid      string    `protobuf:"bytes,2,opt,name=xid,proto3" json:"xid,omitempty"`
+	BranchId string    `protobuf:"bytes,3,opt,name=branchId,proto3" json:"branchId,omitempty"`
+}
+
+func (x *HeartbeatBranchRequest) Reset() {
+	*x = HeartbeatBranchRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[34]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeartbeatBranchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatBranchRequest) ProtoMessage() {}
+
+func (x *HeartbeatBranchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[34]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatBranchRequest.ProtoReflect.Descriptor instead.
+func (*HeartbeatBranchRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *HeartbeatBranchRequest) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *HeartbeatBranchRequest) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+func (x *HeartbeatBranchRequest) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+type HeartbeatBranchReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code          int32   `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success
+	Error         string  `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	State         TxState `protobuf:"varint,3,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"` // 分支当前的状态，不是PROCESSING时执行方可以停止心跳
+	GlobalTxState TxState `protobuf:"varint,4,opt,name=globalTxState,proto3,enum=saga.TxState" json:"globalTxState,omitempty"`
+}
+
+func (x *HeartbeatBranchReply) Reset() {
+	*x = HeartbeatBranchReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[35]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *HeartbeatBranchReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HeartbeatBranchReply) ProtoMessage() {}
+
+func (x *HeartbeatBranchReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[35]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HeartbeatBranchReply.ProtoReflect.Descriptor instead.
+func (*HeartbeatBranchReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *HeartbeatBranchReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *HeartbeatBranchReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *HeartbeatBranchReply) GetState() TxState {
+	if x != nil {
+		return x.State
+	}
+	return TxState_PROCESSING
+}
+
+func (x *HeartbeatBranchReply) GetGlobalTxState() TxState {
+	if x != nil {
+		return x.GlobalTxState
+	}
+	return TxState_PROCESSING
+}
+
+// 全局事务或者分支的一次状态变化
+type TxStateEvent struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid       string    `protobuf:"bytes,1,opt,name=xid,proto3" json:"xid,omitempty"`
+	BranchId  string    `protobuf:"bytes,2,opt,name=branchId,proto3" json:"branchId,omitempty"`                    // 为空表示是全局事务的状态变化
+	Node      *NodeInfo `protobuf:"bytes,3,opt,name=node,proto3" json:"node,omitempty"`                            // 分支的创建方，全局事务是发起方
+	OldState  TxState   `protobuf:"varint,4,opt,name=oldState,proto3,enum=saga.TxState" json:"oldState,omitempty"` // 和state相同表示新创建的全局事务或分支，或者是开始watch时的当前状态
+	State     TxState   `protobuf:"varint,5,opt,name=state,proto3,enum=saga.TxState" json:"state,omitempty"`
+	CreatedAt int64     `protobuf:"varint,6,opt,name=createdAt,proto3" json:"createdAt,omitempty"` // unix毫秒时间
+	// 为true时不是状态变化，而是全局事务中有node可以领取的work类型的任务(分支的前置补偿完成、重试等待结束或者租约到期释放)
+	// 这时branchId为空，state是全局事务的当前状态
+	WorkReady bool     `protobuf:"varint,7,opt,name=workReady,proto3" json:"workReady,omitempty"`
+	Work      WorkType `protobuf:"varint,8,opt,name=work,proto3,enum=saga.WorkType" json:"work,omitempty"`
+}
+
+func (x *TxStateEvent) Reset() {
+	*x = TxStateEvent{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[36]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *TxStateEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TxStateEvent) ProtoMessage() {}
+
+func (x *TxStateEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[36]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TxStateEvent.ProtoReflect.Descriptor instead.
+func (*TxStateEvent) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *TxStateEvent) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+func (x *TxStateEvent) GetBranchId() string {
+	if x != nil {
+		return x.BranchId
+	}
+	return ""
+}
+
+func (x *TxStateEvent) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *TxStateEvent) GetOldState() TxState {
+	if x != nil {
+		return x.OldState
+	}
+	return TxState_PROCESSING
+}
+
+func (x *TxStateEvent) GetState() TxState {
+	if x != nil {
+		return x.State
+	}
+	return TxState_PROCESSING
+}
+
+func (x *TxStateEvent) GetCreatedAt() int64 {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return 0
+}
+
+func (x *TxStateEvent) GetWorkReady() bool {
+	if x != nil {
+		return x.WorkReady
+	}
+	return false
+}
+
+func (x *TxStateEvent) GetWork() WorkType {
+	if x != nil {
+		return x.Work
+	}
+	return WorkType_COMPENSATION_WORK
+}
+
+type WatchGlobalTransactionRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Xid string `protobuf:"bytes,1,opt,name=xid,proto3" json:"xid,omitempty"`
+}
+
+func (x *WatchGlobalTransactionRequest) Reset() {
+	*x = WatchGlobalTransactionRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[37]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchGlobalTransactionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchGlobalTransactionRequest) ProtoMessage() {}
+
+func (x *WatchGlobalTransactionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[37]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchGlobalTransactionRequest.ProtoReflect.Descriptor instead.
+func (*WatchGlobalTransactionRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{37}
+}
+
+func (x *WatchGlobalTransactionRequest) GetXid() string {
+	if x != nil {
+		return x.Xid
+	}
+	return ""
+}
+
+type WatchGlobalTransactionReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code  int32         `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success，不为0时stream随之结束
+	Error string        `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Event *TxStateEvent `protobuf:"bytes,3,opt,name=event,proto3" json:"event,omitempty"`
+}
+
+func (x *WatchGlobalTransactionReply) Reset() {
+	*x = WatchGlobalTransactionReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[38]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *WatchGlobalTransactionReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchGlobalTransactionReply) ProtoMessage() {}
+
+func (x *WatchGlobalTransactionReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[38]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchGlobalTransactionReply.ProtoReflect.Descriptor instead.
+func (*WatchGlobalTransactionReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{38}
+}
+
+func (x *WatchGlobalTransactionReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *WatchGlobalTransactionReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *WatchGlobalTransactionReply) GetEvent() *TxStateEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+type SubscribeEventsRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Node   *NodeInfo  `protobuf:"bytes,1,opt,name=node,proto3" json:"node,omitempty"`                               // 只推送group和service相同的参与方创建的分支(和发起的全局事务)的事件，group或service为空时不按它过滤
+	States []TxState  `protobuf:"varint,2,rep,packed,name=states,proto3,enum=saga.TxState" json:"states,omitempty"` // 只推送变成这些状态的事件，为空时推送所有状态
+	Cursor string     `protobuf:"bytes,3,opt,name=cursor,proto3" json:"cursor,omitempty"`                           // 上次收到的最后一个事件的cursor，从它之后继续推送，为空时从现在开始
+	Works  []WorkType `protobuf:"varint,4,rep,packed,name=works,proto3,enum=saga.WorkType" json:"works,omitempty"`  // 推送这些类型的任务可以领取的事件(workReady)，works不为空而states为空时不推送状态变化
+}
+
+func (x *SubscribeEventsRequest) Reset() {
+	*x = SubscribeEventsRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[39]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribeEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeEventsRequest) ProtoMessage() {}
+
+func (x *SubscribeEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[39]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeEventsRequest.ProtoReflect.Descriptor instead.
+func (*SubscribeEventsRequest) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{39}
+}
+
+func (x *SubscribeEventsRequest) GetNode() *NodeInfo {
+	if x != nil {
+		return x.Node
+	}
+	return nil
+}
+
+func (x *SubscribeEventsRequest) GetStates() []TxState {
+	if x != nil {
+		return x.States
+	}
+	return nil
+}
+
+func (x *SubscribeEventsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *SubscribeEventsRequest) GetWorks() []WorkType {
+	if x != nil {
+		return x.Works
+	}
+	return nil
+}
+
+type SubscribeEventsReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Code   int32         `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"` // code == 0 means success，不为0时stream随之结束. 6表示cursor已经过期，需要重新同步状态后不带cursor订阅
+	Error  string        `protobuf:"bytes,2,opt,name=error,proto3" json:"error,omitempty"`
+	Event  *TxStateEvent `protobuf:"bytes,3,opt,name=event,proto3" json:"event,omitempty"`   // 补发完cursor之后的事件后有一个没有event的reply，表示订阅成功，cursor是订阅时的位置. 之后没有匹配的事件时也会定时推送没有event的reply，cursor是最新的位置
+	Cursor string        `protobuf:"bytes,4,opt,name=cursor,proto3" json:"cursor,omitempty"` // 这个事件的cursor，没有event时是订阅方已经收到所有匹配事件的位置
+}
+
+func (x *SubscribeEventsReply) Reset() {
+	*x = SubscribeEventsReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_protos_saga_proto_msgTypes[40]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *SubscribeEventsReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SubscribeEventsReply) ProtoMessage() {}
+
+func (x *SubscribeEventsReply) ProtoReflect() protoreflect.Message {
+	mi := &file_protos_saga_proto_msgTypes[40]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SubscribeEventsReply.ProtoReflect.Descriptor instead.
+func (*SubscribeEventsReply) Descriptor() ([]byte, []int) {
+	return file_protos_saga_proto_rawDescGZIP(), []int{40}
+}
+
+func (x *SubscribeEventsReply) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *SubscribeEventsReply) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *SubscribeEventsReply) GetEvent() *TxStateEvent {
+	if x != nil {
+		return x.Event
+	}
+	return nil
+}
+
+func (x *SubscribeEventsReply) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+var File_protos_saga_proto protoreflect.FileDescriptor
+
+var file_protos_saga_proto_rawDesc = []byte{
+	0x0a, 0x11, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x70, 0x72,
+	0x6f, 0x74, 0x6f, 0x12, 0x04, 0x73, 0x61, 0x67, 0x61, 0x22, 0x5a, 0x0a, 0x08, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x14, 0x0a, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x67, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x18, 0x0a, 0x07, 0x73,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61, 0x6e, 0x63,
+	0x65, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0a, 0x69, 0x6e, 0x73, 0x74, 0x61,
+	0x6e, 0x63, 0x65, 0x49, 0x64, 0x22, 0x97, 0x01, 0x0a, 0x0b, 0x52, 0x65, 0x74, 0x72, 0x79, 0x50,
+	0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x20, 0x0a, 0x0b, 0x6d, 0x61, 0x78, 0x41, 0x74, 0x74, 0x65,
+	0x6d, 0x70, 0x74, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x6d, 0x61, 0x78, 0x41,
+	0x74, 0x74, 0x65, 0x6d, 0x70, 0x74, 0x73, 0x12, 0x26, 0x0a, 0x0e, 0x69, 0x6e, 0x69, 0x74, 0x69,
+	0x61, 0x6c, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52,
+	0x0e, 0x69, 0x6e, 0x69, 0x74, 0x69, 0x61, 0x6c, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73, 0x12,
+	0x1e, 0x0a, 0x0a, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x69, 0x65, 0x72, 0x18, 0x03, 0x20,
+	0x01, 0x28, 0x01, 0x52, 0x0a, 0x6d, 0x75, 0x6c, 0x74, 0x69, 0x70, 0x6c, 0x69, 0x65, 0x72, 0x12,
+	0x1e, 0x0a, 0x0a, 0x6d, 0x61, 0x78, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73, 0x18, 0x04, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x0a, 0x6d, 0x61, 0x78, 0x44, 0x65, 0x6c, 0x61, 0x79, 0x4d, 0x73, 0x22,
+	0xe0, 0x01, 0x0a, 0x1e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65,
+	0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x65,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x14, 0x0a, 0x05,
+	0x65, 0x78, 0x74, 0x72, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x78, 0x74,
+	0x72, 0x61, 0x12, 0x33, 0x0a, 0x0b, 0x72, 0x65, 0x74, 0x72, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63,
+	0x79, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x52,
+	0x65, 0x74, 0x72, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52, 0x0b, 0x72, 0x65, 0x74, 0x72,
+	0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x29, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18,
+	0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x04, 0x6d, 0x6f,
+	0x64, 0x65, 0x22, 0x5a, 0x0a, 0x1c, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62,
+	0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03,
+	0x78, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0x9b,
+	0x04, 0x0a, 0x1e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32,
+	0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52,
+	0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x2a, 0x0a, 0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53,
+	0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65,
+	0x79, 0x12, 0x42, 0x0a, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65,
+	0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65,
+	0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43,
+	0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69,
+	0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x33, 0x0a, 0x0b, 0x72, 0x65, 0x74, 0x72, 0x79,
+	0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x11, 0x2e, 0x73,
+	0x61, 0x67, 0x61, 0x2e, 0x52, 0x65, 0x74, 0x72, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x52,
+	0x0b, 0x72, 0x65, 0x74, 0x72, 0x79, 0x50, 0x6f, 0x6c, 0x69, 0x63, 0x79, 0x12, 0x26, 0x0a, 0x0e,
+	0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x42, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x12, 0x28, 0x0a, 0x0f, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x47, 0x72, 0x6f, 0x75, 0x70, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0f, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x53, 0x69, 0x7a, 0x65,
+	0x12, 0x36, 0x0a, 0x0c, 0x72, 0x65, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x52, 0x65,
+	0x63, 0x6f, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x0c, 0x72, 0x65, 0x63, 0x6f,
+	0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x38, 0x0a, 0x17, 0x62, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
+	0x4b, 0x65, 0x79, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x09, 0x52, 0x17, 0x62, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x43, 0x6f, 0x6e, 0x66, 0x69, 0x72, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b,
+	0x65, 0x79, 0x12, 0x38, 0x0a, 0x17, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x54,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x0b, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x17, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x54, 0x69,
+	0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x64, 0x0a, 0x1c,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04,
+	0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65,
+	0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x49, 0x64, 0x22, 0x37, 0x0a, 0x23, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61,
+	0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61,
+	0x69, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0xf9, 0x06, 0x0a, 0x17,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x49, 0x64, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78,
+	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x34, 0x0a, 0x15,
+	0x63, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x69, 0x6c,
+	0x54, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x63, 0x6f, 0x6d,
+	0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x69, 0x6c, 0x54, 0x69, 0x6d,
+	0x65, 0x73, 0x12, 0x2a, 0x0a, 0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76,
+	0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x42,
+	0x0a, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x06,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b,
+	0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x20, 0x0a, 0x0b,
+	0x6e, 0x65, 0x78, 0x74, 0x52, 0x65, 0x74, 0x72, 0x79, 0x41, 0x74, 0x18, 0x08, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x0b, 0x6e, 0x65, 0x78, 0x74, 0x52, 0x65, 0x74, 0x72, 0x79, 0x41, 0x74, 0x12, 0x26,
+	0x0a, 0x0e, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64,
+	0x18, 0x09, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0e, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x42, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x12, 0x39, 0x0a, 0x08, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72,
+	0x65, 0x6e, 0x18, 0x0a, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72, 0x61, 0x6e, 0x63,
+	0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x08, 0x63, 0x68, 0x69, 0x6c, 0x64, 0x72, 0x65,
+	0x6e, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70,
+	0x18, 0x0b, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72,
+	0x6f, 0x75, 0x70, 0x12, 0x28, 0x0a, 0x0f, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f,
+	0x75, 0x70, 0x53, 0x69, 0x7a, 0x65, 0x18, 0x0c, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x36, 0x0a,
+	0x0c, 0x72, 0x65, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x18, 0x0d, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x12, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x52, 0x65, 0x63, 0x6f, 0x76,
+	0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64, 0x65, 0x52, 0x0c, 0x72, 0x65, 0x63, 0x6f, 0x76, 0x65, 0x72,
+	0x79, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x2c, 0x0a, 0x11, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64,
+	0x52, 0x65, 0x74, 0x72, 0x79, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x0e, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x11, 0x66, 0x6f, 0x72, 0x77, 0x61, 0x72, 0x64, 0x52, 0x65, 0x74, 0x72, 0x79, 0x54, 0x69,
+	0x6d, 0x65, 0x73, 0x12, 0x38, 0x0a, 0x17, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6e,
+	0x66, 0x69, 0x72, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x0f,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x17, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6e, 0x66,
+	0x69, 0x72, 0x6d, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12, 0x1e, 0x0a,
+	0x0a, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x18, 0x10, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x0a, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x4f, 0x77, 0x6e, 0x65, 0x72, 0x12, 0x24, 0x0a,
+	0x0d, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x41, 0x74, 0x18, 0x11,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72,
+	0x65, 0x41, 0x74, 0x12, 0x38, 0x0a, 0x17, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74,
+	0x54, 0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x12,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x17, 0x68, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x54,
+	0x69, 0x6d, 0x65, 0x6f, 0x75, 0x74, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x28, 0x0a,
+	0x0f, 0x6c, 0x61, 0x73, 0x74, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x41, 0x74,
+	0x18, 0x13, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0f, 0x6c, 0x61, 0x73, 0x74, 0x48, 0x65, 0x61, 0x72,
+	0x74, 0x62, 0x65, 0x61, 0x74, 0x41, 0x74, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x72, 0x70, 0x68, 0x61,
+	0x6e, 0x65, 0x64, 0x41, 0x74, 0x18, 0x14, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0a, 0x6f, 0x72, 0x70,
+	0x68, 0x61, 0x6e, 0x65, 0x64, 0x41, 0x74, 0x22, 0x8d, 0x02, 0x0a, 0x11, 0x42, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x20, 0x0a,
+	0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x12,
+	0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x73,
+	0x69, 0x7a, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x75,
+	0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0b, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x28, 0x0a, 0x0f, 0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73,
+	0x69, 0x6e, 0x67, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0f,
+	0x70, 0x72, 0x6f, 0x63, 0x65, 0x73, 0x73, 0x69, 0x6e, 0x67, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12,
+	0x26, 0x0a, 0x0e, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74, 0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e,
+	0x74, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0e, 0x63, 0x6f, 0x6d, 0x6d, 0x69, 0x74, 0x74,
+	0x65, 0x64, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x34, 0x0a, 0x15, 0x63, 0x6f, 0x6d, 0x70, 0x65,
+	0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74,
+	0x18, 0x06, 0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x63, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x44, 0x6f, 0x6e, 0x65, 0x43, 0x6f, 0x75, 0x6e, 0x74, 0x12, 0x18, 0x0a,
+	0x07, 0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x64, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x07,
+	0x73, 0x65, 0x74, 0x74, 0x6c, 0x65, 0x64, 0x22, 0xcc, 0x04, 0x0a, 0x21, 0x51, 0x75, 0x65, 0x72,
+	0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a,
 	0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64,
 	0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63,
-	0x68, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63,
-	0x68, 0x49, 0x64, 0x22, 0x37, 0x0a, 0x23, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62,
-	0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74,
-	0x61, 0x69, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69,
-	0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0xbe, 0x02, 0x0a,
-	0x17, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72, 0x61, 0x6e,
-	0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e,
-	0x63, 0x68, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e,
-	0x63, 0x68, 0x49, 0x64, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e,
-	0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74,
-	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54,
-	0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x34, 0x0a,
-	0x15, 0x63, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x69,
-	0x6c, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x15, 0x63, 0x6f,
-	0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x46, 0x61, 0x69, 0x6c, 0x54, 0x69,
-	0x6d, 0x65, 0x73, 0x12, 0x2a, 0x0a, 0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72,
-	0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10, 0x62,
-	0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x12,
-	0x42, 0x0a, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73,
-	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18,
-	0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x1c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d,
-	0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65,
-	0x4b, 0x65, 0x79, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x07,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x8f, 0x03,
-	0x0a, 0x21, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61,
-	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28,
-	0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x10, 0x0a,
-	0x03, 0x78, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12,
-	0x39, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28,
-	0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c,
-	0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x12, 0x30, 0x0a, 0x0b, 0x73, 0x74,
-	0x61, 0x72, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32,
-	0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a, 0x05,
-	0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x39, 0x0a, 0x08, 0x62, 0x72, 0x61,
+	0x6e, 0x63, 0x68, 0x65, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x65, 0x73, 0x12, 0x30, 0x0a, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74, 0x65, 0x72, 0x4e,
+	0x6f, 0x64, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0b, 0x73, 0x74, 0x61, 0x72, 0x74,
+	0x65, 0x72, 0x4e, 0x6f, 0x64, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x06, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x20, 0x0a, 0x0b, 0x65,
+	0x6e, 0x64, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08,
+	0x52, 0x0b, 0x65, 0x6e, 0x64, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73, 0x12, 0x18, 0x0a,
+	0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x08, 0x20, 0x01, 0x28, 0x05, 0x52, 0x07,
+	0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x64, 0x41, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64,
+	0x41, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x75, 0x70, 0x64, 0x61, 0x74, 0x65,
+	0x64, 0x41, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x53, 0x65, 0x63,
+	0x6f, 0x6e, 0x64, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0d, 0x65, 0x78, 0x70, 0x69,
+	0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x3d, 0x0a, 0x0a, 0x62, 0x72, 0x61,
+	0x6e, 0x63, 0x68, 0x54, 0x72, 0x65, 0x65, 0x18, 0x0c, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1d, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x0a, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x65, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x78, 0x74, 0x72,
+	0x61, 0x18, 0x0d, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x78, 0x74, 0x72, 0x61, 0x12, 0x3b,
+	0x0a, 0x0c, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x18, 0x0e,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x42, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x0c, 0x62,
+	0x72, 0x61, 0x6e, 0x63, 0x68, 0x47, 0x72, 0x6f, 0x75, 0x70, 0x73, 0x12, 0x29, 0x0a, 0x04, 0x6d,
+	0x6f, 0x64, 0x65, 0x18, 0x0f, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64, 0x65,
+	0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x22, 0x41, 0x0a, 0x23, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42,
+	0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a,
+	0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x22, 0xcb, 0x01, 0x0a, 0x21, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x35, 0x0a, 0x06, 0x64,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x06, 0x64, 0x65, 0x74, 0x61,
+	0x69, 0x6c, 0x12, 0x33, 0x0a, 0x0d, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x78, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x0d, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x22, 0xa7, 0x01, 0x0a, 0x23, 0x53, 0x75, 0x62, 0x6d,
+	0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69,
+	0x64, 0x12, 0x29, 0x0a, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x52, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x23, 0x0a, 0x05,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61,
 	0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74,
-	0x65, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x6e, 0x64, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x65, 0x73,
-	0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x0b, 0x65, 0x6e, 0x64, 0x42, 0x72, 0x61, 0x6e, 0x63,
-	0x68, 0x65, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x08,
-	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1c, 0x0a,
-	0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x09, 0x20, 0x01, 0x28, 0x03,
-	0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x75,
-	0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x18, 0x0a, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09,
-	0x75, 0x70, 0x64, 0x61, 0x74, 0x65, 0x64, 0x41, 0x74, 0x12, 0x24, 0x0a, 0x0d, 0x65, 0x78, 0x70,
-	0x69, 0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x0b, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x0d, 0x65, 0x78, 0x70, 0x69, 0x72, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22,
-	0x41, 0x0a, 0x23, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
-	0x49, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
-	0x49, 0x64, 0x22, 0xcb, 0x01, 0x0a, 0x21, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e,
-	0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74,
-	0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
-	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05,
-	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
-	0x6f, 0x72, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x03, 0x78, 0x69, 0x64, 0x12, 0x35, 0x0a, 0x06, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x18, 0x04,
-	0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74,
-	0x61, 0x69, 0x6c, 0x52, 0x06, 0x64, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x33, 0x0a, 0x0d, 0x67,
-	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01,
-	0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74,
-	0x65, 0x52, 0x0d, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65,
-	0x22, 0xa7, 0x01, 0x0a, 0x23, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61,
+	0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f,
+	0x6e, 0x22, 0x72, 0x0a, 0x21, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61,
 	0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74,
-	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18,
-	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x29, 0x0a, 0x08, 0x6f, 0x6c,
-	0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73,
-	0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x6f, 0x6c, 0x64,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03,
-	0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74,
-	0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x6c,
-	0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a,
-	0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x72, 0x0a, 0x21, 0x53, 0x75,
-	0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72,
+	0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72,
+	0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32,
+	0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05,
+	0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0xb1, 0x02, 0x0a, 0x23, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74,
+	0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f,
+	0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a,
+	0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12,
+	0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x08, 0x6f,
+	0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x6f, 0x6c,
+	0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6f,
+	0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52,
+	0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6a,
+	0x6f, 0x62, 0x49, 0x64, 0x18, 0x06, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49,
+	0x64, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e,
+	0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x61,
+	0x73, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x18,
+	0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12,
+	0x18, 0x0a, 0x07, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x64, 0x18, 0x09, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x07, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x64, 0x22, 0x72, 0x0a, 0x21, 0x53, 0x75, 0x62,
+	0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12,
+	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54,
+	0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0x3b, 0x0a,
+	0x13, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x3d, 0x0a, 0x11, 0x49, 0x6e,
+	0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
 	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
 	0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01,
-	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61,
-	0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
-	0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0x97,
-	0x02, 0x0a, 0x23, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54,
-	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x26, 0x0a, 0x12, 0x47, 0x65, 0x74,
+	0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69,
+	0x64, 0x22, 0x6a, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12,
+	0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64,
+	0x61, 0x74, 0x61, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x64, 0x0a,
+	0x25, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78,
+	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a,
+	0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69,
+	0x6d, 0x69, 0x74, 0x22, 0x63, 0x0a, 0x23, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61,
+	0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03,
+	0x28, 0x09, 0x52, 0x04, 0x78, 0x69, 0x64, 0x73, 0x22, 0xa7, 0x01, 0x0a, 0x19, 0x42, 0x72, 0x61,
+	0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52,
 	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20,
 	0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e,
 	0x63, 0x68, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e,
-	0x63, 0x68, 0x49, 0x64, 0x12, 0x29, 0x0a, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12,
-	0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d,
-	0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73,
-	0x74, 0x61, 0x74, 0x65, 0x12, 0x1e, 0x0a, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72, 0x73, 0x69,
-	0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0a, 0x6f, 0x6c, 0x64, 0x56, 0x65, 0x72,
-	0x73, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x18, 0x06, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62, 0x49, 0x64, 0x12, 0x20, 0x0a, 0x0b, 0x65, 0x72,
-	0x72, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x18, 0x07, 0x20, 0x01, 0x28, 0x09, 0x52,
-	0x0b, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x52, 0x65, 0x61, 0x73, 0x6f, 0x6e, 0x12, 0x1a, 0x0a, 0x08,
-	0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08,
-	0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x22, 0x72, 0x0a, 0x21, 0x53, 0x75, 0x62, 0x6d,
-	0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a,
+	0x63, 0x68, 0x49, 0x64, 0x12, 0x2a, 0x0a, 0x10, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65,
+	0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x10,
+	0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x4b, 0x65, 0x79,
+	0x12, 0x1a, 0x0a, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0c, 0x52, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x14, 0x0a, 0x05,
+	0x6a, 0x6f, 0x62, 0x49, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x6a, 0x6f, 0x62,
+	0x49, 0x64, 0x22, 0x5f, 0x0a, 0x17, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a,
 	0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64,
 	0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65,
-	0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x22, 0x3b, 0x0a, 0x13,
-	0x49, 0x6e, 0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75,
-	0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
-	0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61, 0x74, 0x61, 0x22, 0x3d, 0x0a, 0x11, 0x49, 0x6e, 0x69,
-	0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12,
-	0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f,
-	0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x22, 0x26, 0x0a, 0x12, 0x47, 0x65, 0x74, 0x53,
-	0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10,
+	0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44,
+	0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x08, 0x73, 0x61, 0x67, 0x61, 0x44,
+	0x61, 0x74, 0x61, 0x22, 0x31, 0x0a, 0x1d, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x47, 0x6c, 0x6f, 0x62,
+	0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0x6c, 0x0a, 0x1b, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x47,
+	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12,
+	0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d,
+	0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x22, 0x85, 0x01, 0x0a, 0x15, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x10,
 	0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64,
-	0x22, 0x6a, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52,
+	0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x12, 0x18, 0x0a, 0x07,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x49, 0x64, 0x12, 0x24, 0x0a, 0x0d, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x45,
+	0x78, 0x70, 0x69, 0x72, 0x65, 0x41, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x0d, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x45, 0x78, 0x70, 0x69, 0x72, 0x65, 0x41, 0x74, 0x22, 0xb2, 0x01, 0x0a,
+	0x1c, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a,
+	0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64,
+	0x65, 0x12, 0x22, 0x0a, 0x0c, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64,
+	0x73, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x65,
+	0x63, 0x6f, 0x6e, 0x64, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x03,
+	0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d, 0x69, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78,
+	0x69, 0x64, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x22, 0x0a,
+	0x04, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x77, 0x6f, 0x72,
+	0x6b, 0x22, 0x80, 0x01, 0x0a, 0x14, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69,
+	0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x49, 0x74, 0x65, 0x6d, 0x12, 0x31, 0x0a, 0x05, 0x6c, 0x65,
+	0x61, 0x73, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72,
+	0x6b, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x52, 0x05, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x12, 0x35, 0x0a,
+	0x06, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1d, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x06, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x22, 0x78, 0x0a, 0x1a, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x6d,
+	0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x30, 0x0a, 0x05,
+	0x69, 0x74, 0x65, 0x6d, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57,
+	0x6f, 0x72, 0x6b, 0x49, 0x74, 0x65, 0x6d, 0x52, 0x05, 0x69, 0x74, 0x65, 0x6d, 0x73, 0x22, 0x9b,
+	0x01, 0x0a, 0x1c, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12,
+	0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e,
+	0x6f, 0x64, 0x65, 0x12, 0x33, 0x0a, 0x06, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20,
+	0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x65,
+	0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x4c, 0x65, 0x61, 0x73, 0x65,
+	0x52, 0x06, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x12, 0x22, 0x0a, 0x0c, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x0c,
+	0x6c, 0x65, 0x61, 0x73, 0x65, 0x53, 0x65, 0x63, 0x6f, 0x6e, 0x64, 0x73, 0x22, 0x7b, 0x0a, 0x1a,
+	0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f,
+	0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14,
+	0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65,
+	0x72, 0x72, 0x6f, 0x72, 0x12, 0x33, 0x0a, 0x06, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x18, 0x03,
+	0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x4c, 0x65, 0x61, 0x73,
+	0x65, 0x52, 0x06, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x22, 0x79, 0x0a, 0x1e, 0x52, 0x65, 0x6c,
+	0x65, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e,
+	0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e,
+	0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12,
+	0x33, 0x0a, 0x06, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32,
+	0x1b, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x4c, 0x65, 0x61, 0x73, 0x65, 0x52, 0x06, 0x6c, 0x65,
+	0x61, 0x73, 0x65, 0x73, 0x22, 0x64, 0x0a, 0x1c, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x43,
+	0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52,
 	0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01,
 	0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f,
-	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x12,
-	0x0a, 0x04, 0x64, 0x61, 0x74, 0x61, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x04, 0x64, 0x61,
-	0x74, 0x61, 0x12, 0x18, 0x0a, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x04, 0x20,
-	0x01, 0x28, 0x05, 0x52, 0x07, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x22, 0x64, 0x0a, 0x25,
-	0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
-	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x25, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x18,
-	0x01, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53,
-	0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x14, 0x0a, 0x05,
-	0x6c, 0x69, 0x6d, 0x69, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x6c, 0x69, 0x6d,
-	0x69, 0x74, 0x22, 0x63, 0x0a, 0x23, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
-	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74,
-	0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64,
+	0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x1a,
+	0x0a, 0x08, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x08, 0x72, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x64, 0x22, 0x6a, 0x0a, 0x16, 0x48, 0x65,
+	0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e,
+	0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x49, 0x64, 0x22, 0x9a, 0x01, 0x0a, 0x14, 0x48, 0x65, 0x61, 0x72, 0x74,
+	0x62, 0x65, 0x61, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12,
+	0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63,
+	0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61,
+	0x74, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
+	0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x33,
+	0x0a, 0x0d, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x18,
+	0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53,
+	0x74, 0x61, 0x74, 0x65, 0x52, 0x0d, 0x67, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x78, 0x53, 0x74,
+	0x61, 0x74, 0x65, 0x22, 0x90, 0x02, 0x0a, 0x0c, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x45,
+	0x76, 0x65, 0x6e, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x49, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x49, 0x64, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x29, 0x0a, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
+	0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x08, 0x6f, 0x6c, 0x64, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x23, 0x0a, 0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x0d, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52,
+	0x05, 0x73, 0x74, 0x61, 0x74, 0x65, 0x12, 0x1c, 0x0a, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74, 0x65,
+	0x64, 0x41, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x09, 0x63, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x64, 0x41, 0x74, 0x12, 0x1c, 0x0a, 0x09, 0x77, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x61, 0x64,
+	0x79, 0x18, 0x07, 0x20, 0x01, 0x28, 0x08, 0x52, 0x09, 0x77, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x61,
+	0x64, 0x79, 0x12, 0x22, 0x0a, 0x04, 0x77, 0x6f, 0x72, 0x6b, 0x18, 0x08, 0x20, 0x01, 0x28, 0x0e,
+	0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x54, 0x79, 0x70, 0x65,
+	0x52, 0x04, 0x77, 0x6f, 0x72, 0x6b, 0x22, 0x31, 0x0a, 0x1d, 0x57, 0x61, 0x74, 0x63, 0x68, 0x47,
+	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x10, 0x0a, 0x03, 0x78, 0x69, 0x64, 0x18, 0x01,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x03, 0x78, 0x69, 0x64, 0x22, 0x71, 0x0a, 0x1b, 0x57, 0x61, 0x74,
+	0x63, 0x68, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64, 0x65,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a, 0x05,
+	0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72, 0x72,
+	0x6f, 0x72, 0x12, 0x28, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x0b, 0x32, 0x12, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65,
+	0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x22, 0xa1, 0x01, 0x0a,
+	0x16, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x22, 0x0a, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4e, 0x6f, 0x64,
+	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x04, 0x6e, 0x6f, 0x64, 0x65, 0x12, 0x25, 0x0a, 0x06, 0x73,
+	0x74, 0x61, 0x74, 0x65, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0d, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74,
+	0x65, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x09, 0x52, 0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x12, 0x24, 0x0a, 0x05, 0x77, 0x6f,
+	0x72, 0x6b, 0x73, 0x18, 0x04, 0x20, 0x03, 0x28, 0x0e, 0x32, 0x0e, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x57, 0x6f, 0x72, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x52, 0x05, 0x77, 0x6f, 0x72, 0x6b, 0x73,
+	0x22, 0x82, 0x01, 0x0a, 0x14, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x45, 0x76,
+	0x65, 0x6e, 0x74, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x12, 0x0a, 0x04, 0x63, 0x6f, 0x64,
 	0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05, 0x52, 0x04, 0x63, 0x6f, 0x64, 0x65, 0x12, 0x14, 0x0a,
 	0x05, 0x65, 0x72, 0x72, 0x6f, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x05, 0x65, 0x72,
-	0x72, 0x6f, 0x72, 0x12, 0x12, 0x0a, 0x04, 0x78, 0x69, 0x64, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28,
-	0x09, 0x52, 0x04, 0x78, 0x69, 0x64, 0x73, 0x2a, 0x86, 0x01, 0x0a, 0x07, 0x54, 0x78, 0x53, 0x74,
-	0x61, 0x74, 0x65, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x52, 0x4f, 0x43, 0x45, 0x53, 0x53, 0x49, 0x4e,
-	0x47, 0x10, 0x00, 0x12, 0x0d, 0x0a, 0x09, 0x43, 0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x54, 0x45, 0x44,
-	0x10, 0x01, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f, 0x4d, 0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49,
-	0x4f, 0x4e, 0x5f, 0x44, 0x4f, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f,
-	0x4d, 0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52,
-	0x10, 0x03, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d, 0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49,
-	0x4f, 0x4e, 0x5f, 0x44, 0x4f, 0x4e, 0x45, 0x10, 0x04, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d,
-	0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x10, 0x05,
-	0x32, 0xa5, 0x07, 0x0a, 0x0a, 0x53, 0x61, 0x67, 0x61, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12,
-	0x63, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54,
-	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x2e, 0x73, 0x61, 0x67,
-	0x61, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72,
-	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x22, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c,
-	0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x12, 0x63, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72,
-	0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
-	0x24, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61,
-	0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
-	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x72, 0x65,
-	0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72, 0x0a, 0x1c, 0x51, 0x75, 0x65,
-	0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
-	0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61,
-	0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75, 0x65, 0x72,
-	0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
-	0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72, 0x0a,
-	0x1c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x29, 0x2e,
-	0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68,
-	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69,
-	0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
-	0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73,
-	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x12, 0x72, 0x0a, 0x1c, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61,
-	0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74,
-	0x65, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47,
+	0x72, 0x6f, 0x72, 0x12, 0x28, 0x0a, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x18, 0x03, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x12, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x52, 0x05, 0x65, 0x76, 0x65, 0x6e, 0x74, 0x12, 0x16, 0x0a,
+	0x06, 0x63, 0x75, 0x72, 0x73, 0x6f, 0x72, 0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x63,
+	0x75, 0x72, 0x73, 0x6f, 0x72, 0x2a, 0xb3, 0x01, 0x0a, 0x07, 0x54, 0x78, 0x53, 0x74, 0x61, 0x74,
+	0x65, 0x12, 0x0e, 0x0a, 0x0a, 0x50, 0x52, 0x4f, 0x43, 0x45, 0x53, 0x53, 0x49, 0x4e, 0x47, 0x10,
+	0x00, 0x12, 0x0d, 0x0a, 0x09, 0x43, 0x4f, 0x4d, 0x4d, 0x49, 0x54, 0x54, 0x45, 0x44, 0x10, 0x01,
+	0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f, 0x4d, 0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x44, 0x4f, 0x49, 0x4e, 0x47, 0x10, 0x02, 0x12, 0x16, 0x0a, 0x12, 0x43, 0x4f, 0x4d, 0x50,
+	0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x03,
+	0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d, 0x50, 0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e,
+	0x5f, 0x44, 0x4f, 0x4e, 0x45, 0x10, 0x04, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d, 0x50, 0x45,
+	0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x46, 0x41, 0x49, 0x4c, 0x10, 0x05, 0x12, 0x0c,
+	0x0a, 0x08, 0x52, 0x45, 0x54, 0x52, 0x59, 0x49, 0x4e, 0x47, 0x10, 0x06, 0x12, 0x0e, 0x0a, 0x0a,
+	0x43, 0x4f, 0x4e, 0x46, 0x49, 0x52, 0x4d, 0x49, 0x4e, 0x47, 0x10, 0x07, 0x12, 0x0d, 0x0a, 0x09,
+	0x43, 0x4f, 0x4e, 0x46, 0x49, 0x52, 0x4d, 0x45, 0x44, 0x10, 0x08, 0x2a, 0x24, 0x0a, 0x0f, 0x54,
+	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x4d, 0x6f, 0x64, 0x65, 0x12, 0x08,
+	0x0a, 0x04, 0x53, 0x41, 0x47, 0x41, 0x10, 0x00, 0x12, 0x07, 0x0a, 0x03, 0x54, 0x43, 0x43, 0x10,
+	0x01, 0x2a, 0x29, 0x0a, 0x0c, 0x52, 0x65, 0x63, 0x6f, 0x76, 0x65, 0x72, 0x79, 0x4d, 0x6f, 0x64,
+	0x65, 0x12, 0x0c, 0x0a, 0x08, 0x42, 0x41, 0x43, 0x4b, 0x57, 0x41, 0x52, 0x44, 0x10, 0x00, 0x12,
+	0x0b, 0x0a, 0x07, 0x46, 0x4f, 0x52, 0x57, 0x41, 0x52, 0x44, 0x10, 0x01, 0x2a, 0x4b, 0x0a, 0x08,
+	0x57, 0x6f, 0x72, 0x6b, 0x54, 0x79, 0x70, 0x65, 0x12, 0x15, 0x0a, 0x11, 0x43, 0x4f, 0x4d, 0x50,
+	0x45, 0x4e, 0x53, 0x41, 0x54, 0x49, 0x4f, 0x4e, 0x5f, 0x57, 0x4f, 0x52, 0x4b, 0x10, 0x00, 0x12,
+	0x16, 0x0a, 0x12, 0x46, 0x4f, 0x52, 0x57, 0x41, 0x52, 0x44, 0x5f, 0x52, 0x45, 0x54, 0x52, 0x59,
+	0x5f, 0x57, 0x4f, 0x52, 0x4b, 0x10, 0x01, 0x12, 0x10, 0x0a, 0x0c, 0x43, 0x4f, 0x4e, 0x46, 0x49,
+	0x52, 0x4d, 0x5f, 0x57, 0x4f, 0x52, 0x4b, 0x10, 0x02, 0x32, 0xaa, 0x0c, 0x0a, 0x0a, 0x53, 0x61,
+	0x67, 0x61, 0x53, 0x65, 0x72, 0x76, 0x65, 0x72, 0x12, 0x63, 0x0a, 0x17, 0x43, 0x72, 0x65, 0x61,
+	0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x12, 0x24, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74,
+	0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69,
+	0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x63, 0x0a,
+	0x17, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x24, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
+	0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e,
+	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x22,
+	0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x72, 0x65, 0x61, 0x74, 0x65, 0x42, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x72, 0x0a, 0x1c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61,
+	0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61,
+	0x69, 0x6c, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47,
 	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73,
-	0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
-	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65,
-	0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72, 0x0a, 0x1c, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42,
+	0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69,
+	0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72, 0x0a, 0x1c, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42,
 	0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62,
-	0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
-	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42, 0x72,
-	0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53,
-	0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x42, 0x0a, 0x0c, 0x49, 0x6e, 0x69,
-	0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x19, 0x2e, 0x73, 0x61, 0x67, 0x61,
-	0x2e, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x49, 0x6e, 0x69, 0x74,
-	0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x3f, 0x0a,
-	0x0b, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x18, 0x2e, 0x73,
-	0x61, 0x67, 0x61, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52,
-	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x47, 0x65,
-	0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x78,
-	0x0a, 0x1e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e,
-	0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73,
-	0x12, 0x2b, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62,
-	0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66,
-	0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e,
-	0x73, 0x61, 0x67, 0x61, 0x2e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54,
-	0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61,
-	0x74, 0x65, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x15, 0x5a, 0x05, 0x2e, 0x3b, 0x61, 0x70,
-	0x69, 0xaa, 0x02, 0x0b, 0x73, 0x61, 0x67, 0x61, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65, 0x72, 0x62,
-	0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75,
+	0x65, 0x72, 0x79, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x44, 0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x51, 0x75, 0x65, 0x72, 0x79, 0x42, 0x72,
+	0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x44,
+	0x65, 0x74, 0x61, 0x69, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72, 0x0a, 0x1c, 0x53, 0x75,
+	0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x29, 0x2e, 0x73, 0x61, 0x67,
+	0x61, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65,
+	0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62,
+	0x6d, 0x69, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63,
+	0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x72,
+	0x0a, 0x1c, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x12, 0x29,
+	0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e,
+	0x63, 0x68, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61,
+	0x74, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x27, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x53, 0x75, 0x62, 0x6d, 0x69, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x54, 0x72, 0x61,
+	0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x53, 0x74, 0x61, 0x74, 0x65, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x42, 0x0a, 0x0c, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61,
+	0x74, 0x61, 0x12, 0x19, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x61,
+	0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x49, 0x6e, 0x69, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74,
+	0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x3f, 0x0a, 0x0b, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67,
+	0x61, 0x44, 0x61, 0x74, 0x61, 0x12, 0x18, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x47, 0x65, 0x74,
+	0x53, 0x61, 0x67, 0x61, 0x44, 0x61, 0x74, 0x61, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x16, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x47, 0x65, 0x74, 0x53, 0x61, 0x67, 0x61, 0x44, 0x61,
+	0x74, 0x61, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x78, 0x0a, 0x1e, 0x4c, 0x69, 0x73, 0x74, 0x47,
+	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x12, 0x2b, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x4c, 0x69, 0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x52,
+	0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x29, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x4c, 0x69,
+	0x73, 0x74, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x4f, 0x66, 0x53, 0x74, 0x61, 0x74, 0x65, 0x73, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x12, 0x60, 0x0a, 0x16, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x21, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x6c, 0x6f, 0x73, 0x65, 0x47, 0x6c, 0x6f,
+	0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x5d, 0x0a, 0x15, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x12, 0x22, 0x2e, 0x73,
+	0x61, 0x67, 0x61, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
+	0x1a, 0x20, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x43, 0x6c, 0x61, 0x69, 0x6d, 0x43, 0x6f, 0x6d,
+	0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x70,
+	0x6c, 0x79, 0x12, 0x5d, 0x0a, 0x15, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x70, 0x65,
+	0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x12, 0x22, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x20, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x52, 0x65, 0x6e, 0x65, 0x77, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x70, 0x6c,
+	0x79, 0x12, 0x63, 0x0a, 0x17, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6d, 0x70,
+	0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x12, 0x24, 0x2e, 0x73,
+	0x61, 0x67, 0x61, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73, 0x65, 0x43, 0x6f, 0x6d, 0x70, 0x65,
+	0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72, 0x6b, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x22, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x52, 0x65, 0x6c, 0x65, 0x61, 0x73,
+	0x65, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x57, 0x6f, 0x72,
+	0x6b, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x4b, 0x0a, 0x0f, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62,
+	0x65, 0x61, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x12, 0x1c, 0x2e, 0x73, 0x61, 0x67, 0x61,
+	0x2e, 0x48, 0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x48,
+	0x65, 0x61, 0x72, 0x74, 0x62, 0x65, 0x61, 0x74, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x52, 0x65,
+	0x70, 0x6c, 0x79, 0x12, 0x62, 0x0a, 0x16, 0x57, 0x61, 0x74, 0x63, 0x68, 0x47, 0x6c, 0x6f, 0x62,
+	0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x23, 0x2e,
+	0x73, 0x61, 0x67, 0x61, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x47, 0x6c, 0x6f, 0x62, 0x61, 0x6c,
+	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x21, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e, 0x57, 0x61, 0x74, 0x63, 0x68, 0x47,
+	0x6c, 0x6f, 0x62, 0x61, 0x6c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x30, 0x01, 0x12, 0x4d, 0x0a, 0x0f, 0x53, 0x75, 0x62, 0x73, 0x63,
+	0x72, 0x69, 0x62, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x12, 0x1c, 0x2e, 0x73, 0x61, 0x67,
+	0x61, 0x2e, 0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74,
+	0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x73, 0x61, 0x67, 0x61, 0x2e,
+	0x53, 0x75, 0x62, 0x73, 0x63, 0x72, 0x69, 0x62, 0x65, 0x45, 0x76, 0x65, 0x6e, 0x74, 0x73, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x30, 0x01, 0x32, 0x62, 0x0a, 0x12, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68,
+	0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x4c, 0x0a, 0x0a,
+	0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61, 0x74, 0x65, 0x12, 0x1f, 0x2e, 0x73, 0x61, 0x67,
+	0x61, 0x2e, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1d, 0x2e, 0x73, 0x61,
+	0x67, 0x61, 0x2e, 0x42, 0x72, 0x61, 0x6e, 0x63, 0x68, 0x43, 0x6f, 0x6d, 0x70, 0x65, 0x6e, 0x73,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x15, 0x5a, 0x05, 0x2e, 0x3b,
+	0x61, 0x70, 0x69, 0xaa, 0x02, 0x0b, 0x73, 0x61, 0x67, 0x61, 0x5f, 0x73, 0x65, 0x72, 0x76, 0x65,
+	0x72, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -1716,71 +3830,143 @@ func file_protos_saga_proto_rawDescGZIP() []byte {
 	return file_protos_saga_proto_rawDescData
 }
 
-var file_protos_saga_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
-var file_protos_saga_proto_msgTypes = make([]protoimpl.MessageInfo, 20)
+var file_protos_saga_proto_enumTypes = make([]protoimpl.EnumInfo, 4)
+var file_protos_saga_proto_msgTypes = make([]protoimpl.MessageInfo, 41)
 var file_protos_saga_proto_goTypes = []interface{}{
 	(TxState)(0),                                  // 0: saga.TxState
-	(*NodeInfo)(nil),                              // 1: saga.NodeInfo
-	(*CreateGlobalTransactionRequest)(nil),        // 2: saga.CreateGlobalTransactionRequest
-	(*CreateGlobalTransactionReply)(nil),          // 3: saga.CreateGlobalTransactionReply
-	(*CreateBranchTransactionRequest)(nil),        // 4: saga.CreateBranchTransactionRequest
-	(*CreateBranchTransactionReply)(nil),          // 5: saga.CreateBranchTransactionReply
-	(*QueryGlobalTransactionDetailRequest)(nil),   // 6: saga.QueryGlobalTransactionDetailRequest
-	(*TransactionBranchDetail)(nil),               // 7: saga.TransactionBranchDetail
-	(*QueryGlobalTransactionDetailReply)(nil),     // 8: saga.QueryGlobalTransactionDetailReply
-	(*QueryBranchTransactionDetailRequest)(nil),   // 9: saga.QueryBranchTransactionDetailRequest
-	(*QueryBranchTransactionDetailReply)(nil),     // 10: saga.QueryBranchTransactionDetailReply
-	(*SubmitGlobalTransactionStateRequest)(nil),   // 11: saga.SubmitGlobalTransactionStateRequest
-	(*SubmitGlobalTransactionStateReply)(nil),     // 12: saga.SubmitGlobalTransactionStateReply
-	(*SubmitBranchTransactionStateRequest)(nil),   // 13: saga.SubmitBranchTransactionStateRequest
-	(*SubmitBranchTransactionStateReply)(nil),     // 14: saga.SubmitBranchTransactionStateReply
-	(*InitSagaDataRequest)(nil),                   // 15: saga.InitSagaDataRequest
-	(*InitSagaDataReply)(nil),                     // 16: saga.InitSagaDataReply
-	(*GetSagaDataRequest)(nil),                    // 17: saga.GetSagaDataRequest
-	(*GetSagaDataReply)(nil),                      // 18: saga.GetSagaDataReply
-	(*ListGlobalTransactionsOfStatesRequest)(nil), // 19: saga.ListGlobalTransactionsOfStatesRequest
-	(*ListGlobalTransactionsOfStatesReply)(nil),   // 20: saga.ListGlobalTransactionsOfStatesReply
+	(TransactionMode)(0),                          // 1: saga.TransactionMode
+	(RecoveryMode)(0),                             // 2: saga.RecoveryMode
+	(WorkType)(0),                                 // 3: saga.WorkType
+	(*NodeInfo)(nil),                              // 4: saga.NodeInfo
+	(*RetryPolicy)(nil),                           // 5: saga.RetryPolicy
+	(*CreateGlobalTransactionRequest)(nil),        // 6: saga.CreateGlobalTransactionRequest
+	(*CreateGlobalTransactionReply)(nil),          // 7: saga.CreateGlobalTransactionReply
+	(*CreateBranchTransactionRequest)(nil),        // 8: saga.CreateBranchTransactionRequest
+	(*CreateBranchTransactionReply)(nil),          // 9: saga.CreateBranchTransactionReply
+	(*QueryGlobalTransactionDetailRequest)(nil),   // 10: saga.QueryGlobalTransactionDetailRequest
+	(*TransactionBranchDetail)(nil),               // 11: saga.TransactionBranchDetail
+	(*BranchGroupDetail)(nil),                     // 12: saga.BranchGroupDetail
+	(*QueryGlobalTransactionDetailReply)(nil),     // 13: saga.QueryGlobalTransactionDetailReply
+	(*QueryBranchTransactionDetailRequest)(nil),   // 14: saga.QueryBranchTransactionDetailRequest
+	(*QueryBranchTransactionDetailReply)(nil),     // 15: saga.QueryBranchTransactionDetailReply
+	(*SubmitGlobalTransactionStateRequest)(nil),   // 16: saga.SubmitGlobalTransactionStateRequest
+	(*SubmitGlobalTransactionStateReply)(nil),     // 17: saga.SubmitGlobalTransactionStateReply
+	(*SubmitBranchTransactionStateRequest)(nil),   // 18: saga.SubmitBranchTransactionStateRequest
+	(*SubmitBranchTransactionStateReply)(nil),     // 19: saga.SubmitBranchTransactionStateReply
+	(*InitSagaDataRequest)(nil),                   // 20: saga.InitSagaDataRequest
+	(*InitSagaDataReply)(nil),                     // 21: saga.InitSagaDataReply
+	(*GetSagaDataRequest)(nil),                    // 22: saga.GetSagaDataRequest
+	(*GetSagaDataReply)(nil),                      // 23: saga.GetSagaDataReply
+	(*ListGlobalTransactionsOfStatesRequest)(nil), // 24: saga.ListGlobalTransactionsOfStatesRequest
+	(*ListGlobalTransactionsOfStatesReply)(nil),   // 25: saga.ListGlobalTransactionsOfStatesReply
+	(*BranchCompensationRequest)(nil),             // 26: saga.BranchCompensationRequest
+	(*BranchCompensationReply)(nil),               // 27: saga.BranchCompensationReply
+	(*CloseGlobalTransactionRequest)(nil),         // 28: saga.CloseGlobalTransactionRequest
+	(*CloseGlobalTransactionReply)(nil),           // 29: saga.CloseGlobalTransactionReply
+	(*CompensationWorkLease)(nil),                 // 30: saga.CompensationWorkLease
+	(*ClaimCompensationWorkRequest)(nil),          // 31: saga.ClaimCompensationWorkRequest
+	(*CompensationWorkItem)(nil),                  // 32: saga.CompensationWorkItem
+	(*ClaimCompensationWorkReply)(nil),            // 33: saga.ClaimCompensationWorkReply
+	(*RenewCompensationWorkRequest)(nil),          // 34: saga.RenewCompensationWorkRequest
+	(*RenewCompensationWorkReply)(nil),            // 35: saga.RenewCompensationWorkReply
+	(*ReleaseCompensationWorkRequest)(nil),        // 36: saga.ReleaseCompensationWorkRequest
+	(*ReleaseCompensationWorkReply)(nil),          // 37: saga.ReleaseCompensationWorkReply
+	(*HeartbeatBranchRequest)(nil),                // 38: saga.HeartbeatBranchRequest
+	(*HeartbeatBranchReply)(nil),                  // 39: saga.HeartbeatBranchReply
+	(*TxStateEvent)(nil),                          // 40: saga.TxStateEvent
+	(*WatchGlobalTransactionRequest)(nil),         // 41: saga.WatchGlobalTransactionRequest
+	(*WatchGlobalTransactionReply)(nil),           // 42: saga.WatchGlobalTransactionReply
+	(*SubscribeEventsRequest)(nil),                // 43: saga.SubscribeEventsRequest
+	(*SubscribeEventsReply)(nil),                  // 44: saga.SubscribeEventsReply
 }
 var file_protos_saga_proto_depIdxs = []int32{
-	1,  // 0: saga.CreateGlobalTransactionRequest.node:type_name -> saga.NodeInfo
-	1,  // 1: saga.CreateBranchTransactionRequest.node:type_name -> saga.NodeInfo
-	1,  // 2: saga.TransactionBranchDetail.node:type_name -> saga.NodeInfo
-	0,  // 3: saga.TransactionBranchDetail.state:type_name -> saga.TxState
-	7,  // 4: saga.QueryGlobalTransactionDetailReply.branches:type_name -> saga.TransactionBranchDetail
-	1,  // 5: saga.QueryGlobalTransactionDetailReply.starterNode:type_name -> saga.NodeInfo
-	0,  // 6: saga.QueryGlobalTransactionDetailReply.state:type_name -> saga.TxState
-	7,  // 7: saga.QueryBranchTransactionDetailReply.detail:type_name -> saga.TransactionBranchDetail
-	0,  // 8: saga.QueryBranchTransactionDetailReply.globalTxState:type_name -> saga.TxState
-	0,  // 9: saga.SubmitGlobalTransactionStateRequest.oldState:type_name -> saga.TxState
-	0,  // 10: saga.SubmitGlobalTransactionStateRequest.state:type_name -> saga.TxState
-	0,  // 11: saga.SubmitGlobalTransactionStateReply.state:type_name -> saga.TxState
-	0,  // 12: saga.SubmitBranchTransactionStateRequest.oldState:type_name -> saga.TxState
-	0,  // 13: saga.SubmitBranchTransactionStateRequest.state:type_name -> saga.TxState
-	0,  // 14: saga.SubmitBranchTransactionStateReply.state:type_name -> saga.TxState
-	0,  // 15: saga.ListGlobalTransactionsOfStatesRequest.states:type_name -> saga.TxState
-	2,  // 16: saga.SagaServer.CreateGlobalTransaction:input_type -> saga.CreateGlobalTransactionRequest
-	4,  // 17: saga.SagaServer.CreateBranchTransaction:input_type -> saga.CreateBranchTransactionRequest
-	6,  // 18: saga.SagaServer.QueryGlobalTransactionDetail:input_type -> saga.QueryGlobalTransactionDetailRequest
-	9,  // 19: saga.SagaServer.QueryBranchTransactionDetail:input_type -> saga.QueryBranchTransactionDetailRequest
-	11, // 20: saga.SagaServer.SubmitGlobalTransactionState:input_type -> saga.SubmitGlobalTransactionStateRequest
-	13, // 21: saga.SagaServer.SubmitBranchTransactionState:input_type -> saga.SubmitBranchTransactionStateRequest
-	15, // 22: saga.SagaServer.InitSagaData:input_type -> saga.InitSagaDataRequest
-	17, // 23: saga.SagaServer.GetSagaData:input_type -> saga.GetSagaDataRequest
-	19, // 24: saga.SagaServer.ListGlobalTransactionsOfStates:input_type -> saga.ListGlobalTransactionsOfStatesRequest
-	3,  // 25: saga.SagaServer.CreateGlobalTransaction:output_type -> saga.CreateGlobalTransactionReply
-	5,  // 26: saga.SagaServer.CreateBranchTransaction:output_type -> saga.CreateBranchTransactionReply
-	8,  // 27: saga.SagaServer.QueryGlobalTransactionDetail:output_type -> saga.QueryGlobalTransactionDetailReply
-	10, // 28: saga.SagaServer.QueryBranchTransactionDetail:output_type -> saga.QueryBranchTransactionDetailReply
-	12, // 29: saga.SagaServer.SubmitGlobalTransactionState:output_type -> saga.SubmitGlobalTransactionStateReply
-	14, // 30: saga.SagaServer.SubmitBranchTransactionState:output_type -> saga.SubmitBranchTransactionStateReply
-	16, // 31: saga.SagaServer.InitSagaData:output_type -> saga.InitSagaDataReply
-	18, // 32: saga.SagaServer.GetSagaData:output_type -> saga.GetSagaDataReply
-	20, // 33: saga.SagaServer.ListGlobalTransactionsOfStates:output_type -> saga.ListGlobalTransactionsOfStatesReply
-	25, // [25:34] is the sub-list for method output_type
-	16, // [16:25] is the sub-list for method input_type
-	16, // [16:16] is the sub-list for extension type_name
-	16, // [16:16] is the sub-list for extension extendee
-	0,  // [0:16] is the sub-list for field type_name
+	4,  // 0: saga.CreateGlobalTransactionRequest.node:type_name -> saga.NodeInfo
+	5,  // 1: saga.CreateGlobalTransactionRequest.retryPolicy:type_name -> saga.RetryPolicy
+	1,  // 2: saga.CreateGlobalTransactionRequest.mode:type_name -> saga.TransactionMode
+	4,  // 3: saga.CreateBranchTransactionRequest.node:type_name -> saga.NodeInfo
+	5,  // 4: saga.CreateBranchTransactionRequest.retryPolicy:type_name -> saga.RetryPolicy
+	2,  // 5: saga.CreateBranchTransactionRequest.recoveryMode:type_name -> saga.RecoveryMode
+	4,  // 6: saga.TransactionBranchDetail.node:type_name -> saga.NodeInfo
+	0,  // 7: saga.TransactionBranchDetail.state:type_name -> saga.TxState
+	11, // 8: saga.TransactionBranchDetail.children:type_name -> saga.TransactionBranchDetail
+	2,  // 9: saga.TransactionBranchDetail.recoveryMode:type_name -> saga.RecoveryMode
+	11, // 10: saga.QueryGlobalTransactionDetailReply.branches:type_name -> saga.TransactionBranchDetail
+	4,  // 11: saga.QueryGlobalTransactionDetailReply.starterNode:type_name -> saga.NodeInfo
+	0,  // 12: saga.QueryGlobalTransactionDetailReply.state:type_name -> saga.TxState
+	11, // 13: saga.QueryGlobalTransactionDetailReply.branchTree:type_name -> saga.TransactionBranchDetail
+	12, // 14: saga.QueryGlobalTransactionDetailReply.branchGroups:type_name -> saga.BranchGroupDetail
+	1,  // 15: saga.QueryGlobalTransactionDetailReply.mode:type_name -> saga.TransactionMode
+	11, // 16: saga.QueryBranchTransactionDetailReply.detail:type_name -> saga.TransactionBranchDetail
+	0,  // 17: saga.QueryBranchTransactionDetailReply.globalTxState:type_name -> saga.TxState
+	0,  // 18: saga.SubmitGlobalTransactionStateRequest.oldState:type_name -> saga.TxState
+	0,  // 19: saga.SubmitGlobalTransactionStateRequest.state:type_name -> saga.TxState
+	0,  // 20: saga.SubmitGlobalTransactionStateReply.state:type_name -> saga.TxState
+	0,  // 21: saga.SubmitBranchTransactionStateRequest.oldState:type_name -> saga.TxState
+	0,  // 22: saga.SubmitBranchTransactionStateRequest.state:type_name -> saga.TxState
+	0,  // 23: saga.SubmitBranchTransactionStateReply.state:type_name -> saga.TxState
+	0,  // 24: saga.ListGlobalTransactionsOfStatesRequest.states:type_name -> saga.TxState
+	0,  // 25: saga.CloseGlobalTransactionReply.state:type_name -> saga.TxState
+	4,  // 26: saga.ClaimCompensationWorkRequest.node:type_name -> saga.NodeInfo
+	3,  // 27: saga.ClaimCompensationWorkRequest.work:type_name -> saga.WorkType
+	30, // 28: saga.CompensationWorkItem.lease:type_name -> saga.CompensationWorkLease
+	11, // 29: saga.CompensationWorkItem.branch:type_name -> saga.TransactionBranchDetail
+	32, // 30: saga.ClaimCompensationWorkReply.items:type_name -> saga.CompensationWorkItem
+	4,  // 31: saga.RenewCompensationWorkRequest.node:type_name -> saga.NodeInfo
+	30, // 32: saga.RenewCompensationWorkRequest.leases:type_name -> saga.CompensationWorkLease
+	30, // 33: saga.RenewCompensationWorkReply.leases:type_name -> saga.CompensationWorkLease
+	4,  // 34: saga.ReleaseCompensationWorkRequest.node:type_name -> saga.NodeInfo
+	30, // 35: saga.ReleaseCompensationWorkRequest.leases:type_name -> saga.CompensationWorkLease
+	4,  // 36: saga.HeartbeatBranchRequest.node:type_name -> saga.NodeInfo
+	0,  // 37: saga.HeartbeatBranchReply.state:type_name -> saga.TxState
+	0,  // 38: saga.HeartbeatBranchReply.globalTxState:type_name -> saga.TxState
+	4,  // 39: saga.TxStateEvent.node:type_name -> saga.NodeInfo
+	0,  // 40: saga.TxStateEvent.oldState:type_name -> saga.TxState
+	0,  // 41: saga.TxStateEvent.state:type_name -> saga.TxState
+	3,  // 42: saga.TxStateEvent.work:type_name -> saga.WorkType
+	40, // 43: saga.WatchGlobalTransactionReply.event:type_name -> saga.TxStateEvent
+	4,  // 44: saga.SubscribeEventsRequest.node:type_name -> saga.NodeInfo
+	0,  // 45: saga.SubscribeEventsRequest.states:type_name -> saga.TxState
+	3,  // 46: saga.SubscribeEventsRequest.works:type_name -> saga.WorkType
+	40, // 47: saga.SubscribeEventsReply.event:type_name -> saga.TxStateEvent
+	6,  // 48: saga.SagaServer.CreateGlobalTransaction:input_type -> saga.CreateGlobalTransactionRequest
+	8,  // 49: saga.SagaServer.CreateBranchTransaction:input_type -> saga.CreateBranchTransactionRequest
+	10, // 50: saga.SagaServer.QueryGlobalTransactionDetail:input_type -> saga.QueryGlobalTransactionDetailRequest
+	14, // 51: saga.SagaServer.QueryBranchTransactionDetail:input_type -> saga.QueryBranchTransactionDetailRequest
+	16, // 52: saga.SagaServer.SubmitGlobalTransactionState:input_type -> saga.SubmitGlobalTransactionStateRequest
+	18, // 53: saga.SagaServer.SubmitBranchTransactionState:input_type -> saga.SubmitBranchTransactionStateRequest
+	20, // 54: saga.SagaServer.InitSagaData:input_type -> saga.InitSagaDataRequest
+	22, // 55: saga.SagaServer.GetSagaData:input_type -> saga.GetSagaDataRequest
+	24, // 56: saga.SagaServer.ListGlobalTransactionsOfStates:input_type -> saga.ListGlobalTransactionsOfStatesRequest
+	28, // 57: saga.SagaServer.CloseGlobalTransaction:input_type -> saga.CloseGlobalTransactionRequest
+	31, // 58: saga.SagaServer.ClaimCompensationWork:input_type -> saga.ClaimCompensationWorkRequest
+	34, // 59: saga.SagaServer.RenewCompensationWork:input_type -> saga.RenewCompensationWorkRequest
+	36, // 60: saga.SagaServer.ReleaseCompensationWork:input_type -> saga.ReleaseCompensationWorkRequest
+	38, // 61: saga.SagaServer.HeartbeatBranch:input_type -> saga.HeartbeatBranchRequest
+	41, // 62: saga.SagaServer.WatchGlobalTransaction:input_type -> saga.WatchGlobalTransactionRequest
+	43, // 63: saga.SagaServer.SubscribeEvents:input_type -> saga.SubscribeEventsRequest
+	26, // 64: saga.BranchCompensation.Compensate:input_type -> saga.BranchCompensationRequest
+	7,  // 65: saga.SagaServer.CreateGlobalTransaction:output_type -> saga.CreateGlobalTransactionReply
+	9,  // 66: saga.SagaServer.CreateBranchTransaction:output_type -> saga.CreateBranchTransactionReply
+	13, // 67: saga.SagaServer.QueryGlobalTransactionDetail:output_type -> saga.QueryGlobalTransactionDetailReply
+	15, // 68: saga.SagaServer.QueryBranchTransactionDetail:output_type -> saga.QueryBranchTransactionDetailReply
+	17, // 69: saga.SagaServer.SubmitGlobalTransactionState:output_type -> saga.SubmitGlobalTransactionStateReply
+	19, // 70: saga.SagaServer.SubmitBranchTransactionState:output_type -> saga.SubmitBranchTransactionStateReply
+	21, // 71: saga.SagaServer.InitSagaData:output_type -> saga.InitSagaDataReply
+	23, // 72: saga.SagaServer.GetSagaData:output_type -> saga.GetSagaDataReply
+	25, // 73: saga.SagaServer.ListGlobalTransactionsOfStates:output_type -> saga.ListGlobalTransactionsOfStatesReply
+	29, // 74: saga.SagaServer.CloseGlobalTransaction:output_type -> saga.CloseGlobalTransactionReply
+	33, // 75: saga.SagaServer.ClaimCompensationWork:output_type -> saga.ClaimCompensationWorkReply
+	35, // 76: saga.SagaServer.RenewCompensationWork:output_type -> saga.RenewCompensationWorkReply
+	37, // 77: saga.SagaServer.ReleaseCompensationWork:output_type -> saga.ReleaseCompensationWorkReply
+	39, // 78: saga.SagaServer.HeartbeatBranch:output_type -> saga.HeartbeatBranchReply
+	42, // 79: saga.SagaServer.WatchGlobalTransaction:output_type -> saga.WatchGlobalTransactionReply
+	44, // 80: saga.SagaServer.SubscribeEvents:output_type -> saga.SubscribeEventsReply
+	27, // 81: saga.BranchCompensation.Compensate:output_type -> saga.BranchCompensationReply
+	65, // [65:82] is the sub-list for method output_type
+	48, // [48:65] is the sub-list for method input_type
+	48, // [48:48] is the sub-list for extension type_name
+	48, // [48:48] is the sub-list for extension extendee
+	0,  // [0:48] is the sub-list for field type_name
 }
 
 func init() { file_protos_saga_proto_init() }
@@ -1801,8 +3987,260 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateGlobalTransactionRequest); i {
+		file_protos_saga_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RetryPolicy); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateGlobalTransactionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateGlobalTransactionReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateBranchTransactionRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CreateBranchTransactionReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryGlobalTransactionDetailRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TransactionBranchDetail); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BranchGroupDetail); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryGlobalTransactionDetailReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryBranchTransactionDetailRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*QueryBranchTransactionDetailReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitGlobalTransactionStateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitGlobalTransactionStateReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitBranchTransactionStateRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubmitBranchTransactionStateReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitSagaDataRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*InitSagaDataReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSagaDataRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*GetSagaDataReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[20].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListGlobalTransactionsOfStatesRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[21].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ListGlobalTransactionsOfStatesReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_protos_saga_proto_msgTypes[22].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BranchCompensationRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1813,8 +4251,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateGlobalTransactionReply); i {
+		file_protos_saga_proto_msgTypes[23].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BranchCompensationReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1825,8 +4263,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateBranchTransactionRequest); i {
+		file_protos_saga_proto_msgTypes[24].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CloseGlobalTransactionRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1837,8 +4275,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*CreateBranchTransactionReply); i {
+		file_protos_saga_proto_msgTypes[25].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CloseGlobalTransactionReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1849,8 +4287,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryGlobalTransactionDetailRequest); i {
+		file_protos_saga_proto_msgTypes[26].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompensationWorkLease); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1861,8 +4299,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*TransactionBranchDetail); i {
+		file_protos_saga_proto_msgTypes[27].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimCompensationWorkRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1873,8 +4311,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[7].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryGlobalTransactionDetailReply); i {
+		file_protos_saga_proto_msgTypes[28].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*CompensationWorkItem); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1885,8 +4323,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryBranchTransactionDetailRequest); i {
+		file_protos_saga_proto_msgTypes[29].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ClaimCompensationWorkReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1897,8 +4335,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*QueryBranchTransactionDetailReply); i {
+		file_protos_saga_proto_msgTypes[30].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RenewCompensationWorkRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1909,8 +4347,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubmitGlobalTransactionStateRequest); i {
+		file_protos_saga_proto_msgTypes[31].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RenewCompensationWorkReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1921,8 +4359,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubmitGlobalTransactionStateReply); i {
+		file_protos_saga_proto_msgTypes[32].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseCompensationWorkRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1933,8 +4371,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubmitBranchTransactionStateRequest); i {
+		file_protos_saga_proto_msgTypes[33].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*ReleaseCompensationWorkReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1945,8 +4383,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*SubmitBranchTransactionStateReply); i {
+		file_protos_saga_proto_msgTypes[34].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeartbeatBranchRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1957,8 +4395,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[14].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InitSagaDataRequest); i {
+		file_protos_saga_proto_msgTypes[35].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*HeartbeatBranchReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1969,8 +4407,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[15].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*InitSagaDataReply); i {
+		file_protos_saga_proto_msgTypes[36].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*TxStateEvent); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1981,8 +4419,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[16].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetSagaDataRequest); i {
+		file_protos_saga_proto_msgTypes[37].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchGlobalTransactionRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -1993,8 +4431,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[17].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*GetSagaDataReply); i {
+		file_protos_saga_proto_msgTypes[38].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*WatchGlobalTransactionReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2005,8 +4443,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[18].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListGlobalTransactionsOfStatesRequest); i {
+		file_protos_saga_proto_msgTypes[39].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubscribeEventsRequest); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2017,8 +4455,8 @@ func file_protos_saga_proto_init() {
 				return nil
 			}
 		}
-		file_protos_saga_proto_msgTypes[19].Exporter = func(v interface{}, i int) interface{} {
-			switch v := v.(*ListGlobalTransactionsOfStatesReply); i {
+		file_protos_saga_proto_msgTypes[40].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*SubscribeEventsReply); i {
 			case 0:
 				return &v.state
 			case 1:
@@ -2035,10 +4473,10 @@ func file_protos_saga_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_protos_saga_proto_rawDesc,
-			NumEnums:      1,
-			NumMessages:   20,
+			NumEnums:      4,
+			NumMessages:   41,
 			NumExtensions: 0,
-			NumServices:   1,
+			NumServices:   2,
 		},
 		GoTypes:           file_protos_saga_proto_goTypes,
 		DependencyIndexes: file_protos_saga_proto_depIdxs,
@@ -2072,6 +4510,17 @@ type SagaServerClient interface {
 	InitSagaData(ctx context.Context, in *InitSagaDataRequest, opts ...grpc.CallOption) (*InitSagaDataReply, error)
 	GetSagaData(ctx context.Context, in *GetSagaDataRequest, opts ...grpc.CallOption) (*GetSagaDataReply, error)
 	ListGlobalTransactionsOfStates(ctx context.Context, in *ListGlobalTransactionsOfStatesRequest, opts ...grpc.CallOption) (*ListGlobalTransactionsOfStatesReply, error)
+	CloseGlobalTransaction(ctx context.Context, in *CloseGlobalTransactionRequest, opts ...grpc.CallOption) (*CloseGlobalTransactionReply, error)
+	// 领取待补偿分支(或者等待重试的向前恢复分支、等待confirm的TCC分支)的租约，租约期间其他参与方领取不到同一个分支，避免多个worker同时执行
+	ClaimCompensationWork(ctx context.Context, in *ClaimCompensationWorkRequest, opts ...grpc.CallOption) (*ClaimCompensationWorkReply, error)
+	RenewCompensationWork(ctx context.Context, in *RenewCompensationWorkRequest, opts ...grpc.CallOption) (*RenewCompensationWorkReply, error)
+	ReleaseCompensationWork(ctx context.Context, in *ReleaseCompensationWorkRequest, opts ...grpc.CallOption) (*ReleaseCompensationWorkReply, error)
+	// 执行中的分支定时发送心跳，超时没有心跳的分支会被标记为失去心跳
+	HeartbeatBranch(ctx context.Context, in *HeartbeatBranchRequest, opts ...grpc.CallOption) (*HeartbeatBranchReply, error)
+	// 推送全局事务和各分支的状态变化，全局事务进入COMMITTED、COMPENSATION_DONE或者COMPENSATION_FAIL后结束
+	WatchGlobalTransaction(ctx context.Context, in *WatchGlobalTransactionRequest, opts ...grpc.CallOption) (SagaServer_WatchGlobalTransactionClient, error)
+	// 长期订阅各全局事务和分支的状态变化，断开后可以用最后收到的cursor继续订阅
+	SubscribeEvents(ctx context.Context, in *SubscribeEventsRequest, opts ...grpc.CallOption) (SagaServer_SubscribeEventsClient, error)
 }
 
 type sagaServerClient struct {
@@ -2163,6 +4612,115 @@ func (c *sagaServerClient) ListGlobalTransactionsOfStates(ctx context.Context, i
 	return out, nil
 }
 
+func (c *sagaServerClient) CloseGlobalTransaction(ctx context.Context, in *CloseGlobalTransactionRequest, opts ...grpc.CallOption) (*CloseGlobalTransactionReply, error) {
+	out := new(CloseGlobalTransactionReply)
+	err := c.cc.Invoke(ctx, "/saga.SagaServer/CloseGlobalTransaction", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sagaServerClient) ClaimCompensationWork(ctx context.Context, in *ClaimCompensationWorkRequest, opts ...grpc.CallOption) (*ClaimCompensationWorkReply, error) {
+	out := new(ClaimCompensationWorkReply)
+	err := c.cc.Invoke(ctx, "/saga.SagaServer/ClaimCompensationWork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sagaServerClient) RenewCompensationWork(ctx context.Context, in *RenewCompensationWorkRequest, opts ...grpc.CallOption) (*RenewCompensationWorkReply, error) {
+	out := new(RenewCompensationWorkReply)
+	err := c.cc.Invoke(ctx, "/saga.SagaServer/RenewCompensationWork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sagaServerClient) ReleaseCompensationWork(ctx context.Context, in *ReleaseCompensationWorkRequest, opts ...grpc.CallOption) (*ReleaseCompensationWorkReply, error) {
+	out := new(ReleaseCompensationWorkReply)
+	err := c.cc.Invoke(ctx, "/saga.SagaServer/ReleaseCompensationWork", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sagaServerClient) HeartbeatBranch(ctx context.Context, in *HeartbeatBranchRequest, opts ...grpc.CallOption) (*HeartbeatBranchReply, error) {
+	out := new(HeartbeatBranchReply)
+	err := c.cc.Invoke(ctx, "/saga.SagaServer/HeartbeatBranch", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *sagaServerClient) WatchGlobalTransaction(ctx context.Context, in *WatchGlobalTransactionRequest, opts ...grpc.CallOption) (SagaServer_WatchGlobalTransactionClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SagaServer_serviceDesc.Streams[0], "/saga.SagaServer/WatchGlobalTransaction", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sagaServerWatchGlobalTransactionClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SagaServer_WatchGlobalTransactionClient interface {
+	Recv() (*WatchGlobalTransactionReply, error)
+	grpc.ClientStream
+}
+
+type sagaServerWatchGlobalTransactionClient struct {
+	grpc.ClientStream
+}
+
+func (x *sagaServerWatchGlobalTransactionClient) Recv() (*WatchGlobalTransactionReply, error) {
+	m := new(WatchGlobalTransactionReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *sagaServerClient) SubscribeEvents(ctx context.Context, in *SubscribeEventsRequest, opts ...grpc.CallOption) (SagaServer_SubscribeEventsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_SagaServer_serviceDesc.Streams[1], "/saga.SagaServer/SubscribeEvents", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &sagaServerSubscribeEventsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type SagaServer_SubscribeEventsClient interface {
+	Recv() (*SubscribeEventsReply, error)
+	grpc.ClientStream
+}
+
+type sagaServerSubscribeEventsClient struct {
+	grpc.ClientStream
+}
+
+func (x *sagaServerSubscribeEventsClient) Recv() (*SubscribeEventsReply, error) {
+	m := new(SubscribeEventsReply)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // SagaServerServer is the server API for SagaServer service.
 type SagaServerServer interface {
 	CreateGlobalTransaction(context.Context, *CreateGlobalTransactionRequest) (*CreateGlobalTransactionReply, error)
@@ -2174,6 +4732,17 @@ type SagaServerServer interface {
 	InitSagaData(context.Context, *InitSagaDataRequest) (*InitSagaDataReply, error)
 	GetSagaData(context.Context, *GetSagaDataRequest) (*GetSagaDataReply, error)
 	ListGlobalTransactionsOfStates(context.Context, *ListGlobalTransactionsOfStatesRequest) (*ListGlobalTransactionsOfStatesReply, error)
+	CloseGlobalTransaction(context.Context, *CloseGlobalTransactionRequest) (*CloseGlobalTransactionReply, error)
+	// 领取待补偿分支(或者等待重试的向前恢复分支、等待confirm的TCC分支)的租约，租约期间其他参与方领取不到同一个分支，避免多个worker同时执行
+	ClaimCompensationWork(context.Context, *ClaimCompensationWorkRequest) (*ClaimCompensationWorkReply, error)
+	RenewCompensationWork(context.Context, *RenewCompensationWorkRequest) (*RenewCompensationWorkReply, error)
+	ReleaseCompensationWork(context.Context, *ReleaseCompensationWorkRequest) (*ReleaseCompensationWorkReply, error)
+	// 执行中的分支定时发送心跳，超时没有心跳的分支会被标记为失去心跳
+	HeartbeatBranch(context.Context, *HeartbeatBranchRequest) (*HeartbeatBranchReply, error)
+	// 推送全局事务和各分支的状态变化，全局事务进入COMMITTED、COMPENSATION_DONE或者COMPENSATION_FAIL后结束
+	WatchGlobalTransaction(*WatchGlobalTransactionRequest, SagaServer_WatchGlobalTransactionServer) error
+	// 长期订阅各全局事务和分支的状态变化，断开后可以用最后收到的cursor继续订阅
+	SubscribeEvents(*SubscribeEventsRequest, SagaServer_SubscribeEventsServer) error
 }
 
 // UnimplementedSagaServerServer can be embedded to have forward compatible implementations.
@@ -2207,6 +4776,27 @@ func (*UnimplementedSagaServerServer) GetSagaData(context.Context, *GetSagaDataR
 func (*UnimplementedSagaServerServer) ListGlobalTransactionsOfStates(context.Context, *ListGlobalTransactionsOfStatesRequest) (*ListGlobalTransactionsOfStatesReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListGlobalTransactionsOfStates not implemented")
 }
+func (*UnimplementedSagaServerServer) CloseGlobalTransaction(context.Context, *CloseGlobalTransactionRequest) (*CloseGlobalTransactionReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CloseGlobalTransaction not implemented")
+}
+func (*UnimplementedSagaServerServer) ClaimCompensationWork(context.Context, *ClaimCompensationWorkRequest) (*ClaimCompensationWorkReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClaimCompensationWork not implemented")
+}
+func (*UnimplementedSagaServerServer) RenewCompensationWork(context.Context, *RenewCompensationWorkRequest) (*RenewCompensationWorkReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RenewCompensationWork not implemented")
+}
+func (*UnimplementedSagaServerServer) ReleaseCompensationWork(context.Context, *ReleaseCompensationWorkRequest) (*ReleaseCompensationWorkReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseCompensationWork not implemented")
+}
+func (*UnimplementedSagaServerServer) HeartbeatBranch(context.Context, *HeartbeatBranchRequest) (*HeartbeatBranchReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HeartbeatBranch not implemented")
+}
+func (*UnimplementedSagaServerServer) WatchGlobalTransaction(*WatchGlobalTransactionRequest, SagaServer_WatchGlobalTransactionServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchGlobalTransaction not implemented")
+}
+func (*UnimplementedSagaServerServer) SubscribeEvents(*SubscribeEventsRequest, SagaServer_SubscribeEventsServer) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeEvents not implemented")
+}
 
 func RegisterSagaServerServer(s *grpc.Server, srv SagaServerServer) {
 	s.RegisterService(&_SagaServer_serviceDesc, srv)
@@ -2374,6 +4964,138 @@ func _SagaServer_ListGlobalTransactionsOfStates_Handler(srv interface{}, ctx con
 	return interceptor(ctx, in, info, handler)
 }
 
+func _SagaServer_CloseGlobalTransaction_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CloseGlobalTransactionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SagaServerServer).CloseGlobalTransaction(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.SagaServer/CloseGlobalTransaction",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SagaServerServer).CloseGlobalTransaction(ctx, req.(*CloseGlobalTransactionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SagaServer_ClaimCompensationWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClaimCompensationWorkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SagaServerServer).ClaimCompensationWork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.SagaServer/ClaimCompensationWork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SagaServerServer).ClaimCompensationWork(ctx, req.(*ClaimCompensationWorkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SagaServer_RenewCompensationWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RenewCompensationWorkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SagaServerServer).RenewCompensationWork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.SagaServer/RenewCompensationWork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SagaServerServer).RenewCompensationWork(ctx, req.(*RenewCompensationWorkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SagaServer_ReleaseCompensationWork_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseCompensationWorkRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SagaServerServer).ReleaseCompensationWork(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.SagaServer/ReleaseCompensationWork",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SagaServerServer).ReleaseCompensationWork(ctx, req.(*ReleaseCompensationWorkRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SagaServer_HeartbeatBranch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HeartbeatBranchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SagaServerServer).HeartbeatBranch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.SagaServer/HeartbeatBranch",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SagaServerServer).HeartbeatBranch(ctx, req.(*HeartbeatBranchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SagaServer_WatchGlobalTransaction_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchGlobalTransactionRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SagaServerServer).WatchGlobalTransaction(m, &sagaServerWatchGlobalTransactionServer{stream})
+}
+
+type SagaServer_WatchGlobalTransactionServer interface {
+	Send(*WatchGlobalTransactionReply) error
+	grpc.ServerStream
+}
+
+type sagaServerWatchGlobalTransactionServer struct {
+	grpc.ServerStream
+}
+
+func (x *sagaServerWatchGlobalTransactionServer) Send(m *WatchGlobalTransactionReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _SagaServer_SubscribeEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(SubscribeEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(SagaServerServer).SubscribeEvents(m, &sagaServerSubscribeEventsServer{stream})
+}
+
+type SagaServer_SubscribeEventsServer interface {
+	Send(*SubscribeEventsReply) error
+	grpc.ServerStream
+}
+
+type sagaServerSubscribeEventsServer struct {
+	grpc.ServerStream
+}
+
+func (x *sagaServerSubscribeEventsServer) Send(m *SubscribeEventsReply) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 var _SagaServer_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "saga.SagaServer",
 	HandlerType: (*SagaServerServer)(nil),
@@ -2414,6 +5136,109 @@ var _SagaServer_serviceDesc = grpc.ServiceDesc{
 			MethodName: "ListGlobalTransactionsOfStates",
 			Handler:    _SagaServer_ListGlobalTransactionsOfStates_Handler,
 		},
+		{
+			MethodName: "CloseGlobalTransaction",
+			Handler:    _SagaServer_CloseGlobalTransaction_Handler,
+		},
+		{
+			MethodName: "ClaimCompensationWork",
+			Handler:    _SagaServer_ClaimCompensationWork_Handler,
+		},
+		{
+			MethodName: "RenewCompensationWork",
+			Handler:    _SagaServer_RenewCompensationWork_Handler,
+		},
+		{
+			MethodName: "ReleaseCompensationWork",
+			Handler:    _SagaServer_ReleaseCompensationWork_Handler,
+		},
+		{
+			MethodName: "HeartbeatBranch",
+			Handler:    _SagaServer_HeartbeatBranch_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchGlobalTransaction",
+			Handler:       _SagaServer_WatchGlobalTransaction_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "SubscribeEvents",
+			Handler:       _SagaServer_SubscribeEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "protos/saga.proto",
+}
+
+// BranchCompensationClient is the client API for BranchCompensation service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type BranchCompensationClient interface {
+	Compensate(ctx context.Context, in *BranchCompensationRequest, opts ...grpc.CallOption) (*BranchCompensationReply, error)
+}
+
+type branchCompensationClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewBranchCompensationClient(cc grpc.ClientConnInterface) BranchCompensationClient {
+	return &branchCompensationClient{cc}
+}
+
+func (c *branchCompensationClient) Compensate(ctx context.Context, in *BranchCompensationRequest, opts ...grpc.CallOption) (*BranchCompensationReply, error) {
+	out := new(BranchCompensationReply)
+	err := c.cc.Invoke(ctx, "/saga.BranchCompensation/Compensate", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// BranchCompensationServer is the server API for BranchCompensation service.
+type BranchCompensationServer interface {
+	Compensate(context.Context, *BranchCompensationRequest) (*BranchCompensationReply, error)
+}
+
+// UnimplementedBranchCompensationServer can be embedded to have forward compatible implementations.
+type UnimplementedBranchCompensationServer struct {
+}
+
+func (*UnimplementedBranchCompensationServer) Compensate(context.Context, *BranchCompensationRequest) (*BranchCompensationReply, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Compensate not implemented")
+}
+
+func RegisterBranchCompensationServer(s *grpc.Server, srv BranchCompensationServer) {
+	s.RegisterService(&_BranchCompensation_serviceDesc, srv)
+}
+
+func _BranchCompensation_Compensate_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BranchCompensationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BranchCompensationServer).Compensate(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/saga.BranchCompensation/Compensate",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BranchCompensationServer).Compensate(ctx, req.(*BranchCompensationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+var _BranchCompensation_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "saga.BranchCompensation",
+	HandlerType: (*BranchCompensationServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Compensate",
+			Handler:    _BranchCompensation_Compensate_Handler,
+		},
 	},
 	Streams:  []grpc.StreamDesc{},
 	Metadata: "protos/saga.proto",
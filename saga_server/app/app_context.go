@@ -1,9 +1,11 @@
 package app
 
-import "database/sql"
+import "github.com/zoowii/saga_server/db"
 
 type ApplicationContext interface {
 	Init() error
 	Close() error
-	GetDb() (*sql.DB, error)
+	GetStore() (db.Store, error)
+	// 执行存储还没执行的表结构migrations，返回本次执行的版本号
+	Migrate() ([]int, error)
 }
@@ -12,3 +12,31 @@ func SetDbUrl(dbUrl string) Option {
 		return
 	}
 }
+
+/**
+ * 使用内存存储，不需要数据库，用于测试和演示
+ */
+func UseMemoryStore() Option {
+	return func(app ApplicationContext) (err error) {
+		impl, ok := app.(*applicationContextImpl)
+		if !ok {
+			return
+		}
+		impl.options.useMemoryStore = true
+		return
+	}
+}
+
+/**
+ * 是否在启动时自动执行还没执行的表结构migrations，默认为true
+ */
+func SetAutoMigrate(autoMigrate bool) Option {
+	return func(app ApplicationContext) (err error) {
+		impl, ok := app.(*applicationContextImpl)
+		if !ok {
+			return
+		}
+		impl.options.disableAutoMigrate = !autoMigrate
+		return
+	}
+}
@@ -0,0 +1,1403 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/app"
+	"github.com/zoowii/saga_server/services"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	testNode = &pb.NodeInfo{
+		Group:      "testGroup",
+		Service:    "testClientService",
+		InstanceId: "testClientInstanceId",
+	}
+	// 测试用的进程内saga server地址
+	address string
+)
+
+type testOrderForm struct {
+	OrderId string   `json:"orderId"`
+	Amount  int64    `json:"amount"`
+	Steps   []string `json:"steps"`
+}
+
+// 在进程内启动使用内存存储的saga server
+func TestMain(m *testing.M) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("net.Listen err: %v", err)
+	}
+	address = listener.Addr().String()
+	sagaApp, err := app.NewApplicationContext(app.UseMemoryStore())
+	if err != nil {
+		log.Fatalf("saga app context err: %v", err)
+	}
+	sagaServerService, err := services.NewSagaServerService(sagaApp)
+	if err != nil {
+		log.Fatalf("saga server service err: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	pb.RegisterSagaServerServer(grpcServer, sagaServerService)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	// 重试等待结束后通知worker领取
+	bgCtx, cancelBg := context.WithCancel(context.Background())
+	workNotifier := services.NewWorkNotifier(sagaServerService, 20*time.Millisecond)
+	go workNotifier.Run(bgCtx)
+	code := m.Run()
+	cancelBg()
+	grpcServer.Stop()
+	_ = sagaApp.Close()
+	os.Exit(code)
+}
+
+func newTestSagaContext(t *testing.T) (sagaContext *SagaContext, closeFn func()) {
+	conn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	converter := NewJsonSagaDataConverter()
+	converter.RegisterSagaDataType(&testOrderForm{})
+	sagaContext = NewSagaContext(NewSagaCollaborator(conn, testNode), NewSagaResolver(), converter)
+	closeFn = func() {
+		_ = conn.Close()
+	}
+	return
+}
+
+func appendStepAction(name string) BranchFunc {
+	return func(ctx context.Context, sagaData interface{}) error {
+		form := sagaData.(*testOrderForm)
+		form.Steps = append(form.Steps, name)
+		return nil
+	}
+}
+
+func TestJsonSagaDataConverter(t *testing.T) {
+	converter := NewJsonSagaDataConverter()
+	converter.RegisterSagaDataTypeWithName("Demo.OrderForm", &testOrderForm{})
+	bytes, err := converter.Serialize(&testOrderForm{OrderId: "order1", Amount: 100})
+	if err != nil {
+		t.Fatalf("serialize err: %v", err)
+	}
+	if string(bytes) != `{"dataType":"Demo.OrderForm","data":{"orderId":"order1","amount":100,"steps":null}}` {
+		t.Errorf("unexpected serialized saga data %s", string(bytes))
+	}
+	sagaData, err := converter.Deserialize(bytes)
+	if err != nil {
+		t.Fatalf("deserialize err: %v", err)
+	}
+	form, ok := sagaData.(*testOrderForm)
+	if !ok || form.OrderId != "order1" || form.Amount != 100 {
+		t.Errorf("unexpected deserialized saga data %v", sagaData)
+	}
+	_, err = NewJsonSagaDataConverter().Deserialize(bytes)
+	if err == nil {
+		t.Errorf("deserialize unregistered saga data type should fail")
+	}
+}
+
+func TestSagaSessionCommit(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	resolver := sagaContext.Resolver
+	resolver.BindStep(&Step{
+		ServiceKey:      "order.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "order.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	resolver.BindStep(&Step{
+		ServiceKey:      "order.pay",
+		CompensationKey: "order.refund",
+		Compensation:    appendStepAction("refund"),
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			form := sagaData.(*testOrderForm)
+			form.Steps = append(form.Steps, "pay")
+			// 分支中执行的步骤作为它的下级分支
+			return SessionFromContext(ctx).Invoke(ctx, "order.notify", sagaData)
+		},
+	})
+	resolver.BindStep(&Step{
+		ServiceKey: "order.notify",
+		Action:     appendStepAction("notify"),
+	})
+	if resolver.ResolveBranch("order.refund") == nil {
+		t.Fatalf("compensation of step not bound")
+	}
+
+	form := &testOrderForm{OrderId: "order1", Amount: 100}
+	session, err := sagaContext.Start(ctx, form, WithExtra("test client session"))
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	for _, key := range []string{"order.reserve", "order.pay"} {
+		if err = session.Invoke(ctx, key, form); err != nil {
+			t.Fatalf("invoke %s err: %v", key, err)
+		}
+	}
+	state, err := session.Commit(ctx)
+	if err != nil {
+		t.Fatalf("commit err: %v", err)
+	}
+	if state != pb.TxState_COMMITTED {
+		t.Errorf("global tx state should be COMMITTED but got %s", state.String())
+	}
+
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if len(detail.BranchTree) != 2 || len(detail.BranchTree[1].Children) != 1 ||
+		detail.BranchTree[1].Children[0].BranchServiceKey != "order.notify" {
+		t.Errorf("unexpected branch tree %v", detail.BranchTree)
+	}
+	for _, branch := range detail.Branches {
+		if branch.State != pb.TxState_COMMITTED {
+			t.Errorf("branch %s state should be COMMITTED but got %s", branch.BranchId, branch.State.String())
+		}
+	}
+
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	saved := sagaData.(*testOrderForm)
+	if saved.OrderId != "order1" || len(saved.Steps) != 3 {
+		t.Errorf("unexpected saga data %v", saved)
+	}
+}
+
+func TestSagaSessionRollbackWhenStepFailed(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	stepErr := errors.New("balance not enough")
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "order.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "order.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "order.pay",
+		CompensationKey: "order.refund",
+		Compensation:    appendStepAction("refund"),
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			return stepErr
+		},
+	})
+	form := &testOrderForm{OrderId: "order2", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "order.reserve", form); err != nil {
+		t.Fatalf("invoke reserve err: %v", err)
+	}
+	if err = session.Invoke(ctx, "order.pay", form); err != stepErr {
+		t.Fatalf("invoke pay should return the step error but got %v", err)
+	}
+	if err = session.Invoke(ctx, "order.unknown", form); err == nil {
+		t.Errorf("invoke unregistered step should fail")
+	}
+	state, err := session.Rollback(ctx)
+	if err != nil {
+		t.Fatalf("rollback err: %v", err)
+	}
+	if state != pb.TxState_COMPENSATION_DOING {
+		t.Errorf("global tx state should be COMPENSATION_DOING but got %s", state.String())
+	}
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if len(detail.Branches) != 2 {
+		t.Fatalf("global tx should have 2 branches but got %d", len(detail.Branches))
+	}
+	failedBranch := detail.Branches[1]
+	if failedBranch.BranchServiceKey != "order.pay" || failedBranch.State != pb.TxState_COMPENSATION_DOING {
+		t.Errorf("unexpected failed branch %v", failedBranch)
+	}
+}
+
+func TestCompensationWorker(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "worker.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "worker.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey: "worker.notify",
+		Action:     appendStepAction("notify"),
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "worker.pay",
+		CompensationKey: "worker.refund",
+		Compensation:    appendStepAction("refund"),
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			return errors.New("pay failed")
+		},
+	})
+	form := &testOrderForm{OrderId: "order3", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	for _, key := range []string{"worker.reserve", "worker.notify", "worker.pay"} {
+		_ = session.Invoke(ctx, key, form)
+	}
+	if _, err = session.Rollback(ctx); err != nil {
+		t.Fatalf("rollback err: %v", err)
+	}
+
+	worker := NewCompensationWorker(sagaContext, 50*time.Millisecond)
+	if err = worker.Start(ctx); err != nil {
+		t.Fatalf("start worker err: %v", err)
+	}
+	if err = worker.Start(ctx); err == nil {
+		t.Errorf("start worker twice should fail")
+	}
+	var detail *pb.QueryGlobalTransactionDetailReply
+	for i := 0; i < 100; i++ {
+		detail, err = sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+		if err != nil {
+			t.Fatalf("query global tx err: %v", err)
+		}
+		if detail.State == pb.TxState_COMPENSATION_DONE {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	worker.Stop()
+	worker.Stop()
+	if detail.State != pb.TxState_COMPENSATION_DONE {
+		t.Fatalf("global tx state should be COMPENSATION_DONE but got %s", detail.State.String())
+	}
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	// 补偿按分支创建的倒序执行，没有补偿方法的分支直接标记为已补偿
+	steps := sagaData.(*testOrderForm).Steps
+	if len(steps) != 4 || steps[2] != "refund" || steps[3] != "cancelReserve" {
+		t.Errorf("unexpected compensation steps %v", steps)
+	}
+}
+
+// 记录服务端从ctx中恢复的xid和branchId
+type testSagaMetadataServer struct {
+	mu       sync.Mutex
+	xids     []string
+	branches []string
+}
+
+func (s *testSagaMetadataServer) record(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.xids = append(s.xids, XidFromContext(ctx))
+	s.branches = append(s.branches, BranchIdFromContext(ctx))
+}
+
+func (s *testSagaMetadataServer) Compensate(ctx context.Context,
+	req *pb.BranchCompensationRequest) (*pb.BranchCompensationReply, error) {
+	s.record(ctx)
+	return &pb.BranchCompensationReply{}, nil
+}
+
+var testSagaMetadataStreamDesc = grpc.StreamDesc{
+	StreamName:    "Watch",
+	ServerStreams: true,
+}
+
+func TestSagaMetadataInterceptors(t *testing.T) {
+	metadataServer := &testSagaMetadataServer{}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen err: %v", err)
+	}
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(UnaryServerInterceptor()),
+		grpc.StreamInterceptor(StreamServerInterceptor()))
+	pb.RegisterBranchCompensationServer(grpcServer, metadataServer)
+	streamDesc := testSagaMetadataStreamDesc
+	streamDesc.Handler = func(srv interface{}, stream grpc.ServerStream) error {
+		metadataServer.record(stream.Context())
+		return stream.SendMsg(&pb.NodeInfo{})
+	}
+	grpcServer.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "saga.test.SagaMetadata",
+		HandlerType: (*interface{})(nil),
+		Streams:     []grpc.StreamDesc{streamDesc},
+	}, metadataServer)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+		grpc.WithStreamInterceptor(StreamClientInterceptor()))
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	defer conn.Close()
+	compensationClient := pb.NewBranchCompensationClient(conn)
+
+	ctx := context.Background()
+	sagaCtx := ContextWithBranchId(ContextWithXid(ctx, "xid1"), "branch1")
+	for _, callCtx := range []context.Context{ctx, sagaCtx} {
+		if _, err = compensationClient.Compensate(callCtx, &pb.BranchCompensationRequest{}); err != nil {
+			t.Fatalf("Compensate err: %v", err)
+		}
+	}
+	stream, err := conn.NewStream(sagaCtx, &testSagaMetadataStreamDesc, "/saga.test.SagaMetadata/Watch")
+	if err != nil {
+		t.Fatalf("NewStream err: %v", err)
+	}
+	if err = stream.CloseSend(); err != nil {
+		t.Fatalf("CloseSend err: %v", err)
+	}
+	if err = stream.RecvMsg(&pb.NodeInfo{}); err != nil {
+		t.Fatalf("RecvMsg err: %v", err)
+	}
+
+	metadataServer.mu.Lock()
+	defer metadataServer.mu.Unlock()
+	expectedXids := []string{"", "xid1", "xid1"}
+	expectedBranches := []string{"", "branch1", "branch1"}
+	if len(metadataServer.xids) != len(expectedXids) {
+		t.Fatalf("server should receive %d calls but got %d", len(expectedXids), len(metadataServer.xids))
+	}
+	for i := range expectedXids {
+		if metadataServer.xids[i] != expectedXids[i] || metadataServer.branches[i] != expectedBranches[i] {
+			t.Errorf("call %d should receive xid %q branch %q but got %q %q", i,
+				expectedXids[i], expectedBranches[i], metadataServer.xids[i], metadataServer.branches[i])
+		}
+	}
+}
+
+// 用BranchCompensation服务模拟参与方的业务方法，jobId控制执行结果
+type testCompensableServer struct{}
+
+func (s *testCompensableServer) Compensate(ctx context.Context,
+	req *pb.BranchCompensationRequest) (*pb.BranchCompensationReply, error) {
+	switch req.JobId {
+	case "error":
+		return nil, errors.New("handler error")
+	case "businessFail":
+		return &pb.BranchCompensationReply{Code: 1, Error: "business failed"}, nil
+	}
+	return &pb.BranchCompensationReply{Error: BranchIdFromContext(ctx)}, nil
+}
+
+func TestCompensableRegistryEnlistBranches(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	const fullMethod = "/saga.BranchCompensation/Compensate"
+	registry := NewCompensableRegistry(sagaContext.Collaborator)
+	registry.RegisterMethod(&CompensableMethod{
+		FullMethod:      fullMethod,
+		CompensationKey: "test.cancelCompensable",
+		ReplyError: func(reply interface{}) error {
+			if r := reply.(*pb.BranchCompensationReply); r.Code != 0 {
+				return errors.New(r.Error)
+			}
+			return nil
+		},
+	})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen err: %v", err)
+	}
+	grpcServer := grpc.NewServer(grpc.UnaryInterceptor(registry.UnaryServerInterceptor()))
+	pb.RegisterBranchCompensationServer(grpcServer, &testCompensableServer{})
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	defer grpcServer.Stop()
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()))
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	defer conn.Close()
+	participant := pb.NewBranchCompensationClient(conn)
+
+	ctx := context.Background()
+	// 不在saga中的调用不创建分支
+	if _, err = participant.Compensate(ctx, &pb.BranchCompensationRequest{}); err != nil {
+		t.Fatalf("call without saga err: %v", err)
+	}
+
+	var remoteBranchId string
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey: "compensable.step",
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			reply, err := participant.Compensate(ctx, &pb.BranchCompensationRequest{})
+			if err != nil {
+				return err
+			}
+			remoteBranchId = reply.Error
+			return nil
+		},
+	})
+	session, err := sagaContext.Start(ctx, &testOrderForm{OrderId: "order7"})
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "compensable.step", &testOrderForm{OrderId: "order7"}); err != nil {
+		t.Fatalf("invoke step err: %v", err)
+	}
+	sessionCtx := session.Bind(ctx)
+	if _, err = participant.Compensate(sessionCtx, &pb.BranchCompensationRequest{JobId: "error"}); err == nil {
+		t.Errorf("handler error should be returned to caller")
+	}
+	reply, err := participant.Compensate(sessionCtx, &pb.BranchCompensationRequest{JobId: "businessFail"})
+	if err != nil || reply.Code != 1 {
+		t.Errorf("business failure reply should be returned to caller but got %v %v", reply, err)
+	}
+
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if len(detail.Branches) != 4 {
+		t.Fatalf("global tx should have 4 branches but got %d", len(detail.Branches))
+	}
+	stepBranch, remoteBranch := detail.Branches[0], detail.Branches[1]
+	if remoteBranch.BranchId != remoteBranchId || remoteBranch.ParentBranchId != stepBranch.BranchId ||
+		remoteBranch.BranchServiceKey != fullMethod ||
+		remoteBranch.BranchCompensationServiceKey != "test.cancelCompensable" ||
+		remoteBranch.State != pb.TxState_COMMITTED {
+		t.Errorf("unexpected enlisted branch %v", remoteBranch)
+	}
+	for _, failedBranch := range detail.Branches[2:] {
+		if len(failedBranch.ParentBranchId) > 0 || failedBranch.State != pb.TxState_COMPENSATION_DOING {
+			t.Errorf("unexpected failed branch %v", failedBranch)
+		}
+	}
+}
+
+func newTestOrderSagaDefinition(name string, payAction BranchFunc) *SagaDefinition {
+	if payAction == nil {
+		payAction = appendStepAction("pay")
+	}
+	return NewSagaDefinition(name).
+		Step("reserve", appendStepAction("reserve"), appendStepAction("cancelReserve")).
+		Step("pay", payAction, appendStepAction("refund")).
+		Step("", appendStepAction("notify"), nil)
+}
+
+func newTestSagaOrchestrator(t *testing.T, opts ...grpc.DialOption) (orchestrator *SagaOrchestrator, closeFn func()) {
+	conn, err := grpc.Dial(address, append(opts, grpc.WithInsecure())...)
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	converter := NewJsonSagaDataConverter()
+	converter.RegisterSagaDataType(&testOrderForm{})
+	orchestrator = NewSagaOrchestrator(NewSagaCollaborator(conn, testNode), converter)
+	closeFn = func() {
+		_ = conn.Close()
+	}
+	return
+}
+
+func assertTestSagaResult(t *testing.T, orchestrator *SagaOrchestrator, xid string,
+	state pb.TxState, steps []string) {
+	ctx := context.Background()
+	detail, err := orchestrator.sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if detail.State != state {
+		t.Errorf("saga %s state should be %s but got %s", xid, state.String(), detail.State.String())
+	}
+	sagaData, err := orchestrator.sagaContext.Join(xid).SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	savedSteps := sagaData.(*testOrderForm).Steps
+	if fmt.Sprint(savedSteps) != fmt.Sprint(steps) {
+		t.Errorf("saga %s steps should be %v but got %v", xid, steps, savedSteps)
+	}
+}
+
+func TestSagaOrchestratorCommitAndRollback(t *testing.T) {
+	orchestrator, closeFn := newTestSagaOrchestrator(t)
+	defer closeFn()
+	ctx := context.Background()
+	payErr := errors.New("pay failed")
+	if err := orchestrator.Register(newTestOrderSagaDefinition("orderSaga", nil)); err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	if err := orchestrator.Register(newTestOrderSagaDefinition("orderSaga", nil)); err == nil {
+		t.Errorf("register saga definition twice should fail")
+	}
+	err := orchestrator.Register(newTestOrderSagaDefinition("failedOrderSaga",
+		func(ctx context.Context, sagaData interface{}) error {
+			return payErr
+		}))
+	if err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+
+	xid, err := orchestrator.Start(ctx, "orderSaga", &testOrderForm{OrderId: "order8"})
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMMITTED, []string{"reserve", "pay", "notify"})
+
+	xid, err = orchestrator.Start(ctx, "failedOrderSaga", &testOrderForm{OrderId: "order9"})
+	if err != payErr {
+		t.Fatalf("start failed saga should return the step error but got %v", err)
+	}
+	// 失败的步骤也要补偿，按倒序执行
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMPENSATION_DONE,
+		[]string{"reserve", "refund", "cancelReserve"})
+}
+
+// 模拟进程在saga执行过程中崩溃，用新的orchestrator恢复
+func TestSagaOrchestratorResumeAfterCrash(t *testing.T) {
+	ctx := context.Background()
+	var crashedXid string
+	// 第一个步骤完成并记录后崩溃
+	crashAfterCommit := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		if submitReq, ok := req.(*pb.SubmitBranchTransactionStateRequest); ok && err == nil &&
+			submitReq.State == pb.TxState_COMMITTED {
+			crashedXid = submitReq.Xid
+			runtime.Goexit()
+		}
+		return err
+	}
+	crashedOrchestrator, closeCrashed := newTestSagaOrchestrator(t, grpc.WithUnaryInterceptor(crashAfterCommit))
+	defer closeCrashed()
+	// 执行第二个步骤的过程中崩溃，步骤的结果没有记录
+	var interruptedXid string
+	crashInPay := func(ctx context.Context, sagaData interface{}) error {
+		interruptedXid = SessionFromContext(ctx).Xid()
+		runtime.Goexit()
+		return nil
+	}
+	if err := crashedOrchestrator.Register(newTestOrderSagaDefinition("resumedOrderSaga", nil)); err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	runUntilCrash := func(orchestrator *SagaOrchestrator, name string, orderId string) {
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_, _ = orchestrator.Start(ctx, name, &testOrderForm{OrderId: orderId})
+			t.Errorf("saga %s should crash", name)
+		}()
+		<-done
+	}
+	runUntilCrash(crashedOrchestrator, "resumedOrderSaga", "order10")
+	plainOrchestrator, closePlain := newTestSagaOrchestrator(t)
+	defer closePlain()
+	if err := plainOrchestrator.Register(newTestOrderSagaDefinition("interruptedOrderSaga", crashInPay)); err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	runUntilCrash(plainOrchestrator, "interruptedOrderSaga", "order11")
+
+	orchestrator, closeFn := newTestSagaOrchestrator(t)
+	defer closeFn()
+	for _, definition := range []*SagaDefinition{
+		newTestOrderSagaDefinition("resumedOrderSaga", nil),
+		newTestOrderSagaDefinition("interruptedOrderSaga", nil),
+	} {
+		if err := orchestrator.Register(definition); err != nil {
+			t.Fatalf("register saga err: %v", err)
+		}
+	}
+	count, err := orchestrator.ResumeAll(ctx)
+	if err != nil {
+		t.Fatalf("resume sagas err: %v", err)
+	}
+	if count != 2 {
+		t.Errorf("should resume 2 sagas but got %d", count)
+	}
+	// 从没有完成的步骤继续执行，已经完成的步骤不重复执行
+	assertTestSagaResult(t, orchestrator, crashedXid, pb.TxState_COMMITTED, []string{"reserve", "pay", "notify"})
+	// 无法确定是否执行过的步骤回滚并补偿
+	assertTestSagaResult(t, orchestrator, interruptedXid, pb.TxState_COMPENSATION_DONE,
+		[]string{"reserve", "refund", "cancelReserve"})
+	if err = orchestrator.Resume(ctx, crashedXid); err != nil {
+		t.Errorf("resume finished saga should do nothing but got %v", err)
+	}
+}
+
+// 并行步骤共享saga data，修改时需要同步
+func lockedAppendStepAction(mu *sync.Mutex, name string) BranchFunc {
+	return func(ctx context.Context, sagaData interface{}) error {
+		mu.Lock()
+		defer mu.Unlock()
+		form := sagaData.(*testOrderForm)
+		form.Steps = append(form.Steps, name)
+		return nil
+	}
+}
+
+func TestSagaOrchestratorParallelSteps(t *testing.T) {
+	orchestrator, closeFn := newTestSagaOrchestrator(t)
+	defer closeFn()
+	ctx := context.Background()
+	mu := &sync.Mutex{}
+	mailErr := errors.New("mail failed")
+	newDefinition := func(name string, mailAction BranchFunc) *SagaDefinition {
+		return NewSagaDefinition(name).
+			Step("reserve", appendStepAction("reserve"), appendStepAction("cancelReserve")).
+			ParallelStep("notify", "sms", lockedAppendStepAction(mu, "sms"), lockedAppendStepAction(mu, "cancelSms")).
+			ParallelStep("notify", "mail", mailAction, lockedAppendStepAction(mu, "cancelMail")).
+			Step("ship", appendStepAction("ship"), nil)
+	}
+	invalidDefinition := newDefinition("invalidParallelSaga", lockedAppendStepAction(mu, "mail")).
+		ParallelStep("notify", "push", lockedAppendStepAction(mu, "push"), nil)
+	if err := orchestrator.Register(invalidDefinition); err == nil {
+		t.Errorf("register saga with not adjacent group steps should fail")
+	}
+	if err := orchestrator.Register(newDefinition("parallelSaga", lockedAppendStepAction(mu, "mail"))); err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	err := orchestrator.Register(newDefinition("failedParallelSaga",
+		func(ctx context.Context, sagaData interface{}) error {
+			return mailErr
+		}))
+	if err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+
+	xid, err := orchestrator.Start(ctx, "parallelSaga", &testOrderForm{OrderId: "order12"})
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	detail, err := orchestrator.sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if detail.State != pb.TxState_COMMITTED || len(detail.BranchGroups) != 1 ||
+		detail.BranchGroups[0].BranchGroup != "notify" || detail.BranchGroups[0].CommittedCount != 2 {
+		t.Fatalf("invalid parallel saga result %v", detail)
+	}
+	sagaData, err := orchestrator.sagaContext.Join(xid).SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	steps := sagaData.(*testOrderForm).Steps
+	if len(steps) != 4 || steps[0] != "reserve" || steps[3] != "ship" ||
+		!((steps[1] == "sms" && steps[2] == "mail") || (steps[1] == "mail" && steps[2] == "sms")) {
+		t.Errorf("invalid steps of parallel saga %v", steps)
+	}
+
+	xid, err = orchestrator.Start(ctx, "failedParallelSaga", &testOrderForm{OrderId: "order13"})
+	if err != mailErr {
+		t.Fatalf("start failed saga should return the step error but got %v", err)
+	}
+	// 组内的分支都补偿完成后才补偿更早的步骤
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMPENSATION_DONE,
+		[]string{"reserve", "sms", "cancelMail", "cancelSms", "cancelReserve"})
+}
+
+// orchestrator也通过租约补偿，其他实例持有租约的步骤不会被同时补偿
+func TestSagaOrchestratorSkipsLeasedStep(t *testing.T) {
+	ctx := context.Background()
+	otherConn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	defer otherConn.Close()
+	otherInstance := NewSagaCollaborator(otherConn, &pb.NodeInfo{
+		Group:      testNode.Group,
+		Service:    testNode.Service,
+		InstanceId: "otherClientInstanceId",
+	})
+	// orchestrator领取补偿任务之前，其他实例先领取了同一个全局事务的补偿任务
+	var otherItems []*pb.CompensationWorkItem
+	claimedByOther := func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if claimReq, ok := req.(*pb.ClaimCompensationWorkRequest); ok && otherItems == nil {
+			items, err := otherInstance.ClaimCompensationWorkOfXid(ctx, claimReq.Xid, 60, 10)
+			if err != nil {
+				return err
+			}
+			otherItems = items
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	orchestrator, closeFn := newTestSagaOrchestrator(t, grpc.WithUnaryInterceptor(claimedByOther))
+	defer closeFn()
+	payErr := errors.New("pay failed")
+	err = orchestrator.Register(newTestOrderSagaDefinition("leasedOrderSaga",
+		func(ctx context.Context, sagaData interface{}) error {
+			return payErr
+		}))
+	if err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	xid, err := orchestrator.Start(ctx, "leasedOrderSaga", &testOrderForm{OrderId: "order26"})
+	if err == nil || err == payErr {
+		t.Fatalf("compensation of leased step should not finish, got %v", err)
+	}
+	if len(otherItems) != 1 {
+		t.Fatalf("other instance should lease the last step, got %v", otherItems)
+	}
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMPENSATION_DOING, []string{"reserve"})
+
+	if _, err = otherInstance.ReleaseCompensationWork(ctx, []*pb.CompensationWorkLease{otherItems[0].Lease}); err != nil {
+		t.Fatalf("release compensation work err: %v", err)
+	}
+	if err = orchestrator.Resume(ctx, xid); err != nil {
+		t.Fatalf("resume saga err: %v", err)
+	}
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMPENSATION_DONE,
+		[]string{"reserve", "refund", "cancelReserve"})
+}
+
+func TestSagaOrchestratorForwardStep(t *testing.T) {
+	orchestrator, closeFn := newTestSagaOrchestrator(t)
+	defer closeFn()
+	ctx := context.Background()
+	historyFailTimes := 2
+	definition := NewSagaDefinition("forwardSaga").
+		Step("reserve", appendStepAction("reserve"), appendStepAction("cancelReserve")).
+		ForwardStep("history", func(ctx context.Context, sagaData interface{}) error {
+			if historyFailTimes > 0 {
+				historyFailTimes--
+				return errors.New("history service unavailable")
+			}
+			return appendStepAction("history")(ctx, sagaData)
+		}, nil).
+		Step("notify", appendStepAction("notify"), nil)
+	if err := orchestrator.Register(definition); err != nil {
+		t.Fatalf("register saga err: %v", err)
+	}
+	xid, err := orchestrator.Start(ctx, "forwardSaga", &testOrderForm{OrderId: "order14"},
+		WithRetryPolicy(&pb.RetryPolicy{InitialDelayMs: 10, MaxDelayMs: 10}))
+	if err != nil {
+		t.Fatalf("failed forward step should not fail the saga, got %v", err)
+	}
+	// 向前恢复的步骤还在重试，全局事务被关闭但没有提交
+	detail, err := orchestrator.sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if detail.State != pb.TxState_PROCESSING || !detail.EndBranches {
+		t.Fatalf("saga with retrying step should be closed and processing, got %v", detail)
+	}
+	for i := 0; i < 10 && detail.State == pb.TxState_PROCESSING; i++ {
+		time.Sleep(20 * time.Millisecond)
+		if err = orchestrator.Resume(ctx, xid); err != nil {
+			t.Fatalf("resume saga err: %v", err)
+		}
+		detail, err = orchestrator.sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+		if err != nil {
+			t.Fatalf("query global tx err: %v", err)
+		}
+	}
+	assertTestSagaResult(t, orchestrator, xid, pb.TxState_COMMITTED, []string{"reserve", "notify", "history"})
+	for _, branch := range detail.Branches {
+		if branch.BranchServiceKey == definition.stepServiceKey(1) && branch.ForwardRetryTimes != 2 {
+			t.Errorf("forward step should fail twice, got %d", branch.ForwardRetryTimes)
+		}
+	}
+}
+
+func TestTccSessionConfirmedByWorker(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	confirmFailTimes := 1
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "tcc.freeze",
+		Action:          appendStepAction("freeze"),
+		CompensationKey: "tcc.unfreeze",
+		Compensation:    appendStepAction("unfreeze"),
+		ConfirmKey:      "tcc.deduct",
+		Confirm: func(ctx context.Context, sagaData interface{}) error {
+			if confirmFailTimes > 0 {
+				confirmFailTimes--
+				return errors.New("deduct failed")
+			}
+			return appendStepAction("deduct")(ctx, sagaData)
+		},
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey: "tcc.notify",
+		Action:     appendStepAction("notify"),
+	})
+	form := &testOrderForm{OrderId: "order20", Amount: 100}
+	session, err := sagaContext.Start(ctx, form, WithTransactionMode(pb.TransactionMode_TCC),
+		WithRetryPolicy(&pb.RetryPolicy{InitialDelayMs: 10, MaxDelayMs: 10}))
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	for _, key := range []string{"tcc.freeze", "tcc.notify"} {
+		if err = session.Invoke(ctx, key, form); err != nil {
+			t.Fatalf("invoke %s err: %v", key, err)
+		}
+	}
+	state, err := session.Commit(ctx)
+	if err != nil || state != pb.TxState_CONFIRMING {
+		t.Fatalf("commit TCC saga should start confirming, got %v %v", state, err)
+	}
+
+	worker := NewCompensationWorker(sagaContext, time.Second)
+	var detail *pb.QueryGlobalTransactionDetailReply
+	for i := 0; i < 100; i++ {
+		if _, err = worker.DoWork(ctx); err != nil {
+			t.Fatalf("worker do work err: %v", err)
+		}
+		detail, err = sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+		if err != nil {
+			t.Fatalf("query global tx err: %v", err)
+		}
+		if detail.State == pb.TxState_COMMITTED {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if detail.State != pb.TxState_COMMITTED {
+		t.Fatalf("global tx state should be COMMITTED but got %s", detail.State.String())
+	}
+	for _, branch := range detail.Branches {
+		if branch.State != pb.TxState_CONFIRMED {
+			t.Errorf("branch %s should be confirmed, got %s", branch.BranchServiceKey, branch.State.String())
+		}
+	}
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	steps := sagaData.(*testOrderForm).Steps
+	if len(steps) != 3 || steps[2] != "deduct" {
+		t.Errorf("unexpected TCC steps %v", steps)
+	}
+}
+
+// TCC分支的confirm也通过租约领取，其他实例持有租约期间worker不会同时执行
+func TestTccConfirmLeasedByOtherInstance(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	var mu sync.Mutex
+	confirmTimes := 0
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "leasedTcc.freeze",
+		Action:          appendStepAction("freeze"),
+		CompensationKey: "leasedTcc.unfreeze",
+		Compensation:    appendStepAction("unfreeze"),
+		ConfirmKey:      "leasedTcc.deduct",
+		Confirm: func(ctx context.Context, sagaData interface{}) error {
+			mu.Lock()
+			confirmTimes++
+			mu.Unlock()
+			return appendStepAction("deduct")(ctx, sagaData)
+		},
+	})
+	otherConn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	defer otherConn.Close()
+	otherInstance := NewSagaCollaborator(otherConn, &pb.NodeInfo{
+		Group:      testNode.Group,
+		Service:    testNode.Service,
+		InstanceId: "otherClientInstanceId",
+	})
+	worker := NewCompensationWorker(sagaContext, time.Second)
+
+	form := &testOrderForm{OrderId: "order31", Amount: 100}
+	session, err := sagaContext.Start(ctx, form, WithTransactionMode(pb.TransactionMode_TCC))
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "leasedTcc.freeze", form); err != nil {
+		t.Fatalf("invoke freeze err: %v", err)
+	}
+	if state, err := session.Commit(ctx); err != nil || state != pb.TxState_CONFIRMING {
+		t.Fatalf("commit TCC saga should start confirming, got %v %v", state, err)
+	}
+	items, err := otherInstance.ClaimWork(ctx, pb.WorkType_CONFIRM_WORK, session.Xid(), 60, 10)
+	if err != nil {
+		t.Fatalf("claim confirm work err: %v", err)
+	}
+	if len(items) != 1 || items[0].Branch.State != pb.TxState_CONFIRMING {
+		t.Fatalf("other instance should lease the confirming branch, got %v", items)
+	}
+	if _, err = worker.DoWork(ctx); err != nil {
+		t.Fatalf("worker do work err: %v", err)
+	}
+	mu.Lock()
+	times := confirmTimes
+	mu.Unlock()
+	if times != 0 {
+		t.Fatalf("leased confirm should not be run by worker, run %d times", times)
+	}
+
+	if _, err = otherInstance.ReleaseCompensationWork(ctx, []*pb.CompensationWorkLease{items[0].Lease}); err != nil {
+		t.Fatalf("release confirm work err: %v", err)
+	}
+	if _, err = worker.DoWork(ctx); err != nil {
+		t.Fatalf("worker do work err: %v", err)
+	}
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	mu.Lock()
+	times = confirmTimes
+	mu.Unlock()
+	if detail.State != pb.TxState_COMMITTED || times != 1 {
+		t.Fatalf("released confirm should be run once and committed, got %s after %d runs",
+			detail.State.String(), times)
+	}
+}
+
+// 同一个服务的多个worker同时运行时，每个分支只被补偿一次
+func TestCompensationWorkersClaimWork(t *testing.T) {
+	ctx := context.Background()
+	var mu sync.Mutex
+	compensations := make(map[string]int)
+	newWorker := func() (*SagaContext, *CompensationWorker, func()) {
+		sagaContext, closeFn := newTestSagaContext(t)
+		for _, key := range []string{"claim.reserve", "claim.pay"} {
+			name := key
+			sagaContext.Resolver.BindStep(&Step{
+				ServiceKey:      name,
+				Action:          appendStepAction(name),
+				CompensationKey: name + ".cancel",
+				Compensation: func(ctx context.Context, sagaData interface{}) error {
+					mu.Lock()
+					compensations[BranchIdFromContext(ctx)]++
+					mu.Unlock()
+					time.Sleep(20 * time.Millisecond)
+					return nil
+				},
+			})
+		}
+		return sagaContext, NewCompensationWorker(sagaContext, time.Second), closeFn
+	}
+	sagaContext, worker1, closeFn1 := newWorker()
+	defer closeFn1()
+	_, worker2, closeFn2 := newWorker()
+	defer closeFn2()
+
+	xids := make([]string, 0)
+	for i := 0; i < 3; i++ {
+		form := &testOrderForm{OrderId: fmt.Sprintf("order2%d", i)}
+		session, err := sagaContext.Start(ctx, form)
+		if err != nil {
+			t.Fatalf("start saga err: %v", err)
+		}
+		for _, key := range []string{"claim.reserve", "claim.pay"} {
+			if err = session.Invoke(ctx, key, form); err != nil {
+				t.Fatalf("invoke %s err: %v", key, err)
+			}
+		}
+		if _, err = session.Rollback(ctx); err != nil {
+			t.Fatalf("rollback err: %v", err)
+		}
+		xids = append(xids, session.Xid())
+	}
+
+	for round := 0; round < 4; round++ {
+		var wg sync.WaitGroup
+		for _, worker := range []*CompensationWorker{worker1, worker2} {
+			wg.Add(1)
+			go func(worker *CompensationWorker) {
+				defer wg.Done()
+				if _, err := worker.DoWork(ctx); err != nil {
+					t.Errorf("worker do work err: %v", err)
+				}
+			}(worker)
+		}
+		wg.Wait()
+	}
+	for _, xid := range xids {
+		detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+		if err != nil {
+			t.Fatalf("query global tx err: %v", err)
+		}
+		if detail.State != pb.TxState_COMPENSATION_DONE {
+			t.Fatalf("global tx %s should be COMPENSATION_DONE, got %s", xid, detail.State.String())
+		}
+		for _, branch := range detail.Branches {
+			mu.Lock()
+			times := compensations[branch.BranchId]
+			mu.Unlock()
+			if times != 1 {
+				t.Errorf("branch %s compensated %d times", branch.BranchId, times)
+			}
+		}
+	}
+}
+
+// 向前恢复的分支也通过租约重试，其他实例持有租约期间worker不会同时重新执行
+func TestCompensationWorkerLeasesForwardRetry(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	var mu sync.Mutex
+	runTimes := 0
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey: "leasedForward.history",
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			mu.Lock()
+			defer mu.Unlock()
+			runTimes++
+			if runTimes == 1 {
+				return errors.New("history service unavailable")
+			}
+			return appendStepAction("history")(ctx, sagaData)
+		},
+		RecoveryMode: pb.RecoveryMode_FORWARD,
+		RetryPolicy:  &pb.RetryPolicy{InitialDelayMs: 10, MaxDelayMs: 10},
+	})
+	otherConn, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc.Dial err: %v", err)
+	}
+	defer otherConn.Close()
+	otherInstance := NewSagaCollaborator(otherConn, &pb.NodeInfo{
+		Group:      testNode.Group,
+		Service:    testNode.Service,
+		InstanceId: "otherClientInstanceId",
+	})
+	worker := NewCompensationWorker(sagaContext, time.Second)
+
+	form := &testOrderForm{OrderId: "order30"}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "leasedForward.history", form); err != nil {
+		t.Fatalf("failed forward step should not return error, got %v", err)
+	}
+	if _, err = session.Commit(ctx); err != nil {
+		t.Fatalf("close saga err: %v", err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	items, err := otherInstance.ClaimWork(ctx, pb.WorkType_FORWARD_RETRY_WORK, session.Xid(), 60, 10)
+	if err != nil {
+		t.Fatalf("claim forward retry work err: %v", err)
+	}
+	if len(items) != 1 || items[0].Branch.State != pb.TxState_RETRYING {
+		t.Fatalf("other instance should lease the retrying branch, got %v", items)
+	}
+	if _, err = worker.DoWork(ctx); err != nil {
+		t.Fatalf("worker do work err: %v", err)
+	}
+	mu.Lock()
+	times := runTimes
+	mu.Unlock()
+	if times != 1 {
+		t.Fatalf("leased forward step should not be retried by worker, run %d times", times)
+	}
+	// 不带租约的提交被拒绝
+	branch := items[0].Branch
+	_, err = sagaContext.Collaborator.SubmitBranchTxState(ctx, session.Xid(), branch.BranchId, branch.State,
+		pb.TxState_COMMITTED, branch.Version, generateJobId(), "", nil)
+	var serverErr *SagaServerError
+	if !errors.As(err, &serverErr) || serverErr.Code != int32(services.CompensationLeasedError) {
+		t.Fatalf("submit leased branch without lease should be rejected, got %v", err)
+	}
+
+	if _, err = otherInstance.ReleaseCompensationWork(ctx, []*pb.CompensationWorkLease{items[0].Lease}); err != nil {
+		t.Fatalf("release forward retry work err: %v", err)
+	}
+	if _, err = worker.DoWork(ctx); err != nil {
+		t.Fatalf("worker do work err: %v", err)
+	}
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if detail.State != pb.TxState_COMMITTED || runTimes != 2 {
+		t.Fatalf("released forward step should be retried once and committed, got %s after %d runs",
+			detail.State.String(), runTimes)
+	}
+}
+
+// 配置了心跳超时的步骤执行期间自动发送心跳
+func TestSagaSessionHeartbeatDuringStep(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	var heartbeats []int64
+	queryHeartbeat := func(ctx context.Context) error {
+		reply, err := sagaContext.Collaborator.QueryBranchTx(ctx, BranchIdFromContext(ctx))
+		if err != nil {
+			return err
+		}
+		heartbeats = append(heartbeats, reply.Detail.LastHeartbeatAt)
+		return nil
+	}
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey: "heartbeat.slow",
+		Action: func(ctx context.Context, sagaData interface{}) error {
+			if err := queryHeartbeat(ctx); err != nil {
+				return err
+			}
+			time.Sleep(1200 * time.Millisecond)
+			if err := queryHeartbeat(ctx); err != nil {
+				return err
+			}
+			return appendStepAction("slow")(ctx, sagaData)
+		},
+		HeartbeatTimeoutSeconds: 1,
+	})
+	form := &testOrderForm{OrderId: "order23", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "heartbeat.slow", form); err != nil {
+		t.Fatalf("invoke heartbeat.slow err: %v", err)
+	}
+	if len(heartbeats) != 2 || heartbeats[0] <= 0 || heartbeats[1]-heartbeats[0] < 300 {
+		t.Fatalf("heartbeat should be sent while the step runs, got %v", heartbeats)
+	}
+	detail, err := sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+	if err != nil {
+		t.Fatalf("query global tx err: %v", err)
+	}
+	if len(detail.Branches) != 1 || detail.Branches[0].State != pb.TxState_COMMITTED ||
+		detail.Branches[0].OrphanedAt != 0 {
+		t.Fatalf("branch should be committed without orphaned mark, got %v", detail.Branches)
+	}
+}
+
+// 回滚后不需要轮询，watch在补偿完成时返回
+func TestWatchGlobalTxUntilCompensated(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "watch.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "watch.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	form := &testOrderForm{OrderId: "order24", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	if err = session.Invoke(ctx, "watch.reserve", form); err != nil {
+		t.Fatalf("invoke watch.reserve err: %v", err)
+	}
+	if _, err = session.Rollback(ctx); err != nil {
+		t.Fatalf("rollback err: %v", err)
+	}
+
+	type watchResult struct {
+		state  pb.TxState
+		events []*pb.TxStateEvent
+		err    error
+	}
+	results := make(chan *watchResult, 1)
+	go func() {
+		result := &watchResult{}
+		result.state, result.err = sagaContext.Collaborator.WatchGlobalTx(ctx, session.Xid(),
+			func(event *pb.TxStateEvent) error {
+				result.events = append(result.events, event)
+				return nil
+			})
+		results <- result
+	}()
+	worker := NewCompensationWorker(sagaContext, time.Second)
+	var result *watchResult
+	for i := 0; i < 100 && result == nil; i++ {
+		if _, err = worker.DoWork(ctx); err != nil {
+			t.Fatalf("worker do work err: %v", err)
+		}
+		select {
+		case result = <-results:
+		case <-time.After(20 * time.Millisecond):
+		}
+	}
+	if result == nil || result.err != nil || result.state != pb.TxState_COMPENSATION_DONE {
+		t.Fatalf("watch should return COMPENSATION_DONE, got %v", result)
+	}
+	compensated := false
+	for _, event := range result.events {
+		if len(event.BranchId) > 0 && event.State == pb.TxState_COMPENSATION_DONE {
+			compensated = true
+		}
+	}
+	if !compensated {
+		t.Errorf("branch compensation should be pushed, got %v", result.events)
+	}
+}
+
+// worker订阅自己分支的补偿事件，定时轮询间隔很长时回滚后也能马上补偿
+func TestCompensationWorkerPushedWork(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx := context.Background()
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "push.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "push.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "push.pay",
+		Action:          appendStepAction("pay"),
+		CompensationKey: "push.refund",
+		Compensation:    appendStepAction("refund"),
+	})
+	worker := NewCompensationWorker(sagaContext, time.Hour)
+	if err := worker.Start(ctx); err != nil {
+		t.Fatalf("start worker err: %v", err)
+	}
+	defer worker.Stop()
+	// 等待worker订阅成功
+	time.Sleep(200 * time.Millisecond)
+
+	form := &testOrderForm{OrderId: "order25", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	for _, key := range []string{"push.reserve", "push.pay"} {
+		if err = session.Invoke(ctx, key, form); err != nil {
+			t.Fatalf("invoke %s err: %v", key, err)
+		}
+	}
+	if _, err = session.Rollback(ctx); err != nil {
+		t.Fatalf("rollback err: %v", err)
+	}
+	var detail *pb.QueryGlobalTransactionDetailReply
+	for i := 0; i < 100; i++ {
+		detail, err = sagaContext.Collaborator.QueryGlobalTx(ctx, session.Xid())
+		if err != nil {
+			t.Fatalf("query global tx err: %v", err)
+		}
+		if detail.State == pb.TxState_COMPENSATION_DONE {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if detail.State != pb.TxState_COMPENSATION_DONE {
+		t.Fatalf("global tx state should be COMPENSATION_DONE but got %s", detail.State.String())
+	}
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	// 后一个分支补偿完成的事件触发前一个分支的补偿
+	steps := sagaData.(*testOrderForm).Steps
+	if len(steps) != 4 || steps[2] != "refund" || steps[3] != "cancelReserve" {
+		t.Errorf("unexpected compensation steps %v", steps)
+	}
+}
+
+// 定时轮询间隔很长时补偿完全由推送驱动，每个分支的每次状态变化都推送给订阅方，任务通知不推送给只订阅状态的订阅方
+func TestCompensationWorkerWithoutPolling(t *testing.T) {
+	sagaContext, closeFn := newTestSagaContext(t)
+	defer closeFn()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "pushOnly.reserve",
+		Action:          appendStepAction("reserve"),
+		CompensationKey: "pushOnly.cancelReserve",
+		Compensation:    appendStepAction("cancelReserve"),
+	})
+	sagaContext.Resolver.BindStep(&Step{
+		ServiceKey:      "pushOnly.pay",
+		Action:          appendStepAction("pay"),
+		CompensationKey: "pushOnly.refund",
+		Compensation:    appendStepAction("refund"),
+	})
+	worker := NewCompensationWorker(sagaContext, time.Hour)
+	if err := worker.Start(ctx); err != nil {
+		t.Fatalf("start worker err: %v", err)
+	}
+	defer worker.Stop()
+	events := make(chan *pb.TxStateEvent, 100)
+	go func() {
+		_, _ = sagaContext.Collaborator.SubscribeEvents(ctx, nil, "",
+			func(event *pb.TxStateEvent, cursor string) error {
+				events <- event
+				return nil
+			})
+	}()
+	// 等待worker和测试的订阅成功
+	time.Sleep(200 * time.Millisecond)
+
+	form := &testOrderForm{OrderId: "order26", Amount: 100}
+	session, err := sagaContext.Start(ctx, form)
+	if err != nil {
+		t.Fatalf("start saga err: %v", err)
+	}
+	for _, key := range []string{"pushOnly.reserve", "pushOnly.pay"} {
+		if err = session.Invoke(ctx, key, form); err != nil {
+			t.Fatalf("invoke %s err: %v", key, err)
+		}
+	}
+	if _, err = session.Rollback(ctx); err != nil {
+		t.Fatalf("rollback err: %v", err)
+	}
+	transitions := make(map[string][]string)
+	timeout := time.After(5 * time.Second)
+	for {
+		var event *pb.TxStateEvent
+		select {
+		case event = <-events:
+		case <-timeout:
+			t.Fatalf("global tx not compensated by pushed events, got %v", transitions)
+		}
+		if event.Xid != session.Xid() {
+			continue
+		}
+		transitions[event.BranchId] = append(transitions[event.BranchId],
+			event.OldState.String()+"=>"+event.State.String())
+		if len(event.BranchId) < 1 && event.State == pb.TxState_COMPENSATION_DONE {
+			break
+		}
+	}
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		t.Fatalf("get saga data err: %v", err)
+	}
+	steps := sagaData.(*testOrderForm).Steps
+	if len(steps) != 4 || steps[2] != "refund" || steps[3] != "cancelReserve" {
+		t.Errorf("unexpected compensation steps %v", steps)
+	}
+	expected := "PROCESSING=>PROCESSING,PROCESSING=>COMMITTED,COMMITTED=>COMPENSATION_DOING,COMPENSATION_DOING=>COMPENSATION_DONE"
+	branchCount := 0
+	for branchId, branchTransitions := range transitions {
+		if len(branchId) < 1 {
+			continue
+		}
+		branchCount++
+		if got := strings.Join(branchTransitions, ","); got != expected {
+			t.Errorf("branch %s should push every transition, got %s", branchId, got)
+		}
+	}
+	if branchCount != 2 {
+		t.Errorf("should receive events of 2 branches, got %v", transitions)
+	}
+}
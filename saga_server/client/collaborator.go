@@ -0,0 +1,550 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
+	pb "github.com/zoowii/saga_server/api"
+	"google.golang.org/grpc"
+	"io"
+)
+
+const (
+	okCode                          int32 = 0
+	resourceChangedErrorCode        int32 = 3
+	forwardRecoveryPendingErrorCode int32 = 5
+	eventCursorExpiredErrorCode     int32 = 6
+
+	defaultGlobalTxExpireSeconds = 60
+	// 乐观修改状态时版本号过期的最大重试次数
+	maxSubmitStateTryTimes = 10
+)
+
+/**
+ * saga server返回的code不为0时的错误
+ */
+type SagaServerError struct {
+	Code    int32
+	Message string
+}
+
+func (e *SagaServerError) Error() string {
+	return fmt.Sprintf("saga server error code %d: %s", e.Code, e.Message)
+}
+
+func replyError(code int32, message string) error {
+	if code == okCode {
+		return nil
+	}
+	return &SagaServerError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+/**
+ * 判断是否是saga server返回的版本号过期错误
+ */
+func IsResourceChangedError(err error) bool {
+	var serverErr *SagaServerError
+	return errors.As(err, &serverErr) && serverErr.Code == resourceChangedErrorCode
+}
+
+/**
+ * 判断是否是全局事务还有向前恢复的分支在重试，不能提交的错误
+ */
+func IsForwardRecoveryPendingError(err error) bool {
+	var serverErr *SagaServerError
+	return errors.As(err, &serverErr) && serverErr.Code == forwardRecoveryPendingErrorCode
+}
+
+/**
+ * 判断是否是订阅事件的cursor已经过期，这时需要重新同步状态后不带cursor订阅
+ */
+func IsEventCursorExpiredError(err error) bool {
+	var serverErr *SagaServerError
+	return errors.As(err, &serverErr) && serverErr.Code == eventCursorExpiredErrorCode
+}
+
+func generateJobId() string {
+	return uuid.New().String()
+}
+
+/**
+ * 对api.SagaServerClient的封装，参与方通过它和saga server交互
+ */
+type SagaCollaborator struct {
+	Client pb.SagaServerClient
+	Node   *pb.NodeInfo
+}
+
+func NewSagaCollaborator(cc grpc.ClientConnInterface, node *pb.NodeInfo) *SagaCollaborator {
+	return &SagaCollaborator{
+		Client: pb.NewSagaServerClient(cc),
+		Node:   node,
+	}
+}
+
+/**
+ * 创建全局事务时的可选参数
+ */
+type GlobalTxOption func(req *pb.CreateGlobalTransactionRequest)
+
+func WithExpireSeconds(expireSeconds int64) GlobalTxOption {
+	return func(req *pb.CreateGlobalTransactionRequest) {
+		req.ExpireSeconds = expireSeconds
+	}
+}
+
+func WithRetryPolicy(retryPolicy *pb.RetryPolicy) GlobalTxOption {
+	return func(req *pb.CreateGlobalTransactionRequest) {
+		req.RetryPolicy = retryPolicy
+	}
+}
+
+/**
+ * TCC模式下分支执行的是try，提交全局事务时由server驱动各分支的confirm
+ */
+func WithTransactionMode(mode pb.TransactionMode) GlobalTxOption {
+	return func(req *pb.CreateGlobalTransactionRequest) {
+		req.Mode = mode
+	}
+}
+
+func WithExtra(extra string) GlobalTxOption {
+	return func(req *pb.CreateGlobalTransactionRequest) {
+		req.Extra = extra
+	}
+}
+
+func (c *SagaCollaborator) CreateGlobalTx(ctx context.Context, opts ...GlobalTxOption) (xid string, err error) {
+	req := &pb.CreateGlobalTransactionRequest{
+		Node:          c.Node,
+		ExpireSeconds: defaultGlobalTxExpireSeconds,
+	}
+	for _, opt := range opts {
+		opt(req)
+	}
+	reply, err := c.Client.CreateGlobalTransaction(ctx, req)
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	xid = reply.Xid
+	return
+}
+
+func (c *SagaCollaborator) CreateBranchTx(ctx context.Context,
+	req *pb.CreateBranchTransactionRequest) (branchTxId string, err error) {
+	if req.Node == nil {
+		req.Node = c.Node
+	}
+	reply, err := c.Client.CreateBranchTransaction(ctx, req)
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	branchTxId = reply.BranchId
+	return
+}
+
+func (c *SagaCollaborator) QueryGlobalTx(ctx context.Context,
+	xid string) (reply *pb.QueryGlobalTransactionDetailReply, err error) {
+	reply, err = c.Client.QueryGlobalTransactionDetail(ctx, &pb.QueryGlobalTransactionDetailRequest{
+		Xid: xid,
+	})
+	if err != nil {
+		return
+	}
+	err = replyError(reply.Code, reply.Error)
+	return
+}
+
+func (c *SagaCollaborator) QueryBranchTx(ctx context.Context,
+	branchTxId string) (reply *pb.QueryBranchTransactionDetailReply, err error) {
+	reply, err = c.Client.QueryBranchTransactionDetail(ctx, &pb.QueryBranchTransactionDetailRequest{
+		BranchId: branchTxId,
+	})
+	if err != nil {
+		return
+	}
+	err = replyError(reply.Code, reply.Error)
+	return
+}
+
+func (c *SagaCollaborator) SubmitGlobalTxState(ctx context.Context, xid string,
+	oldState pb.TxState, state pb.TxState, oldVersion int32) (newState pb.TxState, err error) {
+	reply, err := c.Client.SubmitGlobalTransactionState(ctx, &pb.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   oldState,
+		State:      state,
+		OldVersion: oldVersion,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	newState = reply.State
+	return
+}
+
+/**
+ * 乐观的修改全局事务状态，如果版本号过期了就查询最新版本重试
+ */
+func (c *SagaCollaborator) SubmitGlobalTxStateOptimism(ctx context.Context,
+	xid string, state pb.TxState) (newState pb.TxState, err error) {
+	for tryCount := 0; tryCount < maxSubmitStateTryTimes; tryCount++ {
+		var detail *pb.QueryGlobalTransactionDetailReply
+		detail, err = c.QueryGlobalTx(ctx, xid)
+		if err != nil {
+			return
+		}
+		newState, err = c.SubmitGlobalTxState(ctx, xid, detail.State, state, detail.Version)
+		if IsResourceChangedError(err) {
+			continue
+		}
+		return
+	}
+	err = errors.New("retry too many times but version expired")
+	return
+}
+
+func (c *SagaCollaborator) SubmitBranchTxState(ctx context.Context, xid string, branchTxId string,
+	oldState pb.TxState, state pb.TxState, oldVersion int32,
+	jobId string, errorReason string, sagaData []byte) (newState pb.TxState, err error) {
+	return c.submitBranchTxState(ctx, &pb.SubmitBranchTransactionStateRequest{
+		Xid:         xid,
+		BranchId:    branchTxId,
+		OldState:    oldState,
+		State:       state,
+		OldVersion:  oldVersion,
+		JobId:       jobId,
+		ErrorReason: errorReason,
+		SagaData:    sagaData,
+	})
+}
+
+/**
+ * 持有补偿任务的租约时提交补偿结果，租约期间server拒绝没有这个租约的参与方提交的补偿结果
+ */
+func (c *SagaCollaborator) SubmitLeasedBranchTxState(ctx context.Context, lease *pb.CompensationWorkLease,
+	oldState pb.TxState, state pb.TxState, oldVersion int32,
+	jobId string, errorReason string, sagaData []byte) (newState pb.TxState, err error) {
+	return c.submitBranchTxState(ctx, &pb.SubmitBranchTransactionStateRequest{
+		Xid:         lease.Xid,
+		BranchId:    lease.BranchId,
+		OldState:    oldState,
+		State:       state,
+		OldVersion:  oldVersion,
+		JobId:       jobId,
+		ErrorReason: errorReason,
+		SagaData:    sagaData,
+		LeaseId:     lease.LeaseId,
+	})
+}
+
+func (c *SagaCollaborator) submitBranchTxState(ctx context.Context,
+	req *pb.SubmitBranchTransactionStateRequest) (newState pb.TxState, err error) {
+	reply, err := c.Client.SubmitBranchTransactionState(ctx, req)
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	newState = reply.State
+	return
+}
+
+/**
+ * 用分支事务的最新状态和版本号提交状态，版本号过期时重试. 同一次执行重试时使用同一个jobId
+ */
+func (c *SagaCollaborator) SubmitBranchTxStateOptimism(ctx context.Context, xid string, branchTxId string,
+	state pb.TxState, jobId string, errorReason string, sagaData []byte) (newState pb.TxState, err error) {
+	for tryCount := 0; tryCount < maxSubmitStateTryTimes; tryCount++ {
+		var detail *pb.QueryBranchTransactionDetailReply
+		detail, err = c.QueryBranchTx(ctx, branchTxId)
+		if err != nil {
+			return
+		}
+		newState, err = c.SubmitBranchTxState(ctx, xid, branchTxId, detail.Detail.State, state,
+			detail.Detail.Version, jobId, errorReason, sagaData)
+		if IsResourceChangedError(err) {
+			continue
+		}
+		return
+	}
+	err = errors.New("retry too many times but version expired")
+	return
+}
+
+func (c *SagaCollaborator) ListGlobalTransactionsOfStates(ctx context.Context,
+	states []pb.TxState, limit int32) (xids []string, err error) {
+	reply, err := c.Client.ListGlobalTransactionsOfStates(ctx, &pb.ListGlobalTransactionsOfStatesRequest{
+		States: states,
+		Limit:  limit,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	xids = reply.Xids
+	return
+}
+
+func (c *SagaCollaborator) InitSagaData(ctx context.Context, xid string, data []byte) (err error) {
+	reply, err := c.Client.InitSagaData(ctx, &pb.InitSagaDataRequest{
+		Xid:  xid,
+		Data: data,
+	})
+	if err != nil {
+		return
+	}
+	err = replyError(reply.Code, reply.Error)
+	return
+}
+
+func (c *SagaCollaborator) GetSagaData(ctx context.Context, xid string) (reply *pb.GetSagaDataReply, err error) {
+	reply, err = c.Client.GetSagaData(ctx, &pb.GetSagaDataRequest{
+		Xid: xid,
+	})
+	if err != nil {
+		return
+	}
+	err = replyError(reply.Code, reply.Error)
+	return
+}
+
+/**
+ * 声明全局事务不再有新的分支，各分支都committed后全局事务由server自动提交
+ */
+func (c *SagaCollaborator) CloseGlobalTx(ctx context.Context, xid string) (state pb.TxState, err error) {
+	reply, err := c.Client.CloseGlobalTransaction(ctx, &pb.CloseGlobalTransactionRequest{
+		Xid: xid,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	state = reply.State
+	return
+}
+
+/**
+ * 领取自己(c.Node的group和service)创建的待补偿分支的租约，租约期间其他参与方领取不到同样的分支
+ */
+func (c *SagaCollaborator) ClaimCompensationWork(ctx context.Context,
+	leaseSeconds int32, limit int32) (items []*pb.CompensationWorkItem, err error) {
+	return c.ClaimCompensationWorkOfXid(ctx, "", leaseSeconds, limit)
+}
+
+/**
+ * 和ClaimCompensationWork相同，xid不为空时只领取这个全局事务中的分支
+ */
+func (c *SagaCollaborator) ClaimCompensationWorkOfXid(ctx context.Context, xid string,
+	leaseSeconds int32, limit int32) (items []*pb.CompensationWorkItem, err error) {
+	return c.ClaimWork(ctx, pb.WorkType_COMPENSATION_WORK, xid, leaseSeconds, limit)
+}
+
+/**
+ * 领取自己创建的分支的work类型任务的租约，xid不为空时只领取这个全局事务中的分支
+ */
+func (c *SagaCollaborator) ClaimWork(ctx context.Context, work pb.WorkType, xid string,
+	leaseSeconds int32, limit int32) (items []*pb.CompensationWorkItem, err error) {
+	reply, err := c.Client.ClaimCompensationWork(ctx, &pb.ClaimCompensationWorkRequest{
+		Node:         c.Node,
+		LeaseSeconds: leaseSeconds,
+		Limit:        limit,
+		Xid:          xid,
+		Work:         work,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	items = reply.Items
+	return
+}
+
+/**
+ * 延长租约，返回续约成功的租约，已经被其他参与方领取的不返回
+ */
+func (c *SagaCollaborator) RenewCompensationWork(ctx context.Context,
+	leases []*pb.CompensationWorkLease, leaseSeconds int32) (renewed []*pb.CompensationWorkLease, err error) {
+	reply, err := c.Client.RenewCompensationWork(ctx, &pb.RenewCompensationWorkRequest{
+		Node:         c.Node,
+		Leases:       leases,
+		LeaseSeconds: leaseSeconds,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	renewed = reply.Leases
+	return
+}
+
+func (c *SagaCollaborator) ReleaseCompensationWork(ctx context.Context,
+	leases []*pb.CompensationWorkLease) (released int32, err error) {
+	reply, err := c.Client.ReleaseCompensationWork(ctx, &pb.ReleaseCompensationWorkRequest{
+		Node:   c.Node,
+		Leases: leases,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	released = reply.Released
+	return
+}
+
+/**
+ * 发送执行中分支的心跳，返回分支和全局事务当前的状态
+ */
+func (c *SagaCollaborator) HeartbeatBranch(ctx context.Context, xid string,
+	branchTxId string) (state pb.TxState, globalTxState pb.TxState, err error) {
+	reply, err := c.Client.HeartbeatBranch(ctx, &pb.HeartbeatBranchRequest{
+		Node:     c.Node,
+		Xid:      xid,
+		BranchId: branchTxId,
+	})
+	if err != nil {
+		return
+	}
+	if err = replyError(reply.Code, reply.Error); err != nil {
+		return
+	}
+	state = reply.State
+	globalTxState = reply.GlobalTxState
+	return
+}
+
+func isTerminalGlobalTxState(state pb.TxState) bool {
+	return state == pb.TxState_COMMITTED || state == pb.TxState_COMPENSATION_DONE ||
+		state == pb.TxState_COMPENSATION_FAIL
+}
+
+/**
+ * 接收全局事务xid的状态变化直到全局事务结束，返回结束时的状态，不需要轮询QueryGlobalTx
+ * 先收到的是全局事务和各分支当前的状态，onEvent可以为空，onEvent返回错误时停止接收
+ */
+func (c *SagaCollaborator) WatchGlobalTx(ctx context.Context, xid string,
+	onEvent func(event *pb.TxStateEvent) error) (state pb.TxState, err error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.Client.WatchGlobalTransaction(ctx, &pb.WatchGlobalTransactionRequest{
+		Xid: xid,
+	})
+	if err != nil {
+		return
+	}
+	for {
+		var reply *pb.WatchGlobalTransactionReply
+		reply, err = stream.Recv()
+		if err == io.EOF {
+			err = fmt.Errorf("watch of xid %s closed before finished", xid)
+			return
+		}
+		if err != nil {
+			return
+		}
+		if err = replyError(reply.Code, reply.Error); err != nil {
+			return
+		}
+		event := reply.Event
+		if onEvent != nil {
+			if err = onEvent(event); err != nil {
+				return
+			}
+		}
+		if len(event.BranchId) < 1 {
+			state = event.State
+			if isTerminalGlobalTxState(state) {
+				return
+			}
+		}
+	}
+}
+
+/**
+ * 订阅c.Node的group和service创建的分支(和发起的全局事务)变成states中某个状态的事件，states为空时订阅所有状态
+ * 从cursor之后开始接收，阻塞直到stream断开、ctx结束或者onEvent返回错误
+ * 返回最后处理完的事件的cursor，断开后用它重新订阅可以接着接收，中间不会遗漏事件
+ */
+func (c *SagaCollaborator) SubscribeEvents(ctx context.Context, states []pb.TxState, cursor string,
+	onEvent func(event *pb.TxStateEvent, cursor string) error) (lastCursor string, err error) {
+	return c.subscribeEvents(ctx, &pb.SubscribeEventsRequest{
+		Node:   c.Node,
+		States: states,
+		Cursor: cursor,
+	}, nil, onEvent)
+}
+
+/**
+ * 订阅c.Node的group和service现在可以领取works中类型任务的通知，event.Xid中有event.Work类型的任务可以用ClaimWork领取
+ * 订阅成功(补发完cursor之后的通知)时调用onSubscribed，cursor为空时订阅之前已经可以领取的任务不会通知，需要在这之后自己领取一次
+ * 其他和SubscribeEvents相同
+ */
+func (c *SagaCollaborator) SubscribeWork(ctx context.Context, works []pb.WorkType, cursor string,
+	onSubscribed func(), onWork func(event *pb.TxStateEvent, cursor string) error) (lastCursor string, err error) {
+	return c.subscribeEvents(ctx, &pb.SubscribeEventsRequest{
+		Node:   c.Node,
+		Cursor: cursor,
+		Works:  works,
+	}, onSubscribed, onWork)
+}
+
+func (c *SagaCollaborator) subscribeEvents(ctx context.Context, req *pb.SubscribeEventsRequest,
+	onSubscribed func(), onEvent func(event *pb.TxStateEvent, cursor string) error) (lastCursor string, err error) {
+	lastCursor = req.Cursor
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	subscribed := false
+	stream, err := c.Client.SubscribeEvents(ctx, req)
+	if err != nil {
+		return
+	}
+	for {
+		var reply *pb.SubscribeEventsReply
+		reply, err = stream.Recv()
+		if err == io.EOF {
+			err = errors.New("event subscription closed by saga server")
+			return
+		}
+		if err != nil {
+			return
+		}
+		if err = replyError(reply.Code, reply.Error); err != nil {
+			return
+		}
+		// 表示订阅成功的reply没有事件，只有订阅时的位置
+		if reply.Event != nil {
+			if err = onEvent(reply.Event, reply.Cursor); err != nil {
+				return
+			}
+		} else if !subscribed {
+			subscribed = true
+			if onSubscribed != nil {
+				onSubscribed()
+			}
+		}
+		lastCursor = reply.Cursor
+	}
+}
@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"google.golang.org/grpc"
+	"log"
+	"sync"
+)
+
+/**
+ * 声明一个grpc方法和它的补偿，相当于C#中的[Compensable(nameof(...))]
+ * 带着saga上下文调用这个方法时自动在调用方的全局事务中创建分支事务
+ */
+type CompensableMethod struct {
+	FullMethod      string // grpc方法全名 /package.Service/Method
+	ServiceKey      string // 分支事务的服务标识，为空时使用FullMethod
+	CompensationKey string // 补偿方法的服务标识，比如 grpc://host:port/package.Service/Method
+	ConfirmKey      string // TCC模式下confirm方法的服务标识，格式和CompensationKey相同
+	RetryPolicy     *pb.RetryPolicy
+	// 执行期间定时发送分支心跳，超过这个时间没有心跳时server认为执行方已经崩溃，0表示不发送心跳
+	HeartbeatTimeoutSeconds int32
+	// 可选，业务返回失败但没有返回error时(比如reply中success=false)，把reply转换成error
+	ReplyError func(reply interface{}) error
+}
+
+func (m *CompensableMethod) serviceKey() string {
+	if len(m.ServiceKey) > 0 {
+		return m.ServiceKey
+	}
+	return m.FullMethod
+}
+
+/**
+ * 注册需要自动加入saga的grpc方法，通过UnaryServerInterceptor生效
+ */
+type CompensableRegistry struct {
+	collaborator *SagaCollaborator
+
+	mu      sync.RWMutex
+	methods map[string]*CompensableMethod // FullMethod => method
+}
+
+func NewCompensableRegistry(collaborator *SagaCollaborator) *CompensableRegistry {
+	return &CompensableRegistry{
+		collaborator: collaborator,
+		methods:      make(map[string]*CompensableMethod),
+	}
+}
+
+func (r *CompensableRegistry) Register(fullMethod string, compensationKey string) {
+	r.RegisterMethod(&CompensableMethod{
+		FullMethod:      fullMethod,
+		CompensationKey: compensationKey,
+	})
+}
+
+func (r *CompensableRegistry) RegisterMethod(method *CompensableMethod) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.methods[method.FullMethod] = method
+}
+
+func (r *CompensableRegistry) resolve(fullMethod string) *CompensableMethod {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.methods[fullMethod]
+}
+
+/**
+ * 从metadata恢复saga上下文. 调用的是注册过的方法并且带有xid时，
+ * 以调用方的分支为上级分支创建分支事务，执行成功提交COMMITTED，失败提交COMPENSATION_DOING
+ * 没有注册的方法或者不在saga中的调用只恢复上下文
+ */
+func (r *CompensableRegistry) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (reply interface{}, err error) {
+		ctx = incomingContextWithSaga(ctx)
+		method := r.resolve(info.FullMethod)
+		xid := XidFromContext(ctx)
+		if method == nil || len(xid) < 1 {
+			return handler(ctx, req)
+		}
+		branchTxId, err := r.collaborator.CreateBranchTx(ctx, &pb.CreateBranchTransactionRequest{
+			Xid:                          xid,
+			BranchServiceKey:             method.serviceKey(),
+			BranchCompensationServiceKey: method.CompensationKey,
+			BranchConfirmServiceKey:      method.ConfirmKey,
+			RetryPolicy:                  method.RetryPolicy,
+			ParentBranchId:               BranchIdFromContext(ctx),
+			HeartbeatTimeoutSeconds:      method.HeartbeatTimeoutSeconds,
+		})
+		if err != nil {
+			err = fmt.Errorf("enlist %s in saga %s error: %s", info.FullMethod, xid, err.Error())
+			return
+		}
+		jobId := generateJobId()
+		stopHeartbeat := startBranchHeartbeat(ctx, r.collaborator, xid, branchTxId, method.HeartbeatTimeoutSeconds)
+		reply, err = handler(ContextWithBranchId(ctx, branchTxId), req)
+		stopHeartbeat()
+		failure := err
+		if failure == nil && method.ReplyError != nil {
+			failure = method.ReplyError(reply)
+		}
+		if failure != nil {
+			// 业务失败时仍然返回handler本身的结果
+			_, submitErr := r.collaborator.SubmitBranchTxStateOptimism(ctx, xid, branchTxId,
+				pb.TxState_COMPENSATION_DOING, jobId, failure.Error(), nil)
+			if submitErr != nil {
+				log.Printf("submit branch %s COMPENSATION_DOING error %s\n", branchTxId, submitErr.Error())
+			}
+			return
+		}
+		_, err = r.collaborator.SubmitBranchTxStateOptimism(ctx, xid, branchTxId,
+			pb.TxState_COMMITTED, jobId, "", nil)
+		if err != nil {
+			// 没有记录成功时让调用方失败，由全局事务的补偿处理已经执行的业务
+			err = fmt.Errorf("submit branch %s COMMITTED error: %s", branchTxId, err.Error())
+			reply = nil
+		}
+		return
+	}
+}
@@ -0,0 +1,40 @@
+package client
+
+import "context"
+
+type contextKey string
+
+const (
+	xidContextKey      contextKey = "saga.xid"
+	branchIdContextKey contextKey = "saga.branchId"
+	sessionContextKey  contextKey = "saga.session"
+)
+
+/**
+ * 在ctx中绑定当前的全局事务
+ */
+func ContextWithXid(ctx context.Context, xid string) context.Context {
+	return context.WithValue(ctx, xidContextKey, xid)
+}
+
+func XidFromContext(ctx context.Context) string {
+	xid, _ := ctx.Value(xidContextKey).(string)
+	return xid
+}
+
+/**
+ * 在ctx中绑定当前执行中的分支事务，在分支中创建的分支事务以它作为上级分支
+ */
+func ContextWithBranchId(ctx context.Context, branchId string) context.Context {
+	return context.WithValue(ctx, branchIdContextKey, branchId)
+}
+
+func BranchIdFromContext(ctx context.Context) string {
+	branchId, _ := ctx.Value(branchIdContextKey).(string)
+	return branchId
+}
+
+func SessionFromContext(ctx context.Context) *SagaSession {
+	session, _ := ctx.Value(sessionContextKey).(*SagaSession)
+	return session
+}
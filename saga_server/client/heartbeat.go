@@ -0,0 +1,53 @@
+package client
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"log"
+	"sync"
+	"time"
+)
+
+/**
+ * 在分支执行期间每隔心跳超时的三分之一发送一次心跳，分支不再是PROCESSING时停止
+ * 返回的stop停止心跳并等待后台goroutine退出，heartbeatTimeoutSeconds<=0时不发送心跳
+ */
+func startBranchHeartbeat(ctx context.Context, collaborator *SagaCollaborator, xid string,
+	branchTxId string, heartbeatTimeoutSeconds int32) (stop func()) {
+	if heartbeatTimeoutSeconds <= 0 {
+		return func() {}
+	}
+	interval := time.Duration(heartbeatTimeoutSeconds) * time.Second / 3
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				state, _, err := collaborator.HeartbeatBranch(ctx, xid, branchTxId)
+				if err != nil {
+					log.Printf("heartbeat of branch %s error %s\n", branchTxId, err.Error())
+					continue
+				}
+				if state != pb.TxState_PROCESSING {
+					return
+				}
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			wg.Wait()
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package client
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// 在grpc调用的metadata中传递当前全局事务和分支事务的key
+const (
+	XidMetadataKey      = "saga-xid"
+	BranchIdMetadataKey = "saga-branch-id"
+)
+
+/**
+ * 把ctx中的xid和branchId加入到发出的grpc请求的metadata
+ */
+func outgoingContextWithSaga(ctx context.Context) context.Context {
+	xid := XidFromContext(ctx)
+	if len(xid) < 1 {
+		return ctx
+	}
+	pairs := []string{XidMetadataKey, xid}
+	if branchId := BranchIdFromContext(ctx); len(branchId) > 0 {
+		pairs = append(pairs, BranchIdMetadataKey, branchId)
+	}
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
+
+/**
+ * 从收到的grpc请求的metadata中恢复xid和branchId到ctx
+ */
+func incomingContextWithSaga(ctx context.Context) context.Context {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx
+	}
+	xids := md.Get(XidMetadataKey)
+	if len(xids) < 1 || len(xids[0]) < 1 {
+		return ctx
+	}
+	ctx = ContextWithXid(ctx, xids[0])
+	if branchIds := md.Get(BranchIdMetadataKey); len(branchIds) > 0 && len(branchIds[0]) > 0 {
+		ctx = ContextWithBranchId(ctx, branchIds[0])
+	}
+	return ctx
+}
+
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContextWithSaga(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn,
+		method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContextWithSaga(ctx), desc, cc, method, opts...)
+	}
+}
+
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(incomingContextWithSaga(ctx), req)
+	}
+}
+
+/**
+ * 替换了Context()的ServerStream
+ */
+type sagaServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *sagaServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		return handler(srv, &sagaServerStream{
+			ServerStream: ss,
+			ctx:          incomingContextWithSaga(ss.Context()),
+		})
+	}
+}
@@ -0,0 +1,420 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// 编排式saga的全局事务extra的前缀，之后是saga定义的名称
+const (
+	orchestratorExtraPrefix = "orchestrator:"
+	// ResumeAll每次最多恢复的未完成全局事务数量
+	defaultResumeBatchSize = 1000
+)
+
+/**
+ * 编排式saga中的一个步骤
+ */
+type SagaStepDefinition struct {
+	Key          string // 步骤的唯一key，为空时使用在Steps中的索引
+	Action       BranchFunc
+	Compensation BranchFunc // 为空表示不需要补偿
+	RetryPolicy  *pb.RetryPolicy
+	Group        string // 相邻的同一组的步骤作为一个并行分支组并发执行，为空表示单独执行
+	RecoveryMode pb.RecoveryMode
+}
+
+/**
+ * 预先定义好按顺序执行的步骤的saga，相当于C#中的SagaDefinition
+ */
+type SagaDefinition struct {
+	Name  string
+	Steps []*SagaStepDefinition
+}
+
+func NewSagaDefinition(name string) *SagaDefinition {
+	return &SagaDefinition{Name: name}
+}
+
+/**
+ * 追加一个步骤，compensation可以为nil
+ */
+func (d *SagaDefinition) Step(key string, action BranchFunc, compensation BranchFunc) *SagaDefinition {
+	d.Steps = append(d.Steps, &SagaStepDefinition{
+		Key:          key,
+		Action:       action,
+		Compensation: compensation,
+	})
+	return d
+}
+
+/**
+ * 追加一个向前恢复的步骤，失败后不回滚saga，由worker或者Resume重试直到成功
+ * 所有步骤完成后这类步骤还在重试时全局事务被关闭，重试成功后server自动提交
+ */
+func (d *SagaDefinition) ForwardStep(key string, action BranchFunc, compensation BranchFunc) *SagaDefinition {
+	d.Steps = append(d.Steps, &SagaStepDefinition{
+		Key:          key,
+		Action:       action,
+		Compensation: compensation,
+		RecoveryMode: pb.RecoveryMode_FORWARD,
+	})
+	return d
+}
+
+/**
+ * 追加一个并行步骤，和前后相邻的同一group的步骤并发执行，全部成功后才执行之后的步骤
+ */
+func (d *SagaDefinition) ParallelStep(group string, key string, action BranchFunc,
+	compensation BranchFunc) *SagaDefinition {
+	d.Steps = append(d.Steps, &SagaStepDefinition{
+		Key:          key,
+		Action:       action,
+		Compensation: compensation,
+		Group:        group,
+	})
+	return d
+}
+
+/**
+ * 按执行顺序把步骤分成执行单元，返回各单元的步骤索引. 相邻的同一组的步骤是一个单元，其他步骤各自是一个单元
+ */
+func (d *SagaDefinition) stepUnits() (units [][]int) {
+	for i, step := range d.Steps {
+		last := len(units) - 1
+		if len(step.Group) > 0 && last >= 0 && d.Steps[units[last][0]].Group == step.Group {
+			units[last] = append(units[last], i)
+			continue
+		}
+		units = append(units, []int{i})
+	}
+	return
+}
+
+func (d *SagaDefinition) stepServiceKey(index int) string {
+	key := d.Steps[index].Key
+	if len(key) < 1 {
+		key = strconv.Itoa(index)
+	}
+	return orchestratorExtraPrefix + d.Name + ":" + key
+}
+
+/**
+ * 执行编排式saga. 每个步骤作为一个分支事务执行，步骤完成时提交COMMITTED和saga data，
+ * 所以saga server中的分支状态就是执行进度，进程崩溃后可以通过Resume继续执行或者补偿
+ */
+type SagaOrchestrator struct {
+	// 使用独立的resolver，步骤的方法不会被其他worker执行
+	sagaContext *SagaContext
+	compensator *CompensationWorker
+
+	mu          sync.Mutex
+	definitions map[string]*SagaDefinition
+	running     map[string]bool // 本进程中正在执行的xid
+}
+
+func NewSagaOrchestrator(collaborator *SagaCollaborator, converter SagaDataConverter) *SagaOrchestrator {
+	sagaContext := NewSagaContext(collaborator, NewSagaResolver(), converter)
+	return &SagaOrchestrator{
+		sagaContext: sagaContext,
+		compensator: NewCompensationWorker(sagaContext, 0),
+		definitions: make(map[string]*SagaDefinition),
+		running:     make(map[string]bool),
+	}
+}
+
+func (o *SagaOrchestrator) Register(definition *SagaDefinition) (err error) {
+	if len(definition.Name) < 1 || len(definition.Steps) < 1 {
+		err = errors.New("saga definition needs a name and at least one step")
+		return
+	}
+	groups := make(map[string]bool)
+	for _, unit := range definition.stepUnits() {
+		group := definition.Steps[unit[0]].Group
+		if len(group) < 1 {
+			continue
+		}
+		if groups[group] {
+			err = fmt.Errorf("steps of group %s in saga %s are not adjacent", group, definition.Name)
+			return
+		}
+		groups[group] = true
+	}
+	steps := make([]*Step, 0, len(definition.Steps))
+	serviceKeys := make(map[string]bool)
+	for i, stepDefinition := range definition.Steps {
+		if stepDefinition.Action == nil {
+			err = fmt.Errorf("step %d of saga %s has no action", i, definition.Name)
+			return
+		}
+		serviceKey := definition.stepServiceKey(i)
+		if serviceKeys[serviceKey] {
+			err = fmt.Errorf("duplicate step key %s in saga %s", serviceKey, definition.Name)
+			return
+		}
+		serviceKeys[serviceKey] = true
+		step := &Step{
+			ServiceKey:   serviceKey,
+			Action:       stepDefinition.Action,
+			RetryPolicy:  stepDefinition.RetryPolicy,
+			RecoveryMode: stepDefinition.RecoveryMode,
+		}
+		if stepDefinition.Compensation != nil {
+			step.CompensationKey = serviceKey + ":compensation"
+			step.Compensation = stepDefinition.Compensation
+		}
+		steps = append(steps, step)
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if _, ok := o.definitions[definition.Name]; ok {
+		err = fmt.Errorf("saga definition %s already registered", definition.Name)
+		return
+	}
+	o.definitions[definition.Name] = definition
+	for _, step := range steps {
+		o.sagaContext.Resolver.BindStep(step)
+	}
+	return
+}
+
+func (o *SagaOrchestrator) definition(name string) *SagaDefinition {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.definitions[name]
+}
+
+/**
+ * 标记xid在本进程中执行，已经在执行时返回false
+ */
+func (o *SagaOrchestrator) markRunning(xid string) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.running[xid] {
+		return false
+	}
+	o.running[xid] = true
+	return true
+}
+
+func (o *SagaOrchestrator) unmarkRunning(xid string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	delete(o.running, xid)
+}
+
+/**
+ * 创建全局事务并按顺序执行saga的各步骤，全部成功后提交全局事务
+ * 某个步骤失败时回滚并按倒序补偿已经执行的步骤，返回步骤的错误
+ */
+func (o *SagaOrchestrator) Start(ctx context.Context, name string, sagaData interface{},
+	opts ...GlobalTxOption) (xid string, err error) {
+	definition := o.definition(name)
+	if definition == nil {
+		err = fmt.Errorf("saga definition %s not registered", name)
+		return
+	}
+	opts = append(opts, WithExtra(orchestratorExtraPrefix+name))
+	session, err := o.sagaContext.Start(ctx, sagaData, opts...)
+	if err != nil {
+		return
+	}
+	xid = session.Xid()
+	o.markRunning(xid)
+	defer o.unmarkRunning(xid)
+	err = o.execute(ctx, definition, session, sagaData, 0)
+	return
+}
+
+func (o *SagaOrchestrator) execute(ctx context.Context, definition *SagaDefinition,
+	session *SagaSession, sagaData interface{}, fromStep int) (err error) {
+	for _, unit := range definition.stepUnits() {
+		if unit[0] < fromStep {
+			continue
+		}
+		if len(unit) == 1 && len(definition.Steps[unit[0]].Group) < 1 {
+			err = session.Invoke(ctx, definition.stepServiceKey(unit[0]), sagaData)
+		} else {
+			serviceKeys := make([]string, 0, len(unit))
+			for _, i := range unit {
+				serviceKeys = append(serviceKeys, definition.stepServiceKey(i))
+			}
+			err = session.InvokeParallel(ctx, definition.Steps[unit[0]].Group, serviceKeys, sagaData)
+		}
+		if err != nil {
+			return o.rollback(ctx, session, err)
+		}
+	}
+	_, err = session.Commit(ctx)
+	return
+}
+
+/**
+ * 回滚全局事务并补偿，返回cause
+ */
+func (o *SagaOrchestrator) rollback(ctx context.Context, session *SagaSession, cause error) error {
+	if _, err := session.Rollback(ctx); err != nil {
+		return fmt.Errorf("%s, and rollback saga %s error: %s", cause.Error(), session.Xid(), err.Error())
+	}
+	if err := o.compensate(ctx, session.Xid()); err != nil {
+		return fmt.Errorf("%s, and compensate saga %s error: %s", cause.Error(), session.Xid(), err.Error())
+	}
+	return cause
+}
+
+/**
+ * 按倒序补偿全局事务中还没有补偿的步骤，补偿没有全部完成时返回错误，之后可以再次Resume
+ * 和worker一样通过租约领取补偿任务，同一个服务的CompensationWorker不会同时补偿同一个步骤
+ * server每次返回一批现在可以补偿的步骤，补偿完后继续领取，领取不到或者有步骤补偿失败时结束
+ */
+func (o *SagaOrchestrator) compensate(ctx context.Context, xid string) (err error) {
+	collaborator := o.sagaContext.Collaborator
+	for ctx.Err() == nil {
+		count, failed := o.compensator.processClaimedWork(ctx, pb.WorkType_COMPENSATION_WORK, xid)
+		if count < 1 || failed > 0 {
+			break
+		}
+	}
+	detail, err := collaborator.QueryGlobalTx(ctx, xid)
+	if err != nil {
+		return
+	}
+	if detail.State != pb.TxState_COMPENSATION_DONE {
+		err = fmt.Errorf("saga %s compensation not finished, state %s", xid, detail.State.String())
+	}
+	return
+}
+
+/**
+ * 恢复进程崩溃时没有执行完的saga
+ * 执行中的saga从第一个没有记录完成的步骤继续执行，执行结果没有记录的步骤无法确定是否已经执行，回滚并补偿
+ * 回滚中的saga继续补偿. 返回nil表示saga已经提交或者补偿完成
+ */
+func (o *SagaOrchestrator) Resume(ctx context.Context, xid string) (err error) {
+	detail, err := o.sagaContext.Collaborator.QueryGlobalTx(ctx, xid)
+	if err != nil {
+		return
+	}
+	return o.resumeGlobalTx(ctx, detail)
+}
+
+func (o *SagaOrchestrator) resumeGlobalTx(ctx context.Context,
+	detail *pb.QueryGlobalTransactionDetailReply) (err error) {
+	xid := detail.Xid
+	if !strings.HasPrefix(detail.Extra, orchestratorExtraPrefix) {
+		err = fmt.Errorf("global tx %s is not an orchestrated saga", xid)
+		return
+	}
+	name := detail.Extra[len(orchestratorExtraPrefix):]
+	definition := o.definition(name)
+	if definition == nil {
+		err = fmt.Errorf("saga definition %s not registered", name)
+		return
+	}
+	if !o.markRunning(xid) {
+		err = fmt.Errorf("saga %s is running", xid)
+		return
+	}
+	defer o.unmarkRunning(xid)
+
+	session := o.sagaContext.Join(xid)
+	switch detail.State {
+	case pb.TxState_COMMITTED, pb.TxState_COMPENSATION_DONE:
+		return
+	case pb.TxState_COMPENSATION_DOING, pb.TxState_COMPENSATION_ERROR:
+		return o.compensate(ctx, xid)
+	case pb.TxState_PROCESSING:
+	default:
+		err = fmt.Errorf("saga %s can't resume from state %s", xid, detail.State.String())
+		return
+	}
+
+	branchesByServiceKey := make(map[string]*pb.TransactionBranchDetail)
+	for _, branch := range detail.Branches {
+		branchesByServiceKey[branch.BranchServiceKey] = branch
+	}
+	// 执行结果没有记录的步骤，包括并行分支组中还在PROCESSING的分支，都是崩溃时中断的，标记为需要补偿后回滚
+	interrupted := func(cause error) error {
+		for _, branch := range detail.Branches {
+			if branch.State == pb.TxState_PROCESSING {
+				session.submitBranchFailure(ctx, branch.BranchId, generateJobId(), cause)
+			}
+		}
+		return o.rollback(ctx, session, cause)
+	}
+	nextStep := 0
+	for _, unit := range definition.stepUnits() {
+		created := 0
+		for _, i := range unit {
+			branch := branchesByServiceKey[definition.stepServiceKey(i)]
+			if branch == nil {
+				continue
+			}
+			created++
+			// 等待重试的向前恢复步骤不影响之后的步骤
+			if branch.State != pb.TxState_COMMITTED && branch.State != pb.TxState_RETRYING {
+				return interrupted(fmt.Errorf("step %s of saga %s interrupted in state %s",
+					definition.stepServiceKey(i), xid, branch.State.String()))
+			}
+		}
+		if created == 0 {
+			break
+		}
+		if created < len(unit) {
+			// 并行分支组只创建了部分分支
+			return interrupted(fmt.Errorf("step group %s of saga %s interrupted",
+				definition.Steps[unit[0]].Group, xid))
+		}
+		nextStep = unit[len(unit)-1] + 1
+	}
+	if count, _ := o.compensator.processClaimedWork(ctx, pb.WorkType_FORWARD_RETRY_WORK, xid); count > 0 {
+		log.Printf("retried %d forward recovery steps of saga %s\n", count, xid)
+	}
+	sagaData, err := session.SagaData(ctx)
+	if err != nil {
+		return
+	}
+	log.Printf("resume saga %s %s from step %d\n", name, xid, nextStep)
+	return o.execute(ctx, definition, session, sagaData, nextStep)
+}
+
+/**
+ * 恢复所有由本节点发起的没有完成的编排式saga，一般在进程启动时调用. 返回恢复的saga数量
+ * 单个saga恢复失败只记录日志，之后可以再次调用
+ */
+func (o *SagaOrchestrator) ResumeAll(ctx context.Context) (count int, err error) {
+	collaborator := o.sagaContext.Collaborator
+	xids, err := collaborator.ListGlobalTransactionsOfStates(ctx, []pb.TxState{
+		pb.TxState_PROCESSING, pb.TxState_COMPENSATION_DOING, pb.TxState_COMPENSATION_ERROR,
+	}, defaultResumeBatchSize)
+	if err != nil {
+		return
+	}
+	node := collaborator.Node
+	for _, xid := range xids {
+		detail, queryErr := collaborator.QueryGlobalTx(ctx, xid)
+		if queryErr != nil {
+			log.Printf("query global tx %s error %s\n", xid, queryErr.Error())
+			continue
+		}
+		starter := detail.StarterNode
+		if !strings.HasPrefix(detail.Extra, orchestratorExtraPrefix) || node == nil || starter == nil ||
+			starter.Group != node.Group || starter.Service != node.Service || starter.InstanceId != node.InstanceId {
+			continue
+		}
+		if o.definition(detail.Extra[len(orchestratorExtraPrefix):]) == nil {
+			continue
+		}
+		count++
+		if resumeErr := o.resumeGlobalTx(ctx, detail); resumeErr != nil {
+			log.Printf("resume saga %s error %s\n", xid, resumeErr.Error())
+		}
+	}
+	return
+}
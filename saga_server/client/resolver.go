@@ -0,0 +1,81 @@
+package client
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"sync"
+)
+
+/**
+ * 分支事务的业务方法或者补偿方法，sagaData是反序列化后的saga data指针
+ */
+type BranchFunc func(ctx context.Context, sagaData interface{}) error
+
+/**
+ * 一个分支步骤和它的补偿，相当于C#中加了[Compensable]的方法
+ */
+type Step struct {
+	ServiceKey      string // 分支事务的服务标识
+	Action          BranchFunc
+	CompensationKey string // 补偿方法的服务标识，为空表示不需要补偿
+	Compensation    BranchFunc
+	RetryPolicy     *pb.RetryPolicy // 覆盖全局事务的补偿重试策略，可以为空
+	// FORWARD表示失败后不回滚全局事务，由worker按重试策略的间隔重试直到成功
+	RecoveryMode pb.RecoveryMode
+	ConfirmKey   string // TCC模式下confirm方法的服务标识，为空表示不需要confirm
+	Confirm      BranchFunc
+	// 执行中超过这个时间没有心跳时server认为执行方已经崩溃，0表示不发送心跳
+	HeartbeatTimeoutSeconds int32
+}
+
+/**
+ * 根据service key找到分支事务的方法和补偿方法
+ */
+type SagaResolver struct {
+	mu       sync.RWMutex
+	branches map[string]BranchFunc // serviceKey => func
+	steps    map[string]*Step      // step.ServiceKey => step
+}
+
+func NewSagaResolver() *SagaResolver {
+	return &SagaResolver{
+		branches: make(map[string]BranchFunc),
+		steps:    make(map[string]*Step),
+	}
+}
+
+func (r *SagaResolver) BindBranch(serviceKey string, fn BranchFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.branches[serviceKey] = fn
+}
+
+func (r *SagaResolver) ResolveBranch(serviceKey string) BranchFunc {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.branches[serviceKey]
+}
+
+/**
+ * 同时注册分支步骤和它的补偿方法、confirm方法
+ */
+func (r *SagaResolver) BindStep(step *Step) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.steps[step.ServiceKey] = step
+	if step.Action != nil {
+		r.branches[step.ServiceKey] = step.Action
+	}
+	if len(step.CompensationKey) > 0 && step.Compensation != nil {
+		r.branches[step.CompensationKey] = step.Compensation
+	}
+	if len(step.ConfirmKey) > 0 && step.Confirm != nil {
+		r.branches[step.ConfirmKey] = step.Confirm
+	}
+}
+
+func (r *SagaResolver) ResolveStep(serviceKey string) *Step {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.steps[serviceKey]
+}
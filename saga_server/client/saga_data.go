@@ -0,0 +1,121 @@
+package client
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+/**
+ * saga data和保存在saga server中的bytes之间的转换
+ */
+type SagaDataConverter interface {
+	Serialize(sagaData interface{}) ([]byte, error)
+	// 返回新创建的saga data指针，bytes为空时返回nil
+	Deserialize(bytes []byte) (interface{}, error)
+}
+
+/**
+ * 和C#的JsonSagaDataConverter相同的格式 {"dataType": 类型名, "data": saga data}
+ * 反序列化的类型需要先通过RegisterSagaDataType注册
+ */
+type JsonSagaDataConverter struct {
+	mu    sync.RWMutex
+	types map[string]reflect.Type // dataType => saga data的结构体类型
+}
+
+func NewJsonSagaDataConverter() *JsonSagaDataConverter {
+	return &JsonSagaDataConverter{
+		types: make(map[string]reflect.Type),
+	}
+}
+
+type jsonSagaDataWrapper struct {
+	DataType string          `json:"dataType"`
+	Data     json.RawMessage `json:"data"`
+}
+
+func sagaDataStructType(sagaData interface{}) reflect.Type {
+	t := reflect.TypeOf(sagaData)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+/**
+ * saga data的类型名，用包路径加类型名
+ */
+func SagaDataTypeName(sagaData interface{}) string {
+	t := sagaDataStructType(sagaData)
+	return t.PkgPath() + "." + t.Name()
+}
+
+/**
+ * 注册saga data类型，sample是该类型的值或者指针
+ */
+func (c *JsonSagaDataConverter) RegisterSagaDataType(sample interface{}) {
+	c.RegisterSagaDataTypeWithName(SagaDataTypeName(sample), sample)
+}
+
+/**
+ * 用指定的类型名注册saga data类型，用于和其他语言的参与方共享saga data
+ */
+func (c *JsonSagaDataConverter) RegisterSagaDataTypeWithName(dataType string, sample interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.types[dataType] = sagaDataStructType(sample)
+}
+
+func (c *JsonSagaDataConverter) typeName(sagaData interface{}) string {
+	t := sagaDataStructType(sagaData)
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for name, registered := range c.types {
+		if registered == t {
+			return name
+		}
+	}
+	return SagaDataTypeName(sagaData)
+}
+
+func (c *JsonSagaDataConverter) Serialize(sagaData interface{}) (bytes []byte, err error) {
+	if sagaData == nil {
+		return
+	}
+	data, err := json.Marshal(sagaData)
+	if err != nil {
+		return
+	}
+	bytes, err = json.Marshal(&jsonSagaDataWrapper{
+		DataType: c.typeName(sagaData),
+		Data:     data,
+	})
+	return
+}
+
+func (c *JsonSagaDataConverter) Deserialize(bytes []byte) (sagaData interface{}, err error) {
+	if len(bytes) < 1 {
+		return
+	}
+	wrapper := &jsonSagaDataWrapper{}
+	err = json.Unmarshal(bytes, wrapper)
+	if err != nil {
+		return
+	}
+	c.mu.RLock()
+	t, ok := c.types[wrapper.DataType]
+	c.mu.RUnlock()
+	if !ok {
+		err = fmt.Errorf("saga data type %s not registered", wrapper.DataType)
+		return
+	}
+	value := reflect.New(t)
+	err = json.Unmarshal(wrapper.Data, value.Interface())
+	if err != nil {
+		return
+	}
+	sagaData = value.Interface()
+	return
+}
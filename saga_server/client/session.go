@@ -0,0 +1,246 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"log"
+	"sync"
+)
+
+/**
+ * 参与方发起saga全局事务的入口，组合了和server的交互、分支方法的查找和saga data的序列化
+ */
+type SagaContext struct {
+	Collaborator *SagaCollaborator
+	Resolver     *SagaResolver
+	Converter    SagaDataConverter
+}
+
+func NewSagaContext(collaborator *SagaCollaborator, resolver *SagaResolver,
+	converter SagaDataConverter) *SagaContext {
+	return &SagaContext{
+		Collaborator: collaborator,
+		Resolver:     resolver,
+		Converter:    converter,
+	}
+}
+
+/**
+ * 创建全局事务并用sagaData初始化saga data
+ */
+func (c *SagaContext) Start(ctx context.Context, sagaData interface{},
+	opts ...GlobalTxOption) (session *SagaSession, err error) {
+	xid, err := c.Collaborator.CreateGlobalTx(ctx, opts...)
+	if err != nil {
+		return
+	}
+	data, err := c.Converter.Serialize(sagaData)
+	if err != nil {
+		return
+	}
+	err = c.Collaborator.InitSagaData(ctx, xid, data)
+	if err != nil {
+		return
+	}
+	session = c.Join(xid)
+	return
+}
+
+/**
+ * 加入已经存在的全局事务，比如由上游服务创建的全局事务
+ */
+func (c *SagaContext) Join(xid string) *SagaSession {
+	return &SagaSession{
+		sagaContext: c,
+		xid:         xid,
+	}
+}
+
+/**
+ * 一个全局事务中的执行过程
+ */
+type SagaSession struct {
+	sagaContext *SagaContext
+	xid         string
+}
+
+func (s *SagaSession) Xid() string {
+	return s.xid
+}
+
+/**
+ * 把当前全局事务绑定到ctx中，之后用这个ctx执行的分支方法可以通过SessionFromContext拿到它
+ */
+func (s *SagaSession) Bind(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, sessionContextKey, s)
+	return ContextWithXid(ctx, s.xid)
+}
+
+/**
+ * 作为一个分支事务执行serviceKey对应的步骤
+ * 如果ctx中有同一个全局事务的执行中分支，新分支作为它的下级分支
+ * 执行成功时提交COMMITTED和修改后的saga data，失败时提交COMPENSATION_DOING并返回执行的错误
+ */
+func (s *SagaSession) Invoke(ctx context.Context, serviceKey string, sagaData interface{}) (err error) {
+	step := s.sagaContext.Resolver.ResolveStep(serviceKey)
+	if step == nil || step.Action == nil {
+		err = fmt.Errorf("saga step %s not registered", serviceKey)
+		return
+	}
+	branchTxId, err := s.sagaContext.Collaborator.CreateBranchTx(ctx, s.branchTxRequest(ctx, step))
+	if err != nil {
+		return
+	}
+	jobId := generateJobId()
+	actionErr := s.runStep(ctx, step, branchTxId, sagaData)
+	return s.submitBranchResult(ctx, branchTxId, jobId, actionErr, sagaData)
+}
+
+/**
+ * 作为一个并行分支组并发执行多个已经注册的步骤，相当于C#中并行调用多个参与方
+ * 先创建组内所有分支，再并发执行各步骤，全部结束后按顺序提交各分支的结果，返回第一个步骤的错误
+ * 各步骤共享同一个sagaData，并发修改sagaData时需要步骤自己同步
+ */
+func (s *SagaSession) InvokeParallel(ctx context.Context, group string, serviceKeys []string,
+	sagaData interface{}) (err error) {
+	if len(group) < 1 || len(serviceKeys) < 1 {
+		err = errors.New("parallel invoke needs a group name and at least one step")
+		return
+	}
+	steps := make([]*Step, 0, len(serviceKeys))
+	for _, serviceKey := range serviceKeys {
+		step := s.sagaContext.Resolver.ResolveStep(serviceKey)
+		if step == nil || step.Action == nil {
+			err = fmt.Errorf("saga step %s not registered", serviceKey)
+			return
+		}
+		steps = append(steps, step)
+	}
+	collaborator := s.sagaContext.Collaborator
+	branchTxIds := make([]string, 0, len(steps))
+	for _, step := range steps {
+		req := s.branchTxRequest(ctx, step)
+		req.BranchGroup = group
+		req.BranchGroupSize = int32(len(steps))
+		var branchTxId string
+		branchTxId, err = collaborator.CreateBranchTx(ctx, req)
+		if err != nil {
+			// 已经创建的分支还没有执行，提交失败后由补偿处理
+			for _, created := range branchTxIds {
+				s.submitBranchFailure(ctx, created, generateJobId(), err)
+			}
+			return
+		}
+		branchTxIds = append(branchTxIds, branchTxId)
+	}
+	actionErrs := make([]error, len(steps))
+	var wg sync.WaitGroup
+	for i, step := range steps {
+		wg.Add(1)
+		go func(i int, step *Step) {
+			defer wg.Done()
+			actionErrs[i] = s.runStep(ctx, step, branchTxIds[i], sagaData)
+		}(i, step)
+	}
+	wg.Wait()
+	for i, branchTxId := range branchTxIds {
+		submitErr := s.submitBranchResult(ctx, branchTxId, generateJobId(), actionErrs[i], sagaData)
+		if submitErr != nil && err == nil {
+			err = submitErr
+		}
+	}
+	return
+}
+
+/**
+ * 在分支branchTxId中执行步骤，步骤配置了心跳超时时执行期间定时发送分支心跳
+ */
+func (s *SagaSession) runStep(ctx context.Context, step *Step, branchTxId string, sagaData interface{}) error {
+	stopHeartbeat := startBranchHeartbeat(ctx, s.sagaContext.Collaborator, s.xid, branchTxId,
+		step.HeartbeatTimeoutSeconds)
+	defer stopHeartbeat()
+	return step.Action(ContextWithBranchId(s.Bind(ctx), branchTxId), sagaData)
+}
+
+func (s *SagaSession) branchTxRequest(ctx context.Context, step *Step) *pb.CreateBranchTransactionRequest {
+	req := &pb.CreateBranchTransactionRequest{
+		Xid:                          s.xid,
+		BranchServiceKey:             step.ServiceKey,
+		BranchCompensationServiceKey: step.CompensationKey,
+		RetryPolicy:                  step.RetryPolicy,
+		RecoveryMode:                 step.RecoveryMode,
+		BranchConfirmServiceKey:      step.ConfirmKey,
+		HeartbeatTimeoutSeconds:      step.HeartbeatTimeoutSeconds,
+	}
+	if XidFromContext(ctx) == s.xid {
+		req.ParentBranchId = BranchIdFromContext(ctx)
+	}
+	return req
+}
+
+/**
+ * 提交分支的执行结果，成功时提交COMMITTED和sagaData，失败时提交COMPENSATION_DOING并返回actionErr
+ * 向前恢复的分支失败时server改成RETRYING，之后由worker重试，这时不返回错误，saga继续执行
+ */
+func (s *SagaSession) submitBranchResult(ctx context.Context, branchTxId string, jobId string,
+	actionErr error, sagaData interface{}) (err error) {
+	if actionErr != nil {
+		if s.submitBranchFailure(ctx, branchTxId, jobId, actionErr) == pb.TxState_RETRYING {
+			log.Printf("branch %s failed and will be retried: %s\n", branchTxId, actionErr.Error())
+			return
+		}
+		err = actionErr
+		return
+	}
+	data, err := s.sagaContext.Converter.Serialize(sagaData)
+	if err != nil {
+		return
+	}
+	_, err = s.sagaContext.Collaborator.SubmitBranchTxStateOptimism(ctx, s.xid, branchTxId,
+		pb.TxState_COMMITTED, jobId, "", data)
+	return
+}
+
+func (s *SagaSession) submitBranchFailure(ctx context.Context, branchTxId string, jobId string,
+	cause error) (state pb.TxState) {
+	state, submitErr := s.sagaContext.Collaborator.SubmitBranchTxStateOptimism(ctx, s.xid, branchTxId,
+		pb.TxState_COMPENSATION_DOING, jobId, cause.Error(), nil)
+	if submitErr != nil {
+		log.Printf("submit branch %s COMPENSATION_DOING error %s\n", branchTxId, submitErr.Error())
+	}
+	return
+}
+
+func (s *SagaSession) Commit(ctx context.Context) (state pb.TxState, err error) {
+	state, err = s.sagaContext.Collaborator.SubmitGlobalTxStateOptimism(ctx, s.xid, pb.TxState_COMMITTED)
+	if IsForwardRecoveryPendingError(err) {
+		// 还有向前恢复的分支在重试，关闭全局事务，重试成功后由server自动提交
+		return s.Close(ctx)
+	}
+	return
+}
+
+func (s *SagaSession) Rollback(ctx context.Context) (state pb.TxState, err error) {
+	return s.sagaContext.Collaborator.SubmitGlobalTxStateOptimism(ctx, s.xid, pb.TxState_COMPENSATION_DOING)
+}
+
+/**
+ * 不再创建新分支，所有分支committed后由server自动提交全局事务
+ */
+func (s *SagaSession) Close(ctx context.Context) (state pb.TxState, err error) {
+	return s.sagaContext.Collaborator.CloseGlobalTx(ctx, s.xid)
+}
+
+/**
+ * 读取saga server中最新的saga data
+ */
+func (s *SagaSession) SagaData(ctx context.Context) (sagaData interface{}, err error) {
+	reply, err := s.sagaContext.Collaborator.GetSagaData(ctx, s.xid)
+	if err != nil {
+		return
+	}
+	sagaData, err = s.sagaContext.Converter.Deserialize(reply.Data)
+	return
+}
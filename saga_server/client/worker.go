@@ -0,0 +1,544 @@
+package client
+
+import (
+	"context"
+	"errors"
+	pb "github.com/zoowii/saga_server/api"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// 任务通过推送的通知领取，定时的一轮处理只是兜底，间隔比较长
+	defaultWorkerInterval = 30 * time.Second
+	// 每轮从saga server获取的未完成全局事务和每次领取的任务的最大数量
+	defaultWorkerBatchSize = 100
+	// 领取补偿任务的租约时长
+	defaultWorkerLeaseSeconds = 30
+	// 订阅断开后重新订阅的间隔
+	defaultWorkerResubscribeDelay = time.Second
+)
+
+// worker领取的任务类型，按这个顺序领取
+var workerWorkTypes = []pb.WorkType{
+	pb.WorkType_FORWARD_RETRY_WORK, pb.WorkType_CONFIRM_WORK, pb.WorkType_COMPENSATION_WORK,
+}
+
+/**
+ * 和C#的CollaboratorSagaWorker相同，从saga server获取未完成的xids，超时的全局事务提交为补偿中
+ * 待补偿、等待重试的向前恢复分支和等待confirm的TCC分支通过租约领取，通过BranchServiceKey(confirm是BranchConfirmServiceKey)找到自己负责的分支并执行补偿、重新执行或者confirm，多个worker实例不会同时执行同一个分支
+ * Run时订阅saga server推送的任务通知，收到通知马上领取，定时的一轮处理只是兜底
+ */
+type CompensationWorker struct {
+	sagaContext  *SagaContext
+	interval     time.Duration
+	batchSize    int32
+	leaseSeconds int32
+
+	mu     sync.Mutex
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewCompensationWorker(sagaContext *SagaContext, interval time.Duration) *CompensationWorker {
+	if interval <= 0 {
+		interval = defaultWorkerInterval
+	}
+	return &CompensationWorker{
+		sagaContext:  sagaContext,
+		interval:     interval,
+		batchSize:    defaultWorkerBatchSize,
+		leaseSeconds: defaultWorkerLeaseSeconds,
+	}
+}
+
+/**
+ * 在后台goroutine中运行worker，直到调用Stop或者ctx结束
+ */
+func (w *CompensationWorker) Start(ctx context.Context) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.cancel != nil {
+		return errors.New("compensation worker already started")
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	w.cancel = cancel
+	w.done = done
+	go func() {
+		defer close(done)
+		w.Run(runCtx)
+	}()
+	return nil
+}
+
+/**
+ * 停止后台运行的worker并等待正在进行的一轮处理结束
+ */
+func (w *CompensationWorker) Stop() {
+	w.mu.Lock()
+	cancel, done := w.cancel, w.done
+	w.cancel = nil
+	w.done = nil
+	w.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+/**
+ * 阻塞运行直到ctx结束
+ * 订阅saga server推送的任务通知，收到后马上领取那个全局事务中的任务，包括其他参与方的分支补偿完成后轮到自己的补偿、重试等待结束和租约到期的任务
+ * 订阅成功和cursor过期重新订阅时处理一轮，之后定时的一轮处理只是订阅断开期间的兜底
+ */
+func (w *CompensationWorker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	pending := newPendingWork()
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		w.subscribeWork(ctx, pending)
+	}()
+	defer wg.Wait()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			w.runRound(ctx)
+		case <-pending.ready:
+			works, fullRound := pending.take()
+			if fullRound {
+				w.runRound(ctx)
+				continue
+			}
+			count := 0
+			for _, item := range works {
+				processed, _ := w.processClaimedWork(ctx, item.work, item.xid)
+				count += processed
+			}
+			if count > 0 {
+				log.Printf("compensation worker processed %d pushed branches\n", count)
+			}
+		}
+	}
+}
+
+func (w *CompensationWorker) runRound(ctx context.Context) {
+	count, err := w.DoWork(ctx)
+	if err != nil {
+		log.Printf("compensation worker error %s\n", err.Error())
+	} else if count > 0 {
+		log.Printf("compensation worker processed %d branches\n", count)
+	}
+}
+
+type claimableWork struct {
+	work pb.WorkType
+	xid  string
+}
+
+/**
+ * 推送过来还没有领取的任务，订阅的goroutine添加，Run取出后领取，还没取出的相同任务合并成一个
+ */
+type pendingWork struct {
+	mu        sync.Mutex
+	works     []claimableWork
+	added     map[claimableWork]bool
+	fullRound bool
+	ready     chan struct{}
+}
+
+func newPendingWork() *pendingWork {
+	return &pendingWork{
+		added: make(map[claimableWork]bool),
+		ready: make(chan struct{}, 1),
+	}
+}
+
+func (p *pendingWork) notify() {
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+func (p *pendingWork) add(work pb.WorkType, xid string) {
+	p.mu.Lock()
+	item := claimableWork{work: work, xid: xid}
+	if !p.added[item] {
+		p.added[item] = true
+		p.works = append(p.works, item)
+	}
+	p.mu.Unlock()
+	p.notify()
+}
+
+/**
+ * 可能丢失了通知，需要处理一轮所有的任务
+ */
+func (p *pendingWork) requestFullRound() {
+	p.mu.Lock()
+	p.fullRound = true
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *pendingWork) take() (works []claimableWork, fullRound bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	works, fullRound = p.works, p.fullRound
+	p.works = nil
+	p.added = make(map[claimableWork]bool)
+	p.fullRound = false
+	return
+}
+
+/**
+ * 订阅自己可以领取的各类任务的通知并加入pending，直到ctx结束
+ * 断开后用最后的cursor重新订阅，不带cursor订阅成功时(第一次订阅或者cursor过期)处理一轮代替订阅之前的通知
+ */
+func (w *CompensationWorker) subscribeWork(ctx context.Context, pending *pendingWork) {
+	collaborator := w.sagaContext.Collaborator
+	cursor := ""
+	for {
+		fresh := len(cursor) < 1
+		var err error
+		cursor, err = collaborator.SubscribeWork(ctx, workerWorkTypes, cursor,
+			func() {
+				if fresh {
+					pending.requestFullRound()
+				}
+			},
+			func(event *pb.TxStateEvent, cursor string) error {
+				pending.add(event.Work, event.Xid)
+				return nil
+			})
+		if ctx.Err() != nil {
+			return
+		}
+		if IsEventCursorExpiredError(err) {
+			cursor = ""
+			continue
+		}
+		log.Printf("subscribe claimable work error %v, retry later\n", err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(defaultWorkerResubscribeDelay):
+		}
+	}
+}
+
+func isExpiredGlobalTx(globalTx *pb.QueryGlobalTransactionDetailReply, now time.Time) bool {
+	expireAt := time.Unix(globalTx.CreatedAt, 0).Add(time.Duration(globalTx.ExpireSeconds) * time.Second)
+	return now.After(expireAt)
+}
+
+func isBranchWaitingCompensation(branch *pb.TransactionBranchDetail) bool {
+	return branch.State == pb.TxState_COMPENSATION_DOING || branch.State == pb.TxState_COMPENSATION_ERROR
+}
+
+func hasRetryingBranch(globalTx *pb.QueryGlobalTransactionDetailReply) bool {
+	for _, branch := range globalTx.Branches {
+		if branch.State == pb.TxState_RETRYING {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 处理一轮未完成的全局事务，返回本轮执行的补偿、重试和confirm次数
+ * 单个全局事务处理出错只记录日志，不影响其他全局事务
+ */
+func (w *CompensationWorker) DoWork(ctx context.Context) (count int, err error) {
+	collaborator := w.sagaContext.Collaborator
+	xids, err := collaborator.ListGlobalTransactionsOfStates(ctx, []pb.TxState{
+		pb.TxState_PROCESSING,
+	}, w.batchSize)
+	if err != nil {
+		return
+	}
+	for _, xid := range xids {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+			return
+		}
+		globalTx, queryErr := collaborator.QueryGlobalTx(ctx, xid)
+		if queryErr != nil {
+			log.Printf("query global tx %s error %s\n", xid, queryErr.Error())
+			continue
+		}
+		// 正常处理中的全局事务超时的要进入补偿中状态，向前恢复的分支通过租约领取后重试
+		// 有向前恢复的分支在重试的全局事务超时也不回滚
+		if globalTx.State != pb.TxState_PROCESSING || !isExpiredGlobalTx(globalTx, time.Now()) ||
+			hasRetryingBranch(globalTx) {
+			continue
+		}
+		_, submitErr := collaborator.SubmitGlobalTxState(ctx, xid, globalTx.State,
+			pb.TxState_COMPENSATION_DOING, globalTx.Version)
+		if submitErr != nil {
+			log.Printf("submit expired global tx %s COMPENSATION_DOING error %s\n", xid, submitErr.Error())
+		}
+	}
+	for _, work := range workerWorkTypes {
+		processed, _ := w.processClaimedWork(ctx, work, "")
+		count += processed
+	}
+	return
+}
+
+/**
+ * 从saga server领取work类型任务的租约并执行，补偿任务server按补偿顺序返回现在可以补偿的分支，xid不为空时只领取这个全局事务的分支
+ * 处理完后释放所有租约，不是自己能执行的分支释放后可以被其他worker领取
+ * 执行时间超过租约的一半时给剩下的分支续约，续约失败(已经被其他worker领取)的分支不再执行
+ * 返回执行任务的分支数量和其中执行失败的数量
+ */
+func (w *CompensationWorker) processClaimedWork(ctx context.Context, work pb.WorkType,
+	xid string) (count int, failed int) {
+	collaborator := w.sagaContext.Collaborator
+	items, err := collaborator.ClaimWork(ctx, work, xid, w.leaseSeconds, w.batchSize)
+	if err != nil {
+		log.Printf("claim %s error %s\n", work.String(), err.Error())
+		return
+	}
+	if len(items) < 1 {
+		return
+	}
+	leases := make([]*pb.CompensationWorkLease, 0, len(items))
+	held := make(map[string]bool)
+	for _, item := range items {
+		leases = append(leases, item.Lease)
+		held[item.Lease.BranchId] = true
+	}
+	defer func() {
+		if _, releaseErr := collaborator.ReleaseCompensationWork(ctx, leases); releaseErr != nil {
+			log.Printf("release compensation work error %s\n", releaseErr.Error())
+		}
+	}()
+	leaseDuration := time.Duration(w.leaseSeconds) * time.Second
+	renewAt := time.Now().Add(leaseDuration / 2)
+	for i, item := range items {
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Now().After(renewAt) {
+			renewed, renewErr := collaborator.RenewCompensationWork(ctx, leases[i:], w.leaseSeconds)
+			if renewErr != nil {
+				log.Printf("renew compensation work error %s\n", renewErr.Error())
+				return
+			}
+			held = make(map[string]bool)
+			for _, lease := range renewed {
+				held[lease.BranchId] = true
+			}
+			renewAt = time.Now().Add(leaseDuration / 2)
+		}
+		if !held[item.Lease.BranchId] {
+			continue
+		}
+		handled, success := w.runClaimedWork(ctx, work, item)
+		if !handled {
+			// 不是自己负责的分支
+			continue
+		}
+		count++
+		if !success {
+			failed++
+		}
+	}
+	return
+}
+
+/**
+ * 执行一个领取到的任务，handled表示分支是否是自己负责的
+ */
+func (w *CompensationWorker) runClaimedWork(ctx context.Context, work pb.WorkType,
+	item *pb.CompensationWorkItem) (handled bool, success bool) {
+	resolver := w.sagaContext.Resolver
+	branch := item.Branch
+	serviceKey := branch.BranchServiceKey
+	if work == pb.WorkType_CONFIRM_WORK {
+		serviceKey = branch.BranchConfirmServiceKey
+	}
+	action := resolver.ResolveBranch(serviceKey)
+	if action == nil {
+		return
+	}
+	handled = true
+	switch work {
+	case pb.WorkType_FORWARD_RETRY_WORK:
+		success = w.retryForwardBranch(ctx, item.Lease, branch, action)
+	case pb.WorkType_CONFIRM_WORK:
+		success = w.confirmBranch(ctx, item.Lease, branch, action)
+	default:
+		success = w.compensateBranch(ctx, item.Lease, branch)
+	}
+	return
+}
+
+/**
+ * 执行一个分支的补偿方法并带着租约上报COMPENSATION_DONE或者COMPENSATION_ERROR，每次补偿使用新的jobId
+ */
+func (w *CompensationWorker) compensateBranch(ctx context.Context, lease *pb.CompensationWorkLease,
+	branch *pb.TransactionBranchDetail) (success bool) {
+	collaborator := w.sagaContext.Collaborator
+	jobId := generateJobId()
+	compensationKey := branch.BranchCompensationServiceKey
+	if len(compensationKey) < 1 {
+		// 补偿方法为空，直接标记为已经补偿
+		_, err := collaborator.SubmitLeasedBranchTxState(ctx, lease, branch.State,
+			pb.TxState_COMPENSATION_DONE, branch.Version, jobId, "", nil)
+		if err != nil {
+			log.Printf("submit branch %s COMPENSATION_DONE error %s\n", branch.BranchId, err.Error())
+			return
+		}
+		success = true
+		return
+	}
+	compensationErr := w.runCompensation(ctx, lease, branch, jobId)
+	if compensationErr == nil {
+		success = true
+		return
+	}
+	log.Printf("branch %s compensation %s error %s\n", branch.BranchId, compensationKey, compensationErr.Error())
+	// 补偿失败时分支可能已经被其他请求修改，用最新的版本上报
+	latest, err := collaborator.QueryBranchTx(ctx, branch.BranchId)
+	if err != nil {
+		log.Printf("query branch %s error %s\n", branch.BranchId, err.Error())
+		return
+	}
+	if !isBranchWaitingCompensation(latest.Detail) {
+		return
+	}
+	_, err = collaborator.SubmitLeasedBranchTxState(ctx, lease, latest.Detail.State,
+		pb.TxState_COMPENSATION_ERROR, latest.Detail.Version, jobId, compensationErr.Error(), nil)
+	if err != nil {
+		log.Printf("submit branch %s COMPENSATION_ERROR error %s\n", branch.BranchId, err.Error())
+	}
+	return
+}
+
+func (w *CompensationWorker) runCompensation(ctx context.Context, lease *pb.CompensationWorkLease,
+	branch *pb.TransactionBranchDetail, jobId string) (err error) {
+	xid := lease.Xid
+	compensation := w.sagaContext.Resolver.ResolveBranch(branch.BranchCompensationServiceKey)
+	if compensation == nil {
+		err = errors.New("compensation " + branch.BranchCompensationServiceKey + " not registered")
+		return
+	}
+	collaborator := w.sagaContext.Collaborator
+	converter := w.sagaContext.Converter
+	sagaDataReply, err := collaborator.GetSagaData(ctx, xid)
+	if err != nil {
+		return
+	}
+	sagaData, err := converter.Deserialize(sagaDataReply.Data)
+	if err != nil {
+		return
+	}
+	compensationCtx := ContextWithBranchId(ContextWithXid(ctx, xid), branch.BranchId)
+	err = compensation(compensationCtx, sagaData)
+	if err != nil {
+		return
+	}
+	changedSagaData, err := converter.Serialize(sagaData)
+	if err != nil {
+		return
+	}
+	_, err = collaborator.SubmitLeasedBranchTxState(ctx, lease, branch.State,
+		pb.TxState_COMPENSATION_DONE, branch.Version, jobId, "", changedSagaData)
+	return
+}
+
+/**
+ * 重新执行领取到的向前恢复分支，成功时提交COMMITTED和修改后的saga data，失败时提交RETRYING等待下次重试
+ */
+func (w *CompensationWorker) retryForwardBranch(ctx context.Context, lease *pb.CompensationWorkLease,
+	branch *pb.TransactionBranchDetail, action BranchFunc) (success bool) {
+	collaborator := w.sagaContext.Collaborator
+	converter := w.sagaContext.Converter
+	xid := lease.Xid
+	jobId := generateJobId()
+	state := pb.TxState_COMMITTED
+	var data []byte
+	actionErr := func() (err error) {
+		sagaDataReply, err := collaborator.GetSagaData(ctx, xid)
+		if err != nil {
+			return
+		}
+		sagaData, err := converter.Deserialize(sagaDataReply.Data)
+		if err != nil {
+			return
+		}
+		err = action(ContextWithBranchId(ContextWithXid(ctx, xid), branch.BranchId), sagaData)
+		if err != nil {
+			return
+		}
+		data, err = converter.Serialize(sagaData)
+		return
+	}()
+	errorReason := ""
+	if actionErr != nil {
+		log.Printf("retry forward branch %s error %s\n", branch.BranchId, actionErr.Error())
+		state = pb.TxState_RETRYING
+		errorReason = actionErr.Error()
+	}
+	_, err := collaborator.SubmitLeasedBranchTxState(ctx, lease, branch.State,
+		state, branch.Version, jobId, errorReason, data)
+	if err != nil {
+		log.Printf("submit branch %s %s error %s\n", branch.BranchId, state.String(), err.Error())
+		return
+	}
+	success = actionErr == nil
+	return
+}
+
+/**
+ * 执行领取到的分支的confirm，成功时提交CONFIRMED和修改后的saga data，失败时提交CONFIRMING等待下次重试
+ */
+func (w *CompensationWorker) confirmBranch(ctx context.Context, lease *pb.CompensationWorkLease,
+	branch *pb.TransactionBranchDetail, confirm BranchFunc) (success bool) {
+	collaborator := w.sagaContext.Collaborator
+	converter := w.sagaContext.Converter
+	xid := lease.Xid
+	jobId := generateJobId()
+	state := pb.TxState_CONFIRMED
+	var data []byte
+	confirmErr := func() (err error) {
+		sagaDataReply, err := collaborator.GetSagaData(ctx, xid)
+		if err != nil {
+			return
+		}
+		sagaData, err := converter.Deserialize(sagaDataReply.Data)
+		if err != nil {
+			return
+		}
+		err = confirm(ContextWithBranchId(ContextWithXid(ctx, xid), branch.BranchId), sagaData)
+		if err != nil {
+			return
+		}
+		data, err = converter.Serialize(sagaData)
+		return
+	}()
+	errorReason := ""
+	if confirmErr != nil {
+		log.Printf("confirm branch %s error %s\n", branch.BranchId, confirmErr.Error())
+		state = pb.TxState_CONFIRMING
+		errorReason = confirmErr.Error()
+	}
+	_, err := collaborator.SubmitLeasedBranchTxState(ctx, lease, branch.State,
+		state, branch.Version, jobId, errorReason, data)
+	if err != nil {
+		log.Printf("submit branch %s %s error %s\n", branch.BranchId, state.String(), err.Error())
+		return
+	}
+	success = confirmErr == nil
+	return
+}
@@ -6,29 +6,84 @@ import (
 	"fmt"
 	"github.com/zoowii/saga_server/api"
 	"strings"
+	"time"
 )
 
-func CreateGlobalTx(ctx context.Context, db *sql.DB, record *GlobalTxEntity) (xid string, err error) {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return
+/**
+ * *sql.DB和*sql.Tx共有的执行sql的方法
+ */
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+/**
+ * 不同数据库之间sql语法的差异部分
+ */
+type sqlDialect struct {
+	name string
+	// 插入saga_data，如果xid已存在则覆盖data并增加版本号. 参数是(xid, data, version)
+	insertSagaDataSql string
+	// 把mysql风格的sql(?占位符和`引号)转换成本数据库的sql，为nil则不转换
+	rebind func(query string) string
+	// 不支持LastInsertId的数据库通过insert ... returning id获取新记录id
+	insertReturningId bool
+	// 查询表(参数1)中是否有列(参数2)，返回匹配的行数. 为空时迁移语句自己处理列已存在的情况
+	columnExistsSql string
+	// 查询表(参数1)中是否有索引(参数2)，返回匹配的行数. 为空时迁移语句自己处理索引已存在的情况
+	indexExistsSql string
+}
+
+var mysqlDialect = &sqlDialect{
+	name: "mysql",
+	insertSagaDataSql: "insert into saga_data " +
+		" (xid, `data`, `version`) values (?,?,?) " +
+		" on duplicate key update `data`=values(`data`), `version` = `version` + 1",
+	columnExistsSql: "select count(*) from information_schema.columns" +
+		" where table_schema = database() and table_name = ? and column_name = ?",
+	indexExistsSql: "select count(*) from information_schema.statistics" +
+		" where table_schema = database() and table_name = ? and index_name = ?",
+}
+
+/**
+ * 基于database/sql的StoreOps实现
+ */
+type sqlDaos struct {
+	exec    sqlExecutor
+	dialect *sqlDialect
+}
+
+func (d *sqlDaos) rebind(query string) string {
+	if d.dialect.rebind == nil {
+		return query
 	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
-	stmt, err := tx.Prepare("insert into global_tx (xid, `state`, `end_branches`, `version`, " +
+	return d.dialect.rebind(query)
+}
+
+func (d *sqlDaos) execContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return d.exec.ExecContext(ctx, d.rebind(query), args...)
+}
+
+func (d *sqlDaos) queryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return d.exec.QueryContext(ctx, d.rebind(query), args...)
+}
+
+func (d *sqlDaos) queryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return d.exec.QueryRowContext(ctx, d.rebind(query), args...)
+}
+
+func (d *sqlDaos) CreateGlobalTx(ctx context.Context, record *GlobalTxEntity) (xid string, err error) {
+	retryPolicy := record.RetryPolicy
+	_, err = d.execContext(ctx, "insert into global_tx (xid, `state`, `end_branches`, `version`, " +
 		" creator_group, creator_service," +
-		" creator_instance_id, expire_seconds, extra)" +
-		" values (?, ?, ?, ?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return
-	}
-	_, err = stmt.ExecContext(ctx, record.Xid, record.State, record.EndBranches, record.Version,
-		record.CreatorGroup, record.CreatorService, record.CreatorInstanceId, record.ExpireSeconds, record.Extra)
+		" creator_instance_id, expire_seconds, extra," +
+		" retry_max_attempts, retry_initial_delay_ms, retry_multiplier, retry_max_delay_ms, mode)" +
+		" values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		record.Xid, record.State, record.EndBranches, record.Version,
+		record.CreatorGroup, record.CreatorService, record.CreatorInstanceId, record.ExpireSeconds, record.Extra,
+		retryPolicy.MaxAttempts, retryPolicy.InitialDelayMs, retryPolicy.Multiplier, retryPolicy.MaxDelayMs,
+		record.Mode)
 	if err != nil {
 		return
 	}
@@ -36,29 +91,28 @@ func CreateGlobalTx(ctx context.Context, db *sql.DB, record *GlobalTxEntity) (xi
 	return
 }
 
-func CreateBranchTx(ctx context.Context, db *sql.DB, record *BranchTxEntity) (branchTxId string, err error) {
-	tx, err := db.BeginTx(ctx, nil)
-	if err != nil {
-		return
-	}
-	defer func() {
-		if err != nil {
-			_ = tx.Rollback()
-		} else {
-			err = tx.Commit()
-		}
-	}()
-	stmt, err := tx.PrepareContext(ctx, "insert into branch_tx (branch_tx_id, xid, `state`, `version`, " +
+func (d *sqlDaos) CreateBranchTx(ctx context.Context, record *BranchTxEntity) (branchTxId string, err error) {
+	retryPolicy := record.RetryPolicy
+	lastHeartbeatAt := record.LastHeartbeatAt
+	if lastHeartbeatAt != nil {
+		utc := lastHeartbeatAt.UTC()
+		lastHeartbeatAt = &utc
+	}
+	_, err = d.execContext(ctx, "insert into branch_tx (branch_tx_id, xid, `state`, `version`, " +
 		" compensation_fail_times, node_group, node_service," +
-		" node_instance_id, branch_service_key, branch_compensation_service_key)" +
-		" values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)")
-	if err != nil {
-		return
-	}
-	_, err = stmt.ExecContext(ctx, record.BranchTxId, record.Xid, record.State, record.Version,
+		" node_instance_id, branch_service_key, branch_compensation_service_key," +
+		" retry_max_attempts, retry_initial_delay_ms, retry_multiplier, retry_max_delay_ms, next_retry_at," +
+		" parent_branch_tx_id, branch_group, branch_group_size, recovery_mode, forward_retry_times," +
+		" branch_confirm_service_key, heartbeat_timeout_seconds, last_heartbeat_at)" +
+		" values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		record.BranchTxId, record.Xid, record.State, record.Version,
 		record.CompensationFailTimes,
 		record.NodeGroup, record.NodeService, record.NodeInstanceId,
-		record.BranchServiceKey, record.BranchCompensationServiceKey)
+		record.BranchServiceKey, record.BranchCompensationServiceKey,
+		retryPolicy.MaxAttempts, retryPolicy.InitialDelayMs, retryPolicy.Multiplier, retryPolicy.MaxDelayMs,
+		record.NextRetryAt, record.ParentBranchTxId, record.BranchGroup, record.BranchGroupSize,
+		record.RecoveryMode, record.ForwardRetryTimes, record.BranchConfirmServiceKey,
+		record.HeartbeatTimeoutSeconds, lastHeartbeatAt)
 	if err != nil {
 		return
 	}
@@ -67,21 +121,55 @@ func CreateBranchTx(ctx context.Context, db *sql.DB, record *BranchTxEntity) (br
 }
 
 const (
-	globalTxTableSelectColumnsSql = "id, created_at, updated_at, xid, `state`, `version`, creator_group, creator_service, creator_instance_id, " +
-		" expire_seconds, extra"
+	globalTxTableSelectColumnsSql = "id, created_at, updated_at, xid, `state`, end_branches, `version`, " +
+		" creator_group, creator_service, creator_instance_id, " +
+		" expire_seconds, extra, retry_max_attempts, retry_initial_delay_ms, retry_multiplier, retry_max_delay_ms, mode"
 	branchTxTableSelectColumnsSql = "id, created_at, updated_at, branch_tx_id, xid, `state`, `version`, compensation_fail_times, node_group, " +
-		" node_service, node_instance_id, branch_service_key, branch_compensation_service_key"
+		" node_service, node_instance_id, branch_service_key, branch_compensation_service_key, " +
+		" retry_max_attempts, retry_initial_delay_ms, retry_multiplier, retry_max_delay_ms, next_retry_at, " +
+		" parent_branch_tx_id, branch_group, branch_group_size, recovery_mode, forward_retry_times," +
+		" branch_confirm_service_key, lease_id, lease_owner, lease_expire_at," +
+		" heartbeat_timeout_seconds, last_heartbeat_at, orphaned_at"
 	branchTxCompensationFailLogTableSelectColumnsSql = "id, created_at, updated_at, xid, branch_tx_id, job_id, `reason`"
+	branchTxForwardRetryLogTableSelectColumnsSql = "id, created_at, updated_at, xid, branch_tx_id, job_id, `reason`"
+	txLogTableSelectColumnsSql = "id, created_at, updated_at, xid, branch_tx_id, " +
+		" operator_group, operator_service, operator_instance_id, log_type, log_params"
 )
 
-func FindGlobalTxByXidOrNull(ctx context.Context, db *sql.DB, xid string) (result *GlobalTxEntity, err error) {
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanGlobalTx(row rowScanner) (entity *GlobalTxEntity, err error) {
+	entity = &GlobalTxEntity{}
+	err = row.Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt, &entity.Xid, &entity.State,
+		&entity.EndBranches, &entity.Version, &entity.CreatorGroup, &entity.CreatorService, &entity.CreatorInstanceId,
+		&entity.ExpireSeconds, &entity.Extra,
+		&entity.RetryPolicy.MaxAttempts, &entity.RetryPolicy.InitialDelayMs,
+		&entity.RetryPolicy.Multiplier, &entity.RetryPolicy.MaxDelayMs, &entity.Mode)
+	return
+}
+
+func scanBranchTx(row rowScanner) (entity *BranchTxEntity, err error) {
+	entity = &BranchTxEntity{}
+	err = row.Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt, &entity.BranchTxId, &entity.Xid,
+		&entity.State, &entity.Version, &entity.CompensationFailTimes,
+		&entity.NodeGroup, &entity.NodeService, &entity.NodeInstanceId,
+		&entity.BranchServiceKey, &entity.BranchCompensationServiceKey,
+		&entity.RetryPolicy.MaxAttempts, &entity.RetryPolicy.InitialDelayMs,
+		&entity.RetryPolicy.Multiplier, &entity.RetryPolicy.MaxDelayMs, &entity.NextRetryAt,
+		&entity.ParentBranchTxId, &entity.BranchGroup, &entity.BranchGroupSize,
+		&entity.RecoveryMode, &entity.ForwardRetryTimes, &entity.BranchConfirmServiceKey,
+		&entity.LeaseId, &entity.LeaseOwner, &entity.LeaseExpireAt,
+		&entity.HeartbeatTimeoutSeconds, &entity.LastHeartbeatAt, &entity.OrphanedAt)
+	return
+}
+
+func (d *sqlDaos) FindGlobalTxByXidOrNull(ctx context.Context, xid string) (result *GlobalTxEntity, err error) {
 	s := "select " + globalTxTableSelectColumnsSql +
 		" from global_tx where xid = ? order by id asc limit 1"
-	row := db.QueryRowContext(ctx, s, xid)
-	entity := &GlobalTxEntity{}
-	err = row.Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt, &entity.Xid, &entity.State, &entity.Version,
-		&entity.CreatorGroup, &entity.CreatorService, &entity.CreatorInstanceId,
-		&entity.ExpireSeconds, &entity.Extra)
+	row := d.queryRowContext(ctx, s, xid)
+	entity, err := scanGlobalTx(row)
 	if err != nil && err == sql.ErrNoRows {
 		err = nil
 		result = nil
@@ -105,16 +193,26 @@ func placeholders(n int) string {
 	return b.String()
 }
 
-func FindXidsOfGlobalTxsByStates(ctx context.Context, db *sql.DB,
-	states []api.TxState, limit int32) (result []string, err error) {
-	s := fmt.Sprintf("select xid " +
-		" from global_tx where `state` in (%s) order by id desc limit ?", placeholders(len(states)))
-	args := make([]interface{}, len(states)+1)
-	for i, v := range states {
-		args[i] = v
-	}
-	args[len(args)-1] = limit
-	rows, err := db.QueryContext(ctx, s, args...)
+func (d *sqlDaos) FindXidsOfGlobalTxsByStates(ctx context.Context,
+	states []api.TxState, now time.Time, limit int32) (result []string, err error) {
+	// 补偿中的全局事务，只有在等待重试的分支而没有可以立即补偿的分支时不返回
+	s := fmt.Sprintf("select g.xid " +
+		" from global_tx g where g.`state` in (%s) and not (g.`state` = ?" +
+		" and exists (select 1 from branch_tx b where b.xid = g.xid and b.`state` in (?, ?)" +
+		"   and b.next_retry_at > ?)" +
+		" and not exists (select 1 from branch_tx b where b.xid = g.xid and b.`state` in (?, ?)" +
+		"   and (b.next_retry_at is null or b.next_retry_at <= ?)))" +
+		" order by g.id desc limit ?", placeholders(len(states)))
+	args := make([]interface{}, 0, len(states)+8)
+	for _, v := range states {
+		args = append(args, v)
+	}
+	now = now.UTC()
+	args = append(args, api.TxState_COMPENSATION_DOING,
+		api.TxState_COMPENSATION_DOING, api.TxState_COMPENSATION_ERROR, now,
+		api.TxState_COMPENSATION_DOING, api.TxState_COMPENSATION_ERROR, now,
+		limit)
+	rows, err := d.queryContext(ctx, s, args...)
 	if err != nil {
 		return
 	}
@@ -129,40 +227,63 @@ func FindXidsOfGlobalTxsByStates(ctx context.Context, db *sql.DB,
 		}
 		result = append(result, item)
 	}
+	err = rows.Err()
+	return
+}
+
+func (d *sqlDaos) FindGlobalTxsByStates(ctx context.Context, states []api.TxState,
+	afterId uint64, limit int32) (result []*GlobalTxEntity, err error) {
+	s := fmt.Sprintf("select " + globalTxTableSelectColumnsSql +
+		" from global_tx where `state` in (%s) and id > ? order by id asc limit ?", placeholders(len(states)))
+	args := make([]interface{}, 0, len(states)+2)
+	for _, v := range states {
+		args = append(args, v)
+	}
+	args = append(args, afterId, limit)
+	rows, err := d.queryContext(ctx, s, args...)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	result = make([]*GlobalTxEntity, 0)
+	for rows.Next() {
+		var entity *GlobalTxEntity
+		entity, err = scanGlobalTx(rows)
+		if err != nil {
+			return
+		}
+		result = append(result, entity)
+	}
+	err = rows.Err()
 	return
 }
 
-func FindAllBranchTxsByXid(ctx context.Context, db *sql.DB, xid string) (result []*BranchTxEntity, err error) {
+func (d *sqlDaos) FindAllBranchTxsByXid(ctx context.Context, xid string) (result []*BranchTxEntity, err error) {
 	s := "select " + branchTxTableSelectColumnsSql +
 		" from branch_tx where xid = ? order by id asc"
-	rows, err := db.QueryContext(ctx, s, xid)
+	rows, err := d.queryContext(ctx, s, xid)
 	if err != nil {
 		return
 	}
 	defer rows.Close()
 	for rows.Next() {
-		entity := &BranchTxEntity{}
-		err = rows.Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt, &entity.BranchTxId, &entity.Xid,
-			&entity.State, &entity.Version, &entity.CompensationFailTimes,
-			&entity.NodeGroup, &entity.NodeService, &entity.NodeInstanceId,
-			&entity.BranchServiceKey, &entity.BranchCompensationServiceKey)
+		var entity *BranchTxEntity
+		entity, err = scanBranchTx(rows)
 		if err != nil {
 			return
 		}
 		result = append(result, entity)
 	}
+	err = rows.Err()
 	return
 }
 
-func FindBranchTxByBranchTxId(ctx context.Context, db *sql.DB, branchTxId string) (result *BranchTxEntity, err error) {
+func (d *sqlDaos) FindBranchTxByBranchTxId(ctx context.Context, branchTxId string) (result *BranchTxEntity, err error) {
 	s := "select " + branchTxTableSelectColumnsSql +
 		" from branch_tx where branch_tx_id = ? order by id asc limit 1"
-	row := db.QueryRowContext(ctx, s, branchTxId)
-	entity := &BranchTxEntity{}
-	err = row.Scan(&entity.Id, &entity.CreatedAt, &entity.UpdatedAt, &entity.BranchTxId, &entity.Xid,
-		&entity.State, &entity.Version, &entity.CompensationFailTimes,
-		&entity.NodeGroup, &entity.NodeService, &entity.NodeInstanceId,
-		&entity.BranchServiceKey, &entity.BranchCompensationServiceKey)
+	row := d.queryRowContext(ctx, s, branchTxId)
+	entity, err := scanBranchTx(row)
 	if err != nil && err == sql.ErrNoRows {
 		err = nil
 		result = nil
@@ -175,14 +296,9 @@ func FindBranchTxByBranchTxId(ctx context.Context, db *sql.DB, branchTxId string
 	return
 }
 
-func UpdateGlobalTxState(ctx context.Context, tx *sql.Tx, xid string,
-	oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update global_tx set `state` = ?, `version` = `version`+1 " +
-		" where xid= ? and `state`= ? and `version` = ?")
-	if err != nil {
-		return
-	}
-	sqlResult, err := stmt.ExecContext(ctx, state, xid, oldState, oldVersion)
+func (d *sqlDaos) execAndCountRows(ctx context.Context,
+	query string, args ...interface{}) (rowsChanged int64, err error) {
+	sqlResult, err := d.execContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
@@ -190,58 +306,134 @@ func UpdateGlobalTxState(ctx context.Context, tx *sql.Tx, xid string,
 	return
 }
 
-func UpdateBranchTxState(ctx context.Context, tx *sql.Tx, xid string,
+func (d *sqlDaos) UpdateGlobalTxState(ctx context.Context, xid string,
+	oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update global_tx set `state` = ?, `version` = `version`+1 " +
+		" where xid= ? and `state`= ? and `version` = ?",
+		state, xid, oldState, oldVersion)
+}
+
+func (d *sqlDaos) UpdateGlobalTxEndBranches(ctx context.Context,
+	xid string, oldVersion int32) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update global_tx set end_branches = ?, `version` = `version`+1 " +
+		" where xid= ? and `version` = ?",
+		true, xid, oldVersion)
+}
+
+func (d *sqlDaos) UpdateBranchTxState(ctx context.Context, xid string,
 	branchTxId string, oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update branch_tx set `state` = ?, `version` = `version` + 1 " +
-		" where  branch_tx_id = ? and xid = ? and `version` = ? and `state` = ?")
-	if err != nil {
-		return
-	}
-	sqlResult, err := stmt.ExecContext(ctx, state, branchTxId, xid, oldVersion, oldState)
-	if err != nil {
-		return
-	}
-	rowsChanged, err = sqlResult.RowsAffected()
-	return
+	return d.execAndCountRows(ctx, "update branch_tx set `state` = ?, `version` = `version` + 1 " +
+		" where  branch_tx_id = ? and xid = ? and `version` = ? and `state` = ?",
+		state, branchTxId, xid, oldVersion, oldState)
+}
+
+func (d *sqlDaos) UpdateLeasedBranchTxState(ctx context.Context, xid string, branchTxId string, oldVersion int32,
+	oldState int, state int, leaseId string, now time.Time) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set `state` = ?, `version` = `version` + 1 " +
+		" where  branch_tx_id = ? and xid = ? and `version` = ? and `state` = ? " +
+		" and (lease_expire_at is null or lease_expire_at <= ? or lease_id = ?)",
+		state, branchTxId, xid, oldVersion, oldState, now.UTC(), leaseId)
 }
 
-func UpdateBranchTxCompensationFailTimes(ctx context.Context, tx *sql.Tx,
-	id uint64, oldVersion int32, failTimes int32) (rowsChanged int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update branch_tx set `compensation_fail_times` = ?, " +
-		" `version` = `version` + 1 " +
-		" where  id = ? and `version` = ?")
+func (d *sqlDaos) UpdateBranchTxCompensationFailTimes(ctx context.Context,
+	id uint64, oldVersion int32, failTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	if nextRetryAt != nil {
+		utc := nextRetryAt.UTC()
+		nextRetryAt = &utc
+	}
+	return d.execAndCountRows(ctx, "update branch_tx set `compensation_fail_times` = ?, " +
+		" next_retry_at = ?, `version` = `version` + 1 " +
+		" where  id = ? and `version` = ?",
+		failTimes, nextRetryAt, id, oldVersion)
+}
+
+func (d *sqlDaos) UpdateBranchTxForwardRetryTimes(ctx context.Context,
+	id uint64, oldVersion int32, retryTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	if nextRetryAt != nil {
+		utc := nextRetryAt.UTC()
+		nextRetryAt = &utc
+	}
+	return d.execAndCountRows(ctx, "update branch_tx set `forward_retry_times` = ?, " +
+		" next_retry_at = ?, `version` = `version` + 1 " +
+		" where  id = ? and `version` = ?",
+		retryTimes, nextRetryAt, id, oldVersion)
+}
+
+func (d *sqlDaos) ClaimBranchTxLease(ctx context.Context, branchTxId string, leaseId string, leaseOwner string,
+	leaseExpireAt time.Time, now time.Time) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set lease_id = ?, lease_owner = ?, lease_expire_at = ? " +
+		" where branch_tx_id = ? and `state` in (?, ?, ?, ?) and (lease_expire_at is null or lease_expire_at <= ?)",
+		leaseId, leaseOwner, leaseExpireAt.UTC(), branchTxId,
+		int(api.TxState_COMPENSATION_DOING), int(api.TxState_COMPENSATION_ERROR), int(api.TxState_RETRYING),
+		int(api.TxState_CONFIRMING), now.UTC())
+}
+
+func (d *sqlDaos) RenewBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string, leaseExpireAt time.Time) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set lease_expire_at = ? " +
+		" where branch_tx_id = ? and lease_id = ?",
+		leaseExpireAt.UTC(), branchTxId, leaseId)
+}
+
+func (d *sqlDaos) ReleaseBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set lease_id = ?, lease_owner = ?, lease_expire_at = ? " +
+		" where branch_tx_id = ? and lease_id = ?",
+		"", "", nil, branchTxId, leaseId)
+}
+
+func (d *sqlDaos) UpdateBranchTxHeartbeat(ctx context.Context,
+	branchTxId string, heartbeatAt time.Time) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set last_heartbeat_at = ?, orphaned_at = ? " +
+		" where branch_tx_id = ? and `state` = ?",
+		heartbeatAt.UTC(), nil, branchTxId, int(api.TxState_PROCESSING))
+}
+
+func (d *sqlDaos) FindXidsOfBranchTxsDueBetween(ctx context.Context,
+	after time.Time, upTo time.Time) (result []string, err error) {
+	after = after.UTC()
+	upTo = upTo.UTC()
+	rows, err := d.queryContext(ctx, "select distinct xid from branch_tx " +
+		" where (next_retry_at > ? and next_retry_at <= ?) or (lease_expire_at > ? and lease_expire_at <= ?)",
+		after, upTo, after, upTo)
 	if err != nil {
 		return
 	}
-	sqlResult, err := stmt.ExecContext(ctx, failTimes, id, oldVersion)
-	if err != nil {
-		return
+	defer rows.Close()
+
+	result = make([]string, 0)
+	for rows.Next() {
+		var item string
+		err = rows.Scan(&item)
+		if err != nil {
+			return
+		}
+		result = append(result, item)
 	}
-	rowsChanged, err = sqlResult.RowsAffected()
+	err = rows.Err()
 	return
 }
 
-func UpdateBranchesStateByXid(ctx context.Context, tx *sql.Tx,
+func (d *sqlDaos) MarkBranchTxOrphaned(ctx context.Context, branchTxId string,
+	heartbeatBefore time.Time, orphanedAt time.Time) (rowsChanged int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx set orphaned_at = ? " +
+		" where branch_tx_id = ? and orphaned_at is null and last_heartbeat_at <= ?",
+		orphanedAt.UTC(), branchTxId, heartbeatBefore.UTC())
+}
+
+func (d *sqlDaos) UpdateBranchesStateByXid(ctx context.Context,
 	xid string, state int) (rowsChanged int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update branch_tx set `state` = ?, `version` = `version` + 1 " +
-		" where xid = ?")
-	if err != nil {
-		return
-	}
-	sqlResult, err := stmt.ExecContext(ctx, state, xid)
-	if err != nil {
-		return
-	}
-	rowsChanged, err = sqlResult.RowsAffected()
-	return
+	return d.execAndCountRows(ctx, "update branch_tx set `state` = ?, `version` = `version` + 1 " +
+		" where xid = ?",
+		state, xid)
 }
 
-func FindBranchTxCompensationFailLogByJobId(ctx context.Context, tx *sql.Tx,
+func (d *sqlDaos) FindBranchTxCompensationFailLogByJobId(ctx context.Context,
 	jobId string) (result *BranchTxCompensationFailLogEntity, err error) {
 	querySql := "select " + branchTxCompensationFailLogTableSelectColumnsSql +
 		" from branch_tx_compensation_fail_log " +
 		" where job_id = ?"
-	row := tx.QueryRowContext(ctx, querySql, jobId)
+	row := d.queryRowContext(ctx, querySql, jobId)
 	record := &BranchTxCompensationFailLogEntity{}
 	err = row.Scan(&record.Id, &record.CreatedAt, &record.UpdatedAt,
 		&record.Xid, &record.BranchTxId, &record.JobId, &record.Reason)
@@ -257,15 +449,13 @@ func FindBranchTxCompensationFailLogByJobId(ctx context.Context, tx *sql.Tx,
 	return
 }
 
-func InsertBranchTxCompensationFailLog(ctx context.Context, tx *sql.Tx,
-	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "insert into `branch_tx_compensation_fail_log` (" +
-		"xid, branch_tx_id, job_id, `reason`" +
-		") values (?, ?, ?, ?)")
-	if err != nil {
+func (d *sqlDaos) insertAndGetId(ctx context.Context,
+	query string, args ...interface{}) (recordId uint64, err error) {
+	if d.dialect.insertReturningId {
+		err = d.queryRowContext(ctx, query+" returning id", args...).Scan(&recordId)
 		return
 	}
-	sqlResult, err := stmt.ExecContext(ctx, xid, branchTxId, jobId, reason)
+	sqlResult, err := d.execContext(ctx, query, args...)
 	if err != nil {
 		return
 	}
@@ -277,60 +467,99 @@ func InsertBranchTxCompensationFailLog(ctx context.Context, tx *sql.Tx,
 	return
 }
 
-// 修改xid下的分支事务，把状态{oldState}的改成状态{newState}
-func UpdateBranchTxsByXidFromStateToState(ctx context.Context, tx *sql.Tx,
-	xid string, oldState int, newState int) (rowsAffected int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update branch_tx " +
-		" set `state` = ?, `version` = `version` + 1 " +
-		" where xid = ? and `state` = ?")
-	if err != nil {
+func (d *sqlDaos) InsertBranchTxCompensationFailLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	return d.insertAndGetId(ctx, "insert into `branch_tx_compensation_fail_log` (" +
+		"xid, branch_tx_id, job_id, `reason`" +
+		") values (?, ?, ?, ?)",
+		xid, branchTxId, jobId, reason)
+}
+
+func (d *sqlDaos) FindBranchTxForwardRetryLogByJobId(ctx context.Context,
+	jobId string) (result *BranchTxForwardRetryLogEntity, err error) {
+	querySql := "select " + branchTxForwardRetryLogTableSelectColumnsSql +
+		" from branch_tx_forward_retry_log " +
+		" where job_id = ?"
+	row := d.queryRowContext(ctx, querySql, jobId)
+	record := &BranchTxForwardRetryLogEntity{}
+	err = row.Scan(&record.Id, &record.CreatedAt, &record.UpdatedAt,
+		&record.Xid, &record.BranchTxId, &record.JobId, &record.Reason)
+	if err == sql.ErrNoRows {
+		err = nil
 		return
 	}
-	sqlResult, err := stmt.ExecContext(ctx, newState, xid, oldState)
 	if err != nil {
 		return
 	}
-	rowsAffected, err = sqlResult.RowsAffected()
+	result = record
 	return
 }
 
-func InsertSagaData(ctx context.Context, tx *sql.Tx,
-	xid string, data []byte) (rowsAffected int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "insert into saga_data " +
-		" (xid, `data`, `version`) values (?,?,?) " +
-		" on duplicate key update `data`=?, `version` = `version` + 1")
+func (d *sqlDaos) InsertBranchTxForwardRetryLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	return d.insertAndGetId(ctx, "insert into `branch_tx_forward_retry_log` (" +
+		"xid, branch_tx_id, job_id, `reason`" +
+		") values (?, ?, ?, ?)",
+		xid, branchTxId, jobId, reason)
+}
+
+func (d *sqlDaos) InsertTxLog(ctx context.Context, record *TxLogEntity) (recordId uint64, err error) {
+	return d.insertAndGetId(ctx, "insert into tx_log (" +
+		"xid, branch_tx_id, operator_group, operator_service, operator_instance_id, " +
+		" log_type, log_params" +
+		") values (?, ?, ?, ?, ?, ?, ?)",
+		record.Xid, record.BranchTxId, record.OperatorGroup, record.OperatorService, record.OperatorInstanceId,
+		record.LogType, record.LogParams)
+}
+
+func (d *sqlDaos) FindTxLogsByXid(ctx context.Context, xid string) (result []*TxLogEntity, err error) {
+	s := "select " + txLogTableSelectColumnsSql +
+		" from tx_log where xid = ? order by id asc"
+	rows, err := d.queryContext(ctx, s, xid)
 	if err != nil {
 		return
 	}
-	sqlResult, err := stmt.ExecContext(ctx, xid, data, 0, data)
-	if err != nil {
-		return
+	defer rows.Close()
+	for rows.Next() {
+		record := &TxLogEntity{}
+		err = rows.Scan(&record.Id, &record.CreatedAt, &record.UpdatedAt, &record.Xid, &record.BranchTxId,
+			&record.OperatorGroup, &record.OperatorService, &record.OperatorInstanceId,
+			&record.LogType, &record.LogParams)
+		if err != nil {
+			return
+		}
+		result = append(result, record)
 	}
-	rowsAffected, err = sqlResult.RowsAffected()
+	err = rows.Err()
 	return
 }
 
-func UpdateSagaData(ctx context.Context, tx *sql.Tx,
+func (d *sqlDaos) UpdateBranchTxsByXidFromStateToState(ctx context.Context,
+	xid string, oldState int, newState int) (rowsAffected int64, err error) {
+	return d.execAndCountRows(ctx, "update branch_tx " +
+		" set `state` = ?, `version` = `version` + 1 " +
+		" where xid = ? and `state` = ?",
+		newState, xid, oldState)
+}
+
+func (d *sqlDaos) InsertSagaData(ctx context.Context,
+	xid string, data []byte) (rowsAffected int64, err error) {
+	return d.execAndCountRows(ctx, d.dialect.insertSagaDataSql, xid, data, 0)
+}
+
+func (d *sqlDaos) UpdateSagaData(ctx context.Context,
 	xid string, data []byte, oldVersion int32) (rowsAffected int64, err error) {
-	stmt, err := tx.PrepareContext(ctx, "update saga_data " +
+	return d.execAndCountRows(ctx, "update saga_data " +
 		" set `data` = ?, `version` = `version` + 1 " +
-		" where `xid` = ? and `version` = ?")
-	if err != nil {
-		return
-	}
-	sqlResult, err := stmt.ExecContext(ctx, data, xid, oldVersion)
-	if err != nil {
-		return
-	}
-	rowsAffected, err = sqlResult.RowsAffected()
-	return
+		" where `xid` = ? and `version` = ?",
+		data, xid, oldVersion)
 }
 
-func QuerySagaData(ctx context.Context, tx *sql.Tx,
+func (d *sqlDaos) QuerySagaData(ctx context.Context,
 	xid string) (record *SagaDataEntity, err error) {
 	s := "select id, created_at, updated_at, xid, `data`, `version` from saga_data " +
 		" where xid = ?"
-	row := tx.QueryRowContext(ctx, s, xid)
+	row := d.queryRowContext(ctx, s, xid)
 	record = &SagaDataEntity{}
 	err = row.Scan(&record.Id, &record.CreatedAt, &record.UpdatedAt,
 		&record.Xid, &record.Data, &record.Version)
@@ -0,0 +1,908 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/zoowii/saga_server/api"
+	"sync"
+	"time"
+)
+
+/**
+ * 内存中的各表数据
+ */
+type memoryTables struct {
+	lastId               uint64
+	globalTxs            map[string]*GlobalTxEntity                    // xid => globalTx
+	globalTxList         []*GlobalTxEntity                             // 按id升序
+	branchTxs            map[string]*BranchTxEntity                    // branchTxId => branchTx
+	branchTxList         []*BranchTxEntity                             // 按id升序
+	compensationFailLogs map[string]*BranchTxCompensationFailLogEntity // jobId => log
+	forwardRetryLogs     map[string]*BranchTxForwardRetryLogEntity     // jobId => log
+	txLogs               []*TxLogEntity
+	sagaData             map[string]*SagaDataEntity // xid => sagaData
+}
+
+func newMemoryTables() *memoryTables {
+	return &memoryTables{
+		globalTxs:            make(map[string]*GlobalTxEntity),
+		branchTxs:            make(map[string]*BranchTxEntity),
+		compensationFailLogs: make(map[string]*BranchTxCompensationFailLogEntity),
+		forwardRetryLogs:     make(map[string]*BranchTxForwardRetryLogEntity),
+		sagaData:             make(map[string]*SagaDataEntity),
+	}
+}
+
+/**
+ * 内存存储上的StoreOps实现. 调用方需要持有MemoryStore的锁.
+ * undoLogs不为nil时记录每个修改的撤销操作，用于事务回滚
+ */
+type memoryOps struct {
+	tables   *memoryTables
+	undoLogs *[]func()
+}
+
+func (o *memoryOps) addUndo(f func()) {
+	if o.undoLogs != nil {
+		*o.undoLogs = append(*o.undoLogs, f)
+	}
+}
+
+func (o *memoryOps) nextId() uint64 {
+	o.tables.lastId++
+	return o.tables.lastId
+}
+
+func nowTime() *time.Time {
+	now := time.Now()
+	return &now
+}
+
+func (o *memoryOps) CreateGlobalTx(ctx context.Context, record *GlobalTxEntity) (xid string, err error) {
+	t := o.tables
+	if _, ok := t.globalTxs[record.Xid]; ok {
+		err = fmt.Errorf("duplicate xid %s", record.Xid)
+		return
+	}
+	entity := *record
+	entity.Id = o.nextId()
+	entity.CreatedAt = nowTime()
+	entity.UpdatedAt = entity.CreatedAt
+	t.globalTxs[entity.Xid] = &entity
+	t.globalTxList = append(t.globalTxList, &entity)
+	o.addUndo(func() {
+		delete(t.globalTxs, entity.Xid)
+		t.globalTxList = t.globalTxList[:len(t.globalTxList)-1]
+	})
+	xid = entity.Xid
+	return
+}
+
+func (o *memoryOps) FindGlobalTxByXidOrNull(ctx context.Context, xid string) (result *GlobalTxEntity, err error) {
+	entity, ok := o.tables.globalTxs[xid]
+	if !ok {
+		return
+	}
+	copied := *entity
+	result = &copied
+	return
+}
+
+func (o *memoryOps) FindXidsOfGlobalTxsByStates(ctx context.Context,
+	states []api.TxState, now time.Time, limit int32) (result []string, err error) {
+	result = make([]string, 0)
+	list := o.tables.globalTxList
+	for i := len(list) - 1; i >= 0 && int32(len(result)) < limit; i-- {
+		for _, state := range states {
+			if list[i].State == int(state) {
+				if !o.isGlobalTxBackingOff(list[i], now) {
+					result = append(result, list[i].Xid)
+				}
+				break
+			}
+		}
+	}
+	return
+}
+
+/**
+ * 补偿中的全局事务是否只有在等待重试的待补偿分支
+ */
+func (o *memoryOps) isGlobalTxBackingOff(globalTx *GlobalTxEntity, now time.Time) bool {
+	if globalTx.State != int(api.TxState_COMPENSATION_DOING) {
+		return false
+	}
+	hasBackingOff := false
+	for _, b := range o.tables.branchTxList {
+		if b.Xid != globalTx.Xid {
+			continue
+		}
+		if b.State != int(api.TxState_COMPENSATION_DOING) && b.State != int(api.TxState_COMPENSATION_ERROR) {
+			continue
+		}
+		if b.NextRetryAt == nil || !b.NextRetryAt.After(now) {
+			return false
+		}
+		hasBackingOff = true
+	}
+	return hasBackingOff
+}
+
+func (o *memoryOps) FindGlobalTxsByStates(ctx context.Context, states []api.TxState,
+	afterId uint64, limit int32) (result []*GlobalTxEntity, err error) {
+	result = make([]*GlobalTxEntity, 0)
+	for _, entity := range o.tables.globalTxList {
+		if int32(len(result)) >= limit {
+			break
+		}
+		if entity.Id <= afterId {
+			continue
+		}
+		for _, state := range states {
+			if entity.State == int(state) {
+				copied := *entity
+				result = append(result, &copied)
+				break
+			}
+		}
+	}
+	return
+}
+
+/**
+ * 修改globalTx, 修改前记录撤销操作
+ */
+func (o *memoryOps) modifyGlobalTx(entity *GlobalTxEntity, modify func(e *GlobalTxEntity)) {
+	old := *entity
+	o.addUndo(func() {
+		*entity = old
+	})
+	modify(entity)
+	entity.Version++
+	entity.UpdatedAt = nowTime()
+}
+
+func (o *memoryOps) UpdateGlobalTxState(ctx context.Context, xid string,
+	oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	entity, ok := o.tables.globalTxs[xid]
+	if !ok || entity.State != oldState || entity.Version != oldVersion {
+		return
+	}
+	o.modifyGlobalTx(entity, func(e *GlobalTxEntity) {
+		e.State = state
+	})
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) UpdateGlobalTxEndBranches(ctx context.Context,
+	xid string, oldVersion int32) (rowsChanged int64, err error) {
+	entity, ok := o.tables.globalTxs[xid]
+	if !ok || entity.Version != oldVersion {
+		return
+	}
+	o.modifyGlobalTx(entity, func(e *GlobalTxEntity) {
+		e.EndBranches = true
+	})
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) CreateBranchTx(ctx context.Context, record *BranchTxEntity) (branchTxId string, err error) {
+	t := o.tables
+	if _, ok := t.branchTxs[record.BranchTxId]; ok {
+		err = fmt.Errorf("duplicate branch tx id %s", record.BranchTxId)
+		return
+	}
+	entity := *record
+	entity.Id = o.nextId()
+	entity.CreatedAt = nowTime()
+	entity.UpdatedAt = entity.CreatedAt
+	t.branchTxs[entity.BranchTxId] = &entity
+	t.branchTxList = append(t.branchTxList, &entity)
+	o.addUndo(func() {
+		delete(t.branchTxs, entity.BranchTxId)
+		t.branchTxList = t.branchTxList[:len(t.branchTxList)-1]
+	})
+	branchTxId = entity.BranchTxId
+	return
+}
+
+func (o *memoryOps) FindAllBranchTxsByXid(ctx context.Context, xid string) (result []*BranchTxEntity, err error) {
+	for _, entity := range o.tables.branchTxList {
+		if entity.Xid == xid {
+			copied := *entity
+			result = append(result, &copied)
+		}
+	}
+	return
+}
+
+func (o *memoryOps) FindBranchTxByBranchTxId(ctx context.Context, branchTxId string) (result *BranchTxEntity, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok {
+		return
+	}
+	copied := *entity
+	result = &copied
+	return
+}
+
+/**
+ * 修改branchTx, 修改前记录撤销操作
+ */
+func (o *memoryOps) modifyBranchTx(entity *BranchTxEntity, modify func(e *BranchTxEntity)) {
+	old := *entity
+	o.addUndo(func() {
+		*entity = old
+	})
+	modify(entity)
+	entity.Version++
+	entity.UpdatedAt = nowTime()
+}
+
+/**
+ * 修改xid下满足条件的各分支事务
+ */
+func (o *memoryOps) modifyBranchTxsOfXid(xid string, match func(e *BranchTxEntity) bool,
+	modify func(e *BranchTxEntity)) (rowsChanged int64) {
+	for _, entity := range o.tables.branchTxList {
+		if entity.Xid != xid || !match(entity) {
+			continue
+		}
+		o.modifyBranchTx(entity, modify)
+		rowsChanged++
+	}
+	return
+}
+
+func (o *memoryOps) UpdateBranchTxState(ctx context.Context, xid string,
+	branchTxId string, oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || entity.Xid != xid || entity.Version != oldVersion || entity.State != oldState {
+		return
+	}
+	o.modifyBranchTx(entity, func(e *BranchTxEntity) {
+		e.State = state
+	})
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) UpdateLeasedBranchTxState(ctx context.Context, xid string, branchTxId string, oldVersion int32,
+	oldState int, state int, leaseId string, now time.Time) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || (entity.LeaseExpireAt != nil && entity.LeaseExpireAt.After(now) && entity.LeaseId != leaseId) {
+		return
+	}
+	return o.UpdateBranchTxState(ctx, xid, branchTxId, oldVersion, oldState, state)
+}
+
+func (o *memoryOps) UpdateBranchTxCompensationFailTimes(ctx context.Context,
+	id uint64, oldVersion int32, failTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	for _, entity := range o.tables.branchTxList {
+		if entity.Id != id {
+			continue
+		}
+		if entity.Version != oldVersion {
+			return
+		}
+		o.modifyBranchTx(entity, func(e *BranchTxEntity) {
+			e.CompensationFailTimes = failTimes
+			e.NextRetryAt = nextRetryAt
+		})
+		rowsChanged = 1
+		return
+	}
+	return
+}
+
+func (o *memoryOps) UpdateBranchTxForwardRetryTimes(ctx context.Context,
+	id uint64, oldVersion int32, retryTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	for _, entity := range o.tables.branchTxList {
+		if entity.Id != id {
+			continue
+		}
+		if entity.Version != oldVersion {
+			return
+		}
+		o.modifyBranchTx(entity, func(e *BranchTxEntity) {
+			e.ForwardRetryTimes = retryTimes
+			e.NextRetryAt = nextRetryAt
+		})
+		rowsChanged = 1
+		return
+	}
+	return
+}
+
+/**
+ * 修改branchTx的租约，租约不是分支的状态所以不修改版本号
+ */
+func (o *memoryOps) modifyBranchTxLease(entity *BranchTxEntity, leaseId string, leaseOwner string,
+	leaseExpireAt *time.Time) {
+	old := *entity
+	o.addUndo(func() {
+		*entity = old
+	})
+	entity.LeaseId = leaseId
+	entity.LeaseOwner = leaseOwner
+	entity.LeaseExpireAt = leaseExpireAt
+	entity.UpdatedAt = nowTime()
+}
+
+func isTimeBetween(t *time.Time, after time.Time, upTo time.Time) bool {
+	return t != nil && t.After(after) && !t.After(upTo)
+}
+
+func (o *memoryOps) FindXidsOfBranchTxsDueBetween(ctx context.Context,
+	after time.Time, upTo time.Time) (result []string, err error) {
+	result = make([]string, 0)
+	found := make(map[string]bool)
+	for _, entity := range o.tables.branchTxList {
+		if found[entity.Xid] {
+			continue
+		}
+		if isTimeBetween(entity.NextRetryAt, after, upTo) || isTimeBetween(entity.LeaseExpireAt, after, upTo) {
+			found[entity.Xid] = true
+			result = append(result, entity.Xid)
+		}
+	}
+	return
+}
+
+func isBranchTxLeasableState(state int) bool {
+	return state == int(api.TxState_COMPENSATION_DOING) || state == int(api.TxState_COMPENSATION_ERROR) ||
+		state == int(api.TxState_RETRYING) || state == int(api.TxState_CONFIRMING)
+}
+
+func (o *memoryOps) ClaimBranchTxLease(ctx context.Context, branchTxId string, leaseId string, leaseOwner string,
+	leaseExpireAt time.Time, now time.Time) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || (entity.LeaseExpireAt != nil && entity.LeaseExpireAt.After(now)) {
+		return
+	}
+	if !isBranchTxLeasableState(entity.State) {
+		return
+	}
+	o.modifyBranchTxLease(entity, leaseId, leaseOwner, &leaseExpireAt)
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) RenewBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string, leaseExpireAt time.Time) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || entity.LeaseId != leaseId {
+		return
+	}
+	o.modifyBranchTxLease(entity, entity.LeaseId, entity.LeaseOwner, &leaseExpireAt)
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) ReleaseBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || entity.LeaseId != leaseId {
+		return
+	}
+	o.modifyBranchTxLease(entity, "", "", nil)
+	rowsChanged = 1
+	return
+}
+
+/**
+ * 修改branchTx的心跳信息，心跳不是分支的状态所以不修改版本号
+ */
+func (o *memoryOps) modifyBranchTxHeartbeat(entity *BranchTxEntity, modify func(e *BranchTxEntity)) {
+	old := *entity
+	o.addUndo(func() {
+		*entity = old
+	})
+	modify(entity)
+	entity.UpdatedAt = nowTime()
+}
+
+func (o *memoryOps) UpdateBranchTxHeartbeat(ctx context.Context,
+	branchTxId string, heartbeatAt time.Time) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || entity.State != int(api.TxState_PROCESSING) {
+		return
+	}
+	o.modifyBranchTxHeartbeat(entity, func(e *BranchTxEntity) {
+		e.LastHeartbeatAt = &heartbeatAt
+		e.OrphanedAt = nil
+	})
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) MarkBranchTxOrphaned(ctx context.Context, branchTxId string,
+	heartbeatBefore time.Time, orphanedAt time.Time) (rowsChanged int64, err error) {
+	entity, ok := o.tables.branchTxs[branchTxId]
+	if !ok || entity.OrphanedAt != nil || entity.LastHeartbeatAt == nil || entity.LastHeartbeatAt.After(heartbeatBefore) {
+		return
+	}
+	o.modifyBranchTxHeartbeat(entity, func(e *BranchTxEntity) {
+		e.OrphanedAt = &orphanedAt
+	})
+	rowsChanged = 1
+	return
+}
+
+func (o *memoryOps) UpdateBranchesStateByXid(ctx context.Context, xid string, state int) (rowsChanged int64, err error) {
+	rowsChanged = o.modifyBranchTxsOfXid(xid, func(e *BranchTxEntity) bool {
+		return true
+	}, func(e *BranchTxEntity) {
+		e.State = state
+	})
+	return
+}
+
+func (o *memoryOps) UpdateBranchTxsByXidFromStateToState(ctx context.Context,
+	xid string, oldState int, newState int) (rowsAffected int64, err error) {
+	rowsAffected = o.modifyBranchTxsOfXid(xid, func(e *BranchTxEntity) bool {
+		return e.State == oldState
+	}, func(e *BranchTxEntity) {
+		e.State = newState
+	})
+	return
+}
+
+func (o *memoryOps) FindBranchTxCompensationFailLogByJobId(ctx context.Context,
+	jobId string) (result *BranchTxCompensationFailLogEntity, err error) {
+	entity, ok := o.tables.compensationFailLogs[jobId]
+	if !ok {
+		return
+	}
+	copied := *entity
+	result = &copied
+	return
+}
+
+func (o *memoryOps) InsertBranchTxCompensationFailLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	t := o.tables
+	if _, ok := t.compensationFailLogs[jobId]; ok {
+		// 和sql实现的job_id唯一索引一致
+		err = fmt.Errorf("duplicate compensation fail log job id %s", jobId)
+		return
+	}
+	now := nowTime()
+	entity := &BranchTxCompensationFailLogEntity{
+		Id:         o.nextId(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Xid:        xid,
+		BranchTxId: branchTxId,
+		JobId:      jobId,
+		Reason:     reason,
+	}
+	t.compensationFailLogs[jobId] = entity
+	o.addUndo(func() {
+		delete(t.compensationFailLogs, jobId)
+	})
+	recordId = entity.Id
+	return
+}
+
+func (o *memoryOps) FindBranchTxForwardRetryLogByJobId(ctx context.Context,
+	jobId string) (result *BranchTxForwardRetryLogEntity, err error) {
+	entity, ok := o.tables.forwardRetryLogs[jobId]
+	if !ok {
+		return
+	}
+	copied := *entity
+	result = &copied
+	return
+}
+
+func (o *memoryOps) InsertBranchTxForwardRetryLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	t := o.tables
+	if _, ok := t.forwardRetryLogs[jobId]; ok {
+		err = fmt.Errorf("duplicate forward retry log job id %s", jobId)
+		return
+	}
+	now := nowTime()
+	entity := &BranchTxForwardRetryLogEntity{
+		Id:         o.nextId(),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		Xid:        xid,
+		BranchTxId: branchTxId,
+		JobId:      jobId,
+		Reason:     reason,
+	}
+	t.forwardRetryLogs[jobId] = entity
+	o.addUndo(func() {
+		delete(t.forwardRetryLogs, jobId)
+	})
+	recordId = entity.Id
+	return
+}
+
+func (o *memoryOps) InsertTxLog(ctx context.Context, record *TxLogEntity) (recordId uint64, err error) {
+	t := o.tables
+	entity := *record
+	entity.Id = o.nextId()
+	entity.CreatedAt = nowTime()
+	entity.UpdatedAt = entity.CreatedAt
+	t.txLogs = append(t.txLogs, &entity)
+	o.addUndo(func() {
+		t.txLogs = t.txLogs[:len(t.txLogs)-1]
+	})
+	recordId = entity.Id
+	return
+}
+
+func (o *memoryOps) FindTxLogsByXid(ctx context.Context, xid string) (result []*TxLogEntity, err error) {
+	for _, entity := range o.tables.txLogs {
+		if entity.Xid == xid {
+			copied := *entity
+			result = append(result, &copied)
+		}
+	}
+	return
+}
+
+func (o *memoryOps) modifySagaData(entity *SagaDataEntity, data []byte) {
+	old := *entity
+	o.addUndo(func() {
+		*entity = old
+	})
+	entity.Data = data
+	entity.Version++
+	entity.UpdatedAt = nowTime()
+}
+
+func (o *memoryOps) InsertSagaData(ctx context.Context, xid string, data []byte) (rowsAffected int64, err error) {
+	t := o.tables
+	if entity, ok := t.sagaData[xid]; ok {
+		o.modifySagaData(entity, data)
+		rowsAffected = 1
+		return
+	}
+	now := nowTime()
+	t.sagaData[xid] = &SagaDataEntity{
+		Id:        o.nextId(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Xid:       xid,
+		Data:      data,
+		Version:   0,
+	}
+	o.addUndo(func() {
+		delete(t.sagaData, xid)
+	})
+	rowsAffected = 1
+	return
+}
+
+func (o *memoryOps) UpdateSagaData(ctx context.Context,
+	xid string, data []byte, oldVersion int32) (rowsAffected int64, err error) {
+	entity, ok := o.tables.sagaData[xid]
+	if !ok || entity.Version != oldVersion {
+		return
+	}
+	o.modifySagaData(entity, data)
+	rowsAffected = 1
+	return
+}
+
+func (o *memoryOps) QuerySagaData(ctx context.Context, xid string) (record *SagaDataEntity, err error) {
+	entity, ok := o.tables.sagaData[xid]
+	if !ok {
+		return
+	}
+	copied := *entity
+	record = &copied
+	return
+}
+
+/**
+ * 基于内存的Store实现，并发安全，用于测试和演示，进程退出后数据丢失.
+ * 事务持有整个存储的锁直到Commit或Rollback，所以事务之间是串行的
+ */
+type MemoryStore struct {
+	mu     sync.Mutex
+	tables *memoryTables
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tables: newMemoryTables(),
+	}
+}
+
+/**
+ * 在锁内执行单个操作
+ */
+func (s *MemoryStore) withLock(f func(ops *memoryOps)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	f(&memoryOps{tables: s.tables})
+}
+
+func (s *MemoryStore) BeginTx(ctx context.Context) (StoreTx, error) {
+	s.mu.Lock()
+	tx := &memoryStoreTx{
+		store: s,
+	}
+	tx.memoryOps = memoryOps{tables: s.tables, undoLogs: &tx.undoLogs}
+	return tx, nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
+
+func (s *MemoryStore) CreateGlobalTx(ctx context.Context, record *GlobalTxEntity) (xid string, err error) {
+	s.withLock(func(ops *memoryOps) {
+		xid, err = ops.CreateGlobalTx(ctx, record)
+	})
+	return
+}
+
+func (s *MemoryStore) FindGlobalTxByXidOrNull(ctx context.Context, xid string) (result *GlobalTxEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindGlobalTxByXidOrNull(ctx, xid)
+	})
+	return
+}
+
+func (s *MemoryStore) FindXidsOfGlobalTxsByStates(ctx context.Context,
+	states []api.TxState, now time.Time, limit int32) (result []string, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindXidsOfGlobalTxsByStates(ctx, states, now, limit)
+	})
+	return
+}
+
+func (s *MemoryStore) FindGlobalTxsByStates(ctx context.Context, states []api.TxState,
+	afterId uint64, limit int32) (result []*GlobalTxEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindGlobalTxsByStates(ctx, states, afterId, limit)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateGlobalTxState(ctx context.Context, xid string,
+	oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateGlobalTxState(ctx, xid, oldVersion, oldState, state)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateGlobalTxEndBranches(ctx context.Context,
+	xid string, oldVersion int32) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateGlobalTxEndBranches(ctx, xid, oldVersion)
+	})
+	return
+}
+
+func (s *MemoryStore) CreateBranchTx(ctx context.Context, record *BranchTxEntity) (branchTxId string, err error) {
+	s.withLock(func(ops *memoryOps) {
+		branchTxId, err = ops.CreateBranchTx(ctx, record)
+	})
+	return
+}
+
+func (s *MemoryStore) FindAllBranchTxsByXid(ctx context.Context, xid string) (result []*BranchTxEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindAllBranchTxsByXid(ctx, xid)
+	})
+	return
+}
+
+func (s *MemoryStore) FindBranchTxByBranchTxId(ctx context.Context,
+	branchTxId string) (result *BranchTxEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindBranchTxByBranchTxId(ctx, branchTxId)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchTxState(ctx context.Context, xid string,
+	branchTxId string, oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateBranchTxState(ctx, xid, branchTxId, oldVersion, oldState, state)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateLeasedBranchTxState(ctx context.Context, xid string, branchTxId string, oldVersion int32,
+	oldState int, state int, leaseId string, now time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateLeasedBranchTxState(ctx, xid, branchTxId, oldVersion, oldState, state, leaseId, now)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchTxCompensationFailTimes(ctx context.Context,
+	id uint64, oldVersion int32, failTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateBranchTxCompensationFailTimes(ctx, id, oldVersion, failTimes, nextRetryAt)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchTxForwardRetryTimes(ctx context.Context,
+	id uint64, oldVersion int32, retryTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateBranchTxForwardRetryTimes(ctx, id, oldVersion, retryTimes, nextRetryAt)
+	})
+	return
+}
+
+func (s *MemoryStore) ClaimBranchTxLease(ctx context.Context, branchTxId string, leaseId string, leaseOwner string,
+	leaseExpireAt time.Time, now time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.ClaimBranchTxLease(ctx, branchTxId, leaseId, leaseOwner, leaseExpireAt, now)
+	})
+	return
+}
+
+func (s *MemoryStore) RenewBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string, leaseExpireAt time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.RenewBranchTxLease(ctx, branchTxId, leaseId, leaseExpireAt)
+	})
+	return
+}
+
+func (s *MemoryStore) ReleaseBranchTxLease(ctx context.Context,
+	branchTxId string, leaseId string) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.ReleaseBranchTxLease(ctx, branchTxId, leaseId)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchTxHeartbeat(ctx context.Context,
+	branchTxId string, heartbeatAt time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateBranchTxHeartbeat(ctx, branchTxId, heartbeatAt)
+	})
+	return
+}
+
+func (s *MemoryStore) FindXidsOfBranchTxsDueBetween(ctx context.Context,
+	after time.Time, upTo time.Time) (result []string, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindXidsOfBranchTxsDueBetween(ctx, after, upTo)
+	})
+	return
+}
+
+func (s *MemoryStore) MarkBranchTxOrphaned(ctx context.Context, branchTxId string,
+	heartbeatBefore time.Time, orphanedAt time.Time) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.MarkBranchTxOrphaned(ctx, branchTxId, heartbeatBefore, orphanedAt)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchesStateByXid(ctx context.Context,
+	xid string, state int) (rowsChanged int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsChanged, err = ops.UpdateBranchesStateByXid(ctx, xid, state)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateBranchTxsByXidFromStateToState(ctx context.Context,
+	xid string, oldState int, newState int) (rowsAffected int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsAffected, err = ops.UpdateBranchTxsByXidFromStateToState(ctx, xid, oldState, newState)
+	})
+	return
+}
+
+func (s *MemoryStore) FindBranchTxCompensationFailLogByJobId(ctx context.Context,
+	jobId string) (result *BranchTxCompensationFailLogEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindBranchTxCompensationFailLogByJobId(ctx, jobId)
+	})
+	return
+}
+
+func (s *MemoryStore) InsertBranchTxCompensationFailLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		recordId, err = ops.InsertBranchTxCompensationFailLog(ctx, xid, branchTxId, jobId, reason)
+	})
+	return
+}
+
+func (s *MemoryStore) FindBranchTxForwardRetryLogByJobId(ctx context.Context,
+	jobId string) (result *BranchTxForwardRetryLogEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindBranchTxForwardRetryLogByJobId(ctx, jobId)
+	})
+	return
+}
+
+func (s *MemoryStore) InsertBranchTxForwardRetryLog(ctx context.Context,
+	xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		recordId, err = ops.InsertBranchTxForwardRetryLog(ctx, xid, branchTxId, jobId, reason)
+	})
+	return
+}
+
+func (s *MemoryStore) InsertTxLog(ctx context.Context, record *TxLogEntity) (recordId uint64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		recordId, err = ops.InsertTxLog(ctx, record)
+	})
+	return
+}
+
+func (s *MemoryStore) FindTxLogsByXid(ctx context.Context, xid string) (result []*TxLogEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		result, err = ops.FindTxLogsByXid(ctx, xid)
+	})
+	return
+}
+
+func (s *MemoryStore) InsertSagaData(ctx context.Context, xid string, data []byte) (rowsAffected int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsAffected, err = ops.InsertSagaData(ctx, xid, data)
+	})
+	return
+}
+
+func (s *MemoryStore) UpdateSagaData(ctx context.Context,
+	xid string, data []byte, oldVersion int32) (rowsAffected int64, err error) {
+	s.withLock(func(ops *memoryOps) {
+		rowsAffected, err = ops.UpdateSagaData(ctx, xid, data, oldVersion)
+	})
+	return
+}
+
+func (s *MemoryStore) QuerySagaData(ctx context.Context, xid string) (record *SagaDataEntity, err error) {
+	s.withLock(func(ops *memoryOps) {
+		record, err = ops.QuerySagaData(ctx, xid)
+	})
+	return
+}
+
+/**
+ * MemoryStore上的事务，从BeginTx到Commit/Rollback一直持有存储的锁
+ */
+type memoryStoreTx struct {
+	memoryOps
+	store    *MemoryStore
+	undoLogs []func()
+	finished bool
+}
+
+var errMemoryTxFinished = errors.New("memory store tx already finished")
+
+func (t *memoryStoreTx) finish() error {
+	if t.finished {
+		return errMemoryTxFinished
+	}
+	t.finished = true
+	t.undoLogs = nil
+	t.store.mu.Unlock()
+	return nil
+}
+
+func (t *memoryStoreTx) Commit() error {
+	return t.finish()
+}
+
+func (t *memoryStoreTx) Rollback() error {
+	if t.finished {
+		return errMemoryTxFinished
+	}
+	for i := len(t.undoLogs) - 1; i >= 0; i-- {
+		t.undoLogs[i]()
+	}
+	return t.finish()
+}
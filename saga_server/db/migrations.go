@@ -0,0 +1,639 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+)
+
+/**
+ * 一个版本的表结构变更，各数据库分别提供要执行的sql语句. 某个数据库不需要变更时语句为空，但版本仍然会被记录
+ * mysql和sqlite的ADD COLUMN不支持IF NOT EXISTS，每条ALTER TABLE语句只能添加一列，执行前会检查列是否已经存在
+ * mysql的ADD INDEX也一样，每条ALTER TABLE语句只能添加一个索引
+ */
+type migration struct {
+	version  int
+	name     string
+	mysql    []string
+	sqlite   []string
+	postgres []string
+}
+
+func (m *migration) statementsOf(dialect *sqlDialect) []string {
+	switch dialect {
+	case mysqlDialect:
+		return m.mysql
+	case sqliteDialect:
+		return m.sqlite
+	case postgresDialect:
+		return m.postgres
+	}
+	return nil
+}
+
+/**
+ * 支持迁移表结构的Store实现
+ */
+type Migrator interface {
+	// 执行所有还没执行的migrations，返回本次执行的版本号
+	Migrate(ctx context.Context) (appliedVersions []int, err error)
+}
+
+/**
+ * 记录已执行migrations的表
+ */
+const schemaMigrationsTableSql = "create table if not exists schema_migrations (" +
+	" version int not null primary key," +
+	" name varchar(255) not null," +
+	" applied_at timestamp not null default CURRENT_TIMESTAMP)"
+
+/**
+ * 按版本号顺序执行db上还没执行的migrations
+ */
+func migrateSqlDb(ctx context.Context, db *sql.DB, dialect *sqlDialect) (appliedVersions []int, err error) {
+	daos := &sqlDaos{exec: db, dialect: dialect}
+	_, err = daos.execContext(ctx, schemaMigrationsTableSql)
+	if err != nil {
+		return
+	}
+	existedVersions := make(map[int]bool)
+	rows, err := daos.queryContext(ctx, "select version from schema_migrations")
+	if err != nil {
+		return
+	}
+	for rows.Next() {
+		var version int
+		err = rows.Scan(&version)
+		if err != nil {
+			_ = rows.Close()
+			return
+		}
+		existedVersions[version] = true
+	}
+	_ = rows.Close()
+	if err = rows.Err(); err != nil {
+		return
+	}
+	for _, m := range migrations {
+		if existedVersions[m.version] {
+			continue
+		}
+		err = applyMigration(ctx, db, dialect, m)
+		if err != nil {
+			err = fmt.Errorf("apply migration %d %s error: %s", m.version, m.name, err.Error())
+			return
+		}
+		log.Printf("applied %s migration %d %s\n", dialect.name, m.version, m.name)
+		appliedVersions = append(appliedVersions, m.version)
+	}
+	return
+}
+
+// 只添加一列的ALTER TABLE语句，匹配表名和列名
+var addColumnStatementPattern = regexp.MustCompile("(?i)^\\s*ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+ADD\\s+COLUMN\\s+`?(\\w+)`?\\s")
+
+/**
+ * statement是给表添加一列并且这一列已经存在时返回true
+ * 用参考sql建的库或者手动加过列的库没有对应的schema_migrations记录，再次执行migrations时跳过这些列
+ */
+func isAddedColumnExisted(ctx context.Context, daos *sqlDaos, statement string) (existed bool, err error) {
+	if len(daos.dialect.columnExistsSql) < 1 {
+		return
+	}
+	matches := addColumnStatementPattern.FindStringSubmatch(statement)
+	if matches == nil {
+		return
+	}
+	var count int
+	err = daos.queryRowContext(ctx, daos.dialect.columnExistsSql, matches[1], matches[2]).Scan(&count)
+	if err != nil {
+		return
+	}
+	existed = count > 0
+	return
+}
+
+var addIndexStatementPattern = regexp.MustCompile("(?i)^\\s*ALTER\\s+TABLE\\s+`?(\\w+)`?\\s+ADD\\s+INDEX\\s+`?(\\w+)`?\\s")
+
+/**
+ * statement是给表添加一个索引并且这个索引已经存在时返回true，和isAddedColumnExisted相同
+ */
+func isAddedIndexExisted(ctx context.Context, daos *sqlDaos, statement string) (existed bool, err error) {
+	if len(daos.dialect.indexExistsSql) < 1 {
+		return
+	}
+	matches := addIndexStatementPattern.FindStringSubmatch(statement)
+	if matches == nil {
+		return
+	}
+	var count int
+	err = daos.queryRowContext(ctx, daos.dialect.indexExistsSql, matches[1], matches[2]).Scan(&count)
+	if err != nil {
+		return
+	}
+	existed = count > 0
+	return
+}
+
+func applyMigration(ctx context.Context, db *sql.DB, dialect *sqlDialect, m *migration) (err error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	daos := &sqlDaos{exec: tx, dialect: dialect}
+	for _, statement := range m.statementsOf(dialect) {
+		var existed bool
+		existed, err = isAddedColumnExisted(ctx, daos, statement)
+		if err != nil {
+			return
+		}
+		if !existed {
+			existed, err = isAddedIndexExisted(ctx, daos, statement)
+			if err != nil {
+				return
+			}
+		}
+		if existed {
+			continue
+		}
+		_, err = daos.execContext(ctx, statement)
+		if err != nil {
+			return
+		}
+	}
+	_, err = daos.execContext(ctx, "insert into schema_migrations (version, name) values (?, ?)",
+		m.version, m.name)
+	return
+}
+
+/**
+ * 所有的表结构变更，按版本号升序排列，已发布的migration不能再修改
+ */
+var migrations = []*migration{
+	{
+		version: 1,
+		name:    "init tables",
+		mysql: []string{
+			"CREATE TABLE IF NOT EXISTS `global_tx` (\n" +
+				"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `xid` varchar(50) NOT NULL,\n" +
+				"  `state` int(11) NOT NULL,\n" +
+				"  `version` int(11) NOT NULL,\n" +
+				"  `creator_group` varchar(100) DEFAULT NULL,\n" +
+				"  `creator_service` varchar(100) DEFAULT NULL,\n" +
+				"  `creator_instance_id` varchar(100) DEFAULT NULL,\n" +
+				"  `expire_seconds` int(11) NOT NULL,\n" +
+				"  `extra` text,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `global_tx_index_xid` (`xid`),\n" +
+				"  KEY `global_tx_index_creator_group_creator_service` (`creator_group`,`creator_service`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE IF NOT EXISTS `branch_tx` (\n" +
+				"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `branch_tx_id` varchar(50) NOT NULL,\n" +
+				"  `xid` varchar(50) NOT NULL,\n" +
+				"  `state` int(11) NOT NULL,\n" +
+				"  `version` int(11) NOT NULL,\n" +
+				"  `compensation_fail_times` int(11) NOT NULL,\n" +
+				"  `node_group` varchar(100) DEFAULT NULL,\n" +
+				"  `node_service` varchar(100) DEFAULT NULL,\n" +
+				"  `node_instance_id` varchar(100) DEFAULT NULL,\n" +
+				"  `branch_service_key` varchar(255) DEFAULT NULL,\n" +
+				"  `branch_compensation_service_key` varchar(255) DEFAULT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE KEY `branch_tx_idx_branch_tx_id` (`branch_tx_id`),\n" +
+				"  KEY `branch_tx_idx_xid` (`xid`),\n" +
+				"  KEY `branch_tx_node_group_node_service` (`node_group`,`node_service`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE IF NOT EXISTS `branch_tx_compensation_fail_log` (\n" +
+				"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `xid` varchar(50) NOT NULL,\n" +
+				"  `branch_tx_id` varchar(50) DEFAULT NULL,\n" +
+				"  `job_id` varchar(50) NOT NULL,\n" +
+				"  `reason` text NULL DEFAULT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  KEY `branch_tx_compensation_fail_log_idx_xid_branch_tx_id` (`xid`, `branch_tx_id`),\n" +
+				"  KEY `branch_tx_compensation_fail_log_idx_branch_tx_id` (`branch_tx_id`),\n" +
+				"  UNIQUE KEY `branch_tx_compensation_fail_log_idx_job_id` (`job_id`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE IF NOT EXISTS `tx_log` (\n" +
+				"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `xid` varchar(50) NOT NULL,\n" +
+				"  `branch_tx_id` varchar(50) DEFAULT NULL,\n" +
+				"  `operator_group` varchar(100) DEFAULT NULL,\n" +
+				"  `operator_service` varchar(100) DEFAULT NULL,\n" +
+				"  `operator_instance_id` varchar(100) DEFAULT NULL,\n" +
+				"  `log_type` varchar(45) DEFAULT NULL,\n" +
+				"  `log_params` text,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  KEY `tx_log_idx_xid_branch_x_id` (`xid`,`branch_tx_id`),\n" +
+				"  KEY `tx_log_idx_branch_tx_id` (`branch_tx_id`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+			"CREATE TABLE IF NOT EXISTS `saga_data` (\n" +
+				"  `id` BIGINT NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `xid` VARCHAR(45) NOT NULL,\n" +
+				"  `data` BLOB NULL,\n" +
+				"  `version` int(11) NOT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  UNIQUE INDEX `saga_data_unique_idx_xid` (`xid` ASC)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		sqlite: []string{
+			`CREATE TABLE IF NOT EXISTS global_tx (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid VARCHAR(50) NOT NULL,
+  state INTEGER NOT NULL,
+  end_branches BOOLEAN NOT NULL DEFAULT 0,
+  version INTEGER NOT NULL,
+  creator_group VARCHAR(100) DEFAULT NULL,
+  creator_service VARCHAR(100) DEFAULT NULL,
+  creator_instance_id VARCHAR(100) DEFAULT NULL,
+  expire_seconds INTEGER NOT NULL,
+  extra TEXT
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS global_tx_index_xid ON global_tx (xid)`,
+			`CREATE INDEX IF NOT EXISTS global_tx_index_creator_group_creator_service ON global_tx (creator_group, creator_service)`,
+			`CREATE TRIGGER IF NOT EXISTS global_tx_updated_at AFTER UPDATE ON global_tx
+BEGIN
+  UPDATE global_tx SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END`,
+			`CREATE TABLE IF NOT EXISTS branch_tx (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  branch_tx_id VARCHAR(50) NOT NULL,
+  xid VARCHAR(50) NOT NULL,
+  state INTEGER NOT NULL,
+  version INTEGER NOT NULL,
+  compensation_fail_times INTEGER NOT NULL,
+  node_group VARCHAR(100) DEFAULT NULL,
+  node_service VARCHAR(100) DEFAULT NULL,
+  node_instance_id VARCHAR(100) DEFAULT NULL,
+  branch_service_key VARCHAR(255) DEFAULT NULL,
+  branch_compensation_service_key VARCHAR(255) DEFAULT NULL
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_idx_branch_tx_id ON branch_tx (branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_xid ON branch_tx (xid)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_node_group_node_service ON branch_tx (node_group, node_service)`,
+			`CREATE TRIGGER IF NOT EXISTS branch_tx_updated_at AFTER UPDATE ON branch_tx
+BEGIN
+  UPDATE branch_tx SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END`,
+			`CREATE TABLE IF NOT EXISTS branch_tx_compensation_fail_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid VARCHAR(50) NOT NULL,
+  branch_tx_id VARCHAR(50) DEFAULT NULL,
+  job_id VARCHAR(50) NOT NULL,
+  reason TEXT DEFAULT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_xid_branch_tx_id ON branch_tx_compensation_fail_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_branch_tx_id ON branch_tx_compensation_fail_log (branch_tx_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_job_id ON branch_tx_compensation_fail_log (job_id)`,
+			`CREATE TABLE IF NOT EXISTS tx_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid VARCHAR(50) NOT NULL,
+  branch_tx_id VARCHAR(50) DEFAULT NULL,
+  operator_group VARCHAR(100) DEFAULT NULL,
+  operator_service VARCHAR(100) DEFAULT NULL,
+  operator_instance_id VARCHAR(100) DEFAULT NULL,
+  log_type VARCHAR(45) DEFAULT NULL,
+  log_params TEXT
+)`,
+			`CREATE INDEX IF NOT EXISTS tx_log_idx_xid_branch_x_id ON tx_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS tx_log_idx_branch_tx_id ON tx_log (branch_tx_id)`,
+			`CREATE TABLE IF NOT EXISTS saga_data (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid VARCHAR(45) NOT NULL,
+  data BLOB NULL,
+  version INTEGER NOT NULL
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS saga_data_unique_idx_xid ON saga_data (xid)`,
+			`CREATE TRIGGER IF NOT EXISTS saga_data_updated_at AFTER UPDATE ON saga_data
+BEGIN
+  UPDATE saga_data SET updated_at = CURRENT_TIMESTAMP WHERE id = NEW.id;
+END`,
+		},
+		postgres: []string{
+			`CREATE OR REPLACE FUNCTION saga_set_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`,
+			`CREATE TABLE IF NOT EXISTS global_tx (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  state int NOT NULL,
+  end_branches boolean NOT NULL DEFAULT false,
+  version int NOT NULL,
+  creator_group varchar(100) DEFAULT NULL,
+  creator_service varchar(100) DEFAULT NULL,
+  creator_instance_id varchar(100) DEFAULT NULL,
+  expire_seconds int NOT NULL,
+  extra text
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS global_tx_index_xid ON global_tx (xid)`,
+			`CREATE INDEX IF NOT EXISTS global_tx_index_creator_group_creator_service ON global_tx (creator_group, creator_service)`,
+			`DROP TRIGGER IF EXISTS global_tx_updated_at ON global_tx`,
+			`CREATE TRIGGER global_tx_updated_at BEFORE UPDATE ON global_tx
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+			`CREATE TABLE IF NOT EXISTS branch_tx (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  branch_tx_id varchar(50) NOT NULL,
+  xid varchar(50) NOT NULL,
+  state int NOT NULL,
+  version int NOT NULL,
+  compensation_fail_times int NOT NULL,
+  node_group varchar(100) DEFAULT NULL,
+  node_service varchar(100) DEFAULT NULL,
+  node_instance_id varchar(100) DEFAULT NULL,
+  branch_service_key varchar(255) DEFAULT NULL,
+  branch_compensation_service_key varchar(255) DEFAULT NULL
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_idx_branch_tx_id ON branch_tx (branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_xid ON branch_tx (xid)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_node_group_node_service ON branch_tx (node_group, node_service)`,
+			`DROP TRIGGER IF EXISTS branch_tx_updated_at ON branch_tx`,
+			`CREATE TRIGGER branch_tx_updated_at BEFORE UPDATE ON branch_tx
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+			`CREATE TABLE IF NOT EXISTS branch_tx_compensation_fail_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  job_id varchar(50) NOT NULL,
+  reason text NULL DEFAULT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_xid_branch_tx_id ON branch_tx_compensation_fail_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_branch_tx_id ON branch_tx_compensation_fail_log (branch_tx_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_compensation_fail_log_idx_job_id ON branch_tx_compensation_fail_log (job_id)`,
+			`DROP TRIGGER IF EXISTS branch_tx_compensation_fail_log_updated_at ON branch_tx_compensation_fail_log`,
+			`CREATE TRIGGER branch_tx_compensation_fail_log_updated_at BEFORE UPDATE ON branch_tx_compensation_fail_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+			`CREATE TABLE IF NOT EXISTS tx_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  operator_group varchar(100) DEFAULT NULL,
+  operator_service varchar(100) DEFAULT NULL,
+  operator_instance_id varchar(100) DEFAULT NULL,
+  log_type varchar(45) DEFAULT NULL,
+  log_params text
+)`,
+			`CREATE INDEX IF NOT EXISTS tx_log_idx_xid_branch_x_id ON tx_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS tx_log_idx_branch_tx_id ON tx_log (branch_tx_id)`,
+			`DROP TRIGGER IF EXISTS tx_log_updated_at ON tx_log`,
+			`CREATE TRIGGER tx_log_updated_at BEFORE UPDATE ON tx_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+			`CREATE TABLE IF NOT EXISTS saga_data (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(45) NOT NULL,
+  data bytea NULL,
+  version int NOT NULL
+)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS saga_data_unique_idx_xid ON saga_data (xid)`,
+			`DROP TRIGGER IF EXISTS saga_data_updated_at ON saga_data`,
+			`CREATE TRIGGER saga_data_updated_at BEFORE UPDATE ON saga_data
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+		},
+	},
+	{
+		// 早期的mysql建表语句缺少global_tx.end_branches，sqlite和postgres的建表语句中已经包含
+		version: 2,
+		name:    "add global_tx.end_branches",
+		mysql: []string{
+			"ALTER TABLE `global_tx` ADD COLUMN `end_branches` tinyint(1) NOT NULL DEFAULT 0 AFTER `state`",
+		},
+	},
+	{
+		version: 3,
+		name:    "add compensation retry policy",
+		mysql: []string{
+			"ALTER TABLE `global_tx` ADD COLUMN `retry_max_attempts` int(11) NOT NULL DEFAULT 0",
+			"ALTER TABLE `global_tx` ADD COLUMN `retry_initial_delay_ms` bigint(20) NOT NULL DEFAULT 0",
+			"ALTER TABLE `global_tx` ADD COLUMN `retry_multiplier` double NOT NULL DEFAULT 0",
+			"ALTER TABLE `global_tx` ADD COLUMN `retry_max_delay_ms` bigint(20) NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `retry_max_attempts` int(11) NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `retry_initial_delay_ms` bigint(20) NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `retry_multiplier` double NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `retry_max_delay_ms` bigint(20) NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `next_retry_at` timestamp NULL DEFAULT NULL",
+		},
+		sqlite: []string{
+			"ALTER TABLE global_tx ADD COLUMN retry_max_attempts INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE global_tx ADD COLUMN retry_initial_delay_ms INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE global_tx ADD COLUMN retry_multiplier REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE global_tx ADD COLUMN retry_max_delay_ms INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN retry_max_attempts INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN retry_initial_delay_ms INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN retry_multiplier REAL NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN retry_max_delay_ms INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN next_retry_at TIMESTAMP NULL DEFAULT NULL",
+		},
+		postgres: []string{
+			"ALTER TABLE global_tx" +
+				" ADD COLUMN IF NOT EXISTS retry_max_attempts integer NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_initial_delay_ms bigint NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_multiplier double precision NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_max_delay_ms bigint NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx" +
+				" ADD COLUMN IF NOT EXISTS retry_max_attempts integer NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_initial_delay_ms bigint NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_multiplier double precision NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS retry_max_delay_ms bigint NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS next_retry_at timestamp NULL DEFAULT NULL",
+		},
+	},
+	{
+		version: 4,
+		name:    "add branch_tx.parent_branch_tx_id",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD COLUMN `parent_branch_tx_id` varchar(50) NOT NULL DEFAULT '' AFTER `xid`",
+		},
+		sqlite: []string{
+			"ALTER TABLE branch_tx ADD COLUMN parent_branch_tx_id varchar(50) NOT NULL DEFAULT ''",
+		},
+		postgres: []string{
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS parent_branch_tx_id varchar(50) NOT NULL DEFAULT ''",
+		},
+	},
+	{
+		version: 5,
+		name:    "add branch_tx.branch_group",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD COLUMN `branch_group` varchar(100) NOT NULL DEFAULT '' AFTER `parent_branch_tx_id`",
+			"ALTER TABLE `branch_tx` ADD COLUMN `branch_group_size` int(11) NOT NULL DEFAULT 0 AFTER `branch_group`",
+		},
+		sqlite: []string{
+			"ALTER TABLE branch_tx ADD COLUMN branch_group varchar(100) NOT NULL DEFAULT ''",
+			"ALTER TABLE branch_tx ADD COLUMN branch_group_size INTEGER NOT NULL DEFAULT 0",
+		},
+		postgres: []string{
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS branch_group varchar(100) NOT NULL DEFAULT ''," +
+				" ADD COLUMN IF NOT EXISTS branch_group_size integer NOT NULL DEFAULT 0",
+		},
+	},
+	{
+		version: 6,
+		name:    "add branch_tx.recovery_mode and branch_tx_forward_retry_log",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD COLUMN `recovery_mode` int(11) NOT NULL DEFAULT 0 AFTER `branch_group_size`",
+			"ALTER TABLE `branch_tx` ADD COLUMN `forward_retry_times` int(11) NOT NULL DEFAULT 0 AFTER `compensation_fail_times`",
+			"CREATE TABLE IF NOT EXISTS `branch_tx_forward_retry_log` (\n" +
+				"  `id` bigint(20) NOT NULL AUTO_INCREMENT,\n" +
+				"  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,\n" +
+				"  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,\n" +
+				"  `xid` varchar(50) NOT NULL,\n" +
+				"  `branch_tx_id` varchar(50) DEFAULT NULL,\n" +
+				"  `job_id` varchar(50) NOT NULL,\n" +
+				"  `reason` text NULL DEFAULT NULL,\n" +
+				"  PRIMARY KEY (`id`),\n" +
+				"  KEY `branch_tx_forward_retry_log_idx_xid_branch_tx_id` (`xid`, `branch_tx_id`),\n" +
+				"  KEY `branch_tx_forward_retry_log_idx_branch_tx_id` (`branch_tx_id`),\n" +
+				"  UNIQUE KEY `branch_tx_forward_retry_log_idx_job_id` (`job_id`)\n" +
+				") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4",
+		},
+		sqlite: []string{
+			"ALTER TABLE branch_tx ADD COLUMN recovery_mode INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN forward_retry_times INTEGER NOT NULL DEFAULT 0",
+			`CREATE TABLE IF NOT EXISTS branch_tx_forward_retry_log (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid VARCHAR(50) NOT NULL,
+  branch_tx_id VARCHAR(50) DEFAULT NULL,
+  job_id VARCHAR(50) NOT NULL,
+  reason TEXT DEFAULT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_xid_branch_tx_id ON branch_tx_forward_retry_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_branch_tx_id ON branch_tx_forward_retry_log (branch_tx_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_job_id ON branch_tx_forward_retry_log (job_id)`,
+		},
+		postgres: []string{
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS recovery_mode integer NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS forward_retry_times integer NOT NULL DEFAULT 0",
+			`CREATE TABLE IF NOT EXISTS branch_tx_forward_retry_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  job_id varchar(50) NOT NULL,
+  reason text NULL DEFAULT NULL
+)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_xid_branch_tx_id ON branch_tx_forward_retry_log (xid, branch_tx_id)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_branch_tx_id ON branch_tx_forward_retry_log (branch_tx_id)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS branch_tx_forward_retry_log_idx_job_id ON branch_tx_forward_retry_log (job_id)`,
+			`DROP TRIGGER IF EXISTS branch_tx_forward_retry_log_updated_at ON branch_tx_forward_retry_log`,
+			`CREATE TRIGGER branch_tx_forward_retry_log_updated_at BEFORE UPDATE ON branch_tx_forward_retry_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at()`,
+		},
+	},
+	{
+		version: 7,
+		name:    "add global_tx.mode and branch_tx.branch_confirm_service_key",
+		mysql: []string{
+			"ALTER TABLE `global_tx` ADD COLUMN `mode` int(11) NOT NULL DEFAULT 0 AFTER `end_branches`",
+			"ALTER TABLE `branch_tx` ADD COLUMN `branch_confirm_service_key` varchar(255) NOT NULL DEFAULT ''" +
+				" AFTER `branch_compensation_service_key`",
+		},
+		sqlite: []string{
+			"ALTER TABLE global_tx ADD COLUMN mode INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN branch_confirm_service_key varchar(255) NOT NULL DEFAULT ''",
+		},
+		postgres: []string{
+			"ALTER TABLE global_tx ADD COLUMN IF NOT EXISTS mode integer NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS branch_confirm_service_key varchar(255) NOT NULL DEFAULT ''",
+		},
+	},
+	{
+		version: 8,
+		name:    "add branch_tx lease columns",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD COLUMN `lease_id` varchar(50) NOT NULL DEFAULT ''",
+			"ALTER TABLE `branch_tx` ADD COLUMN `lease_owner` varchar(100) NOT NULL DEFAULT ''",
+			"ALTER TABLE `branch_tx` ADD COLUMN `lease_expire_at` timestamp NULL DEFAULT NULL",
+		},
+		sqlite: []string{
+			"ALTER TABLE branch_tx ADD COLUMN lease_id VARCHAR(50) NOT NULL DEFAULT ''",
+			"ALTER TABLE branch_tx ADD COLUMN lease_owner VARCHAR(100) NOT NULL DEFAULT ''",
+			"ALTER TABLE branch_tx ADD COLUMN lease_expire_at TIMESTAMP NULL DEFAULT NULL",
+		},
+		postgres: []string{
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS lease_id varchar(50) NOT NULL DEFAULT ''," +
+				" ADD COLUMN IF NOT EXISTS lease_owner varchar(100) NOT NULL DEFAULT ''," +
+				" ADD COLUMN IF NOT EXISTS lease_expire_at timestamp NULL DEFAULT NULL",
+		},
+	},
+	{
+		version: 9,
+		name:    "add branch_tx heartbeat columns",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD COLUMN `heartbeat_timeout_seconds` int(11) NOT NULL DEFAULT 0",
+			"ALTER TABLE `branch_tx` ADD COLUMN `last_heartbeat_at` timestamp NULL DEFAULT NULL",
+			"ALTER TABLE `branch_tx` ADD COLUMN `orphaned_at` timestamp NULL DEFAULT NULL",
+		},
+		sqlite: []string{
+			"ALTER TABLE branch_tx ADD COLUMN heartbeat_timeout_seconds INTEGER NOT NULL DEFAULT 0",
+			"ALTER TABLE branch_tx ADD COLUMN last_heartbeat_at TIMESTAMP NULL DEFAULT NULL",
+			"ALTER TABLE branch_tx ADD COLUMN orphaned_at TIMESTAMP NULL DEFAULT NULL",
+		},
+		postgres: []string{
+			"ALTER TABLE branch_tx ADD COLUMN IF NOT EXISTS heartbeat_timeout_seconds integer NOT NULL DEFAULT 0," +
+				" ADD COLUMN IF NOT EXISTS last_heartbeat_at timestamp NULL DEFAULT NULL," +
+				" ADD COLUMN IF NOT EXISTS orphaned_at timestamp NULL DEFAULT NULL",
+		},
+	},
+	{
+		version: 10,
+		name:    "add branch_tx next_retry_at and lease_expire_at indexes",
+		mysql: []string{
+			"ALTER TABLE `branch_tx` ADD INDEX `branch_tx_idx_next_retry_at` (`next_retry_at`)",
+			"ALTER TABLE `branch_tx` ADD INDEX `branch_tx_idx_lease_expire_at` (`lease_expire_at`)",
+		},
+		sqlite: []string{
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_next_retry_at ON branch_tx (next_retry_at)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_lease_expire_at ON branch_tx (lease_expire_at)`,
+		},
+		postgres: []string{
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_next_retry_at ON branch_tx (next_retry_at)`,
+			`CREATE INDEX IF NOT EXISTS branch_tx_idx_lease_expire_at ON branch_tx (lease_expire_at)`,
+		},
+	},
+}
@@ -2,6 +2,16 @@ package db
 
 import "time"
 
+/**
+ * 分支事务补偿失败后的重试策略，字段为0表示使用上一级(全局事务或者默认)的配置
+ */
+type RetryPolicy struct {
+	MaxAttempts    int32   // 补偿最多执行的次数，达到后分支标记为补偿失败
+	InitialDelayMs int64   // 第一次补偿失败后等待多少毫秒再重试
+	Multiplier     float64 // 之后每次失败等待时间的增长倍数
+	MaxDelayMs     int64   // 等待时间的上限毫秒数
+}
+
 /**
  * 全局事务
  */
@@ -18,6 +28,8 @@ type GlobalTxEntity struct {
 	CreatorInstanceId string
 	ExpireSeconds int
 	Extra *string
+	RetryPolicy RetryPolicy // 全局事务下各分支的补偿重试策略
+	Mode int // 全局事务模式，0是saga，1是TCC(提交时确认各分支)
 }
 
 /**
@@ -37,6 +49,20 @@ type BranchTxEntity struct {
 	NodeInstanceId string
 	BranchServiceKey string // 分支事务的服务标识，可以找到是分支事务的主体逻辑
 	BranchCompensationServiceKey string // 分支事务的补偿服务标识，可以用来找到分支事务的补偿函数
+	RetryPolicy RetryPolicy // 覆盖全局事务的补偿重试策略
+	NextRetryAt *time.Time // 补偿失败后下次允许重试的时间，为空表示不需要等待
+	ParentBranchTxId string // 上级分支事务ID，为空表示是顶层分支
+	BranchGroup string // 并行执行的分支组，为空表示不属于分支组
+	BranchGroupSize int32 // 分支组的分支总数
+	RecoveryMode int // 分支失败时的恢复方式，0是向后恢复(补偿)，1是向前恢复(重试直到成功)
+	ForwardRetryTimes int32 // 向前恢复的分支执行失败或者TCC分支确认失败的次数
+	BranchConfirmServiceKey string // TCC模式下分支的确认服务标识，为空表示不需要确认
+	LeaseId string // 领取补偿任务的租约ID，为空表示没有被领取
+	LeaseOwner string // 领取补偿任务的参与方实例
+	LeaseExpireAt *time.Time // 租约到期时间，到期后其他参与方可以重新领取
+	HeartbeatTimeoutSeconds int32 // 执行中的分支超过这个时间没有心跳时认为执行方已经崩溃，0表示不检查心跳
+	LastHeartbeatAt *time.Time // 最近一次心跳的时间，创建时就是创建时间
+	OrphanedAt *time.Time // 被标记为失去心跳的时间，为空表示没有被标记
 }
 
 /**
@@ -68,6 +94,16 @@ type BranchTxCompensationFailLogEntity struct {
 	Reason string // 补偿任务失败原因
 }
 
+type BranchTxForwardRetryLogEntity struct {
+	Id uint64
+	CreatedAt *time.Time
+	UpdatedAt *time.Time
+	Xid string
+	BranchTxId string
+	JobId string
+	Reason string // 向前恢复的分支执行失败原因
+}
+
 /**
  * 全局事务中各分支共享的数据
  */
@@ -0,0 +1,67 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+/**
+ * 基于mysql的Store实现
+ */
+type MysqlStore struct {
+	sqlDaos
+	db *sql.DB
+}
+
+func NewMysqlStore(dbUrl string) (store *MysqlStore, err error) {
+	db, err := InitDb(dbUrl)
+	if err != nil {
+		return
+	}
+	store = &MysqlStore{
+		sqlDaos: sqlDaos{exec: db, dialect: mysqlDialect},
+		db:      db,
+	}
+	return
+}
+
+func (s *MysqlStore) BeginTx(ctx context.Context) (StoreTx, error) {
+	return beginSqlStoreTx(ctx, s.db, &s.sqlDaos)
+}
+
+func (s *MysqlStore) Migrate(ctx context.Context) ([]int, error) {
+	return migrateSqlDb(ctx, s.db, mysqlDialect)
+}
+
+func (s *MysqlStore) Close() error {
+	return CloseDb(s.db)
+}
+
+/**
+ * database/sql事务上的StoreTx实现
+ */
+type sqlStoreTx struct {
+	sqlDaos
+	tx *sql.Tx
+}
+
+func beginSqlStoreTx(ctx context.Context, db *sql.DB, daos *sqlDaos) (StoreTx, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	storeTx := &sqlStoreTx{
+		sqlDaos: *daos,
+		tx:      tx,
+	}
+	storeTx.exec = tx
+	return storeTx, nil
+}
+
+func (t *sqlStoreTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *sqlStoreTx) Rollback() error {
+	return t.tx.Rollback()
+}
@@ -0,0 +1,59 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	_ "github.com/mattn/go-sqlite3"
+	"strings"
+)
+
+var sqliteDialect = &sqlDialect{
+	name: "sqlite3",
+	insertSagaDataSql: "insert into saga_data " +
+		" (xid, `data`, `version`) values (?,?,?) " +
+		" on conflict(xid) do update set `data`=excluded.`data`, `version` = `version` + 1",
+	columnExistsSql: "select count(*) from pragma_table_info(?) where name = ?",
+}
+
+/**
+ * 基于sqlite的Store实现，适合没有mysql的开发和CI环境
+ */
+type SqliteStore struct {
+	sqlDaos
+	db *sql.DB
+}
+
+/**
+ * 打开sqlite数据库文件，不存在则创建. 表结构通过Migrate创建
+ */
+func NewSqliteStore(path string) (store *SqliteStore, err error) {
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&_busy_timeout=5000"
+	} else {
+		dsn += "?_busy_timeout=5000"
+	}
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return
+	}
+	// sqlite同一时间只允许一个写连接，限制为单连接避免database is locked错误
+	db.SetMaxOpenConns(1)
+	store = &SqliteStore{
+		sqlDaos: sqlDaos{exec: db, dialect: sqliteDialect},
+		db:      db,
+	}
+	return
+}
+
+func (s *SqliteStore) BeginTx(ctx context.Context) (StoreTx, error) {
+	return beginSqlStoreTx(ctx, s.db, &s.sqlDaos)
+}
+
+func (s *SqliteStore) Migrate(ctx context.Context) ([]int, error) {
+	return migrateSqlDb(ctx, s.db, sqliteDialect)
+}
+
+func (s *SqliteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"context"
+	"github.com/zoowii/saga_server/api"
+	"time"
+)
+
+/**
+ * saga server的存储操作集合，Store和StoreTx都需要实现
+ */
+type StoreOps interface {
+	// 全局事务
+	CreateGlobalTx(ctx context.Context, record *GlobalTxEntity) (xid string, err error)
+	FindGlobalTxByXidOrNull(ctx context.Context, xid string) (result *GlobalTxEntity, err error)
+	// 补偿中的全局事务如果待补偿的分支都还在重试等待中(next_retry_at晚于now)则不返回
+	FindXidsOfGlobalTxsByStates(ctx context.Context, states []api.TxState,
+		now time.Time, limit int32) (result []string, err error)
+	// 按id从小到大查询id大于afterId且状态在states中的全局事务，用于分批扫描
+	FindGlobalTxsByStates(ctx context.Context, states []api.TxState,
+		afterId uint64, limit int32) (result []*GlobalTxEntity, err error)
+	UpdateGlobalTxState(ctx context.Context, xid string,
+		oldVersion int32, oldState int, state int) (rowsChanged int64, err error)
+	// 标记全局事务不再接受新的分支事务
+	UpdateGlobalTxEndBranches(ctx context.Context, xid string, oldVersion int32) (rowsChanged int64, err error)
+
+	// 分支事务
+	CreateBranchTx(ctx context.Context, record *BranchTxEntity) (branchTxId string, err error)
+	FindAllBranchTxsByXid(ctx context.Context, xid string) (result []*BranchTxEntity, err error)
+	FindBranchTxByBranchTxId(ctx context.Context, branchTxId string) (result *BranchTxEntity, err error)
+	// 重试等待(next_retry_at)或者租约(lease_expire_at)在(after, upTo]之间到期的分支所属的xid，不重复
+	FindXidsOfBranchTxsDueBetween(ctx context.Context, after time.Time, upTo time.Time) (result []string, err error)
+	UpdateBranchTxState(ctx context.Context, xid string,
+		branchTxId string, oldVersion int32, oldState int, state int) (rowsChanged int64, err error)
+	// 和UpdateBranchTxState相同，另外只在分支没有租约、租约已经到期(lease_expire_at不晚于now)或者租约是leaseId时修改
+	UpdateLeasedBranchTxState(ctx context.Context, xid string, branchTxId string, oldVersion int32,
+		oldState int, state int, leaseId string, now time.Time) (rowsChanged int64, err error)
+	UpdateBranchTxCompensationFailTimes(ctx context.Context,
+		id uint64, oldVersion int32, failTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error)
+	UpdateBranchTxForwardRetryTimes(ctx context.Context,
+		id uint64, oldVersion int32, retryTimes int32, nextRetryAt *time.Time) (rowsChanged int64, err error)
+	UpdateBranchesStateByXid(ctx context.Context, xid string, state int) (rowsChanged int64, err error)
+	// 补偿、重试和confirm任务的租约，只有待补偿(COMPENSATION_DOING或COMPENSATION_ERROR)、等待重试(RETRYING)或者等待confirm(CONFIRMING)
+	// 并且没有租约或者租约已经到期(lease_expire_at不晚于now)的分支才能领取
+	// 租约不修改分支的版本号
+	ClaimBranchTxLease(ctx context.Context, branchTxId string, leaseId string, leaseOwner string,
+		leaseExpireAt time.Time, now time.Time) (rowsChanged int64, err error)
+	RenewBranchTxLease(ctx context.Context,
+		branchTxId string, leaseId string, leaseExpireAt time.Time) (rowsChanged int64, err error)
+	ReleaseBranchTxLease(ctx context.Context, branchTxId string, leaseId string) (rowsChanged int64, err error)
+	// 记录PROCESSING状态的分支的心跳并清除失去心跳的标记，不修改分支的版本号
+	UpdateBranchTxHeartbeat(ctx context.Context, branchTxId string, heartbeatAt time.Time) (rowsChanged int64, err error)
+	// 把最近心跳不晚于heartbeatBefore并且还没有标记的分支标记为失去心跳，不修改分支的版本号
+	MarkBranchTxOrphaned(ctx context.Context, branchTxId string,
+		heartbeatBefore time.Time, orphanedAt time.Time) (rowsChanged int64, err error)
+	// 修改xid下的分支事务，把状态{oldState}的改成状态{newState}
+	UpdateBranchTxsByXidFromStateToState(ctx context.Context,
+		xid string, oldState int, newState int) (rowsAffected int64, err error)
+
+	// 分支事务补偿失败日志
+	FindBranchTxCompensationFailLogByJobId(ctx context.Context,
+		jobId string) (result *BranchTxCompensationFailLogEntity, err error)
+	InsertBranchTxCompensationFailLog(ctx context.Context,
+		xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error)
+
+	// 向前恢复的分支执行失败日志
+	FindBranchTxForwardRetryLogByJobId(ctx context.Context,
+		jobId string) (result *BranchTxForwardRetryLogEntity, err error)
+	InsertBranchTxForwardRetryLog(ctx context.Context,
+		xid string, branchTxId string, jobId string, reason string) (recordId uint64, err error)
+
+	// 事务日志
+	InsertTxLog(ctx context.Context, record *TxLogEntity) (recordId uint64, err error)
+	FindTxLogsByXid(ctx context.Context, xid string) (result []*TxLogEntity, err error)
+
+	// saga data
+	InsertSagaData(ctx context.Context, xid string, data []byte) (rowsAffected int64, err error)
+	UpdateSagaData(ctx context.Context, xid string, data []byte, oldVersion int32) (rowsAffected int64, err error)
+	QuerySagaData(ctx context.Context, xid string) (record *SagaDataEntity, err error)
+}
+
+/**
+ * 存储上的一个事务，事务内的操作在Commit后才生效
+ */
+type StoreTx interface {
+	StoreOps
+	Commit() error
+	Rollback() error
+}
+
+/**
+ * saga server的存储，不在事务中直接调用StoreOps的操作时每个操作单独生效
+ */
+type Store interface {
+	StoreOps
+	BeginTx(ctx context.Context) (StoreTx, error)
+	Close() error
+}
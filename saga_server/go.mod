@@ -7,6 +7,8 @@ require (
 	github.com/golang/protobuf v1.4.1
 	github.com/google/uuid v1.1.1
 	github.com/hashicorp/consul/api v1.6.0
+	github.com/lib/pq v1.9.0
+	github.com/mattn/go-sqlite3 v1.14.6
 	google.golang.org/grpc v1.31.0
 	google.golang.org/protobuf v1.25.0
 )
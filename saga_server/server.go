@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	pb "github.com/zoowii/saga_server/api"
@@ -26,17 +27,33 @@ func getDbUrl() (string, error) {
 	return "", errors.New("DATABASE_URL not set")
 }
 
-func main() {
-	address := fmt.Sprintf(":%d", port)
-	listener, err := net.Listen(network, address)
+/**
+ * ORPHAN_BRANCH_ROLLBACK=true 时回滚失去心跳的分支所在的全局事务，默认只标记
+ */
+func getOrphanBranchRollback() bool {
+	return os.Getenv("ORPHAN_BRANCH_ROLLBACK") == "true"
+}
+
+/**
+ * saga_server migrate 只执行数据库表结构的migrations然后退出
+ */
+func runMigrate(dbUrl string) {
+	sagaApp, err := app.NewApplicationContext(app.SetDbUrl(dbUrl), app.SetAutoMigrate(false))
 	if err != nil {
-		log.Fatalf("net.Listen err: %v", err)
+		log.Fatalf("saga app context err: %v", err)
 		return
 	}
-	log.Println(address + " net.Listing...")
-	grpcServer := grpc.NewServer()
+	defer sagaApp.Close()
+	appliedVersions, err := sagaApp.Migrate()
+	if err != nil {
+		log.Fatalf("migrate err: %v", err)
+		return
+	}
+	log.Printf("migrate done, applied versions: %v\n", appliedVersions)
+}
 
-	testDbUrl := "root:123456@tcp(127.0.0.1)/saga_server?charset=utf8&checkConnLiveness=true&parseTime=true"
+func main() {
+	testDbUrl := "sqlite://saga_server.db"
 
 	// load config from config file or environment
 	dbUrl, err := getDbUrl()
@@ -46,6 +63,20 @@ func main() {
 		dbUrl = testDbUrl
 	}
 
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(dbUrl)
+		return
+	}
+
+	address := fmt.Sprintf(":%d", port)
+	listener, err := net.Listen(network, address)
+	if err != nil {
+		log.Fatalf("net.Listen err: %v", err)
+		return
+	}
+	log.Println(address + " net.Listing...")
+	grpcServer := grpc.NewServer()
+
 	sagaApp, err := app.NewApplicationContext(app.SetDbUrl(dbUrl))
 	if err != nil {
 		log.Fatalf("saga app context err: %v", err)
@@ -59,6 +90,31 @@ func main() {
 	}
 	pb.RegisterSagaServerServer(grpcServer, sagaServerService)
 
+	// 后台把超时的全局事务转入补偿
+	expireSweeper, err := services.NewExpireSweeper(sagaApp, 0)
+	if err != nil {
+		log.Fatalf("expire sweeper err: %v", err)
+		return
+	}
+	bgCtx, cancelBg := context.WithCancel(context.Background())
+	defer cancelBg()
+	expireSweeper.SetEventBus(sagaServerService.Events())
+	go expireSweeper.Run(bgCtx)
+	// 后台调用补偿key是grpc地址的分支的补偿方法
+	compensationDispatcher := services.NewCompensationDispatcher(sagaServerService, 0)
+	go compensationDispatcher.Run(bgCtx)
+	// 后台通知参与方重试等待结束或者租约到期后可以领取的任务
+	workNotifier := services.NewWorkNotifier(sagaServerService, 0)
+	go workNotifier.Run(bgCtx)
+	// 后台检查执行方停止心跳的分支
+	orphanDetector, err := services.NewOrphanBranchDetector(sagaApp, 0, getOrphanBranchRollback())
+	if err != nil {
+		log.Fatalf("orphan branch detector err: %v", err)
+		return
+	}
+	orphanDetector.SetEventBus(sagaServerService.Events())
+	go orphanDetector.Run(bgCtx)
+
 	// register as service to consul
 	registerServer()
 
@@ -5,10 +5,16 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/app"
 	"github.com/zoowii/saga_server/services"
 	"google.golang.org/grpc"
+	"io"
 	"log"
+	"net"
+	"os"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -23,8 +29,51 @@ var (
 		Service:    testService,
 		InstanceId: testInstanceId,
 	}
+	// 测试用的进程内saga server地址
+	address string
+	// 测试中需要直接构造后台任务时使用
+	testSagaApp app.ApplicationContext
 )
 
+// 在进程内启动使用内存存储的saga server，测试不依赖外部数据库和服务
+func TestMain(m *testing.M) {
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		log.Fatalf("net.Listen err: %v", err)
+	}
+	address = listener.Addr().String()
+	sagaApp, err := app.NewApplicationContext(app.UseMemoryStore())
+	if err != nil {
+		log.Fatalf("saga app context err: %v", err)
+	}
+	testSagaApp = sagaApp
+	sagaServerService, err := services.NewSagaServerService(sagaApp)
+	if err != nil {
+		log.Fatalf("saga server service err: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	api.RegisterSagaServerServer(grpcServer, sagaServerService)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	expireSweeper, err := services.NewExpireSweeper(sagaApp, 100*time.Millisecond)
+	if err != nil {
+		log.Fatalf("expire sweeper err: %v", err)
+	}
+	bgCtx, cancelBg := context.WithCancel(context.Background())
+	expireSweeper.SetEventBus(sagaServerService.Events())
+	go expireSweeper.Run(bgCtx)
+	compensationDispatcher := services.NewCompensationDispatcher(sagaServerService, 100*time.Millisecond)
+	go compensationDispatcher.Run(bgCtx)
+	workNotifier := services.NewWorkNotifier(sagaServerService, 20*time.Millisecond)
+	go workNotifier.Run(bgCtx)
+	code := m.Run()
+	cancelBg()
+	grpcServer.Stop()
+	_ = sagaApp.Close()
+	os.Exit(code)
+}
+
 func generateNewJobId() string {
 	u := uuid.New()
 	return u.String()
@@ -120,6 +169,14 @@ func TestServerCreateBranchTransaction(t *testing.T) {
 	// list tx branches
 	globalTxDetailReply := queryTestGlobalTxDetail(t, client, xid)
 	log.Printf("query global tx detail reply: %v", globalTxDetailReply)
+
+	// 不存在的分支
+	notFoundReply, err := client.QueryBranchTransactionDetail(context.Background(),
+		&api.QueryBranchTransactionDetailRequest{BranchId: "not-existed-branch-id"})
+	if err != nil || notFoundReply.Code != services.NotFoundError {
+		t.Fatalf("query not existed branch tx should be not found, got %v err %v", notFoundReply, err)
+		return
+	}
 }
 
 // submit global state(all states)
@@ -463,3 +520,1876 @@ func TestServerListGlobalTransactionsOfStates(t *testing.T) {
 	log.Printf("list xids of states: %v\n", listReply)
 
 }
+
+// 超时的全局事务由server后台转入补偿
+func TestServerExpiredGlobalTxSwept(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	reply, err := client.CreateGlobalTransaction(ctx, &api.CreateGlobalTransactionRequest{
+		Node:          testNode,
+		ExpireSeconds: 1,
+		Extra:         "test expired global tx",
+	})
+	if err != nil {
+		t.Fatalf("CreateGlobalTransaction err: %v", err)
+		return
+	}
+	xid := reply.Xid
+	branchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+		if globalTxDetail.State == api.TxState_COMPENSATION_DOING {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expired global tx %s not swept, state %v", xid, globalTxDetail.State)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("branch tx of expired global tx should be COMPENSATION_DOING, got %v", branchTx.Detail.State)
+		return
+	}
+}
+
+// 测试用的补偿服务，failTimes次调用失败后才补偿成功
+type testBranchCompensationServer struct {
+	api.UnimplementedBranchCompensationServer
+	mu        sync.Mutex
+	failTimes int
+	calls     []*api.BranchCompensationRequest
+	// 不为空时每次调用在它关闭后才返回
+	blocked chan struct{}
+}
+
+func (s *testBranchCompensationServer) Compensate(ctx context.Context,
+	req *api.BranchCompensationRequest) (*api.BranchCompensationReply, error) {
+	s.mu.Lock()
+	s.calls = append(s.calls, req)
+	callsCount := len(s.calls)
+	s.mu.Unlock()
+	if s.blocked != nil {
+		<-s.blocked
+	}
+	if callsCount <= s.failTimes {
+		return &api.BranchCompensationReply{
+			Code:  services.ServerError,
+			Error: "test compensation error",
+		}, nil
+	}
+	return &api.BranchCompensationReply{
+		Code:     services.Ok,
+		SagaData: []byte(string(req.SagaData) + " compensated"),
+	}, nil
+}
+
+func (s *testBranchCompensationServer) callsCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.calls)
+}
+
+func startTestBranchCompensationServer(t *testing.T,
+	compensationServer *testBranchCompensationServer) (compensationKey string, stop func()) {
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen err: %v", err)
+	}
+	grpcServer := grpc.NewServer()
+	api.RegisterBranchCompensationServer(grpcServer, compensationServer)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	compensationKey = fmt.Sprintf("grpc://%s/saga.BranchCompensation/Compensate", listener.Addr().String())
+	stop = grpcServer.Stop
+	return
+}
+
+func waitTestGlobalTxState(t *testing.T, client api.SagaServerClient,
+	xid string, state api.TxState) (reply *api.QueryGlobalTransactionDetailReply) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		reply = queryTestGlobalTxDetail(t, client, xid)
+		if reply.State == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("global tx %s state %v, expected %v", xid, reply.State, state)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
+
+// 补偿key是grpc地址时由server调用补偿方法
+func TestServerDispatchGrpcCompensation(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{failTimes: 1}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.grpc.process",
+		BranchCompensationServiceKey: compensationKey,
+	})
+	if err != nil || createBranchTxReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createBranchTxReply)
+		return
+	}
+	branchTxId := createBranchTxReply.BranchId
+	_, err = client.InitSagaData(ctx, &api.InitSagaDataRequest{
+		Xid:  xid,
+		Data: []byte("test saga data"),
+	})
+	if err != nil {
+		t.Fatalf("InitSagaData err: %v", err)
+		return
+	}
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMPENSATION_DONE)
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_DONE || branchTx.Detail.CompensationFailTimes != 1 {
+		t.Fatalf("invalid branch tx after compensation: %v", branchTx.Detail)
+		return
+	}
+	if compensationServer.callsCount() != 2 {
+		t.Fatalf("compensation should be called twice, got %d", compensationServer.callsCount())
+		return
+	}
+	compensationServer.mu.Lock()
+	firstCall := compensationServer.calls[0]
+	compensationServer.mu.Unlock()
+	if string(firstCall.SagaData) != "test saga data" || firstCall.BranchId != branchTxId {
+		t.Fatalf("invalid request passed to compensation: %v", firstCall)
+		return
+	}
+	sagaDataReply, err := client.GetSagaData(ctx, &api.GetSagaDataRequest{Xid: xid})
+	if err != nil {
+		t.Fatalf("GetSagaData err: %v", err)
+		return
+	}
+	if string(sagaDataReply.Data) != "test saga data compensated" {
+		t.Fatalf("saga data should be changed by compensation, got %s", sagaDataReply.Data)
+		return
+	}
+}
+
+// 更晚的分支由参与方自己的worker补偿时，server等它补偿完成后才调用更早分支的grpc补偿方法
+func TestServerDispatchGrpcCompensationAfterWorkerBranch(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.grpc.process",
+		BranchCompensationServiceKey: compensationKey,
+	})
+	if err != nil || createBranchTxReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createBranchTxReply)
+		return
+	}
+	grpcBranchTxId := createBranchTxReply.BranchId
+	workerBranchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+
+	// 等待几轮dispatcher，更晚的分支还没补偿完成时不能调用更早分支的补偿
+	time.Sleep(500 * time.Millisecond)
+	if compensationServer.callsCount() != 0 {
+		t.Fatalf("grpc compensation should wait for later branch, got %d calls", compensationServer.callsCount())
+		return
+	}
+	if branchTx := queryTestBranchTxDetail(t, client, grpcBranchTxId); branchTx.Detail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("grpc branch should still be COMPENSATION_DOING, got %v", branchTx.Detail.State)
+		return
+	}
+
+	reply := submitTestBranchTxState(t, client, xid, workerBranchTxId, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	if reply.Code != services.Ok {
+		t.Fatalf("submit worker branch COMPENSATION_DONE failed: %v", reply)
+		return
+	}
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMPENSATION_DONE)
+	if compensationServer.callsCount() != 1 {
+		t.Fatalf("grpc compensation should be called once, got %d", compensationServer.callsCount())
+		return
+	}
+}
+
+// server调用补偿方法时领取分支的租约，同时进行的另一轮调度(例如其他saga server实例)不会再调用同一个分支
+func TestServerDispatchGrpcCompensationLeased(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{blocked: make(chan struct{})}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.grpc.process",
+		BranchCompensationServiceKey: compensationKey,
+	})
+	if err != nil || createBranchTxReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createBranchTxReply)
+		return
+	}
+	branchTxId := createBranchTxReply.BranchId
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+
+	// 后台的调度调用补偿方法后阻塞在调用中
+	deadline := time.Now().Add(5 * time.Second)
+	for compensationServer.callsCount() < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("compensation should be called by the background dispatcher")
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+	if len(branchTx.Detail.LeaseOwner) < 1 || branchTx.Detail.LeaseExpireAt <= 0 {
+		close(compensationServer.blocked)
+		t.Fatalf("branch should be leased while compensating, got %v", branchTx.Detail)
+		return
+	}
+	sagaServerService, err := services.NewSagaServerService(testSagaApp)
+	if err != nil {
+		close(compensationServer.blocked)
+		t.Fatalf("saga server service err: %v", err)
+		return
+	}
+	otherDispatcher := services.NewCompensationDispatcher(sagaServerService, time.Second)
+	if _, err = otherDispatcher.DispatchOnce(ctx); err != nil {
+		close(compensationServer.blocked)
+		t.Fatalf("DispatchOnce err: %v", err)
+		return
+	}
+	close(compensationServer.blocked)
+	if compensationServer.callsCount() != 1 {
+		t.Fatalf("leased branch should not be compensated again, got %d calls", compensationServer.callsCount())
+		return
+	}
+
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMPENSATION_DONE)
+	branchTx = queryTestBranchTxDetail(t, client, branchTxId)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_DONE || compensationServer.callsCount() != 1 {
+		t.Fatalf("branch should be compensated once, got %v with %d calls", branchTx.Detail,
+			compensationServer.callsCount())
+		return
+	}
+}
+
+// 补偿失败后按重试策略等待，等待中的全局事务不出现在列表中
+func TestServerCompensationRetryPolicy(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{failTimes: 100}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	invalidReply, err := client.CreateGlobalTransaction(ctx, &api.CreateGlobalTransactionRequest{
+		Node:        testNode,
+		RetryPolicy: &api.RetryPolicy{Multiplier: 0.5},
+	})
+	if err != nil || invalidReply.Code == services.Ok {
+		t.Fatalf("CreateGlobalTransaction with invalid retry policy should fail, got %v %v", invalidReply, err)
+		return
+	}
+
+	createGlobalTxReply, err := client.CreateGlobalTransaction(ctx, &api.CreateGlobalTransactionRequest{
+		Node:          testNode,
+		ExpireSeconds: 60,
+		RetryPolicy: &api.RetryPolicy{
+			MaxAttempts:    10,
+			InitialDelayMs: 60 * 1000,
+		},
+	})
+	if err != nil || createGlobalTxReply.Code != services.Ok {
+		t.Fatalf("CreateGlobalTransaction err: %v %v", err, createGlobalTxReply)
+		return
+	}
+	xid := createGlobalTxReply.Xid
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.retry.process",
+		BranchCompensationServiceKey: compensationKey,
+		RetryPolicy:                  &api.RetryPolicy{MaxAttempts: 2},
+	})
+	if err != nil || createBranchTxReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createBranchTxReply)
+		return
+	}
+	branchTxId := createBranchTxReply.BranchId
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	var branchTx *api.QueryBranchTransactionDetailReply
+	for {
+		branchTx = queryTestBranchTxDetail(t, client, branchTxId)
+		if branchTx.Detail.CompensationFailTimes > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("branch tx %s compensation not dispatched", branchTxId)
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// 第一次失败后按全局事务的重试策略等待60秒
+	minNextRetryAt := time.Now().Add(50*time.Second).UnixNano() / int64(time.Millisecond)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_ERROR || branchTx.Detail.NextRetryAt < minNextRetryAt {
+		t.Fatalf("invalid branch tx after first compensation error: %v", branchTx.Detail)
+		return
+	}
+	listReply, err := client.ListGlobalTransactionsOfStates(ctx, &api.ListGlobalTransactionsOfStatesRequest{
+		States: []api.TxState{api.TxState_COMPENSATION_DOING},
+		Limit:  1000,
+	})
+	if err != nil {
+		t.Fatalf("ListGlobalTransactionsOfStates err: %v", err)
+		return
+	}
+	for _, item := range listReply.Xids {
+		if item == xid {
+			t.Fatalf("backing off global tx %s should not be listed", xid)
+			return
+		}
+	}
+	time.Sleep(500 * time.Millisecond)
+	if compensationServer.callsCount() != 1 {
+		t.Fatalf("compensation should not be retried while backing off, called %d times",
+			compensationServer.callsCount())
+		return
+	}
+}
+
+func submitTestBranchTxCommitted(t *testing.T, client api.SagaServerClient, xid string, branchTxId string) {
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+	reply, err := client.SubmitBranchTransactionState(context.Background(),
+		&api.SubmitBranchTransactionStateRequest{
+			Xid:        xid,
+			BranchId:   branchTxId,
+			OldState:   branchTx.Detail.State,
+			State:      api.TxState_COMMITTED,
+			OldVersion: branchTx.Detail.Version,
+			JobId:      generateNewJobId(),
+		})
+	if err != nil || reply.Code != services.Ok {
+		t.Fatalf("SubmitBranchTransactionState err: %v %v", err, reply)
+	}
+}
+
+// 关闭全局事务后不再接受新分支，各分支都committed后全局事务自动committed
+func TestServerCloseGlobalTransaction(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId1 := createTestBranchTxOrPanic(t, client, xid, 1)
+	branchTxId2 := createTestBranchTxOrPanic(t, client, xid, 2)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId1)
+
+	closeReply, err := client.CloseGlobalTransaction(ctx, &api.CloseGlobalTransactionRequest{Xid: xid})
+	if err != nil || closeReply.Code != services.Ok || closeReply.State != api.TxState_PROCESSING {
+		t.Fatalf("CloseGlobalTransaction err: %v %v", err, closeReply)
+		return
+	}
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	if !globalTxDetail.EndBranches {
+		t.Fatalf("closed global tx should be marked end branches")
+		return
+	}
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:             testNode,
+		Xid:              xid,
+		BranchServiceKey: "branch.service3.process",
+	})
+	if err != nil || createBranchTxReply.Code == services.Ok {
+		t.Fatalf("CreateBranchTransaction of closed global tx should fail, got %v %v", createBranchTxReply, err)
+		return
+	}
+
+	submitTestBranchTxCommitted(t, client, xid, branchTxId2)
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid)
+	if globalTxDetail.State != api.TxState_COMMITTED {
+		t.Fatalf("global tx should be committed after all branches committed, got %v", globalTxDetail.State)
+		return
+	}
+
+	// 各分支已经committed时关闭直接提交
+	xid2 := createTestGlobalTxOrPanic(t, client)
+	branchTxId3 := createTestBranchTxOrPanic(t, client, xid2, 1)
+	submitTestBranchTxCommitted(t, client, xid2, branchTxId3)
+	closeReply, err = client.CloseGlobalTransaction(ctx, &api.CloseGlobalTransactionRequest{Xid: xid2})
+	if err != nil || closeReply.Code != services.Ok || closeReply.State != api.TxState_COMMITTED {
+		t.Fatalf("CloseGlobalTransaction should commit global tx, got %v %v", closeReply, err)
+		return
+	}
+}
+
+func createTestChildBranchTxOrPanic(t *testing.T, client api.SagaServerClient,
+	xid string, parentBranchId string, compensationKey string) (branchTxId string) {
+	reply, err := client.CreateBranchTransaction(context.Background(), &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.nested.process",
+		BranchCompensationServiceKey: compensationKey,
+		ParentBranchId:               parentBranchId,
+	})
+	if err != nil || reply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, reply)
+		return
+	}
+	branchTxId = reply.BranchId
+	return
+}
+
+// 分支树和下级分支先于上级分支补偿
+func TestServerNestedBranchTransactions(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchA := createTestChildBranchTxOrPanic(t, client, xid, "", compensationKey)
+	branchA1 := createTestChildBranchTxOrPanic(t, client, xid, branchA, compensationKey)
+	branchB := createTestChildBranchTxOrPanic(t, client, xid, "", compensationKey)
+	branchA2 := createTestChildBranchTxOrPanic(t, client, xid, branchA, compensationKey)
+
+	invalidReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:             testNode,
+		Xid:              xid,
+		BranchServiceKey: "branch.nested.process",
+		ParentBranchId:   generateNewJobId(),
+	})
+	if err != nil || invalidReply.Code != services.NotFoundError {
+		t.Fatalf("CreateBranchTransaction with not existed parent should fail, got %v %v", invalidReply, err)
+		return
+	}
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	tree := globalTxDetail.BranchTree
+	if len(globalTxDetail.Branches) != 4 || len(tree) != 2 {
+		t.Fatalf("invalid branch tree %v", tree)
+		return
+	}
+	if tree[0].BranchId != branchA || tree[1].BranchId != branchB || len(tree[1].Children) != 0 {
+		t.Fatalf("invalid top level branches %v", tree)
+		return
+	}
+	if len(tree[0].Children) != 2 || tree[0].Children[0].BranchId != branchA1 ||
+		tree[0].Children[1].BranchId != branchA2 || tree[0].Children[0].ParentBranchId != branchA {
+		t.Fatalf("invalid children of branch %s: %v", branchA, tree[0].Children)
+		return
+	}
+
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMPENSATION_DONE)
+
+	compensationServer.mu.Lock()
+	defer compensationServer.mu.Unlock()
+	expectedOrder := []string{branchB, branchA2, branchA1, branchA}
+	if len(compensationServer.calls) != len(expectedOrder) {
+		t.Fatalf("compensation should be called %d times, got %d", len(expectedOrder), len(compensationServer.calls))
+		return
+	}
+	for i, call := range compensationServer.calls {
+		if call.BranchId != expectedOrder[i] {
+			t.Fatalf("compensation %d should be branch %s, got %s", i, expectedOrder[i], call.BranchId)
+			return
+		}
+	}
+}
+
+func createTestGroupBranchTx(t *testing.T, client api.SagaServerClient, xid string,
+	group string, size int32, compensationKey string) (reply *api.CreateBranchTransactionReply) {
+	reply, err := client.CreateBranchTransaction(context.Background(), &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.group.process",
+		BranchCompensationServiceKey: compensationKey,
+		BranchGroup:                  group,
+		BranchGroupSize:              size,
+	})
+	if err != nil {
+		t.Fatalf("CreateBranchTransaction err: %v", err)
+	}
+	return
+}
+
+// 并行分支组都执行完之后全局事务才能提交，回滚时等组内执行中的分支提交后再补偿
+func TestServerParallelBranchGroup(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	compensationServer := &testBranchCompensationServer{}
+	compensationKey, stop := startTestBranchCompensationServer(t, compensationServer)
+	defer stop()
+
+	// 组内分支都提交后全局事务才能提交
+	xid := createTestGlobalTxOrPanic(t, client)
+	branch1 := createTestGroupBranchTx(t, client, xid, "group1", 2, "")
+	if branch1.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v", branch1)
+		return
+	}
+	invalidReply := createTestGroupBranchTx(t, client, xid, "group1", 3, "")
+	if invalidReply.Code == services.Ok {
+		t.Fatalf("branch with different group size should be refused")
+		return
+	}
+	submitTestBranchTxCommitted(t, client, xid, branch1.BranchId)
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	commitReply, err := client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil || commitReply.Code != services.BranchGroupNotSettledError {
+		t.Fatalf("commit with unsettled branch group should be refused, got %v %v", commitReply, err)
+		return
+	}
+	branch2 := createTestGroupBranchTx(t, client, xid, "group1", 2, "")
+	if branch2.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v", branch2)
+		return
+	}
+	if fullReply := createTestGroupBranchTx(t, client, xid, "group1", 2, ""); fullReply.Code == services.Ok {
+		t.Fatalf("full branch group should refuse new branch")
+		return
+	}
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid)
+	if len(globalTxDetail.BranchGroups) != 1 || globalTxDetail.BranchGroups[0].BranchCount != 2 ||
+		globalTxDetail.BranchGroups[0].ProcessingCount != 1 || globalTxDetail.BranchGroups[0].Settled {
+		t.Fatalf("invalid branch groups %v", globalTxDetail.BranchGroups)
+		return
+	}
+	submitTestBranchTxCommitted(t, client, xid, branch2.BranchId)
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid)
+	if !globalTxDetail.BranchGroups[0].Settled || globalTxDetail.BranchGroups[0].CommittedCount != 2 {
+		t.Fatalf("branch group should be settled, got %v", globalTxDetail.BranchGroups)
+		return
+	}
+	commitReply, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil || commitReply.Code != services.Ok {
+		t.Fatalf("SubmitGlobalTransactionState err: %v %v", err, commitReply)
+		return
+	}
+
+	// 回滚时组内还在执行的分支保持PROCESSING，补偿等它提交后再进行
+	xid2 := createTestGlobalTxOrPanic(t, client)
+	branchA := createTestChildBranchTxOrPanic(t, client, xid2, "", compensationKey)
+	submitTestBranchTxCommitted(t, client, xid2, branchA)
+	groupBranch1 := createTestGroupBranchTx(t, client, xid2, "group2", 2, compensationKey).BranchId
+	groupBranch2 := createTestGroupBranchTx(t, client, xid2, "group2", 2, compensationKey).BranchId
+	submitTestBranchTxCommitted(t, client, xid2, groupBranch1)
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid2)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid2,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	time.Sleep(300 * time.Millisecond)
+	if compensationServer.callsCount() != 0 {
+		t.Fatalf("compensation should wait for processing branch of group, called %d times",
+			compensationServer.callsCount())
+		return
+	}
+	if branchTx := queryTestBranchTxDetail(t, client, groupBranch2); branchTx.Detail.State != api.TxState_PROCESSING {
+		t.Fatalf("processing branch of group should keep processing, got %v", branchTx.Detail.State)
+		return
+	}
+	submitTestBranchTxCommitted(t, client, xid2, groupBranch2)
+	if branchTx := queryTestBranchTxDetail(t, client, groupBranch2); branchTx.Detail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("late committed branch should be compensated, got %v", branchTx.Detail.State)
+		return
+	}
+	waitTestGlobalTxState(t, client, xid2, api.TxState_COMPENSATION_DONE)
+	compensationServer.mu.Lock()
+	defer compensationServer.mu.Unlock()
+	if len(compensationServer.calls) != 3 || compensationServer.calls[2].BranchId != branchA {
+		t.Fatalf("group branches should be compensated before earlier branch, got %v", compensationServer.calls)
+		return
+	}
+}
+
+func submitTestBranchTxState(t *testing.T, client api.SagaServerClient, xid string, branchTxId string,
+	state api.TxState, jobId string) (reply *api.SubmitBranchTransactionStateReply) {
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+	reply, err := client.SubmitBranchTransactionState(context.Background(),
+		&api.SubmitBranchTransactionStateRequest{
+			Xid:         xid,
+			BranchId:    branchTxId,
+			OldState:    branchTx.Detail.State,
+			State:       state,
+			OldVersion:  branchTx.Detail.Version,
+			JobId:       jobId,
+			ErrorReason: "test forward error",
+		})
+	if err != nil {
+		t.Fatalf("SubmitBranchTransactionState err: %v", err)
+	}
+	return
+}
+
+// 向前恢复的分支失败后等待重试，不回滚全局事务，重试成功后关闭的全局事务自动提交
+func TestServerForwardRecoveryBranch(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	backwardBranchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+	createReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:             testNode,
+		Xid:              xid,
+		BranchServiceKey: "branch.forward.process",
+		RecoveryMode:     api.RecoveryMode_FORWARD,
+	})
+	if err != nil || createReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createReply)
+		return
+	}
+	forwardBranchTxId := createReply.BranchId
+	submitTestBranchTxCommitted(t, client, xid, backwardBranchTxId)
+
+	reply := submitTestBranchTxState(t, client, xid, forwardBranchTxId, api.TxState_COMPENSATION_DOING, generateNewJobId())
+	if reply.Code != services.Ok || reply.State != api.TxState_RETRYING {
+		t.Fatalf("failed forward branch should be retrying, got %v", reply)
+		return
+	}
+	jobId := generateNewJobId()
+	submitTestBranchTxState(t, client, xid, forwardBranchTxId, api.TxState_RETRYING, jobId)
+	submitTestBranchTxState(t, client, xid, forwardBranchTxId, api.TxState_RETRYING, jobId)
+	branchTx := queryTestBranchTxDetail(t, client, forwardBranchTxId)
+	if branchTx.Detail.ForwardRetryTimes != 2 || branchTx.Detail.NextRetryAt <= 0 ||
+		branchTx.Detail.RecoveryMode != api.RecoveryMode_FORWARD || branchTx.GlobalTxState != api.TxState_PROCESSING {
+		t.Fatalf("invalid forward branch after retry failed: %v", branchTx)
+		return
+	}
+	reply = submitTestBranchTxState(t, client, xid, backwardBranchTxId, api.TxState_RETRYING, generateNewJobId())
+	if reply.Code == services.Ok {
+		t.Fatalf("backward branch can't be retrying")
+		return
+	}
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	commitReply, err := client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil || commitReply.Code != services.ForwardRecoveryPendingError {
+		t.Fatalf("commit with retrying branch should be refused, got %v %v", commitReply, err)
+		return
+	}
+	closeReply, err := client.CloseGlobalTransaction(ctx, &api.CloseGlobalTransactionRequest{Xid: xid})
+	if err != nil || closeReply.Code != services.Ok || closeReply.State != api.TxState_PROCESSING {
+		t.Fatalf("CloseGlobalTransaction err: %v %v", err, closeReply)
+		return
+	}
+	submitTestBranchTxCommitted(t, client, xid, forwardBranchTxId)
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMMITTED)
+
+	// 回滚时还在重试的向前恢复分支进入补偿
+	xid2 := createTestGlobalTxOrPanic(t, client)
+	createReply, err = client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:             testNode,
+		Xid:              xid2,
+		BranchServiceKey: "branch.forward.process",
+		RecoveryMode:     api.RecoveryMode_FORWARD,
+	})
+	if err != nil || createReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createReply)
+		return
+	}
+	submitTestBranchTxState(t, client, xid2, createReply.BranchId, api.TxState_COMPENSATION_DOING, generateNewJobId())
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid2)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid2,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	branchTx = queryTestBranchTxDetail(t, client, createReply.BranchId)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("retrying branch should be compensated after rollback, got %v", branchTx.Detail.State)
+		return
+	}
+}
+
+// TCC模式下提交全局事务时由server调用各分支的confirm，confirm失败时重试直到成功
+func TestServerTccConfirmBranches(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	confirmServer := &testBranchCompensationServer{failTimes: 1}
+	confirmKey, stop := startTestBranchCompensationServer(t, confirmServer)
+	defer stop()
+
+	createGlobalReply, err := client.CreateGlobalTransaction(ctx, &api.CreateGlobalTransactionRequest{
+		Node:          testNode,
+		ExpireSeconds: 60,
+		Mode:          api.TransactionMode_TCC,
+		RetryPolicy: &api.RetryPolicy{
+			InitialDelayMs: 100,
+			Multiplier:     1,
+			MaxDelayMs:     100,
+		},
+	})
+	if err != nil || createGlobalReply.Code != services.Ok {
+		t.Fatalf("CreateGlobalTransaction err: %v %v", err, createGlobalReply)
+		return
+	}
+	xid := createGlobalReply.Xid
+	createReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         testNode,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.tcc.try",
+		BranchCompensationServiceKey: "branch.tcc.cancel",
+		BranchConfirmServiceKey:      confirmKey,
+	})
+	if err != nil || createReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createReply)
+		return
+	}
+	confirmBranchTxId := createReply.BranchId
+	noConfirmBranchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+	submitTestBranchTxCommitted(t, client, xid, confirmBranchTxId)
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	if globalTxDetail.Mode != api.TransactionMode_TCC {
+		t.Fatalf("invalid global tx mode %v", globalTxDetail.Mode)
+		return
+	}
+	commitReply, err := client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil || commitReply.Code != services.ServerError {
+		t.Fatalf("commit with not tried branch should be refused, got %v %v", commitReply, err)
+		return
+	}
+	submitTestBranchTxCommitted(t, client, xid, noConfirmBranchTxId)
+	globalTxDetail = queryTestGlobalTxDetail(t, client, xid)
+	commitReply, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil || commitReply.Code != services.Ok || commitReply.State != api.TxState_CONFIRMING {
+		t.Fatalf("commit of TCC global tx should start confirming, got %v %v", commitReply, err)
+		return
+	}
+	branchTx := queryTestBranchTxDetail(t, client, noConfirmBranchTxId)
+	if branchTx.Detail.State != api.TxState_CONFIRMED {
+		t.Fatalf("branch without confirm should be confirmed directly, got %v", branchTx.Detail.State)
+		return
+	}
+	// confirm key是grpc地址的分支由server调用，参与方领取不到
+	claimReply, err := client.ClaimCompensationWork(ctx, &api.ClaimCompensationWorkRequest{
+		Node: testNode,
+		Xid:  xid,
+		Work: api.WorkType_CONFIRM_WORK,
+	})
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 0 {
+		t.Fatalf("grpc confirm should not be claimed, got %v %v", claimReply, err)
+		return
+	}
+
+	waitTestGlobalTxState(t, client, xid, api.TxState_COMMITTED)
+	branchTx = queryTestBranchTxDetail(t, client, confirmBranchTxId)
+	if branchTx.Detail.State != api.TxState_CONFIRMED || branchTx.Detail.ForwardRetryTimes != 1 ||
+		branchTx.Detail.BranchConfirmServiceKey != confirmKey {
+		t.Fatalf("invalid branch tx after confirm: %v", branchTx.Detail)
+		return
+	}
+	if confirmServer.callsCount() != 2 {
+		t.Fatalf("confirm should be called twice, got %d", confirmServer.callsCount())
+		return
+	}
+
+	// 非TCC模式的全局事务不能创建带confirm的分支
+	sagaXid := createTestGlobalTxOrPanic(t, client)
+	createReply, err = client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                    testNode,
+		Xid:                     sagaXid,
+		BranchServiceKey:        "branch.tcc.try",
+		BranchConfirmServiceKey: confirmKey,
+	})
+	if err != nil || createReply.Code == services.Ok {
+		t.Fatalf("branch with confirm in saga mode should be refused, got %v %v", createReply, err)
+		return
+	}
+}
+
+// 读到分支之后其他参与方领取了租约，用领取之前的版本号也不能在没有租约时提交补偿结果
+func TestServerSubmitBranchTxAfterOthersClaim(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId)
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId)
+
+	otherInstance := &api.NodeInfo{Group: testGroup, Service: testService, InstanceId: "otherInstanceId"}
+	items := claimTestCompensationWork(t, client, otherInstance, xid)
+	if len(items) != 1 || items[0].Branch.BranchId != branchTxId {
+		t.Fatalf("branch should be claimed by other instance, got %v", items)
+		return
+	}
+	submitReply, err := client.SubmitBranchTransactionState(ctx, &api.SubmitBranchTransactionStateRequest{
+		Xid:        xid,
+		BranchId:   branchTxId,
+		OldState:   branchTx.Detail.State,
+		State:      api.TxState_COMPENSATION_DONE,
+		OldVersion: branchTx.Detail.Version,
+		JobId:      generateNewJobId(),
+	})
+	if err != nil || (submitReply.Code != services.CompensationLeasedError &&
+		submitReply.Code != services.ResourceChangedError) {
+		t.Fatalf("submit with the version read before others claim should fail, got %v %v", submitReply, err)
+		return
+	}
+	if detail := queryTestBranchTxDetail(t, client, branchTxId); detail.Detail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("branch leased by others should not be changed, got %v", detail.Detail)
+		return
+	}
+}
+
+func claimTestCompensationWork(t *testing.T, client api.SagaServerClient, node *api.NodeInfo,
+	xid string) (items []*api.CompensationWorkItem) {
+	reply, err := client.ClaimCompensationWork(context.Background(), &api.ClaimCompensationWorkRequest{
+		Node:         node,
+		LeaseSeconds: 60,
+		Limit:        1000,
+	})
+	if err != nil || reply.Code != services.Ok {
+		t.Fatalf("ClaimCompensationWork err: %v %v", err, reply)
+		return
+	}
+	// 其他测试留下的补偿中的全局事务也可能被领取，只关心xid的
+	for _, item := range reply.Items {
+		if item.Lease.Xid == xid {
+			items = append(items, item)
+		}
+	}
+	return
+}
+
+// 补偿任务通过租约领取，租约期间其他参与方领取不到同一个分支
+func TestServerClaimCompensationWork(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId1 := createTestBranchTxOrPanic(t, client, xid, 1)
+	branchTxId2 := createTestBranchTxOrPanic(t, client, xid, 2)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId1)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId2)
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+
+	otherInstance := &api.NodeInfo{Group: testGroup, Service: testService, InstanceId: "otherInstanceId"}
+	otherService := &api.NodeInfo{Group: testGroup, Service: "otherService", InstanceId: testInstanceId}
+	// 后创建的分支先补偿，更早的分支要等它补偿完成才能领取
+	items := claimTestCompensationWork(t, client, testNode, xid)
+	if len(items) != 1 || items[0].Branch.BranchId != branchTxId2 || len(items[0].Lease.LeaseId) < 1 {
+		t.Fatalf("should claim the last branch only, got %v", items)
+		return
+	}
+	lease := items[0].Lease
+	if items = claimTestCompensationWork(t, client, otherInstance, xid); len(items) != 0 {
+		t.Fatalf("leased branch should not be claimed again, got %v", items)
+		return
+	}
+	branchTx := queryTestBranchTxDetail(t, client, branchTxId2)
+	if branchTx.Detail.LeaseOwner != testInstanceId || branchTx.Detail.LeaseExpireAt != lease.LeaseExpireAt {
+		t.Fatalf("invalid lease of branch %v", branchTx.Detail)
+		return
+	}
+
+	renewReply, err := client.RenewCompensationWork(ctx, &api.RenewCompensationWorkRequest{
+		Node: testNode,
+		Leases: []*api.CompensationWorkLease{
+			{Xid: xid, BranchId: branchTxId2, LeaseId: generateNewJobId()},
+			lease,
+		},
+		LeaseSeconds: 120,
+	})
+	if err != nil || renewReply.Code != services.Ok || len(renewReply.Leases) != 1 ||
+		renewReply.Leases[0].LeaseExpireAt <= lease.LeaseExpireAt {
+		t.Fatalf("only the held lease should be renewed, got %v %v", renewReply, err)
+		return
+	}
+	releaseReply, err := client.ReleaseCompensationWork(ctx, &api.ReleaseCompensationWorkRequest{
+		Node:   testNode,
+		Leases: []*api.CompensationWorkLease{lease},
+	})
+	if err != nil || releaseReply.Code != services.Ok || releaseReply.Released != 1 {
+		t.Fatalf("ReleaseCompensationWork err: %v %v", err, releaseReply)
+		return
+	}
+	if items = claimTestCompensationWork(t, client, otherService, xid); len(items) != 0 {
+		t.Fatalf("branch of other service should not be claimed, got %v", items)
+		return
+	}
+	items = claimTestCompensationWork(t, client, otherInstance, xid)
+	if len(items) != 1 || items[0].Branch.BranchId != branchTxId2 {
+		t.Fatalf("released branch should be claimed by other instance, got %v", items)
+		return
+	}
+
+	// 租约期间只接受持有租约的参与方提交的补偿结果
+	branch := items[0].Branch
+	submitReq := &api.SubmitBranchTransactionStateRequest{
+		Xid:        xid,
+		BranchId:   branchTxId2,
+		OldState:   branch.State,
+		State:      api.TxState_COMPENSATION_DONE,
+		OldVersion: branch.Version,
+		JobId:      generateNewJobId(),
+	}
+	submitReply, err := client.SubmitBranchTransactionState(ctx, submitReq)
+	if err != nil || submitReply.Code != services.CompensationLeasedError {
+		t.Fatalf("submit without the lease should fail, got %v %v", submitReply, err)
+		return
+	}
+	submitReq.LeaseId = items[0].Lease.LeaseId
+	submitReply, err = client.SubmitBranchTransactionState(ctx, submitReq)
+	if err != nil || submitReply.Code != services.Ok || submitReply.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("submit with the lease should succeed, got %v %v", submitReply, err)
+		return
+	}
+	// 指定xid时只领取这个全局事务中的分支
+	claimReply, err := client.ClaimCompensationWork(ctx, &api.ClaimCompensationWorkRequest{
+		Node: testNode,
+		Xid:  generateNewJobId(),
+	})
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 0 {
+		t.Fatalf("claim of not existed xid should return nothing, got %v %v", claimReply, err)
+		return
+	}
+	claimReply, err = client.ClaimCompensationWork(ctx, &api.ClaimCompensationWorkRequest{
+		Node: testNode,
+		Xid:  xid,
+	})
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 1 ||
+		claimReply.Items[0].Branch.BranchId != branchTxId1 {
+		t.Fatalf("earlier branch should be claimed after the later one compensated, got %v %v", claimReply, err)
+		return
+	}
+}
+
+// 等待重试的向前恢复分支到了重试时间后通过租约领取，同一个重试只被一个参与方执行
+func TestServerClaimForwardRetryWork(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	createReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:             testNode,
+		Xid:              xid,
+		BranchServiceKey: "branch.forward.claim",
+		RecoveryMode:     api.RecoveryMode_FORWARD,
+		RetryPolicy:      &api.RetryPolicy{InitialDelayMs: 10, MaxDelayMs: 10},
+	})
+	if err != nil || createReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createReply)
+		return
+	}
+	branchTxId := createReply.BranchId
+	submitTestBranchTxState(t, client, xid, branchTxId, api.TxState_COMPENSATION_DOING, generateNewJobId())
+	claimReq := &api.ClaimCompensationWorkRequest{
+		Node:         testNode,
+		Xid:          xid,
+		LeaseSeconds: 60,
+		Work:         api.WorkType_FORWARD_RETRY_WORK,
+	}
+	// 补偿任务不包含等待重试的分支
+	claimReply, err := client.ClaimCompensationWork(ctx, &api.ClaimCompensationWorkRequest{Node: testNode, Xid: xid})
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 0 {
+		t.Fatalf("retrying branch should not be claimed as compensation, got %v %v", claimReply, err)
+		return
+	}
+	time.Sleep(20 * time.Millisecond)
+	claimReply, err = client.ClaimCompensationWork(ctx, claimReq)
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 1 ||
+		claimReply.Items[0].Branch.BranchId != branchTxId {
+		t.Fatalf("retrying branch should be claimed after backoff, got %v %v", claimReply, err)
+		return
+	}
+	item := claimReply.Items[0]
+	claimReq.Node = &api.NodeInfo{Group: testGroup, Service: testService, InstanceId: "otherInstanceId"}
+	claimReply, err = client.ClaimCompensationWork(ctx, claimReq)
+	if err != nil || claimReply.Code != services.Ok || len(claimReply.Items) != 0 {
+		t.Fatalf("leased retrying branch should not be claimed again, got %v %v", claimReply, err)
+		return
+	}
+
+	submitReq := &api.SubmitBranchTransactionStateRequest{
+		Xid:        xid,
+		BranchId:   branchTxId,
+		OldState:   item.Branch.State,
+		State:      api.TxState_COMMITTED,
+		OldVersion: item.Branch.Version,
+		JobId:      generateNewJobId(),
+	}
+	submitReply, err := client.SubmitBranchTransactionState(ctx, submitReq)
+	if err != nil || submitReply.Code != services.CompensationLeasedError {
+		t.Fatalf("submit without the lease should fail, got %v %v", submitReply, err)
+		return
+	}
+	submitReq.LeaseId = item.Lease.LeaseId
+	submitReply, err = client.SubmitBranchTransactionState(ctx, submitReq)
+	if err != nil || submitReply.Code != services.Ok || submitReply.State != api.TxState_COMMITTED {
+		t.Fatalf("submit with the lease should succeed, got %v %v", submitReply, err)
+		return
+	}
+}
+
+func createTestHeartbeatBranchTx(t *testing.T, client api.SagaServerClient,
+	req *api.CreateBranchTransactionRequest) (branchTxId string) {
+	req.Node = testNode
+	req.BranchServiceKey = "branch.heartbeat.process"
+	req.HeartbeatTimeoutSeconds = 1
+	reply, err := client.CreateBranchTransaction(context.Background(), req)
+	if err != nil || reply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, reply)
+	}
+	branchTxId = reply.BranchId
+	return
+}
+
+func heartbeatTestBranchTx(t *testing.T, client api.SagaServerClient,
+	xid string, branchTxId string) (reply *api.HeartbeatBranchReply) {
+	reply, err := client.HeartbeatBranch(context.Background(), &api.HeartbeatBranchRequest{
+		Node:     testNode,
+		Xid:      xid,
+		BranchId: branchTxId,
+	})
+	if err != nil || reply.Code != services.Ok {
+		t.Fatalf("HeartbeatBranch err: %v %v", err, reply)
+	}
+	return
+}
+
+func newTestOrphanBranchDetector(t *testing.T, rollback bool) *services.OrphanBranchDetector {
+	detector, err := services.NewOrphanBranchDetector(testSagaApp, time.Second, rollback)
+	if err != nil {
+		t.Fatalf("NewOrphanBranchDetector err: %v", err)
+	}
+	return detector
+}
+
+// 执行方停止心跳的分支先被标记，开启回滚时全局事务不等超时就进入补偿
+func TestServerOrphanedBranchDetected(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	orphanBranchTxId := createTestHeartbeatBranchTx(t, client, &api.CreateBranchTransactionRequest{Xid: xid})
+	aliveBranchTxId := createTestHeartbeatBranchTx(t, client, &api.CreateBranchTransactionRequest{Xid: xid})
+	createTestBranchTxOrPanic(t, client, xid, 1)
+	branchTx := queryTestBranchTxDetail(t, client, orphanBranchTxId)
+	if branchTx.Detail.HeartbeatTimeoutSeconds != 1 || branchTx.Detail.LastHeartbeatAt <= 0 {
+		t.Fatalf("branch should record heartbeat when created, got %v", branchTx.Detail)
+		return
+	}
+	otherReply, err := client.HeartbeatBranch(ctx, &api.HeartbeatBranchRequest{
+		Node:     &api.NodeInfo{Group: testGroup, Service: testService, InstanceId: "otherInstanceId"},
+		Xid:      xid,
+		BranchId: orphanBranchTxId,
+	})
+	if err != nil || otherReply.Code == services.Ok {
+		t.Fatalf("heartbeat from other instance should fail, got %v %v", otherReply, err)
+		return
+	}
+
+	keepAlive := func() {
+		for i := 0; i < 4; i++ {
+			time.Sleep(300 * time.Millisecond)
+			heartbeatTestBranchTx(t, client, xid, aliveBranchTxId)
+		}
+	}
+	keepAlive()
+	count, err := newTestOrphanBranchDetector(t, false).DetectOnce(ctx)
+	if err != nil || count < 1 {
+		t.Fatalf("DetectOnce should find the orphaned branch, got %d err %v", count, err)
+		return
+	}
+	branchTx = queryTestBranchTxDetail(t, client, orphanBranchTxId)
+	if branchTx.Detail.OrphanedAt <= 0 || branchTx.Detail.State != api.TxState_PROCESSING ||
+		branchTx.GlobalTxState != api.TxState_PROCESSING {
+		t.Fatalf("orphaned branch should only be flagged, got %v", branchTx)
+		return
+	}
+	if branchTx = queryTestBranchTxDetail(t, client, aliveBranchTxId); branchTx.Detail.OrphanedAt != 0 {
+		t.Fatalf("branch with heartbeat should not be orphaned, got %v", branchTx.Detail)
+		return
+	}
+	// 恢复心跳后清除标记
+	heartbeatTestBranchTx(t, client, xid, orphanBranchTxId)
+	if branchTx = queryTestBranchTxDetail(t, client, orphanBranchTxId); branchTx.Detail.OrphanedAt != 0 {
+		t.Fatalf("heartbeat should clear orphaned mark, got %v", branchTx.Detail)
+		return
+	}
+
+	keepAlive()
+	count, err = newTestOrphanBranchDetector(t, true).DetectOnce(ctx)
+	if err != nil || count < 1 {
+		t.Fatalf("DetectOnce should find the orphaned branch, got %d err %v", count, err)
+		return
+	}
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	if globalTxDetail.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("global tx with orphaned branch should be rolled back, got %v", globalTxDetail.State)
+		return
+	}
+	for _, branch := range globalTxDetail.Branches {
+		if branch.State != api.TxState_COMPENSATION_DOING {
+			t.Fatalf("branch should be compensating after rollback, got %v", branch)
+			return
+		}
+	}
+	if reply := heartbeatTestBranchTx(t, client, xid, aliveBranchTxId); reply.State != api.TxState_COMPENSATION_DOING ||
+		reply.GlobalTxState != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("heartbeat should return the changed state, got %v", reply)
+		return
+	}
+}
+
+// 失去心跳的向前恢复分支改成RETRYING，已经在回滚的全局事务中失去心跳的分支组成员直接进入补偿
+func TestServerOrphanedBranchRollbackModes(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	forwardXid := createTestGlobalTxOrPanic(t, client)
+	forwardBranchTxId := createTestHeartbeatBranchTx(t, client, &api.CreateBranchTransactionRequest{
+		Xid:          forwardXid,
+		RecoveryMode: api.RecoveryMode_FORWARD,
+	})
+	groupXid := createTestGlobalTxOrPanic(t, client)
+	groupBranchTxId1 := createTestHeartbeatBranchTx(t, client, &api.CreateBranchTransactionRequest{
+		Xid:             groupXid,
+		BranchGroup:     "heartbeat.group",
+		BranchGroupSize: 2,
+	})
+	groupBranchTxId2 := createTestHeartbeatBranchTx(t, client, &api.CreateBranchTransactionRequest{
+		Xid:             groupXid,
+		BranchGroup:     "heartbeat.group",
+		BranchGroupSize: 2,
+	})
+	submitTestBranchTxState(t, client, groupXid, groupBranchTxId1, api.TxState_COMPENSATION_DOING, generateNewJobId())
+	globalTxDetail := queryTestGlobalTxDetail(t, client, groupXid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        groupXid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	if branchTx := queryTestBranchTxDetail(t, client, groupBranchTxId2); branchTx.Detail.State != api.TxState_PROCESSING {
+		t.Fatalf("running group branch should wait for its result, got %v", branchTx.Detail)
+		return
+	}
+
+	time.Sleep(1200 * time.Millisecond)
+	count, err := newTestOrphanBranchDetector(t, true).DetectOnce(ctx)
+	if err != nil || count < 2 {
+		t.Fatalf("DetectOnce should find the orphaned branches, got %d err %v", count, err)
+		return
+	}
+	branchTx := queryTestBranchTxDetail(t, client, forwardBranchTxId)
+	if branchTx.Detail.State != api.TxState_RETRYING || branchTx.Detail.ForwardRetryTimes != 1 ||
+		branchTx.GlobalTxState != api.TxState_PROCESSING {
+		t.Fatalf("orphaned forward branch should be retried, got %v", branchTx)
+		return
+	}
+	branchTx = queryTestBranchTxDetail(t, client, groupBranchTxId2)
+	if branchTx.Detail.State != api.TxState_COMPENSATION_DOING || branchTx.Detail.OrphanedAt <= 0 {
+		t.Fatalf("orphaned group branch should be compensated, got %v", branchTx.Detail)
+		return
+	}
+}
+
+// 接收全局事务的状态变化直到stream结束
+func watchTestGlobalTx(client api.SagaServerClient, xid string) (replies chan *api.WatchGlobalTransactionReply,
+	errs chan error) {
+	replies = make(chan *api.WatchGlobalTransactionReply, 100)
+	errs = make(chan error, 1)
+	stream, err := client.WatchGlobalTransaction(context.Background(), &api.WatchGlobalTransactionRequest{
+		Xid: xid,
+	})
+	if err != nil {
+		errs <- err
+		close(replies)
+		return
+	}
+	go func() {
+		defer close(replies)
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			replies <- reply
+		}
+	}()
+	return
+}
+
+func receiveTestTxStateEvent(t *testing.T, replies chan *api.WatchGlobalTransactionReply) *api.TxStateEvent {
+	select {
+	case reply, ok := <-replies:
+		if !ok || reply.Code != services.Ok {
+			t.Fatalf("watch ended unexpectedly, got %v", reply)
+		}
+		return reply.Event
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no event received")
+	}
+	return nil
+}
+
+// watch先推送当前状态，之后推送每次状态变化直到全局事务结束
+func TestServerWatchGlobalTransaction(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId1 := createTestBranchTxOrPanic(t, client, xid, 1)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId1)
+
+	replies, errs := watchTestGlobalTx(client, xid)
+	event := receiveTestTxStateEvent(t, replies)
+	if event.BranchId != branchTxId1 || event.State != api.TxState_COMMITTED || event.OldState != event.State {
+		t.Fatalf("first event should be the current branch state, got %v", event)
+		return
+	}
+	event = receiveTestTxStateEvent(t, replies)
+	if len(event.BranchId) > 0 || event.State != api.TxState_PROCESSING || event.Node.Service != testService {
+		t.Fatalf("snapshot should end with the global tx state, got %v", event)
+		return
+	}
+
+	branchTxId2 := createTestBranchTxOrPanic(t, client, xid, 2)
+	event = receiveTestTxStateEvent(t, replies)
+	if event.BranchId != branchTxId2 || event.State != api.TxState_PROCESSING || event.OldState != event.State {
+		t.Fatalf("created branch should be pushed, got %v", event)
+		return
+	}
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	changed := make(map[string]*api.TxStateEvent)
+	for i := 0; i < 3; i++ {
+		event = receiveTestTxStateEvent(t, replies)
+		changed[event.BranchId] = event
+	}
+	for _, id := range []string{branchTxId1, branchTxId2, ""} {
+		event = changed[id]
+		if event == nil || event.State != api.TxState_COMPENSATION_DOING || event.OldState == event.State {
+			t.Fatalf("rollback should push state change of %s, got %v", id, changed)
+			return
+		}
+	}
+
+	submitTestBranchTxState(t, client, xid, branchTxId2, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	event = receiveTestTxStateEvent(t, replies)
+	if event.BranchId != branchTxId2 || event.OldState != api.TxState_COMPENSATION_DOING ||
+		event.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("compensated branch should be pushed, got %v", event)
+		return
+	}
+	submitTestBranchTxState(t, client, xid, branchTxId1, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	event = receiveTestTxStateEvent(t, replies)
+	if event.BranchId != branchTxId1 || event.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("compensated branch should be pushed before the global tx, got %v", event)
+		return
+	}
+	event = receiveTestTxStateEvent(t, replies)
+	if len(event.BranchId) > 0 || event.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("global tx should be compensation done, got %v", event)
+		return
+	}
+	if _, ok := <-replies; ok || <-errs != io.EOF {
+		t.Fatalf("watch should end after the global tx finished")
+		return
+	}
+
+	// 已经结束的全局事务只推送当前状态
+	replies, errs = watchTestGlobalTx(client, xid)
+	count := 0
+	for range replies {
+		count++
+	}
+	if count != 3 || <-errs != io.EOF {
+		t.Fatalf("watch of finished global tx should only push the snapshot, got %d events", count)
+		return
+	}
+	replies, _ = watchTestGlobalTx(client, generateNewJobId())
+	if reply := <-replies; reply == nil || reply.Code != services.NotFoundError {
+		t.Fatalf("watch of not existed xid should fail, got %v", reply)
+		return
+	}
+}
+
+// 每次状态修改都单独推送，中间状态不会被合并
+func TestServerWatchEveryTransition(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx := context.Background()
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId := createTestBranchTxOrPanic(t, client, xid, 1)
+	submitTestBranchTxCommitted(t, client, xid, branchTxId)
+	replies, errs := watchTestGlobalTx(client, xid)
+	for i := 0; i < 2; i++ {
+		receiveTestTxStateEvent(t, replies)
+	}
+
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	submitTestBranchTxState(t, client, xid, branchTxId, api.TxState_COMPENSATION_ERROR, generateNewJobId())
+	submitTestBranchTxState(t, client, xid, branchTxId, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	expected := []struct {
+		branchId string
+		oldState api.TxState
+		state    api.TxState
+	}{
+		{"", api.TxState_PROCESSING, api.TxState_COMPENSATION_DOING},
+		{branchTxId, api.TxState_COMMITTED, api.TxState_COMPENSATION_DOING},
+		{branchTxId, api.TxState_COMPENSATION_DOING, api.TxState_COMPENSATION_ERROR},
+		{branchTxId, api.TxState_COMPENSATION_ERROR, api.TxState_COMPENSATION_DONE},
+		{"", api.TxState_COMPENSATION_DOING, api.TxState_COMPENSATION_DONE},
+	}
+	for _, e := range expected {
+		event := receiveTestTxStateEvent(t, replies)
+		if event.BranchId != e.branchId || event.OldState != e.oldState || event.State != e.state {
+			t.Fatalf("should receive %s %s => %s, got %v", e.branchId, e.oldState, e.state, event)
+			return
+		}
+	}
+	if _, ok := <-replies; ok || <-errs != io.EOF {
+		t.Fatalf("watch should end after the global tx finished")
+		return
+	}
+}
+
+// 接收订阅的事件直到stream结束
+func subscribeTestEvents(ctx context.Context, client api.SagaServerClient,
+	req *api.SubscribeEventsRequest) (replies chan *api.SubscribeEventsReply) {
+	replies = make(chan *api.SubscribeEventsReply, 100)
+	stream, err := client.SubscribeEvents(ctx, req)
+	if err != nil {
+		close(replies)
+		return
+	}
+	go func() {
+		defer close(replies)
+		for {
+			reply, err := stream.Recv()
+			if err != nil {
+				return
+			}
+			replies <- reply
+		}
+	}()
+	return
+}
+
+// 接收下一个全局事务xid的事件或者表示订阅成功的reply，跳过其他全局事务的事件
+func receiveTestSubscribeReply(t *testing.T, replies chan *api.SubscribeEventsReply,
+	xid string) *api.SubscribeEventsReply {
+	for {
+		select {
+		case reply, ok := <-replies:
+			if !ok {
+				t.Fatalf("subscription ended unexpectedly")
+				return nil
+			}
+			if reply.Event == nil || reply.Event.Xid == xid {
+				return reply
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no event received")
+			return nil
+		}
+	}
+}
+
+// 按参与方和状态订阅事件，断开后用cursor继续订阅可以收到断开期间的事件
+func TestServerSubscribeEvents(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	states := []api.TxState{api.TxState_COMPENSATION_DOING, api.TxState_COMPENSATION_DONE}
+	subscribeCtx, unsubscribe := context.WithCancel(ctx)
+	replies := subscribeTestEvents(subscribeCtx, client, &api.SubscribeEventsRequest{
+		Node:   testNode,
+		States: states,
+	})
+	reply := receiveTestSubscribeReply(t, replies, "")
+	if reply.Code != services.Ok || reply.Event != nil || len(reply.Cursor) < 1 {
+		t.Fatalf("first reply should be the subscribed cursor, got %v", reply)
+		return
+	}
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	branchTxId1 := createTestBranchTxOrPanic(t, client, xid, 1)
+	otherService := &api.NodeInfo{Group: testGroup, Service: "otherService", InstanceId: testInstanceId}
+	createBranchTxReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+		Node:                         otherService,
+		Xid:                          xid,
+		BranchServiceKey:             "branch.other.process",
+		BranchCompensationServiceKey: "branch.other.compensation",
+	})
+	if err != nil || createBranchTxReply.Code != services.Ok {
+		t.Fatalf("CreateBranchTransaction err: %v %v", err, createBranchTxReply)
+		return
+	}
+	branchTxId2 := createBranchTxReply.BranchId
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	// 创建时的PROCESSING和其他service的分支都被过滤掉，按修改的顺序先收到全局事务再收到分支
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if len(reply.Event.BranchId) > 0 || reply.Event.State != api.TxState_COMPENSATION_DOING {
+		t.Fatalf("should receive the global tx started by subscribed node, got %v", reply)
+		return
+	}
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if reply.Event.BranchId != branchTxId1 || reply.Event.State != api.TxState_COMPENSATION_DOING ||
+		len(reply.Cursor) < 1 {
+		t.Fatalf("should receive the branch of subscribed node, got %v", reply)
+		return
+	}
+	lastCursor := reply.Cursor
+	unsubscribe()
+
+	submitTestBranchTxState(t, client, xid, branchTxId2, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	submitTestBranchTxState(t, client, xid, branchTxId1, api.TxState_COMPENSATION_DONE, generateNewJobId())
+	replies = subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{
+		Node:   testNode,
+		States: states,
+		Cursor: lastCursor,
+	})
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if reply.Event == nil || reply.Event.BranchId != branchTxId1 ||
+		reply.Event.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("missed branch event should be replayed, got %v", reply)
+		return
+	}
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if reply.Event == nil || len(reply.Event.BranchId) > 0 || reply.Event.State != api.TxState_COMPENSATION_DONE {
+		t.Fatalf("missed global tx event should be replayed, got %v", reply)
+		return
+	}
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if reply.Event != nil || len(reply.Cursor) < 1 {
+		t.Fatalf("subscribed cursor should follow the replayed events, got %v", reply)
+		return
+	}
+
+	replies = subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{
+		Node:   testNode,
+		Cursor: generateNewJobId() + ":1",
+	})
+	if reply = <-replies; reply == nil || reply.Code != services.EventCursorExpiredError {
+		t.Fatalf("cursor of other server process should be expired, got %v", reply)
+		return
+	}
+	replies = subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{
+		Node:   testNode,
+		Cursor: "invalid cursor",
+	})
+	if reply = <-replies; reply == nil || reply.Code != services.ServerError {
+		t.Fatalf("invalid cursor should fail, got %v", reply)
+		return
+	}
+}
+
+// 没有匹配的事件时定时推送最新的cursor，用它重新订阅不会补发也不会过期
+func TestServerSubscribeEventsHeartbeat(t *testing.T) {
+	listener, err := net.Listen(network, "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen err: %v", err)
+		return
+	}
+	sagaServerService, err := services.NewSagaServerService(testSagaApp)
+	if err != nil {
+		t.Fatalf("saga server service err: %v", err)
+		return
+	}
+	sagaServerService.SetEventHeartbeatInterval(100 * time.Millisecond)
+	grpcServer := grpc.NewServer()
+	api.RegisterSagaServerServer(grpcServer, sagaServerService)
+	go func() {
+		_ = grpcServer.Serve(listener)
+	}()
+	defer grpcServer.Stop()
+	cc, err := grpc.Dial(listener.Addr().String(), grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	quietNode := &api.NodeInfo{Group: testGroup, Service: "quietService"}
+	replies := subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{
+		Node: quietNode,
+	})
+	reply := receiveTestSubscribeReply(t, replies, "")
+	if reply.Code != services.Ok || reply.Event != nil || len(reply.Cursor) < 1 {
+		t.Fatalf("first reply should be the subscribed cursor, got %v", reply)
+		return
+	}
+	subscribedCursor := reply.Cursor
+
+	xid := createTestGlobalTxOrPanic(t, client)
+	createTestBranchTxOrPanic(t, client, xid, 1)
+	reply = receiveTestSubscribeReply(t, replies, xid)
+	if reply.Event != nil || reply.Cursor == subscribedCursor {
+		t.Fatalf("heartbeat should carry the latest cursor, got %v", reply)
+		return
+	}
+	lastCursor := reply.Cursor
+	replies = subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{
+		Node:   quietNode,
+		Cursor: lastCursor,
+	})
+	reply = receiveTestSubscribeReply(t, replies, "")
+	if reply.Code != services.Ok || reply.Event != nil || reply.Cursor != lastCursor {
+		t.Fatalf("subscribe from the heartbeat cursor should replay nothing, got %v", reply)
+		return
+	}
+}
+
+// 接收下一个全局事务xid的任务事件，跳过其他全局事务的事件和没有事件的reply
+func receiveTestWorkEvent(t *testing.T, replies chan *api.SubscribeEventsReply, xid string) *api.TxStateEvent {
+	for {
+		reply := receiveTestSubscribeReply(t, replies, xid)
+		if reply.Event != nil {
+			return reply.Event
+		}
+	}
+}
+
+// 分支变得可以领取时给它的创建方推送任务事件，包括前面的补偿完成和重试等待结束
+func TestServerSubscribeWorkEvents(t *testing.T) {
+	cc, err := grpc.Dial(address, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("grpc dial err: %v", err)
+		return
+	}
+	client := api.NewSagaServerClient(cc)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	nodeA := &api.NodeInfo{Group: testGroup, Service: "workEventServiceA", InstanceId: testInstanceId}
+	nodeB := &api.NodeInfo{Group: testGroup, Service: "workEventServiceB", InstanceId: testInstanceId}
+	works := []api.WorkType{api.WorkType_COMPENSATION_WORK}
+	repliesA := subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{Node: nodeA, Works: works})
+	repliesB := subscribeTestEvents(ctx, client, &api.SubscribeEventsRequest{Node: nodeB, Works: works})
+	for _, replies := range []chan *api.SubscribeEventsReply{repliesA, repliesB} {
+		if reply := receiveTestSubscribeReply(t, replies, ""); reply.Code != services.Ok || reply.Event != nil {
+			t.Fatalf("first reply should be the subscribed cursor, got %v", reply)
+			return
+		}
+	}
+
+	createGlobalReply, err := client.CreateGlobalTransaction(ctx, &api.CreateGlobalTransactionRequest{
+		Node:          testNode,
+		ExpireSeconds: 60,
+		RetryPolicy: &api.RetryPolicy{
+			InitialDelayMs: 100,
+			Multiplier:     1,
+			MaxDelayMs:     100,
+		},
+	})
+	if err != nil || createGlobalReply.Code != services.Ok {
+		t.Fatalf("CreateGlobalTransaction err: %v %v", err, createGlobalReply)
+		return
+	}
+	xid := createGlobalReply.Xid
+	branchTxIds := make([]string, 0, 2)
+	for _, node := range []*api.NodeInfo{nodeA, nodeB} {
+		createReply, err := client.CreateBranchTransaction(ctx, &api.CreateBranchTransactionRequest{
+			Node:                         node,
+			Xid:                          xid,
+			BranchServiceKey:             "branch." + node.Service + ".process",
+			BranchCompensationServiceKey: "branch." + node.Service + ".compensation",
+		})
+		if err != nil || createReply.Code != services.Ok {
+			t.Fatalf("CreateBranchTransaction err: %v %v", err, createReply)
+			return
+		}
+		branchTxIds = append(branchTxIds, createReply.BranchId)
+		submitTestBranchTxCommitted(t, client, xid, createReply.BranchId)
+	}
+	globalTxDetail := queryTestGlobalTxDetail(t, client, xid)
+	_, err = client.SubmitGlobalTransactionState(ctx, &api.SubmitGlobalTransactionStateRequest{
+		Xid:        xid,
+		OldState:   globalTxDetail.State,
+		State:      api.TxState_COMPENSATION_DOING,
+		OldVersion: globalTxDetail.Version,
+	})
+	if err != nil {
+		t.Fatalf("SubmitGlobalTransactionState err: %v", err)
+		return
+	}
+	// 后创建的分支先补偿，只通知它的创建方，状态变化不推送给只订阅任务的订阅方
+	event := receiveTestWorkEvent(t, repliesB, xid)
+	if !event.WorkReady || event.Work != api.WorkType_COMPENSATION_WORK || event.Node.Service != nodeB.Service {
+		t.Fatalf("should receive the compensation work of service B, got %v", event)
+		return
+	}
+	time.Sleep(100 * time.Millisecond)
+	for len(repliesA) > 0 {
+		if reply := <-repliesA; reply.Event != nil && reply.Event.Xid == xid {
+			t.Fatalf("service A should not be notified before service B compensated, got %v", reply)
+			return
+		}
+	}
+	submitTestBranchTxState(t, client, xid, branchTxIds[1], api.TxState_COMPENSATION_DONE, generateNewJobId())
+	event = receiveTestWorkEvent(t, repliesA, xid)
+	if !event.WorkReady || event.Work != api.WorkType_COMPENSATION_WORK || event.Node.Service != nodeA.Service {
+		t.Fatalf("service A should be notified after service B compensated, got %v", event)
+		return
+	}
+
+	// 补偿失败后重试等待期间领取不到，等待结束时再通知一次
+	submitTestBranchTxState(t, client, xid, branchTxIds[0], api.TxState_COMPENSATION_ERROR, generateNewJobId())
+	if items := claimTestCompensationWork(t, client, nodeA, xid); len(items) != 0 {
+		t.Fatalf("backing off branch should not be claimed, got %v", items)
+		return
+	}
+	event = receiveTestWorkEvent(t, repliesA, xid)
+	branchTx := queryTestBranchTxDetail(t, client, branchTxIds[0])
+	if !event.WorkReady || branchTx.Detail.State != api.TxState_COMPENSATION_ERROR ||
+		event.CreatedAt < branchTx.Detail.NextRetryAt {
+		t.Fatalf("service A should be notified after the backoff, got %v of branch %v", event, branchTx.Detail)
+		return
+	}
+	if items := claimTestCompensationWork(t, client, nodeA, xid); len(items) != 1 ||
+		items[0].Branch.BranchId != branchTxIds[0] {
+		t.Fatalf("branch should be claimed after the backoff, got %v", items)
+		return
+	}
+}
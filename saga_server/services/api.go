@@ -2,7 +2,6 @@ package services
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
@@ -10,6 +9,7 @@ import (
 	"github.com/zoowii/saga_server/app"
 	"github.com/zoowii/saga_server/db"
 	"log"
+	"time"
 )
 
 type ReplyErrorCodes = int32
@@ -17,31 +17,55 @@ type ReplyErrorCodes = int32
 const (
 	Ok ReplyErrorCodes = 0
 	//NotImplemented       ReplyErrorCodes = 1
-	ServerError          ReplyErrorCodes = 2
-	ResourceChangedError ReplyErrorCodes = 3
-	NotFoundError        ReplyErrorCodes = 404
+	ServerError                 ReplyErrorCodes = 2
+	ResourceChangedError        ReplyErrorCodes = 3
+	BranchGroupNotSettledError  ReplyErrorCodes = 4 // 并行分支组还有分支没有创建或者还在执行中
+	ForwardRecoveryPendingError ReplyErrorCodes = 5 // 还有向前恢复的分支在等待重试
+	EventCursorExpiredError     ReplyErrorCodes = 6 // 订阅事件的cursor之后的事件已经不在保留的历史中
+	CompensationLeasedError     ReplyErrorCodes = 7 // 分支的补偿、重试或者confirm任务在其他参与方的租约中
+	NotFoundError               ReplyErrorCodes = 404
 )
 
-// TODO: branchId在创建时考虑增加上级branchId的层级关系
-
 type SagaServerService struct {
 	pb.UnimplementedSagaServerServer
 	application app.ApplicationContext
-	dbConn      *sql.DB
+	store       db.Store
+	events      *TxEventBus
+	// SubscribeEvents推送最新cursor的间隔
+	eventHeartbeatInterval time.Duration
 }
 
 func NewSagaServerService(sagaApp app.ApplicationContext) (ss *SagaServerService, err error) {
-	dbConn, err := sagaApp.GetDb()
+	store, err := sagaApp.GetStore()
 	if err != nil {
 		return
 	}
 	ss = &SagaServerService{
 		application: sagaApp,
-		dbConn:      dbConn,
+		store:       store,
+		events:      NewTxEventBus(store),
+
+		eventHeartbeatInterval: defaultTxEventHeartbeatInterval,
 	}
 	return
 }
 
+/**
+ * 设置SubscribeEvents推送最新cursor的间隔，需要在开始服务前设置
+ */
+func (s *SagaServerService) SetEventHeartbeatInterval(interval time.Duration) {
+	if interval > 0 {
+		s.eventHeartbeatInterval = interval
+	}
+}
+
+/**
+ * 全局事务状态变化的事件总线，后台任务修改状态后也通过它发布
+ */
+func (s *SagaServerService) Events() *TxEventBus {
+	return s.events
+}
+
 func generateUniqueId() string {
 	u := uuid.New()
 	return u.String()
@@ -49,13 +73,13 @@ func generateUniqueId() string {
 
 const (
 	defaultGlobalTxExpireSeconds            = 60
-	defaultBranchTxCompensationMaxFailTimes = 3 // 单个branchTx允许补偿任务最大的失败次数（超过则整个全局事务标记为异常失败）
+	defaultBranchTxCompensationMaxFailTimes = 3 // 没有配置重试策略时单个branchTx允许补偿任务最大的失败次数（超过则整个全局事务标记为异常失败）
 )
 
 func (s *SagaServerService) CreateGlobalTransaction(ctx context.Context,
 	req *pb.CreateGlobalTransactionRequest) (res *pb.CreateGlobalTransactionReply, err error) {
 	log.Println("CreateGlobalTransaction")
-	dbConn := s.dbConn
+	store := s.store
 	nodeInfo := req.Node
 	if nodeInfo == nil {
 		nodeInfo = &pb.NodeInfo{}
@@ -64,6 +88,22 @@ func (s *SagaServerService) CreateGlobalTransaction(ctx context.Context,
 	if expireSeconds <= 0 {
 		expireSeconds = defaultGlobalTxExpireSeconds
 	}
+	retryPolicy, err := retryPolicyFromPb(req.RetryPolicy)
+	if err != nil {
+		res = &pb.CreateGlobalTransactionReply{
+			Code:  ServerError,
+			Error: err.Error(),
+		}
+		err = nil
+		return
+	}
+	if !isValidTransactionMode(req.Mode) {
+		res = &pb.CreateGlobalTransactionReply{
+			Code:  ServerError,
+			Error: fmt.Sprintf("invalid transaction mode %d", req.Mode),
+		}
+		return
+	}
 	globalTxRecord := &db.GlobalTxEntity{
 		Xid:               generateUniqueId(),
 		State:             int(pb.TxState_PROCESSING),
@@ -73,8 +113,10 @@ func (s *SagaServerService) CreateGlobalTransaction(ctx context.Context,
 		CreatorInstanceId: nodeInfo.InstanceId,
 		ExpireSeconds:     int(expireSeconds),
 		Extra:             &req.Extra,
+		RetryPolicy:       retryPolicy,
+		Mode:              int(req.Mode),
 	}
-	xid, err := db.CreateGlobalTx(ctx, dbConn, globalTxRecord)
+	xid, err := store.CreateGlobalTx(ctx, globalTxRecord)
 	if err != nil {
 		log.Printf("create global tx error %s\n", err.Error())
 		res = &pb.CreateGlobalTransactionReply{
@@ -83,6 +125,8 @@ func (s *SagaServerService) CreateGlobalTransaction(ctx context.Context,
 		}
 		return
 	}
+	state := pb.TxState(globalTxRecord.State)
+	s.events.Publish(globalTxStateEvent(globalTxRecord, state, state, time.Now()))
 	res = &pb.CreateGlobalTransactionReply{
 		Code: Ok,
 		Xid:  xid,
@@ -93,7 +137,7 @@ func (s *SagaServerService) CreateGlobalTransaction(ctx context.Context,
 func (s *SagaServerService) CreateBranchTransaction(ctx context.Context,
 	req *pb.CreateBranchTransactionRequest) (res *pb.CreateBranchTransactionReply, err error) {
 	log.Println("CreateBranchTransaction")
-	dbConn := s.dbConn
+	store := s.store
 	nodeInfo := req.Node
 	if nodeInfo == nil {
 		nodeInfo = &pb.NodeInfo{}
@@ -115,6 +159,15 @@ func (s *SagaServerService) CreateBranchTransaction(ctx context.Context,
 		return
 	}
 	branchCompensationServiceKey := req.BranchCompensationServiceKey
+	retryPolicy, err := retryPolicyFromPb(req.RetryPolicy)
+	if err != nil {
+		res = &pb.CreateBranchTransactionReply{
+			Code:  ServerError,
+			Error: err.Error(),
+		}
+		err = nil
+		return
+	}
 	branchTxRecord := &db.BranchTxEntity{
 		BranchTxId:                   generateUniqueId(),
 		Xid:                          xid,
@@ -125,16 +178,90 @@ func (s *SagaServerService) CreateBranchTransaction(ctx context.Context,
 		NodeInstanceId:               nodeInfo.InstanceId,
 		BranchServiceKey:             branchServiceKey,
 		BranchCompensationServiceKey: branchCompensationServiceKey,
+		RetryPolicy:                  retryPolicy,
+		ParentBranchTxId:             req.ParentBranchId,
+		BranchGroup:                  req.BranchGroup,
+		BranchGroupSize:              req.BranchGroupSize,
+		RecoveryMode:                 int(req.RecoveryMode),
+		BranchConfirmServiceKey:      req.BranchConfirmServiceKey,
+		HeartbeatTimeoutSeconds:      req.HeartbeatTimeoutSeconds,
 	}
-	branchTxId, err := db.CreateBranchTx(ctx, dbConn, branchTxRecord)
-	if err != nil {
-		log.Printf("create branch tx error %s\n", err.Error())
+	if req.HeartbeatTimeoutSeconds < 0 {
 		res = &pb.CreateBranchTransactionReply{
 			Code:  ServerError,
-			Error: err.Error(),
+			Error: fmt.Sprintf("invalid heartbeat timeout %d", req.HeartbeatTimeoutSeconds),
 		}
 		return
 	}
+	if req.HeartbeatTimeoutSeconds > 0 {
+		// 创建分支相当于第一次心跳
+		now := time.Now()
+		branchTxRecord.LastHeartbeatAt = &now
+	}
+	if len(req.BranchGroup) > 0 && req.BranchGroupSize <= 0 {
+		res = &pb.CreateBranchTransactionReply{
+			Code:  ServerError,
+			Error: fmt.Sprintf("invalid size %d of branch group %s", req.BranchGroupSize, req.BranchGroup),
+		}
+		return
+	}
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) (*pb.CreateBranchTransactionReply, error) {
+		return &pb.CreateBranchTransactionReply{
+			Code:  code,
+			Error: msg,
+		}, nil
+	}
+	tx, err := s.events.BeginTx(ctx, store)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	globalTx, err := tx.FindGlobalTxByXidOrNull(ctx, xid)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if globalTx == nil {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("xid %s not found", xid))
+	}
+	if globalTx.EndBranches {
+		// 发起方已经关闭了这个全局事务，不再接受新的分支
+		return sendErrorResponse(ServerError, fmt.Sprintf("xid %s closed, can't create branch any more", xid))
+	}
+	if len(req.BranchConfirmServiceKey) > 0 && !isTccGlobalTx(globalTx) {
+		return sendErrorResponse(ServerError, fmt.Sprintf("xid %s is not TCC mode, branch confirm not supported", xid))
+	}
+	if len(req.ParentBranchId) > 0 {
+		var parentBranchTx *db.BranchTxEntity
+		parentBranchTx, err = tx.FindBranchTxByBranchTxId(ctx, req.ParentBranchId)
+		if err != nil {
+			return sendErrorResponse(ServerError, err.Error())
+		}
+		if parentBranchTx == nil || parentBranchTx.Xid != xid {
+			return sendErrorResponse(NotFoundError,
+				fmt.Sprintf("parent branch %s not found in xid %s", req.ParentBranchId, xid))
+		}
+	}
+	if len(req.BranchGroup) > 0 {
+		var msg string
+		msg, err = checkBranchGroupJoinable(ctx, tx, globalTx, branchTxRecord)
+		if err != nil {
+			return sendErrorResponse(ServerError, err.Error())
+		}
+		if len(msg) > 0 {
+			return sendErrorResponse(ServerError, msg)
+		}
+	}
+	branchTxId, err := tx.CreateBranchTx(ctx, branchTxRecord)
+	if err != nil {
+		log.Printf("create branch tx error %s\n", err.Error())
+		return sendErrorResponse(ServerError, err.Error())
+	}
 	res = &pb.CreateBranchTransactionReply{
 		Code:     Ok,
 		BranchId: branchTxId,
@@ -143,6 +270,10 @@ func (s *SagaServerService) CreateBranchTransaction(ctx context.Context,
 }
 
 func branchTxToDetailInPb(branchTx *db.BranchTxEntity) *pb.TransactionBranchDetail {
+	var nextRetryAt int64
+	if branchTx.NextRetryAt != nil {
+		nextRetryAt = branchTx.NextRetryAt.UnixNano() / int64(time.Millisecond)
+	}
 	return &pb.TransactionBranchDetail{
 		BranchId: branchTx.BranchTxId,
 		Node: &pb.NodeInfo{
@@ -155,15 +286,27 @@ func branchTxToDetailInPb(branchTx *db.BranchTxEntity) *pb.TransactionBranchDeta
 		CompensationFailTimes:        branchTx.CompensationFailTimes,
 		BranchServiceKey:             branchTx.BranchServiceKey,
 		BranchCompensationServiceKey: branchTx.BranchCompensationServiceKey,
+		NextRetryAt:                  nextRetryAt,
+		ParentBranchId:               branchTx.ParentBranchTxId,
+		BranchGroup:                  branchTx.BranchGroup,
+		BranchGroupSize:              branchTx.BranchGroupSize,
+		RecoveryMode:                 pb.RecoveryMode(branchTx.RecoveryMode),
+		ForwardRetryTimes:            branchTx.ForwardRetryTimes,
+		BranchConfirmServiceKey:      branchTx.BranchConfirmServiceKey,
+		LeaseOwner:                   branchTx.LeaseOwner,
+		LeaseExpireAt:                unixMillis(branchTx.LeaseExpireAt),
+		HeartbeatTimeoutSeconds:      branchTx.HeartbeatTimeoutSeconds,
+		LastHeartbeatAt:              unixMillis(branchTx.LastHeartbeatAt),
+		OrphanedAt:                   unixMillis(branchTx.OrphanedAt),
 	}
 }
 
 func (s *SagaServerService) QueryGlobalTransactionDetail(ctx context.Context,
 	req *pb.QueryGlobalTransactionDetailRequest) (res *pb.QueryGlobalTransactionDetailReply, err error) {
 	log.Println("QueryGlobalTransactionDetail")
-	dbConn := s.dbConn
+	store := s.store
 	xid := req.Xid
-	globalTx, err := db.FindGlobalTxByXidOrNull(ctx, dbConn, xid)
+	globalTx, err := store.FindGlobalTxByXidOrNull(ctx, xid)
 	if err != nil {
 		res = &pb.QueryGlobalTransactionDetailReply{
 			Code:  ServerError,
@@ -178,7 +321,7 @@ func (s *SagaServerService) QueryGlobalTransactionDetail(ctx context.Context,
 		}
 		return
 	}
-	branchTxs, err := db.FindAllBranchTxsByXid(ctx, dbConn, xid)
+	branchTxs, err := store.FindAllBranchTxsByXid(ctx, xid)
 	if err != nil {
 		res = &pb.QueryGlobalTransactionDetailReply{
 			Code:  ServerError,
@@ -204,9 +347,15 @@ func (s *SagaServerService) QueryGlobalTransactionDetail(ctx context.Context,
 			InstanceId: globalTx.CreatorInstanceId,
 		},
 		Branches: branchDetails,
+		BranchTree: branchTxTreeToPb(branchTxs),
+		BranchGroups: branchGroupsToPb(branchTxs),
 		CreatedAt: globalTx.CreatedAt.Unix(),
 		UpdatedAt: globalTx.UpdatedAt.Unix(),
 		ExpireSeconds: int32(globalTx.ExpireSeconds),
+		Mode: pb.TransactionMode(globalTx.Mode),
+	}
+	if globalTx.Extra != nil {
+		res.Extra = *globalTx.Extra
 	}
 	return
 }
@@ -214,7 +363,7 @@ func (s *SagaServerService) QueryGlobalTransactionDetail(ctx context.Context,
 func (s *SagaServerService) QueryBranchTransactionDetail(ctx context.Context,
 	req *pb.QueryBranchTransactionDetailRequest) (*pb.QueryBranchTransactionDetailReply, error) {
 	log.Println("QueryBranchTransactionDetail")
-	dbConn := s.dbConn
+	store := s.store
 	branchTxId := req.BranchId
 
 	sendErrorResponse := func(code ReplyErrorCodes, msg string) (*pb.QueryBranchTransactionDetailReply, error) {
@@ -225,13 +374,16 @@ func (s *SagaServerService) QueryBranchTransactionDetail(ctx context.Context,
 	}
 
 	var err error
-	branchTx, err := db.FindBranchTxByBranchTxId(ctx, dbConn, branchTxId)
+	branchTx, err := store.FindBranchTxByBranchTxId(ctx, branchTxId)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
+	if branchTx == nil {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("branch tx %s not found", branchTxId))
+	}
 	xid := branchTx.Xid
 
-	globalTx, err := db.FindGlobalTxByXidOrNull(ctx, dbConn, xid)
+	globalTx, err := store.FindGlobalTxByXidOrNull(ctx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -258,12 +410,12 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 			Error: msg,
 		}, nil
 	}
-	dbConn := s.dbConn
+	store := s.store
 	xid := req.Xid
 	state := req.State
 	oldState := req.OldState
 	oldVersion := req.OldVersion
-	globalTx, err := db.FindGlobalTxByXidOrNull(ctx, dbConn, xid)
+	globalTx, err := store.FindGlobalTxByXidOrNull(ctx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -273,13 +425,17 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 	if globalTx.State != int(oldState) || globalTx.Version != oldVersion {
 		return sendErrorResponse(ResourceChangedError, fmt.Sprintf("xid %s dirty change", xid))
 	}
+	if globalTx.State == int(pb.TxState_CONFIRMING) {
+		// TCC模式的全局事务已经提交，各分支confirm一直重试直到成功，不能再改状态
+		return sendErrorResponse(ServerError, fmt.Sprintf("xid %s is confirming, can't change state", xid))
+	}
 	if globalTx.State == int(state) {
 		return &pb.SubmitGlobalTransactionStateReply{
 			Code:  Ok,
 			State: state,
 		}, nil
 	}
-	tx, err := dbConn.BeginTx(ctx, nil)
+	tx, err := s.events.BeginTx(ctx, store)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -290,7 +446,43 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 			err = tx.Commit()
 		}
 	}()
-	rowsChanged, err := db.UpdateGlobalTxState(ctx, tx, xid, oldVersion, globalTx.State, int(state))
+	var branches []*db.BranchTxEntity
+	if state == pb.TxState_COMMITTED {
+		// 并行分支组的各分支都执行完之后全局事务才能提交
+		branches, err = tx.FindAllBranchTxsByXid(ctx, xid)
+		if err != nil {
+			return sendErrorResponse(ServerError, err.Error())
+		}
+		if group := firstUnsettledBranchGroup(branches); group != nil {
+			return sendErrorResponse(BranchGroupNotSettledError,
+				fmt.Sprintf("branch group %s of xid %s not settled", group.group, xid))
+		}
+		if hasRetryingBranchTx(branches) {
+			// 向前恢复的分支还在重试，发起方可以关闭全局事务，重试成功后自动提交
+			return sendErrorResponse(ForwardRecoveryPendingError,
+				fmt.Sprintf("xid %s has retrying forward recovery branches", xid))
+		}
+	}
+	if state == pb.TxState_COMMITTED && isTccGlobalTx(globalTx) {
+		// TCC模式的全局事务要各分支都try成功后才能提交，提交后先进入CONFIRMING，各分支confirm成功后才COMMITTED
+		if b := firstNotTriedBranchTx(branches); b != nil {
+			return sendErrorResponse(ServerError, fmt.Sprintf("branch %s of xid %s in state %s can't be confirmed",
+				b.BranchTxId, xid, pb.TxState(b.State).String()))
+		}
+		var rowsChanged int64
+		rowsChanged, err = startConfirmGlobalTx(ctx, tx, globalTx, branches)
+		if err != nil {
+			return sendErrorResponse(ServerError, err.Error())
+		}
+		if rowsChanged < 1 {
+			return sendErrorResponse(ResourceChangedError, fmt.Sprintf("xid %s not change, maybe version expired", xid))
+		}
+		return &pb.SubmitGlobalTransactionStateReply{
+			Code:  Ok,
+			State: pb.TxState(globalTx.State),
+		}, nil
+	}
+	rowsChanged, err := tx.UpdateGlobalTxState(ctx, xid, oldVersion, globalTx.State, int(state))
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -302,7 +494,7 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 	case pb.TxState_COMMITTED:
 		{
 			// 如果全局事务标记为committed，各对应分支事务还没结束的也要这么标记. 需要全局事务发起方在全局事务都committed后才把此全局事务标记为committed
-			_, err = db.UpdateBranchesStateByXid(ctx, tx, xid, int(state))
+			_, err = tx.UpdateBranchesStateByXid(ctx, xid, int(state))
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
@@ -310,7 +502,7 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 	case pb.TxState_COMPENSATION_DOING:
 		{
 			// 全局事务回滚
-			err = logicWhenSubmitGlobalTxCompensationDoing(ctx, dbConn, tx,
+			err = logicWhenSubmitGlobalTxCompensationDoing(ctx, tx,
 				globalTx, oldState)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
@@ -319,7 +511,7 @@ func (s *SagaServerService) SubmitGlobalTransactionState(ctx context.Context,
 	case pb.TxState_COMPENSATION_FAIL:
 		{
 			// 标记全局事务失败了
-			err = logicWhenSubmitGlobalTxCompensationFail(ctx, dbConn, tx, globalTx, oldState)
+			err = logicWhenSubmitGlobalTxCompensationFail(ctx, tx, globalTx, oldState)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
@@ -342,7 +534,7 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 			Error: msg,
 		}, nil
 	}
-	dbConn := s.dbConn
+	store := s.store
 	xid := req.Xid
 	branchTxId := req.BranchId
 	state := req.State
@@ -351,7 +543,7 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 	jobId := req.JobId
 	errorReason := req.ErrorReason
 	sagaData := req.SagaData
-	branchTx, err := db.FindBranchTxByBranchTxId(ctx, dbConn, branchTxId)
+	branchTx, err := store.FindBranchTxByBranchTxId(ctx, branchTxId)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -361,8 +553,23 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 	if branchTx.State != int(oldState) || branchTx.Version != oldVersion || branchTx.Xid != xid {
 		return sendErrorResponse(ResourceChangedError, fmt.Sprintf("branch tx %s dirty change", branchTxId))
 	}
-	if branchTx.State == int(state) && state != pb.TxState_COMPENSATION_ERROR {
-		// 没有改动且不是补偿失败（补偿失败可以多次）
+	if state == pb.TxState_RETRYING && !isForwardBranchTx(branchTx) {
+		return sendErrorResponse(ServerError, fmt.Sprintf("branch tx %s is not forward recovery", branchTxId))
+	}
+	if isBranchLeased(branchTx, time.Now()) && branchTx.LeaseId != req.LeaseId {
+		// 没有领取租约的参与方(例如只轮询全局事务的旧worker)不能提交其他人正在补偿或者重试的分支的结果
+		return sendErrorResponse(CompensationLeasedError, fmt.Sprintf("branch tx %s is leased by %s",
+			branchTxId, branchTx.LeaseOwner))
+	}
+	confirmState := state == pb.TxState_CONFIRMING || state == pb.TxState_CONFIRMED
+	if confirmState != (branchTx.State == int(pb.TxState_CONFIRMING)) && branchTx.State != int(state) {
+		// confirm的结果只能提交给CONFIRMING的分支，CONFIRMING的分支也只接受confirm的结果
+		return sendErrorResponse(ServerError, fmt.Sprintf("branch tx %s in state %s can't change to %s",
+			branchTxId, pb.TxState(branchTx.State).String(), state.String()))
+	}
+	if branchTx.State == int(state) && state != pb.TxState_COMPENSATION_ERROR &&
+		state != pb.TxState_RETRYING && state != pb.TxState_CONFIRMING {
+		// 没有改动且不是补偿失败、重试失败或者confirm失败（可以多次）
 		return &pb.SubmitBranchTransactionStateReply{
 			Code:  Ok,
 			State: state,
@@ -370,7 +577,7 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 	}
 
 	// 修改分支事务状态
-	tx, err := dbConn.BeginTx(ctx, nil)
+	tx, err := s.events.BeginTx(ctx, store)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -381,34 +588,35 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 			err = tx.Commit()
 		}
 	}()
-	rowsChanged, err := updateBranchTxState(ctx, tx, branchTx, int(state))
+	var globalTx *db.GlobalTxEntity
+	globalTx, err = findGlobalTxOrError(ctx, tx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
-	if rowsChanged < 1 {
-		return sendErrorResponse(ResourceChangedError,
-			fmt.Sprintf("branch tx %s not change, maybe version expired", branchTxId))
-	}
-
-	var globalTx *db.GlobalTxEntity
-	globalTx, err = findGlobalTxOrError(ctx, dbConn, xid)
+	state = forwardBranchTxSubmitState(globalTx, branchTx, state)
+	// 修改时再检查一次租约，上面读到分支之后其他参与方可能刚领取了它
+	rowsChanged, err := updateLeasedBranchTxState(ctx, tx, branchTx, int(state), req.LeaseId)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
+	if rowsChanged < 1 {
+		return sendErrorResponse(ResourceChangedError,
+			fmt.Sprintf("branch tx %s not change, maybe version expired or leased by others", branchTxId))
+	}
 
 	if sagaData != nil {
 		var existedSagaDataRecord *db.SagaDataEntity
-		existedSagaDataRecord, err = db.QuerySagaData(ctx, tx, xid)
+		existedSagaDataRecord, err = tx.QuerySagaData(ctx, xid)
 		if err != nil {
 			return sendErrorResponse(ServerError, err.Error())
 		}
 		if existedSagaDataRecord == nil {
-			_, err = db.InsertSagaData(ctx, tx, xid, sagaData)
+			_, err = tx.InsertSagaData(ctx, xid, sagaData)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
 		} else {
-			_, err = db.UpdateSagaData(ctx, tx, xid, sagaData, existedSagaDataRecord.Version)
+			_, err = tx.UpdateSagaData(ctx, xid, sagaData, existedSagaDataRecord.Version)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
@@ -418,21 +626,43 @@ func (s *SagaServerService) SubmitBranchTransactionState(ctx context.Context,
 	switch state {
 	case pb.TxState_COMMITTED:
 		{
-			err = logicWhenSubmitBranchTxCommitted(ctx, dbConn, tx, globalTx, branchTx)
+			err = logicWhenSubmitBranchTxCommitted(ctx, tx, globalTx, branchTx)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
 		}
 	case pb.TxState_COMPENSATION_ERROR:
 		{
-			err = logicWhenSubmitBranchTxCompensationError(ctx, dbConn, tx, globalTx, branchTx, jobId, errorReason)
+			err = logicWhenSubmitBranchTxCompensationError(ctx, tx, globalTx, branchTx, jobId, errorReason)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
 		}
 	case pb.TxState_COMPENSATION_DONE:
 		{
-			err = logicWhenSubmitBranchTxCompensationDone(ctx, dbConn, tx, globalTx, branchTx)
+			err = logicWhenSubmitBranchTxCompensationDone(ctx, tx, globalTx, branchTx)
+			if err != nil {
+				return sendErrorResponse(ServerError, err.Error())
+			}
+		}
+	case pb.TxState_RETRYING:
+		{
+			err = logicWhenSubmitBranchTxRetrying(ctx, tx, globalTx, branchTx, jobId, errorReason)
+			if err != nil {
+				return sendErrorResponse(ServerError, err.Error())
+			}
+		}
+	case pb.TxState_CONFIRMING:
+		{
+			err = logicWhenSubmitBranchTxConfirming(ctx, tx, globalTx, branchTx, jobId, errorReason)
+			if err != nil {
+				return sendErrorResponse(ServerError, err.Error())
+			}
+		}
+	case pb.TxState_CONFIRMED:
+		{
+			// 各分支都confirm成功后全局事务改成COMMITTED
+			err = commitGlobalTxIfAllBranchesConfirmed(ctx, tx, globalTx)
 			if err != nil {
 				return sendErrorResponse(ServerError, err.Error())
 			}
@@ -455,10 +685,10 @@ func (s *SagaServerService) InitSagaData(ctx context.Context,
 			Error: msg,
 		}, nil
 	}
-	dbConn := s.dbConn
+	store := s.store
 	xid := req.Xid
 	data := req.Data
-	tx, err := dbConn.BeginTx(ctx, nil)
+	tx, err := store.BeginTx(ctx)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -469,21 +699,21 @@ func (s *SagaServerService) InitSagaData(ctx context.Context,
 			err = tx.Commit()
 		}
 	}()
-	record, err := db.QuerySagaData(ctx, tx, xid)
+	record, err := tx.QuerySagaData(ctx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
 	if record != nil {
-		log.Printf("xid %s inited saga data before, no need to init again")
+		log.Printf("xid %s inited saga data before, no need to init again", xid)
 		return &pb.InitSagaDataReply{
 			Code: Ok,
 		}, nil
 	}
-	_, err = db.InsertSagaData(ctx, tx, xid, data)
+	_, err = tx.InsertSagaData(ctx, xid, data)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
-	record, err = db.QuerySagaData(ctx, tx, xid)
+	record, err = tx.QuerySagaData(ctx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -506,9 +736,9 @@ func (s *SagaServerService) GetSagaData(ctx context.Context,
 			Error: msg,
 		}, nil
 	}
-	dbConn := s.dbConn
+	store := s.store
 	xid := req.Xid
-	tx, err := dbConn.BeginTx(ctx, nil)
+	tx, err := store.BeginTx(ctx)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -519,7 +749,7 @@ func (s *SagaServerService) GetSagaData(ctx context.Context,
 			err = tx.Commit()
 		}
 	}()
-	sagaDataEntity, err := db.QuerySagaData(ctx, tx, xid)
+	sagaDataEntity, err := tx.QuerySagaData(ctx, xid)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -546,7 +776,7 @@ func (s *SagaServerService) ListGlobalTransactionsOfStates(ctx context.Context,
 			Error: msg,
 		}, nil
 	}
-	dbConn := s.dbConn
+	store := s.store
 	states := req.States
 	limit := req.Limit
 	if limit <= 0 {
@@ -558,7 +788,7 @@ func (s *SagaServerService) ListGlobalTransactionsOfStates(ctx context.Context,
 			Xids: make([]string, 0),
 		}, nil
 	}
-	xids, err := db.FindXidsOfGlobalTxsByStates(ctx, dbConn, states, limit)
+	xids, err := store.FindXidsOfGlobalTxsByStates(ctx, states, time.Now(), limit)
 	if err != nil {
 		return sendErrorResponse(ServerError, err.Error())
 	}
@@ -567,3 +797,399 @@ func (s *SagaServerService) ListGlobalTransactionsOfStates(ctx context.Context,
 		Xids: xids,
 	}, nil
 }
+
+func (s *SagaServerService) CloseGlobalTransaction(ctx context.Context,
+	req *pb.CloseGlobalTransactionRequest) (*pb.CloseGlobalTransactionReply, error) {
+	log.Println("CloseGlobalTransaction")
+	var err error
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) (*pb.CloseGlobalTransactionReply, error) {
+		return &pb.CloseGlobalTransactionReply{
+			Code:  code,
+			Error: msg,
+		}, nil
+	}
+	store := s.store
+	xid := req.Xid
+	tx, err := s.events.BeginTx(ctx, store)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	globalTx, err := tx.FindGlobalTxByXidOrNull(ctx, xid)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if globalTx == nil {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("xid %s not found", xid))
+	}
+	if globalTx.EndBranches {
+		// 重复关闭
+		return &pb.CloseGlobalTransactionReply{
+			Code:  Ok,
+			State: pb.TxState(globalTx.State),
+		}, nil
+	}
+	if globalTx.State != int(pb.TxState_PROCESSING) {
+		return sendErrorResponse(ServerError,
+			fmt.Sprintf("xid %s in state %s can't be closed", xid, pb.TxState(globalTx.State).String()))
+	}
+	rowsChanged, err := tx.UpdateGlobalTxEndBranches(ctx, xid, globalTx.Version)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if rowsChanged < 1 {
+		return sendErrorResponse(ResourceChangedError, fmt.Sprintf("xid %s dirty change", xid))
+	}
+	globalTx.EndBranches = true
+	globalTx.Version += 1
+	// 关闭时各分支已经都committed的话直接提交全局事务
+	err = commitGlobalTxIfAllBranchesCommitted(ctx, tx, globalTx)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	return &pb.CloseGlobalTransactionReply{
+		Code:  Ok,
+		State: pb.TxState(globalTx.State),
+	}, nil
+}
+
+func (s *SagaServerService) ClaimCompensationWork(ctx context.Context,
+	req *pb.ClaimCompensationWorkRequest) (*pb.ClaimCompensationWorkReply, error) {
+	log.Println("ClaimCompensationWork")
+	var err error
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) (*pb.ClaimCompensationWorkReply, error) {
+		return &pb.ClaimCompensationWorkReply{
+			Code:  code,
+			Error: msg,
+		}, nil
+	}
+	store := s.store
+	node := req.Node
+	if node == nil {
+		return sendErrorResponse(ServerError, "empty node")
+	}
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultCompensationClaimLimit
+	}
+	tx, err := store.BeginTx(ctx)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	items, err := claimCompensationWork(ctx, tx, node, req.Work, req.Xid,
+		compensationLeaseDuration(req.LeaseSeconds), limit)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	return &pb.ClaimCompensationWorkReply{
+		Code:  Ok,
+		Items: items,
+	}, nil
+}
+
+/**
+ * 延长租约，租约到期后还没被其他参与方领取的也可以续约
+ */
+func (s *SagaServerService) RenewCompensationWork(ctx context.Context,
+	req *pb.RenewCompensationWorkRequest) (*pb.RenewCompensationWorkReply, error) {
+	log.Println("RenewCompensationWork")
+	store := s.store
+	leaseExpireAt := time.Now().Add(compensationLeaseDuration(req.LeaseSeconds))
+	renewed := make([]*pb.CompensationWorkLease, 0)
+	for _, lease := range req.Leases {
+		if len(lease.LeaseId) < 1 {
+			continue
+		}
+		rowsChanged, err := store.RenewBranchTxLease(ctx, lease.BranchId, lease.LeaseId, leaseExpireAt)
+		if err != nil {
+			return &pb.RenewCompensationWorkReply{
+				Code:  ServerError,
+				Error: err.Error(),
+			}, nil
+		}
+		if rowsChanged < 1 {
+			continue
+		}
+		renewed = append(renewed, &pb.CompensationWorkLease{
+			Xid:           lease.Xid,
+			BranchId:      lease.BranchId,
+			LeaseId:       lease.LeaseId,
+			LeaseExpireAt: unixMillis(&leaseExpireAt),
+		})
+	}
+	return &pb.RenewCompensationWorkReply{
+		Code:   Ok,
+		Leases: renewed,
+	}, nil
+}
+
+func (s *SagaServerService) ReleaseCompensationWork(ctx context.Context,
+	req *pb.ReleaseCompensationWorkRequest) (*pb.ReleaseCompensationWorkReply, error) {
+	log.Println("ReleaseCompensationWork")
+	store := s.store
+	var released int32
+	var releasedXids []string
+	releasedXidSet := make(map[string]bool)
+	for _, lease := range req.Leases {
+		if len(lease.LeaseId) < 1 {
+			continue
+		}
+		rowsChanged, err := store.ReleaseBranchTxLease(ctx, lease.BranchId, lease.LeaseId)
+		if err != nil {
+			return &pb.ReleaseCompensationWorkReply{
+				Code:  ServerError,
+				Error: err.Error(),
+			}, nil
+		}
+		if rowsChanged > 0 {
+			released++
+			if !releasedXidSet[lease.Xid] {
+				releasedXidSet[lease.Xid] = true
+				releasedXids = append(releasedXids, lease.Xid)
+			}
+		}
+	}
+	// 释放的分支如果还没有完成，其他参与方现在可以领取
+	if err := s.events.publishClaimableWork(ctx, releasedXids); err != nil {
+		log.Printf("publish claimable work error %s\n", err.Error())
+	}
+	return &pb.ReleaseCompensationWorkReply{
+		Code:     Ok,
+		Released: released,
+	}, nil
+}
+
+/**
+ * 记录执行中分支的心跳，分支已经不是PROCESSING时不再记录，执行方根据返回的状态停止心跳
+ */
+func (s *SagaServerService) HeartbeatBranch(ctx context.Context,
+	req *pb.HeartbeatBranchRequest) (*pb.HeartbeatBranchReply, error) {
+	log.Println("HeartbeatBranch")
+	store := s.store
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) (*pb.HeartbeatBranchReply, error) {
+		return &pb.HeartbeatBranchReply{
+			Code:  code,
+			Error: msg,
+		}, nil
+	}
+	branchTx, err := store.FindBranchTxByBranchTxId(ctx, req.BranchId)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if branchTx == nil || branchTx.Xid != req.Xid {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("branch %s not found in xid %s", req.BranchId, req.Xid))
+	}
+	if req.Node != nil && len(req.Node.InstanceId) > 0 && req.Node.InstanceId != branchTx.NodeInstanceId {
+		return sendErrorResponse(ServerError, fmt.Sprintf("branch %s not created by instance %s",
+			req.BranchId, req.Node.InstanceId))
+	}
+	globalTx, err := store.FindGlobalTxByXidOrNull(ctx, branchTx.Xid)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if globalTx == nil {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("xid %s not found", branchTx.Xid))
+	}
+	// 只给还在PROCESSING的分支记录心跳，同时被回滚或者结束的分支不会清除失去心跳的标记
+	rowsChanged, err := store.UpdateBranchTxHeartbeat(ctx, branchTx.BranchTxId, time.Now())
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	state := pb.TxState_PROCESSING
+	if rowsChanged < 1 {
+		branchTx, err = store.FindBranchTxByBranchTxId(ctx, branchTx.BranchTxId)
+		if err != nil {
+			return sendErrorResponse(ServerError, err.Error())
+		}
+		if branchTx == nil {
+			return sendErrorResponse(NotFoundError, fmt.Sprintf("branch %s not found in xid %s", req.BranchId, req.Xid))
+		}
+		state = pb.TxState(branchTx.State)
+	}
+	return &pb.HeartbeatBranchReply{
+		Code:          Ok,
+		State:         state,
+		GlobalTxState: pb.TxState(globalTx.State),
+	}, nil
+}
+
+/**
+ * 先推送全局事务和各分支当前的状态，再推送之后的每次状态变化，全局事务结束后关闭stream
+ */
+func (s *SagaServerService) WatchGlobalTransaction(req *pb.WatchGlobalTransactionRequest,
+	stream pb.SagaServer_WatchGlobalTransactionServer) error {
+	log.Println("WatchGlobalTransaction")
+	ctx := stream.Context()
+	xid := req.Xid
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) error {
+		return stream.Send(&pb.WatchGlobalTransactionReply{
+			Code:  code,
+			Error: msg,
+		})
+	}
+	// 发送事件，返回全局事务是否已经结束
+	sendEvent := func(event *TxStateEvent) (finished bool, err error) {
+		err = stream.Send(&pb.WatchGlobalTransactionReply{
+			Code:  Ok,
+			Event: txStateEventToPb(event),
+		})
+		finished = event.isGlobalTxEvent() && isTerminalGlobalTxState(event.State)
+		return
+	}
+	snapshot, sub, err := s.events.WatchXid(ctx, xid)
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	if sub == nil {
+		return sendErrorResponse(NotFoundError, fmt.Sprintf("xid %s not found", xid))
+	}
+	defer sub.Close()
+	for _, event := range snapshot {
+		finished, err := sendEvent(event)
+		if err != nil || finished {
+			return err
+		}
+	}
+	versions := newTxStateVersions(snapshot)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return sendErrorResponse(ServerError, fmt.Sprintf("watch of xid %s is too slow, please watch again", xid))
+			}
+			if !versions.accept(event) {
+				// 已经包含在推送过的当前状态中
+				continue
+			}
+			finished, err := sendEvent(event)
+			if err != nil || finished {
+				return err
+			}
+		}
+	}
+}
+
+/**
+ * 订阅方关心的事件，分支按创建方过滤，全局事务按发起方过滤，任务事件按可以领取任务的参与方过滤
+ */
+func txStateEventFilter(node *pb.NodeInfo, states []pb.TxState,
+	works []pb.WorkType) func(event *TxStateEvent) bool {
+	return func(event *TxStateEvent) bool {
+		if node != nil && len(node.Group) > 0 && node.Group != event.NodeGroup {
+			return false
+		}
+		if node != nil && len(node.Service) > 0 && node.Service != event.NodeService {
+			return false
+		}
+		if event.WorkReady {
+			for _, work := range works {
+				if work == event.Work {
+					return true
+				}
+			}
+			return false
+		}
+		if len(works) > 0 && len(states) < 1 {
+			// 只订阅了任务事件
+			return false
+		}
+		if len(states) < 1 {
+			return true
+		}
+		for _, state := range states {
+			if state == event.State {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+/**
+ * 推送满足条件的状态变化直到订阅方断开，订阅方处理太慢时结束stream，订阅方可以用最后的cursor重新订阅
+ */
+func (s *SagaServerService) SubscribeEvents(req *pb.SubscribeEventsRequest,
+	stream pb.SagaServer_SubscribeEventsServer) error {
+	log.Println("SubscribeEvents")
+	ctx := stream.Context()
+	sendErrorResponse := func(code ReplyErrorCodes, msg string) error {
+		return stream.Send(&pb.SubscribeEventsReply{
+			Code:  code,
+			Error: msg,
+		})
+	}
+	sendEvent := func(event *TxStateEvent) error {
+		return stream.Send(&pb.SubscribeEventsReply{
+			Code:   Ok,
+			Event:  txStateEventToPb(event),
+			Cursor: s.events.Cursor(event),
+		})
+	}
+	replay, head, sub, err := s.events.SubscribeFrom(req.Cursor, txStateEventFilter(req.Node, req.States, req.Works))
+	if err == errTxEventCursorExpired {
+		return sendErrorResponse(EventCursorExpiredError, err.Error())
+	}
+	if err != nil {
+		return sendErrorResponse(ServerError, err.Error())
+	}
+	defer sub.Close()
+	for _, event := range replay {
+		if err = sendEvent(event); err != nil {
+			return err
+		}
+	}
+	// 补发完后告诉订阅方已经订阅成功，之后的事件都会推送，断开后从这个cursor继续不需要重复补发
+	err = stream.Send(&pb.SubscribeEventsReply{
+		Code:   Ok,
+		Cursor: head,
+	})
+	if err != nil {
+		return err
+	}
+	lastCursor := head
+	// 被过滤掉的事件不推送，定时推送没有事件的reply带上最新的cursor，订阅方的cursor不会因为一直没有匹配的事件而过期
+	ticker := time.NewTicker(s.eventHeartbeatInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			cursor := s.events.idleCursor(sub)
+			if len(cursor) < 1 || cursor == lastCursor {
+				continue
+			}
+			err = stream.Send(&pb.SubscribeEventsReply{
+				Code:   Ok,
+				Cursor: cursor,
+			})
+			if err != nil {
+				return err
+			}
+			lastCursor = cursor
+		case event, ok := <-sub.Events():
+			if !ok {
+				return sendErrorResponse(ServerError, "subscriber is too slow, please subscribe again with the last cursor")
+			}
+			if err = sendEvent(event); err != nil {
+				return err
+			}
+			lastCursor = s.events.Cursor(event)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+)
+
+/**
+ * 一个并行分支组的完成情况
+ */
+type branchGroupProgress struct {
+	group                 string
+	size                  int32
+	branches              []*db.BranchTxEntity
+	processingCount       int32
+	committedCount        int32
+	compensationDoneCount int32
+}
+
+func (p *branchGroupProgress) full() bool {
+	return int32(len(p.branches)) >= p.size
+}
+
+/**
+ * 所有分支都已经创建并且都执行完了(不在PROCESSING状态)
+ */
+func (p *branchGroupProgress) settled() bool {
+	return p.full() && p.processingCount == 0
+}
+
+/**
+ * 按分支组第一次出现的顺序统计branches中的各分支组
+ */
+func branchGroupsOf(branches []*db.BranchTxEntity) (groups []*branchGroupProgress) {
+	byName := make(map[string]*branchGroupProgress)
+	for _, b := range branches {
+		if len(b.BranchGroup) < 1 {
+			continue
+		}
+		progress, ok := byName[b.BranchGroup]
+		if !ok {
+			progress = &branchGroupProgress{
+				group: b.BranchGroup,
+				size:  b.BranchGroupSize,
+			}
+			byName[b.BranchGroup] = progress
+			groups = append(groups, progress)
+		}
+		progress.branches = append(progress.branches, b)
+		switch pb.TxState(b.State) {
+		case pb.TxState_PROCESSING:
+			progress.processingCount++
+		case pb.TxState_COMMITTED:
+			progress.committedCount++
+		case pb.TxState_COMPENSATION_DONE:
+			progress.compensationDoneCount++
+		}
+	}
+	return
+}
+
+/**
+ * 返回第一个还没有settled的分支组，都settled时返回nil
+ */
+func firstUnsettledBranchGroup(branches []*db.BranchTxEntity) *branchGroupProgress {
+	for _, progress := range branchGroupsOf(branches) {
+		if !progress.settled() {
+			return progress
+		}
+	}
+	return nil
+}
+
+func branchGroupsToPb(branches []*db.BranchTxEntity) []*pb.BranchGroupDetail {
+	groups := branchGroupsOf(branches)
+	result := make([]*pb.BranchGroupDetail, 0, len(groups))
+	for _, progress := range groups {
+		result = append(result, &pb.BranchGroupDetail{
+			BranchGroup:           progress.group,
+			Size:                  progress.size,
+			BranchCount:           int32(len(progress.branches)),
+			ProcessingCount:       progress.processingCount,
+			CommittedCount:        progress.committedCount,
+			CompensationDoneCount: progress.compensationDoneCount,
+			Settled:               progress.settled(),
+		})
+	}
+	return result
+}
+
+/**
+ * 补偿单元是并行分支组并且组内还有分支在执行中
+ */
+func isBranchGroupUnitProcessing(unit []*db.BranchTxEntity) bool {
+	if len(unit) < 1 || len(unit[0].BranchGroup) < 1 {
+		return false
+	}
+	for _, b := range unit {
+		if b.State == int(pb.TxState_PROCESSING) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 检查分支能否加入它声明的分支组，不能加入时返回原因
+ * 同一组的分支要声明相同的分支总数和上级分支，组满了之后不再接受新的分支
+ */
+func checkBranchGroupJoinable(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (msg string, err error) {
+	if globalTx.State != int(pb.TxState_PROCESSING) {
+		// 全局事务已经在回滚，迟到的组内分支不再执行
+		msg = fmt.Sprintf("xid %s not processing, can't join branch group %s", globalTx.Xid, branchTx.BranchGroup)
+		return
+	}
+	branches, err := tx.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+	if err != nil {
+		return
+	}
+	for _, progress := range branchGroupsOf(branches) {
+		if progress.group != branchTx.BranchGroup {
+			continue
+		}
+		if progress.size != branchTx.BranchGroupSize {
+			msg = fmt.Sprintf("branch group %s size is %d, not %d",
+				progress.group, progress.size, branchTx.BranchGroupSize)
+		} else if progress.branches[0].ParentBranchTxId != branchTx.ParentBranchTxId {
+			msg = fmt.Sprintf("branches of group %s must have the same parent branch", progress.group)
+		} else if progress.full() {
+			msg = fmt.Sprintf("branch group %s is full", progress.group)
+		}
+		return
+	}
+	return
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+)
+
+/**
+ * 按parentBranchTxId把branches组织成树，返回顶层分支和每个分支的下级分支. 各层按创建顺序(id升序)
+ * 上级分支不在branches中的分支也当作顶层分支
+ */
+func groupBranchTxsByParent(branches []*db.BranchTxEntity) (roots []*db.BranchTxEntity,
+	children map[string][]*db.BranchTxEntity) {
+	children = make(map[string][]*db.BranchTxEntity)
+	existed := make(map[string]bool)
+	for _, b := range branches {
+		existed[b.BranchTxId] = true
+	}
+	for _, b := range branches {
+		if len(b.ParentBranchTxId) < 1 || !existed[b.ParentBranchTxId] {
+			roots = append(roots, b)
+			continue
+		}
+		children[b.ParentBranchTxId] = append(children[b.ParentBranchTxId], b)
+	}
+	return
+}
+
+/**
+ * 分支的补偿顺序：同一层按创建的倒序，每个分支的下级分支都在它之前补偿
+ */
+func branchTxsInCompensationOrder(branches []*db.BranchTxEntity) (ordered []*db.BranchTxEntity) {
+	for _, unit := range branchTxUnitsInCompensationOrder(branches) {
+		ordered = append(ordered, unit...)
+	}
+	return
+}
+
+/**
+ * 按补偿顺序分成补偿单元，一个单元是一个分支或者一整个并行分支组
+ * 分支组在它最后创建的分支的位置补偿，组内各分支的下级分支都在这个组之前补偿
+ */
+func branchTxUnitsInCompensationOrder(branches []*db.BranchTxEntity) (units [][]*db.BranchTxEntity) {
+	roots, children := groupBranchTxsByParent(branches)
+	var visit func(nodes []*db.BranchTxEntity)
+	visit = func(nodes []*db.BranchTxEntity) {
+		visitedGroups := make(map[string]bool)
+		for i := len(nodes) - 1; i >= 0; i-- {
+			node := nodes[i]
+			if len(node.BranchGroup) < 1 {
+				visit(children[node.BranchTxId])
+				units = append(units, []*db.BranchTxEntity{node})
+				continue
+			}
+			if visitedGroups[node.BranchGroup] {
+				continue
+			}
+			visitedGroups[node.BranchGroup] = true
+			var unit []*db.BranchTxEntity
+			for j := i; j >= 0; j-- {
+				if nodes[j].BranchGroup == node.BranchGroup {
+					visit(children[nodes[j].BranchTxId])
+					unit = append(unit, nodes[j])
+				}
+			}
+			units = append(units, unit)
+		}
+	}
+	visit(roots)
+	return
+}
+
+/**
+ * 转换成QueryGlobalTransactionDetailReply.branchTree
+ */
+func branchTxTreeToPb(branches []*db.BranchTxEntity) []*pb.TransactionBranchDetail {
+	roots, children := groupBranchTxsByParent(branches)
+	var build func(nodes []*db.BranchTxEntity) []*pb.TransactionBranchDetail
+	build = func(nodes []*db.BranchTxEntity) []*pb.TransactionBranchDetail {
+		result := make([]*pb.TransactionBranchDetail, 0, len(nodes))
+		for _, node := range nodes {
+			detail := branchTxToDetailInPb(node)
+			detail.Children = build(children[node.BranchTxId])
+			result = append(result, detail)
+		}
+		return result
+	}
+	return build(roots)
+}
@@ -0,0 +1,444 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"google.golang.org/grpc"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	grpcCompensationKeyPrefix           = "grpc://"
+	defaultCompensationDispatchInterval = 2 * time.Second
+	defaultCompensationCallTimeout      = 10 * time.Second
+	defaultCompensationDispatchBatch    = 100
+	// server调用补偿或者confirm方法时领取的租约的owner
+	compensationDispatcherLeaseOwner = "saga_server"
+)
+
+/**
+ * 解析 grpc://host:port/package.Service/Method 格式的补偿key
+ * 返回grpc连接地址和完整的方法名 /package.Service/Method
+ */
+func parseGrpcCompensationKey(key string) (target string, method string, ok bool) {
+	if !strings.HasPrefix(key, grpcCompensationKeyPrefix) {
+		return
+	}
+	rest := key[len(grpcCompensationKeyPrefix):]
+	slashIndex := strings.Index(rest, "/")
+	if slashIndex <= 0 {
+		return
+	}
+	target = rest[:slashIndex]
+	method = rest[slashIndex:]
+	// 方法名需要是 /service/method 的格式
+	parts := strings.Split(method[1:], "/")
+	if len(parts) != 2 || len(parts[0]) < 1 || len(parts[1]) < 1 {
+		return
+	}
+	ok = true
+	return
+}
+
+/**
+ * saga server主动调用补偿key是grpc地址的分支事务的补偿方法，以及TCC模式下confirm key是grpc地址的confirm方法
+ * 补偿和confirm结果和参与方worker一样通过SubmitBranchTransactionState的逻辑记录
+ * 调用之前和参与方worker一样领取分支的租约，多个saga server实例或者同时进行的多轮调度不会重复调用同一个分支
+ */
+type CompensationDispatcher struct {
+	service     *SagaServerService
+	store       db.Store
+	interval    time.Duration
+	callTimeout time.Duration
+	batchSize   int32
+	// 租约要比调用的超时时间长，调用和上报结果期间不会被其他实例领取
+	leaseDuration time.Duration
+
+	connsLock sync.Mutex
+	conns     map[string]*grpc.ClientConn // grpc target => conn
+}
+
+func NewCompensationDispatcher(service *SagaServerService, interval time.Duration) *CompensationDispatcher {
+	if interval <= 0 {
+		interval = defaultCompensationDispatchInterval
+	}
+	return &CompensationDispatcher{
+		service:       service,
+		store:         service.store,
+		interval:      interval,
+		callTimeout:   defaultCompensationCallTimeout,
+		batchSize:     defaultCompensationDispatchBatch,
+		leaseDuration: compensationLeaseDuration(0),
+		conns:         make(map[string]*grpc.ClientConn),
+	}
+}
+
+/**
+ * 阻塞运行直到ctx结束，结束时关闭到各参与方的连接
+ */
+func (d *CompensationDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	defer d.closeConns()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := d.DispatchOnce(ctx)
+			if err != nil {
+				log.Printf("dispatch compensations error %s\n", err.Error())
+			} else if count > 0 {
+				log.Printf("dispatched %d branch compensations\n", count)
+			}
+		}
+	}
+}
+
+func (d *CompensationDispatcher) closeConns() {
+	d.connsLock.Lock()
+	defer d.connsLock.Unlock()
+	for target, conn := range d.conns {
+		_ = conn.Close()
+		delete(d.conns, target)
+	}
+}
+
+func (d *CompensationDispatcher) getConn(target string) (conn *grpc.ClientConn, err error) {
+	d.connsLock.Lock()
+	defer d.connsLock.Unlock()
+	conn, ok := d.conns[target]
+	if ok {
+		return
+	}
+	conn, err = grpc.Dial(target, grpc.WithInsecure())
+	if err != nil {
+		return
+	}
+	d.conns[target] = conn
+	return
+}
+
+/**
+ * 扫描一遍COMPENSATION_DOING和CONFIRMING的全局事务并调用其中能由server调用的补偿或者confirm方法，返回本次调用的次数
+ */
+func (d *CompensationDispatcher) DispatchOnce(ctx context.Context) (count int, err error) {
+	var afterId uint64
+	for {
+		var globalTxs []*db.GlobalTxEntity
+		globalTxs, err = d.store.FindGlobalTxsByStates(ctx,
+			[]pb.TxState{pb.TxState_COMPENSATION_DOING, pb.TxState_CONFIRMING}, afterId, d.batchSize)
+		if err != nil {
+			return
+		}
+		for _, globalTx := range globalTxs {
+			afterId = globalTx.Id
+			var n int
+			if globalTx.State == int(pb.TxState_CONFIRMING) {
+				n, err = d.dispatchConfirmGlobalTx(ctx, globalTx)
+			} else {
+				n, err = d.dispatchGlobalTx(ctx, globalTx)
+			}
+			count += n
+			if err != nil {
+				return
+			}
+		}
+		if int32(len(globalTxs)) < d.batchSize {
+			return
+		}
+	}
+}
+
+func isBranchWaitingCompensation(branchTx *db.BranchTxEntity) bool {
+	return branchTx.State == int(pb.TxState_COMPENSATION_DOING) ||
+		branchTx.State == int(pb.TxState_COMPENSATION_ERROR)
+}
+
+/**
+ * 按分支补偿顺序(创建的倒序，下级分支先于上级分支)调用补偿方法
+ * 一组分支中还有等待补偿的分支时本轮不再补偿更早的分支，包括参与方自己的worker补偿的分支、下级分支没补偿完成的分支和补偿失败或者在重试等待中的分支
+ * 并行分支组作为一个整体，组内还有分支在执行中时等待，组内分支各自补偿，整个组补偿完成后才补偿更早的分支
+ */
+func (d *CompensationDispatcher) dispatchGlobalTx(ctx context.Context, globalTx *db.GlobalTxEntity) (count int, err error) {
+	branches, err := d.store.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+	if err != nil {
+		return
+	}
+	_, children := groupBranchTxsByParent(branches)
+	compensated := make(map[string]bool)
+	now := time.Now()
+	for _, unit := range branchTxUnitsInCompensationOrder(branches) {
+		if isBranchGroupUnitProcessing(unit) {
+			return
+		}
+		blocked := false
+		for _, branchTx := range unit {
+			if branchTx.State == int(pb.TxState_COMPENSATION_DONE) {
+				compensated[branchTx.BranchTxId] = true
+				continue
+			}
+			if !isBranchWaitingCompensation(branchTx) {
+				continue
+			}
+			childrenCompensated := true
+			for _, child := range children[branchTx.BranchTxId] {
+				if !compensated[child.BranchTxId] {
+					childrenCompensated = false
+					break
+				}
+			}
+			if !childrenCompensated {
+				blocked = true
+				continue
+			}
+			target, method, ok := parseGrpcCompensationKey(branchTx.BranchCompensationServiceKey)
+			if !ok {
+				// 不是grpc地址的补偿key由参与方自己的worker补偿，更早的分支也要等它补偿完成
+				blocked = true
+				continue
+			}
+			if isBranchBackingOff(branchTx, now) {
+				// 还在重试等待中，更早的分支也要等它补偿完成
+				blocked = true
+				continue
+			}
+			var leaseId string
+			var claimed bool
+			leaseId, claimed, err = d.claimBranch(ctx, branchTx)
+			if err != nil {
+				return
+			}
+			if !claimed {
+				// 其他实例正在补偿，更早的分支也要等它补偿完成
+				blocked = true
+				continue
+			}
+			count++
+			var success bool
+			success, err = d.compensateBranch(ctx, branchTx, target, method, leaseId)
+			if err != nil {
+				return
+			}
+			if !success {
+				blocked = true
+				continue
+			}
+			compensated[branchTx.BranchTxId] = true
+		}
+		if blocked {
+			return
+		}
+	}
+	return
+}
+
+/**
+ * 领取分支的租约，已经被其他实例领取或者领取之后发现分支已经被修改(例如其他实例刚处理完)时claimed为false
+ */
+func (d *CompensationDispatcher) claimBranch(ctx context.Context,
+	branchTx *db.BranchTxEntity) (leaseId string, claimed bool, err error) {
+	leaseId = generateUniqueId()
+	now := time.Now()
+	rowsChanged, err := d.store.ClaimBranchTxLease(ctx, branchTx.BranchTxId, leaseId,
+		compensationDispatcherLeaseOwner, now.Add(d.leaseDuration), now)
+	if err != nil || rowsChanged < 1 {
+		return
+	}
+	latestBranchTx, err := d.store.FindBranchTxByBranchTxId(ctx, branchTx.BranchTxId)
+	if err == nil && latestBranchTx != nil && latestBranchTx.Version == branchTx.Version {
+		claimed = true
+		return
+	}
+	d.releaseBranch(ctx, branchTx, leaseId)
+	return
+}
+
+func (d *CompensationDispatcher) releaseBranch(ctx context.Context, branchTx *db.BranchTxEntity, leaseId string) {
+	if _, err := d.store.ReleaseBranchTxLease(ctx, branchTx.BranchTxId, leaseId); err != nil {
+		log.Printf("release branch %s lease error %s\n", branchTx.BranchTxId, err.Error())
+	}
+}
+
+/**
+ * 调用分支事务的补偿或者confirm方法，两者的请求和返回格式相同
+ */
+func (d *CompensationDispatcher) callBranchMethod(ctx context.Context, branchTx *db.BranchTxEntity,
+	target string, method string, jobId string) (reply *pb.BranchCompensationReply, err error) {
+	conn, err := d.getConn(target)
+	if err != nil {
+		return
+	}
+	sagaData, err := d.store.QuerySagaData(ctx, branchTx.Xid)
+	if err != nil {
+		return
+	}
+	req := &pb.BranchCompensationRequest{
+		Xid:              branchTx.Xid,
+		BranchId:         branchTx.BranchTxId,
+		BranchServiceKey: branchTx.BranchServiceKey,
+		JobId:            jobId,
+	}
+	if sagaData != nil {
+		req.SagaData = sagaData.Data
+	}
+	callCtx, cancel := context.WithTimeout(ctx, d.callTimeout)
+	defer cancel()
+	reply = &pb.BranchCompensationReply{}
+	err = conn.Invoke(callCtx, method, req, reply)
+	if err != nil {
+		return
+	}
+	if reply.Code != Ok {
+		err = errors.New(fmt.Sprintf("%s reply code %d error %s", method, reply.Code, reply.Error))
+		return
+	}
+	return
+}
+
+/**
+ * 调用一个分支事务的补偿方法并上报COMPENSATION_DONE或者COMPENSATION_ERROR，结束后释放领取的租约
+ * success表示补偿成功并已经记录，err只表示上报状态本身出错
+ */
+func (d *CompensationDispatcher) compensateBranch(ctx context.Context, branchTx *db.BranchTxEntity,
+	target string, method string, leaseId string) (success bool, err error) {
+	defer d.releaseBranch(ctx, branchTx, leaseId)
+	jobId := generateUniqueId()
+	reply, callErr := d.callBranchMethod(ctx, branchTx, target, method, jobId)
+	if callErr == nil {
+		var sagaData []byte
+		if len(reply.SagaData) > 0 {
+			sagaData = reply.SagaData
+		}
+		var submitReply *pb.SubmitBranchTransactionStateReply
+		submitReply, err = d.service.SubmitBranchTransactionState(ctx, &pb.SubmitBranchTransactionStateRequest{
+			Xid:        branchTx.Xid,
+			BranchId:   branchTx.BranchTxId,
+			OldState:   pb.TxState(branchTx.State),
+			State:      pb.TxState_COMPENSATION_DONE,
+			OldVersion: branchTx.Version,
+			JobId:      jobId,
+			SagaData:   sagaData,
+			LeaseId:    leaseId,
+		})
+		if err != nil {
+			return
+		}
+		if submitReply.Code != Ok {
+			log.Printf("submit branch %s COMPENSATION_DONE error %s\n", branchTx.BranchTxId, submitReply.Error)
+			return
+		}
+		success = true
+		return
+	}
+
+	log.Printf("branch %s compensation %s error %s\n",
+		branchTx.BranchTxId, branchTx.BranchCompensationServiceKey, callErr.Error())
+	// 补偿失败时分支可能已经被其他请求修改，用最新的版本上报
+	latestBranchTx, err := d.store.FindBranchTxByBranchTxId(ctx, branchTx.BranchTxId)
+	if err != nil {
+		return
+	}
+	if latestBranchTx == nil || !isBranchWaitingCompensation(latestBranchTx) {
+		return
+	}
+	submitReply, err := d.service.SubmitBranchTransactionState(ctx, &pb.SubmitBranchTransactionStateRequest{
+		Xid:         latestBranchTx.Xid,
+		BranchId:    latestBranchTx.BranchTxId,
+		OldState:    pb.TxState(latestBranchTx.State),
+		State:       pb.TxState_COMPENSATION_ERROR,
+		OldVersion:  latestBranchTx.Version,
+		JobId:       jobId,
+		ErrorReason: callErr.Error(),
+		LeaseId:     leaseId,
+	})
+	if err != nil {
+		return
+	}
+	if submitReply.Code != Ok {
+		log.Printf("submit branch %s COMPENSATION_ERROR error %s\n", latestBranchTx.BranchTxId, submitReply.Error)
+	}
+	return
+}
+
+/**
+ * 调用CONFIRMING的全局事务中各分支的confirm方法，各分支的confirm互不依赖，失败的分支按重试策略的间隔重试
+ */
+func (d *CompensationDispatcher) dispatchConfirmGlobalTx(ctx context.Context, globalTx *db.GlobalTxEntity) (count int, err error) {
+	branches, err := d.store.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, branchTx := range branches {
+		if branchTx.State != int(pb.TxState_CONFIRMING) {
+			continue
+		}
+		target, method, ok := parseGrpcCompensationKey(branchTx.BranchConfirmServiceKey)
+		if !ok {
+			// 不是grpc地址的confirm key由参与方自己的worker confirm
+			continue
+		}
+		if isBranchBackingOff(branchTx, now) {
+			continue
+		}
+		leaseId, claimed, claimErr := d.claimBranch(ctx, branchTx)
+		if claimErr != nil {
+			err = claimErr
+			return
+		}
+		if !claimed {
+			continue
+		}
+		count++
+		err = d.confirmBranch(ctx, branchTx, target, method, leaseId)
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+/**
+ * 调用一个分支事务的confirm方法并上报CONFIRMED，失败时上报CONFIRMING记录失败等待重试，结束后释放领取的租约
+ * err只表示上报状态本身出错
+ */
+func (d *CompensationDispatcher) confirmBranch(ctx context.Context, branchTx *db.BranchTxEntity,
+	target string, method string, leaseId string) (err error) {
+	defer d.releaseBranch(ctx, branchTx, leaseId)
+	jobId := generateUniqueId()
+	reply, callErr := d.callBranchMethod(ctx, branchTx, target, method, jobId)
+	req := &pb.SubmitBranchTransactionStateRequest{
+		Xid:        branchTx.Xid,
+		BranchId:   branchTx.BranchTxId,
+		OldState:   pb.TxState(branchTx.State),
+		State:      pb.TxState_CONFIRMED,
+		OldVersion: branchTx.Version,
+		JobId:      jobId,
+		LeaseId:    leaseId,
+	}
+	if callErr == nil {
+		if len(reply.SagaData) > 0 {
+			req.SagaData = reply.SagaData
+		}
+	} else {
+		log.Printf("branch %s confirm %s error %s\n",
+			branchTx.BranchTxId, branchTx.BranchConfirmServiceKey, callErr.Error())
+		req.State = pb.TxState_CONFIRMING
+		req.ErrorReason = callErr.Error()
+	}
+	submitReply, err := d.service.SubmitBranchTransactionState(ctx, req)
+	if err != nil {
+		return
+	}
+	if submitReply.Code != Ok {
+		log.Printf("submit branch %s %s error %s\n", branchTx.BranchTxId, req.State.String(), submitReply.Error)
+	}
+	return
+}
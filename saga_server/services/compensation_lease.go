@@ -0,0 +1,282 @@
+package services
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"time"
+)
+
+const (
+	defaultCompensationLeaseSeconds = 30
+	defaultCompensationClaimLimit   = 20
+)
+
+var allWorkTypes = []pb.WorkType{
+	pb.WorkType_COMPENSATION_WORK, pb.WorkType_FORWARD_RETRY_WORK, pb.WorkType_CONFIRM_WORK,
+}
+
+func compensationLeaseDuration(leaseSeconds int32) time.Duration {
+	if leaseSeconds <= 0 {
+		leaseSeconds = defaultCompensationLeaseSeconds
+	}
+	return time.Duration(leaseSeconds) * time.Second
+}
+
+func isBranchLeased(branchTx *db.BranchTxEntity, now time.Time) bool {
+	return branchTx.LeaseExpireAt != nil && branchTx.LeaseExpireAt.After(now)
+}
+
+func unixMillis(t *time.Time) int64 {
+	if t == nil {
+		return 0
+	}
+	return t.UnixNano() / int64(time.Millisecond)
+}
+
+func compensationWorkLeaseToPb(branchTx *db.BranchTxEntity) *pb.CompensationWorkLease {
+	return &pb.CompensationWorkLease{
+		Xid:           branchTx.Xid,
+		BranchId:      branchTx.BranchTxId,
+		LeaseId:       branchTx.LeaseId,
+		LeaseExpireAt: unixMillis(branchTx.LeaseExpireAt),
+	}
+}
+
+/**
+ * 全局事务中现在可以由参与方补偿的分支，顺序和CompensationDispatcher相同
+ * 按补偿单元的顺序，第一个还有待补偿分支的单元之前的单元都要等待，所以同一个全局事务每次最多返回一个单元中的分支
+ * 补偿key是grpc地址的分支由server调用，重试等待中和租约还没到期的分支也不返回
+ */
+func claimableBranchTxs(branches []*db.BranchTxEntity, now time.Time) (result []*db.BranchTxEntity) {
+	_, children := groupBranchTxsByParent(branches)
+	compensated := make(map[string]bool)
+	for _, unit := range branchTxUnitsInCompensationOrder(branches) {
+		if isBranchGroupUnitProcessing(unit) {
+			return
+		}
+		pending := false
+		for _, branchTx := range unit {
+			if branchTx.State == int(pb.TxState_COMPENSATION_DONE) {
+				compensated[branchTx.BranchTxId] = true
+				continue
+			}
+			if !isBranchWaitingCompensation(branchTx) {
+				continue
+			}
+			pending = true
+			childrenCompensated := true
+			for _, child := range children[branchTx.BranchTxId] {
+				if !compensated[child.BranchTxId] {
+					childrenCompensated = false
+					break
+				}
+			}
+			if !childrenCompensated {
+				continue
+			}
+			if _, _, ok := parseGrpcCompensationKey(branchTx.BranchCompensationServiceKey); ok {
+				continue
+			}
+			if isBranchBackingOff(branchTx, now) || isBranchLeased(branchTx, now) {
+				continue
+			}
+			result = append(result, branchTx)
+		}
+		if pending {
+			return
+		}
+	}
+	return
+}
+
+/**
+ * 全局事务中现在可以由参与方重试的向前恢复分支，重试等待中和租约还没到期的分支不返回
+ */
+func claimableForwardRetryBranchTxs(branches []*db.BranchTxEntity, now time.Time) (result []*db.BranchTxEntity) {
+	for _, branchTx := range branches {
+		if branchTx.State != int(pb.TxState_RETRYING) {
+			continue
+		}
+		if isBranchBackingOff(branchTx, now) || isBranchLeased(branchTx, now) {
+			continue
+		}
+		result = append(result, branchTx)
+	}
+	return
+}
+
+/**
+ * CONFIRMING的全局事务中现在可以由参与方confirm的分支，confirm key是grpc地址的分支由server调用
+ * 重试等待中和租约还没到期的分支不返回
+ */
+func claimableConfirmBranchTxs(branches []*db.BranchTxEntity, now time.Time) (result []*db.BranchTxEntity) {
+	for _, branchTx := range branches {
+		if branchTx.State != int(pb.TxState_CONFIRMING) {
+			continue
+		}
+		if _, _, ok := parseGrpcCompensationKey(branchTx.BranchConfirmServiceKey); ok {
+			continue
+		}
+		if isBranchBackingOff(branchTx, now) || isBranchLeased(branchTx, now) {
+			continue
+		}
+		result = append(result, branchTx)
+	}
+	return
+}
+
+/**
+ * 有work类型的任务可以领取的全局事务的状态
+ */
+func globalTxStatesOfWork(work pb.WorkType) []pb.TxState {
+	switch work {
+	case pb.WorkType_FORWARD_RETRY_WORK:
+		// 全局事务回滚后向前恢复的分支和其他分支一起补偿，不再重试
+		return []pb.TxState{pb.TxState_PROCESSING}
+	case pb.WorkType_CONFIRM_WORK:
+		return []pb.TxState{pb.TxState_CONFIRMING}
+	default:
+		return []pb.TxState{pb.TxState_COMPENSATION_DOING, pb.TxState_COMPENSATION_ERROR}
+	}
+}
+
+/**
+ * 全局事务中现在可以领取的work类型的任务对应的分支
+ */
+func claimableBranchTxsOfWork(work pb.WorkType, globalTx *db.GlobalTxEntity,
+	branches []*db.BranchTxEntity, now time.Time) []*db.BranchTxEntity {
+	if !containsTxState(globalTxStatesOfWork(work), pb.TxState(globalTx.State)) {
+		return nil
+	}
+	switch work {
+	case pb.WorkType_FORWARD_RETRY_WORK:
+		return claimableForwardRetryBranchTxs(branches, now)
+	case pb.WorkType_CONFIRM_WORK:
+		return claimableConfirmBranchTxs(branches, now)
+	default:
+		return claimableBranchTxs(branches, now)
+	}
+}
+
+/**
+ * 全局事务中现在有任务可以领取的参与方，每种任务类型的每个参与方(group和service)一个WorkReady事件
+ */
+func claimableWorkEvents(globalTx *db.GlobalTxEntity, branches []*db.BranchTxEntity,
+	now time.Time) (events []*TxStateEvent) {
+	type workNode struct {
+		work    pb.WorkType
+		group   string
+		service string
+	}
+	notified := make(map[workNode]bool)
+	state := pb.TxState(globalTx.State)
+	for _, work := range allWorkTypes {
+		for _, branchTx := range claimableBranchTxsOfWork(work, globalTx, branches, now) {
+			key := workNode{work: work, group: branchTx.NodeGroup, service: branchTx.NodeService}
+			if notified[key] {
+				continue
+			}
+			notified[key] = true
+			events = append(events, &TxStateEvent{
+				Xid:         globalTx.Xid,
+				NodeGroup:   branchTx.NodeGroup,
+				NodeService: branchTx.NodeService,
+				OldState:    state,
+				State:       state,
+				Version:     globalTx.Version,
+				CreatedAt:   now,
+				WorkReady:   true,
+				Work:        work,
+			})
+		}
+	}
+	return
+}
+
+func containsTxState(states []pb.TxState, state pb.TxState) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 在事务中给node领取最多limit个work类型任务的租约，只领取node的group和service创建的分支，xid不为空时只领取这个全局事务中的分支
+ * 领取时的条件更新保证并发领取时同一个分支只有一个参与方能拿到租约
+ */
+func claimCompensationWork(ctx context.Context, tx db.StoreTx, node *pb.NodeInfo, work pb.WorkType, xid string,
+	leaseDuration time.Duration, limit int32) (items []*pb.CompensationWorkItem, err error) {
+	items = make([]*pb.CompensationWorkItem, 0)
+	now := time.Now()
+	if len(xid) > 0 {
+		var globalTx *db.GlobalTxEntity
+		globalTx, err = tx.FindGlobalTxByXidOrNull(ctx, xid)
+		if err != nil || globalTx == nil {
+			return
+		}
+		items, err = claimWorkOfGlobalTx(ctx, tx, node, work, globalTx, leaseDuration, now, limit, items)
+		return
+	}
+	var afterId uint64
+	for int32(len(items)) < limit {
+		var globalTxs []*db.GlobalTxEntity
+		globalTxs, err = tx.FindGlobalTxsByStates(ctx, globalTxStatesOfWork(work),
+			afterId, defaultCompensationDispatchBatch)
+		if err != nil {
+			return
+		}
+		for _, globalTx := range globalTxs {
+			afterId = globalTx.Id
+			items, err = claimWorkOfGlobalTx(ctx, tx, node, work, globalTx, leaseDuration, now, limit, items)
+			if err != nil || int32(len(items)) >= limit {
+				return
+			}
+		}
+		if int32(len(globalTxs)) < defaultCompensationDispatchBatch {
+			return
+		}
+	}
+	return
+}
+
+/**
+ * 领取一个全局事务中现在可以领取的work类型的任务，领取到的追加到items后返回
+ */
+func claimWorkOfGlobalTx(ctx context.Context, tx db.StoreTx, node *pb.NodeInfo, work pb.WorkType,
+	globalTx *db.GlobalTxEntity, leaseDuration time.Duration, now time.Time, limit int32,
+	items []*pb.CompensationWorkItem) ([]*pb.CompensationWorkItem, error) {
+	branches, err := tx.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+	if err != nil {
+		return items, err
+	}
+	for _, branchTx := range claimableBranchTxsOfWork(work, globalTx, branches, now) {
+		if int32(len(items)) >= limit {
+			break
+		}
+		if branchTx.NodeGroup != node.Group || branchTx.NodeService != node.Service {
+			continue
+		}
+		leaseId := generateUniqueId()
+		leaseExpireAt := now.Add(leaseDuration)
+		rowsChanged, err := tx.ClaimBranchTxLease(ctx, branchTx.BranchTxId, leaseId,
+			node.InstanceId, leaseExpireAt, now)
+		if err != nil {
+			return items, err
+		}
+		if rowsChanged < 1 {
+			// 已经被其他参与方领取
+			continue
+		}
+		branchTx.LeaseId = leaseId
+		branchTx.LeaseOwner = node.InstanceId
+		branchTx.LeaseExpireAt = &leaseExpireAt
+		items = append(items, &pb.CompensationWorkItem{
+			Lease:  compensationWorkLeaseToPb(branchTx),
+			Branch: branchTxToDetailInPb(branchTx),
+		})
+	}
+	return items, nil
+}
@@ -0,0 +1,449 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"hash/fnv"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTxEventSubscriptionBuffer = 256
+	// SubscribeEvents没有推送事件时推送最新cursor的间隔
+	defaultTxEventHeartbeatInterval = 5 * time.Second
+	// 按xid分段的锁的数量
+	txEventXidLockCount = 64
+	// 保留最近发布的事件数量，订阅方断开后用cursor从这里继续接收
+	defaultTxEventHistorySize = 10000
+)
+
+var errTxEventCursorExpired = errors.New("event cursor expired")
+
+/**
+ * 全局事务或者分支的一次状态变化，branchId为空时是全局事务的状态变化
+ * oldState和state相同表示新创建的全局事务或分支，或者订阅时的当前状态
+ * WorkReady为true时不是状态变化，而是全局事务中有Node可以领取的Work类型的任务
+ */
+type TxStateEvent struct {
+	Xid         string
+	BranchId    string
+	NodeGroup   string // 分支的创建方，全局事务是发起方，任务事件是可以领取任务的参与方
+	NodeService string
+	OldState    pb.TxState
+	State       pb.TxState
+	Version     int32 // 修改后全局事务或分支的版本号
+	CreatedAt   time.Time
+	Seq         uint64 // 发布时分配的序号，订阅时的当前状态没有序号
+	WorkReady   bool
+	Work        pb.WorkType
+}
+
+func (e *TxStateEvent) isGlobalTxEvent() bool {
+	return len(e.BranchId) < 1 && !e.WorkReady
+}
+
+func isTerminalGlobalTxState(state pb.TxState) bool {
+	return state == pb.TxState_COMMITTED || state == pb.TxState_COMPENSATION_DONE ||
+		state == pb.TxState_COMPENSATION_FAIL
+}
+
+/**
+ * 一个事件订阅，订阅方处理太慢导致缓冲区满时订阅被关闭，Events()的channel随之关闭
+ */
+type TxEventSubscription struct {
+	bus    *TxEventBus
+	id     uint64
+	filter func(event *TxStateEvent) bool
+	events chan *TxStateEvent
+	closed bool
+}
+
+func (s *TxEventSubscription) Events() <-chan *TxStateEvent {
+	return s.events
+}
+
+func (s *TxEventSubscription) Close() {
+	s.bus.unsubscribe(s)
+}
+
+/**
+ * 进程内的全局事务状态事件总线
+ * 修改状态的事务通过BeginTx开启，事务中的每次状态修改都记录下来，Commit成功后按顺序发给订阅方
+ */
+type TxEventBus struct {
+	store db.Store
+
+	xidLocks [txEventXidLockCount]sync.Mutex
+
+	subscribersLock sync.Mutex
+	subscribers     map[uint64]*TxEventSubscription
+	nextId          uint64
+	// server每次启动的事件序号都从头开始，cursor中带上epoch区分不同的进程
+	epoch       string
+	lastSeq     uint64
+	history     []*TxStateEvent
+	historySize int
+}
+
+func NewTxEventBus(store db.Store) *TxEventBus {
+	return &TxEventBus{
+		store:       store,
+		subscribers: make(map[uint64]*TxEventSubscription),
+		epoch:       generateUniqueId(),
+		historySize: defaultTxEventHistorySize,
+	}
+}
+
+/**
+ * 事件的cursor，格式是 epoch:seq
+ */
+func (b *TxEventBus) Cursor(event *TxStateEvent) string {
+	return b.cursorAt(event.Seq)
+}
+
+func (b *TxEventBus) cursorAt(seq uint64) string {
+	return fmt.Sprintf("%s:%d", b.epoch, seq)
+}
+
+/**
+ * 解析cursor，不是本进程发出的cursor返回errTxEventCursorExpired
+ */
+func (b *TxEventBus) parseCursor(cursor string) (seq uint64, err error) {
+	sepIndex := strings.LastIndex(cursor, ":")
+	if sepIndex < 0 {
+		err = fmt.Errorf("invalid event cursor %s", cursor)
+		return
+	}
+	seq, err = strconv.ParseUint(cursor[sepIndex+1:], 10, 64)
+	if err != nil {
+		err = fmt.Errorf("invalid event cursor %s", cursor)
+		return
+	}
+	if cursor[:sepIndex] != b.epoch {
+		err = errTxEventCursorExpired
+	}
+	return
+}
+
+/**
+ * 订阅满足filter的事件，filter为空时订阅所有事件
+ */
+func (b *TxEventBus) Subscribe(filter func(event *TxStateEvent) bool) *TxEventSubscription {
+	b.subscribersLock.Lock()
+	defer b.subscribersLock.Unlock()
+	return b.addSubscriber(filter)
+}
+
+/**
+ * 从cursor之后继续订阅满足filter的事件，replay是cursor之后已经发布的事件，cursor为空时从现在开始
+ * 补发和注册订阅是原子的，replay和之后从订阅收到的事件之间没有遗漏也没有重复，head是订阅时最后发布的事件的cursor
+ * cursor之后的事件已经不在保留的历史中或者不是本进程发出的cursor时返回errTxEventCursorExpired
+ */
+func (b *TxEventBus) SubscribeFrom(cursor string, filter func(event *TxStateEvent) bool) (
+	replay []*TxStateEvent, head string, sub *TxEventSubscription, err error) {
+	var seq uint64
+	if len(cursor) > 0 {
+		seq, err = b.parseCursor(cursor)
+		if err != nil {
+			return
+		}
+	}
+	b.subscribersLock.Lock()
+	defer b.subscribersLock.Unlock()
+	if len(cursor) > 0 {
+		if seq > b.lastSeq {
+			err = fmt.Errorf("invalid event cursor %s", cursor)
+			return
+		}
+		if seq < b.lastSeq && (len(b.history) < 1 || b.history[0].Seq > seq+1) {
+			err = errTxEventCursorExpired
+			return
+		}
+		for _, event := range b.history {
+			if event.Seq > seq && (filter == nil || filter(event)) {
+				replay = append(replay, event)
+			}
+		}
+	}
+	head = b.cursorAt(b.lastSeq)
+	sub = b.addSubscriber(filter)
+	return
+}
+
+/**
+ * sub的缓冲区中没有待处理的事件时返回最后发布的事件的cursor，之前满足sub条件的事件都已经被取走
+ * 订阅方处理完取走的事件后可以把它作为自己的位置，缓冲区不空或者订阅已经关闭时返回空字符串
+ */
+func (b *TxEventBus) idleCursor(sub *TxEventSubscription) string {
+	b.subscribersLock.Lock()
+	defer b.subscribersLock.Unlock()
+	if sub.closed || len(sub.events) > 0 {
+		return ""
+	}
+	return b.cursorAt(b.lastSeq)
+}
+
+// 调用方需要持有subscribersLock
+func (b *TxEventBus) addSubscriber(filter func(event *TxStateEvent) bool) *TxEventSubscription {
+	b.nextId++
+	sub := &TxEventSubscription{
+		bus:    b,
+		id:     b.nextId,
+		filter: filter,
+		events: make(chan *TxStateEvent, defaultTxEventSubscriptionBuffer),
+	}
+	b.subscribers[sub.id] = sub
+	return sub
+}
+
+func (b *TxEventBus) unsubscribe(sub *TxEventSubscription) {
+	b.subscribersLock.Lock()
+	defer b.subscribersLock.Unlock()
+	if sub.closed {
+		return
+	}
+	sub.closed = true
+	delete(b.subscribers, sub.id)
+	close(sub.events)
+}
+
+func (b *TxEventBus) dispatch(events []*TxStateEvent) {
+	if len(events) < 1 {
+		return
+	}
+	b.subscribersLock.Lock()
+	defer b.subscribersLock.Unlock()
+	for _, event := range events {
+		b.lastSeq++
+		event.Seq = b.lastSeq
+		b.history = append(b.history, event)
+	}
+	if len(b.history) >= 2*b.historySize {
+		// 攒到两倍再裁剪，避免每次发布都复制整个历史
+		b.history = append([]*TxStateEvent(nil), b.history[len(b.history)-b.historySize:]...)
+	}
+	for _, sub := range b.subscribers {
+		for _, event := range events {
+			if sub.filter != nil && !sub.filter(event) {
+				continue
+			}
+			select {
+			case sub.events <- event:
+			default:
+				// 不阻塞发布方，订阅方需要重新订阅并从存储中读取最新状态
+				log.Printf("tx event subscription %d is full, closed\n", sub.id)
+				sub.closed = true
+				delete(b.subscribers, sub.id)
+				close(sub.events)
+			}
+			if sub.closed {
+				break
+			}
+		}
+	}
+}
+
+/**
+ * 开启一个存储事务，事务中修改的全局事务和分支状态在Commit成功后作为状态变化发布，b为空时返回普通的存储事务
+ */
+func (b *TxEventBus) BeginTx(ctx context.Context, store db.Store) (db.StoreTx, error) {
+	tx, err := store.BeginTx(ctx)
+	if err != nil || b == nil {
+		return tx, err
+	}
+	return &txEventRecorder{
+		StoreTx:   tx,
+		ctx:       ctx,
+		bus:       b,
+		globalTxs: make(map[string]*db.GlobalTxEntity),
+	}, nil
+}
+
+/**
+ * 读取xids的当前状态，给现在有任务可以领取的参与方发布WorkReady事件，b为空时什么都不做
+ * 修改提交之后调用，这时读到的是包括其他并发修改在内的最新状态，不能持有xid的锁调用
+ */
+func (b *TxEventBus) publishClaimableWork(ctx context.Context, xids []string) error {
+	if b == nil || len(xids) < 1 {
+		return nil
+	}
+	var events []*TxStateEvent
+	for _, xid := range xids {
+		globalTx, err := b.store.FindGlobalTxByXidOrNull(ctx, xid)
+		if err != nil {
+			return err
+		}
+		if globalTx == nil || isTerminalGlobalTxState(pb.TxState(globalTx.State)) {
+			continue
+		}
+		branches, err := b.store.FindAllBranchTxsByXid(ctx, xid)
+		if err != nil {
+			return err
+		}
+		events = append(events, claimableWorkEvents(globalTx, branches, time.Now())...)
+	}
+	b.Publish(events...)
+	return nil
+}
+
+/**
+ * 发布不在事务中发生的状态变化，b为空时什么都不做
+ */
+func (b *TxEventBus) Publish(events ...*TxStateEvent) {
+	if b == nil || len(events) < 1 {
+		return
+	}
+	unlock := b.lockXids(events)
+	defer unlock()
+	b.dispatch(events)
+}
+
+func (b *TxEventBus) xidLock(xid string) int {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(xid))
+	return int(h.Sum32() % uint32(len(b.xidLocks)))
+}
+
+/**
+ * 锁住events涉及的各个xid，同一个xid的提交和发布串行，订阅方按提交的顺序收到状态变化
+ */
+func (b *TxEventBus) lockXids(events []*TxStateEvent) (unlock func()) {
+	var indexes []int
+	for _, event := range events {
+		index := b.xidLock(event.Xid)
+		found := false
+		for _, i := range indexes {
+			if i == index {
+				found = true
+				break
+			}
+		}
+		if !found {
+			indexes = append(indexes, index)
+		}
+	}
+	// 按固定顺序加锁，避免多个xid时死锁
+	sort.Ints(indexes)
+	for _, index := range indexes {
+		b.xidLocks[index].Lock()
+	}
+	return func() {
+		for i := len(indexes) - 1; i >= 0; i-- {
+			b.xidLocks[indexes[i]].Unlock()
+		}
+	}
+}
+
+/**
+ * 订阅全局事务xid的事件，返回订阅时全局事务和各分支的当前状态(oldState和state相同，全局事务在最后)
+ * 订阅之后才读取当前状态，从订阅收到的事件中可能有已经包含在当前状态中的，用txStateVersions跳过
+ * 全局事务不存在时snapshot和sub都为空，只能收到经过本进程事件总线提交的修改
+ */
+func (b *TxEventBus) WatchXid(ctx context.Context, xid string) (snapshot []*TxStateEvent,
+	sub *TxEventSubscription, err error) {
+	// 持有xid的锁时没有正在提交或者发布中的修改，订阅之前提交的修改都已经发布完
+	// 不能在持有锁时读取存储，提交中的事务可能还占用着存储
+	lock := &b.xidLocks[b.xidLock(xid)]
+	lock.Lock()
+	sub = b.Subscribe(func(event *TxStateEvent) bool {
+		return event.Xid == xid && !event.WorkReady
+	})
+	lock.Unlock()
+	defer func() {
+		if err != nil || snapshot == nil {
+			sub.Close()
+			sub = nil
+		}
+	}()
+	globalTx, err := b.store.FindGlobalTxByXidOrNull(ctx, xid)
+	if err != nil || globalTx == nil {
+		return
+	}
+	branches, err := b.store.FindAllBranchTxsByXid(ctx, xid)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, branchTx := range branches {
+		state := pb.TxState(branchTx.State)
+		snapshot = append(snapshot, branchTxStateEvent(branchTx, state, state, now))
+	}
+	globalState := pb.TxState(globalTx.State)
+	snapshot = append(snapshot, globalTxStateEvent(globalTx, globalState, globalState, now))
+	return
+}
+
+/**
+ * 全局事务和各分支已经推送过的版本号，branchId(全局事务是空字符串) => 版本号
+ */
+type txStateVersions map[string]int32
+
+func newTxStateVersions(snapshot []*TxStateEvent) txStateVersions {
+	versions := make(txStateVersions)
+	for _, event := range snapshot {
+		versions[event.BranchId] = event.Version
+	}
+	return versions
+}
+
+/**
+ * event比已经推送过的状态新时记下它的版本号并返回true
+ */
+func (v txStateVersions) accept(event *TxStateEvent) bool {
+	if version, ok := v[event.BranchId]; ok && event.Version <= version {
+		return false
+	}
+	v[event.BranchId] = event.Version
+	return true
+}
+
+func globalTxStateEvent(globalTx *db.GlobalTxEntity, oldState pb.TxState, state pb.TxState,
+	now time.Time) *TxStateEvent {
+	return &TxStateEvent{
+		Xid:         globalTx.Xid,
+		NodeGroup:   globalTx.CreatorGroup,
+		NodeService: globalTx.CreatorService,
+		OldState:    oldState,
+		State:       state,
+		Version:     globalTx.Version,
+		CreatedAt:   now,
+	}
+}
+
+func branchTxStateEvent(branchTx *db.BranchTxEntity, oldState pb.TxState, state pb.TxState,
+	now time.Time) *TxStateEvent {
+	return &TxStateEvent{
+		Xid:         branchTx.Xid,
+		BranchId:    branchTx.BranchTxId,
+		NodeGroup:   branchTx.NodeGroup,
+		NodeService: branchTx.NodeService,
+		OldState:    oldState,
+		State:       state,
+		Version:     branchTx.Version,
+		CreatedAt:   now,
+	}
+}
+
+func txStateEventToPb(event *TxStateEvent) *pb.TxStateEvent {
+	return &pb.TxStateEvent{
+		Xid:      event.Xid,
+		BranchId: event.BranchId,
+		Node: &pb.NodeInfo{
+			Group:   event.NodeGroup,
+			Service: event.NodeService,
+		},
+		OldState:  event.OldState,
+		State:     event.State,
+		CreatedAt: unixMillis(&event.CreatedAt),
+		WorkReady: event.WorkReady,
+		Work:      event.Work,
+	}
+}
@@ -0,0 +1,163 @@
+package services
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/app"
+	"github.com/zoowii/saga_server/db"
+	"log"
+	"time"
+)
+
+const (
+	defaultExpireSweepInterval  = 5 * time.Second
+	defaultExpireSweepBatchSize = 100
+)
+
+/**
+ * 定时扫描超时的PROCESSING状态的全局事务，把它们改成COMPENSATION_DOING进入补偿流程
+ * 这样不依赖参与方的worker来判断全局事务是否超时
+ */
+type ExpireSweeper struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	events    *TxEventBus
+}
+
+func NewExpireSweeper(sagaApp app.ApplicationContext, interval time.Duration) (sweeper *ExpireSweeper, err error) {
+	store, err := sagaApp.GetStore()
+	if err != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultExpireSweepInterval
+	}
+	sweeper = &ExpireSweeper{
+		store:     store,
+		interval:  interval,
+		batchSize: defaultExpireSweepBatchSize,
+	}
+	return
+}
+
+/**
+ * 设置后，超时转入补偿的全局事务的状态变化发布到events
+ */
+func (s *ExpireSweeper) SetEventBus(events *TxEventBus) {
+	s.events = events
+}
+
+/**
+ * 阻塞运行直到ctx结束
+ */
+func (s *ExpireSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := s.SweepOnce(ctx)
+			if err != nil {
+				log.Printf("sweep expired global txs error %s\n", err.Error())
+			} else if count > 0 {
+				log.Printf("swept %d expired global txs\n", count)
+			}
+		}
+	}
+}
+
+func (s *ExpireSweeper) isExpired(globalTx *db.GlobalTxEntity) bool {
+	if globalTx.CreatedAt == nil {
+		return false
+	}
+	expireSeconds := globalTx.ExpireSeconds
+	if expireSeconds <= 0 {
+		expireSeconds = defaultGlobalTxExpireSeconds
+	}
+	expireAt := globalTx.CreatedAt.Add(time.Duration(expireSeconds) * time.Second)
+	return time.Now().After(expireAt)
+}
+
+/**
+ * 扫描一遍所有PROCESSING的全局事务，返回本次改成COMPENSATION_DOING的数量
+ */
+func (s *ExpireSweeper) SweepOnce(ctx context.Context) (count int, err error) {
+	var afterId uint64
+	for {
+		var globalTxs []*db.GlobalTxEntity
+		globalTxs, err = s.store.FindGlobalTxsByStates(ctx,
+			[]pb.TxState{pb.TxState_PROCESSING}, afterId, s.batchSize)
+		if err != nil {
+			return
+		}
+		for _, globalTx := range globalTxs {
+			afterId = globalTx.Id
+			if !s.isExpired(globalTx) {
+				continue
+			}
+			var swept bool
+			swept, err = s.compensateExpiredGlobalTx(ctx, globalTx.Xid)
+			if err != nil {
+				return
+			}
+			if swept {
+				count++
+			}
+		}
+		if int32(len(globalTxs)) < s.batchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 在事务中把超时的全局事务改成COMPENSATION_DOING，已经被其他请求改过状态的跳过
+ */
+func (s *ExpireSweeper) compensateExpiredGlobalTx(ctx context.Context, xid string) (swept bool, err error) {
+	tx, err := s.events.BeginTx(ctx, s.store)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	globalTx, err := findGlobalTxOrError(ctx, tx, xid)
+	if err != nil {
+		return
+	}
+	if globalTx.State != int(pb.TxState_PROCESSING) || !s.isExpired(globalTx) {
+		return
+	}
+	branches, err := tx.FindAllBranchTxsByXid(ctx, xid)
+	if err != nil {
+		return
+	}
+	if hasRetryingBranchTx(branches) {
+		// 向前恢复的分支还在重试，超时也不回滚
+		return
+	}
+	rowsChanged, err := tx.UpdateGlobalTxState(ctx, xid, globalTx.Version,
+		globalTx.State, int(pb.TxState_COMPENSATION_DOING))
+	if err != nil {
+		return
+	}
+	if rowsChanged < 1 {
+		return
+	}
+	globalTx.State = int(pb.TxState_COMPENSATION_DOING)
+	globalTx.Version += 1
+	err = logicWhenSubmitGlobalTxCompensationDoing(ctx, tx, globalTx, pb.TxState_PROCESSING)
+	if err != nil {
+		return
+	}
+	log.Printf("global tx %s expired, state changed to COMPENSATION_DOING\n", xid)
+	swept = true
+	return
+}
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"log"
+	"time"
+)
+
+func isForwardBranchTx(branchTx *db.BranchTxEntity) bool {
+	return branchTx.RecoveryMode == int(pb.RecoveryMode_FORWARD)
+}
+
+/**
+ * 向前恢复的分支提交的状态转换成实际要修改的状态
+ * 全局事务处理中时分支失败(COMPENSATION_DOING)改成RETRYING等待重试，不触发回滚
+ * 全局事务已经在回滚时，重试失败的分支改成COMPENSATION_DOING，和其他分支一起补偿
+ */
+func forwardBranchTxSubmitState(globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity,
+	state pb.TxState) pb.TxState {
+	if !isForwardBranchTx(branchTx) {
+		return state
+	}
+	processing := globalTx.State == int(pb.TxState_PROCESSING)
+	if processing && state == pb.TxState_COMPENSATION_DOING {
+		return pb.TxState_RETRYING
+	}
+	if !processing && state == pb.TxState_RETRYING {
+		return pb.TxState_COMPENSATION_DOING
+	}
+	return state
+}
+
+/**
+ * 全局事务中是否有还在等待重试的向前恢复分支
+ */
+func hasRetryingBranchTx(branches []*db.BranchTxEntity) bool {
+	for _, b := range branches {
+		if b.State == int(pb.TxState_RETRYING) {
+			return true
+		}
+	}
+	return false
+}
+
+/**
+ * 提交RETRYING状态的分支事务状态时的回调逻辑
+ */
+func logicWhenSubmitBranchTxRetrying(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity, jobId string, errorReason string) (err error) {
+	// 向前恢复的分支每次执行失败都记录日志和失败次数，按重试策略的间隔等待下次重试，没有最大次数
+	log.Printf("RETRYING of jobId %s", jobId)
+	err = recordBranchTxRetryFailure(ctx, tx, globalTx, branchTx, jobId, errorReason)
+	return
+}
+
+/**
+ * 记录一次需要重试直到成功的分支执行失败(向前恢复分支的执行或者TCC分支的confirm)
+ * 失败次数加一并按重试策略设置下次允许重试的时间，为了幂等性，每次执行都要有一个不同的jobId
+ */
+func recordBranchTxRetryFailure(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity, jobId string, errorReason string) (err error) {
+	retryLog, err := tx.FindBranchTxForwardRetryLogByJobId(ctx, jobId)
+	if err != nil {
+		return
+	}
+	if retryLog != nil {
+		// 重复jobId提交
+		return
+	}
+	retryLogId, err := tx.InsertBranchTxForwardRetryLog(ctx, globalTx.Xid, branchTx.BranchTxId, jobId, errorReason)
+	if err != nil {
+		return
+	}
+	if retryLogId <= 0 {
+		return
+	}
+	retryTimes := branchTx.ForwardRetryTimes + 1
+	retryAt := time.Now().Add(retryDelayAfterFailTimes(effectiveRetryPolicy(globalTx, branchTx), retryTimes))
+	rowsChanged, err := tx.UpdateBranchTxForwardRetryTimes(ctx, branchTx.Id, branchTx.Version, retryTimes, &retryAt)
+	if err != nil {
+		return
+	}
+	if rowsChanged <= 0 {
+		return
+	}
+	branchTx.ForwardRetryTimes = retryTimes
+	branchTx.NextRetryAt = &retryAt
+	branchTx.Version += 1
+	return
+}
@@ -0,0 +1,228 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/app"
+	"github.com/zoowii/saga_server/db"
+	"log"
+	"time"
+)
+
+const (
+	defaultOrphanDetectInterval  = 5 * time.Second
+	defaultOrphanDetectBatchSize = 100
+)
+
+/**
+ * 定时扫描执行中但是执行方已经停止心跳的分支，标记为失去心跳
+ * 开启rollback时同时回滚它们的全局事务(向前恢复的分支改成RETRYING重新执行)，不用等到整个全局事务超时
+ */
+type OrphanBranchDetector struct {
+	store     db.Store
+	interval  time.Duration
+	batchSize int32
+	rollback  bool
+	events    *TxEventBus
+}
+
+func NewOrphanBranchDetector(sagaApp app.ApplicationContext, interval time.Duration,
+	rollback bool) (detector *OrphanBranchDetector, err error) {
+	store, err := sagaApp.GetStore()
+	if err != nil {
+		return
+	}
+	if interval <= 0 {
+		interval = defaultOrphanDetectInterval
+	}
+	detector = &OrphanBranchDetector{
+		store:     store,
+		interval:  interval,
+		batchSize: defaultOrphanDetectBatchSize,
+		rollback:  rollback,
+	}
+	return
+}
+
+/**
+ * 设置后，回滚失去心跳的分支引起的状态变化发布到events
+ */
+func (d *OrphanBranchDetector) SetEventBus(events *TxEventBus) {
+	d.events = events
+}
+
+/**
+ * 阻塞运行直到ctx结束
+ */
+func (d *OrphanBranchDetector) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			count, err := d.DetectOnce(ctx)
+			if err != nil {
+				log.Printf("detect orphaned branch txs error %s\n", err.Error())
+			} else if count > 0 {
+				log.Printf("detected %d orphaned branch txs\n", count)
+			}
+		}
+	}
+}
+
+func heartbeatDeadline(branchTx *db.BranchTxEntity, now time.Time) time.Time {
+	return now.Add(-time.Duration(branchTx.HeartbeatTimeoutSeconds) * time.Second)
+}
+
+/**
+ * 执行中并且检查心跳的分支超过心跳超时时间没有心跳，已经标记过的不再返回
+ */
+func isBranchOrphaned(branchTx *db.BranchTxEntity, now time.Time) bool {
+	if branchTx.State != int(pb.TxState_PROCESSING) || branchTx.HeartbeatTimeoutSeconds <= 0 {
+		return false
+	}
+	if branchTx.OrphanedAt != nil || branchTx.LastHeartbeatAt == nil {
+		return false
+	}
+	return !branchTx.LastHeartbeatAt.After(heartbeatDeadline(branchTx, now))
+}
+
+/**
+ * 扫描一遍所有PROCESSING和COMPENSATION_DOING的全局事务，返回本次标记为失去心跳的分支数量
+ * COMPENSATION_DOING的全局事务中还在执行的只有并行分支组的分支，失去心跳后会一直阻塞补偿
+ */
+func (d *OrphanBranchDetector) DetectOnce(ctx context.Context) (count int, err error) {
+	var afterId uint64
+	for {
+		var globalTxs []*db.GlobalTxEntity
+		globalTxs, err = d.store.FindGlobalTxsByStates(ctx,
+			[]pb.TxState{pb.TxState_PROCESSING, pb.TxState_COMPENSATION_DOING}, afterId, d.batchSize)
+		if err != nil {
+			return
+		}
+		for _, globalTx := range globalTxs {
+			afterId = globalTx.Id
+			var branches []*db.BranchTxEntity
+			branches, err = d.store.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+			if err != nil {
+				return
+			}
+			for _, branchTx := range branches {
+				if !isBranchOrphaned(branchTx, time.Now()) {
+					continue
+				}
+				var orphaned bool
+				orphaned, err = d.handleOrphanedBranchTx(ctx, branchTx.Xid, branchTx.BranchTxId)
+				if err != nil {
+					return
+				}
+				if orphaned {
+					count++
+				}
+			}
+		}
+		if int32(len(globalTxs)) < d.batchSize {
+			return
+		}
+	}
+}
+
+/**
+ * 在事务中标记失去心跳的分支，已经被其他请求改过状态或者恢复了心跳的跳过
+ */
+func (d *OrphanBranchDetector) handleOrphanedBranchTx(ctx context.Context,
+	xid string, branchTxId string) (orphaned bool, err error) {
+	tx, err := d.events.BeginTx(ctx, d.store)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}()
+	now := time.Now()
+	branchTx, err := tx.FindBranchTxByBranchTxId(ctx, branchTxId)
+	if err != nil {
+		return
+	}
+	if branchTx == nil || !isBranchOrphaned(branchTx, now) {
+		return
+	}
+	rowsChanged, err := tx.MarkBranchTxOrphaned(ctx, branchTxId, heartbeatDeadline(branchTx, now), now)
+	if err != nil {
+		return
+	}
+	if rowsChanged < 1 {
+		return
+	}
+	orphaned = true
+	log.Printf("branch tx %s of xid %s lost heartbeat of instance %s\n",
+		branchTxId, xid, branchTx.NodeInstanceId)
+	if !d.rollback {
+		return
+	}
+	globalTx, err := findGlobalTxOrError(ctx, tx, xid)
+	if err != nil {
+		return
+	}
+	err = rollbackOrphanedBranchTx(ctx, tx, globalTx, branchTx)
+	return
+}
+
+/**
+ * 失去心跳的分支按执行失败处理
+ * 全局事务处理中时，向前恢复的分支改成RETRYING等待重新执行，其他分支回滚整个全局事务
+ * 全局事务已经在回滚时，分支组中还在等待结果的分支直接进入补偿
+ */
+func rollbackOrphanedBranchTx(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (err error) {
+	xid := globalTx.Xid
+	reason := fmt.Sprintf("no heartbeat from instance %s in %d seconds",
+		branchTx.NodeInstanceId, branchTx.HeartbeatTimeoutSeconds)
+	if globalTx.State == int(pb.TxState_PROCESSING) && isForwardBranchTx(branchTx) {
+		var rowsChanged int64
+		rowsChanged, err = updateBranchTxState(ctx, tx, branchTx, int(pb.TxState_RETRYING))
+		if err != nil || rowsChanged < 1 {
+			return
+		}
+		err = recordBranchTxRetryFailure(ctx, tx, globalTx, branchTx, generateUniqueId(), reason)
+		if err != nil {
+			return
+		}
+		log.Printf("orphaned branch tx %s changed to RETRYING\n", branchTx.BranchTxId)
+		return
+	}
+	if globalTx.State == int(pb.TxState_PROCESSING) {
+		var rowsChanged int64
+		rowsChanged, err = tx.UpdateGlobalTxState(ctx, xid, globalTx.Version,
+			globalTx.State, int(pb.TxState_COMPENSATION_DOING))
+		if err != nil || rowsChanged < 1 {
+			return
+		}
+		globalTx.State = int(pb.TxState_COMPENSATION_DOING)
+		globalTx.Version += 1
+		err = logicWhenSubmitGlobalTxCompensationDoing(ctx, tx, globalTx, pb.TxState_PROCESSING)
+		if err != nil {
+			return
+		}
+		log.Printf("global tx %s changed to COMPENSATION_DOING because of %s\n", xid, reason)
+	}
+	if globalTx.State != int(pb.TxState_COMPENSATION_DOING) {
+		return
+	}
+	// 分支组中的分支回滚时保持PROCESSING等待执行结果，失去心跳的分支不会再提交结果
+	branchTx, err = tx.FindBranchTxByBranchTxId(ctx, branchTx.BranchTxId)
+	if err != nil {
+		return
+	}
+	if branchTx.State == int(pb.TxState_PROCESSING) {
+		_, err = updateBranchTxState(ctx, tx, branchTx, int(pb.TxState_COMPENSATION_DOING))
+	}
+	return
+}
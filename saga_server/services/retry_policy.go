@@ -0,0 +1,83 @@
+package services
+
+import (
+	"errors"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"math"
+	"time"
+)
+
+/**
+ * 全局事务和分支都没有配置时使用的补偿重试策略
+ */
+var defaultRetryPolicy = db.RetryPolicy{
+	MaxAttempts:    defaultBranchTxCompensationMaxFailTimes + 1,
+	InitialDelayMs: 1000,
+	Multiplier:     2,
+	MaxDelayMs:     60 * 1000,
+}
+
+func retryPolicyFromPb(policy *pb.RetryPolicy) (result db.RetryPolicy, err error) {
+	if policy == nil {
+		return
+	}
+	if policy.MaxAttempts < 0 || policy.InitialDelayMs < 0 || policy.MaxDelayMs < 0 {
+		err = errors.New("retryPolicy fields can't be negative")
+		return
+	}
+	if policy.Multiplier != 0 && policy.Multiplier < 1 {
+		err = errors.New("retryPolicy multiplier must be >= 1")
+		return
+	}
+	result = db.RetryPolicy{
+		MaxAttempts:    policy.MaxAttempts,
+		InitialDelayMs: policy.InitialDelayMs,
+		Multiplier:     policy.Multiplier,
+		MaxDelayMs:     policy.MaxDelayMs,
+	}
+	return
+}
+
+/**
+ * 按分支、全局事务、默认配置的顺序合并重试策略，每个字段取第一个非0的值
+ */
+func effectiveRetryPolicy(globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (result db.RetryPolicy) {
+	policies := []db.RetryPolicy{branchTx.RetryPolicy, globalTx.RetryPolicy, defaultRetryPolicy}
+	for _, p := range policies {
+		if result.MaxAttempts == 0 {
+			result.MaxAttempts = p.MaxAttempts
+		}
+		if result.InitialDelayMs == 0 {
+			result.InitialDelayMs = p.InitialDelayMs
+		}
+		if result.Multiplier == 0 {
+			result.Multiplier = p.Multiplier
+		}
+		if result.MaxDelayMs == 0 {
+			result.MaxDelayMs = p.MaxDelayMs
+		}
+	}
+	return
+}
+
+/**
+ * 第failTimes次补偿失败后需要等待的时间，initialDelay * multiplier^(failTimes-1)，不超过maxDelay
+ */
+func retryDelayAfterFailTimes(policy db.RetryPolicy, failTimes int32) time.Duration {
+	if failTimes < 1 {
+		return 0
+	}
+	delayMs := float64(policy.InitialDelayMs) * math.Pow(policy.Multiplier, float64(failTimes-1))
+	if delayMs > float64(policy.MaxDelayMs) {
+		delayMs = float64(policy.MaxDelayMs)
+	}
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+/**
+ * 分支是否还在补偿失败后的重试等待中
+ */
+func isBranchBackingOff(branchTx *db.BranchTxEntity, now time.Time) bool {
+	return branchTx.NextRetryAt != nil && branchTx.NextRetryAt.After(now)
+}
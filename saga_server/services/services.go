@@ -2,20 +2,20 @@ package services
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"fmt"
 	pb "github.com/zoowii/saga_server/api"
 	"github.com/zoowii/saga_server/db"
 	"log"
+	"time"
 )
 
 /**
  * 获取分支事务branchTx所在全局事务中的其他分支事务
  */
-func getBranchesOfXidExcept(ctx context.Context, dbConn *sql.DB,
+func getBranchesOfXidExcept(ctx context.Context, tx db.StoreTx,
 	branchTx *db.BranchTxEntity) (others []*db.BranchTxEntity, err error) {
-	branches, err := db.FindAllBranchTxsByXid(ctx, dbConn, branchTx.Xid)
+	branches, err := tx.FindAllBranchTxsByXid(ctx, branchTx.Xid)
 	if err != nil {
 		return
 	}
@@ -30,9 +30,9 @@ func getBranchesOfXidExcept(ctx context.Context, dbConn *sql.DB,
 /**
  * 更新某个分支事务的状态
  */
-func updateBranchTxState(ctx context.Context, tx *sql.Tx,
+func updateBranchTxState(ctx context.Context, tx db.StoreTx,
 	branchTx *db.BranchTxEntity, newState int) (rowsChanged int64, err error) {
-	rowsChanged, err = db.UpdateBranchTxState(ctx, tx,
+	rowsChanged, err = tx.UpdateBranchTxState(ctx,
 		branchTx.Xid, branchTx.BranchTxId, branchTx.Version, branchTx.State, newState)
 	if err != nil {
 		return
@@ -42,9 +42,24 @@ func updateBranchTxState(ctx context.Context, tx *sql.Tx,
 	return
 }
 
-func findGlobalTxOrError(ctx context.Context, dbConn *sql.DB,
+/**
+ * 更新某个分支事务的状态，分支在其他租约中时不修改
+ */
+func updateLeasedBranchTxState(ctx context.Context, tx db.StoreTx,
+	branchTx *db.BranchTxEntity, newState int, leaseId string) (rowsChanged int64, err error) {
+	rowsChanged, err = tx.UpdateLeasedBranchTxState(ctx, branchTx.Xid, branchTx.BranchTxId,
+		branchTx.Version, branchTx.State, newState, leaseId, time.Now())
+	if err != nil || rowsChanged < 1 {
+		return
+	}
+	branchTx.State = newState
+	branchTx.Version += 1
+	return
+}
+
+func findGlobalTxOrError(ctx context.Context, tx db.StoreTx,
 	xid string) (result *db.GlobalTxEntity, err error) {
-	globalTx, err := db.FindGlobalTxByXidOrNull(ctx, dbConn, xid)
+	globalTx, err := tx.FindGlobalTxByXidOrNull(ctx, xid)
 	if err != nil {
 		return
 	}
@@ -59,19 +74,30 @@ func findGlobalTxOrError(ctx context.Context, dbConn *sql.DB,
 /**
  * 提交CompensationDoing状态的全局事务状态时的回调逻辑
  */
-func logicWhenSubmitGlobalTxCompensationDoing(ctx context.Context, dbConn *sql.DB, tx *sql.Tx,
+func logicWhenSubmitGlobalTxCompensationDoing(ctx context.Context, tx db.StoreTx,
 	globalTx *db.GlobalTxEntity, oldState pb.TxState) (err error) {
 	// 如果oldState是processing，则将processing和committed的branchTxs状态改成COMPENSATION_DOING
+	// 并行分支组中还在执行的分支保持processing，等它提交结果后再补偿，避免补偿和执行并发
+	// 还在等待重试的向前恢复分支不再重试，也进入补偿
 	if oldState != pb.TxState_PROCESSING {
 		return
 	}
 	xid := globalTx.Xid
-	_, err = db.UpdateBranchTxsByXidFromStateToState(ctx, tx, xid,
-		int(pb.TxState_PROCESSING), int(pb.TxState_COMPENSATION_DOING))
+	branches, err := tx.FindAllBranchTxsByXid(ctx, xid)
 	if err != nil {
 		return
 	}
-	_, err = db.UpdateBranchTxsByXidFromStateToState(ctx, tx, xid,
+	for _, b := range branches {
+		processing := b.State == int(pb.TxState_PROCESSING) && len(b.BranchGroup) < 1
+		if !processing && b.State != int(pb.TxState_RETRYING) {
+			continue
+		}
+		_, err = updateBranchTxState(ctx, tx, b, int(pb.TxState_COMPENSATION_DOING))
+		if err != nil {
+			return
+		}
+	}
+	_, err = tx.UpdateBranchTxsByXidFromStateToState(ctx, xid,
 		int(pb.TxState_COMMITTED), int(pb.TxState_COMPENSATION_DOING))
 	if err != nil {
 		return
@@ -82,11 +108,11 @@ func logicWhenSubmitGlobalTxCompensationDoing(ctx context.Context, dbConn *sql.D
 /**
  * 提交CompensationFail状态的全局事务状态时的回调逻辑
  */
-func logicWhenSubmitGlobalTxCompensationFail(ctx context.Context, dbConn *sql.DB, tx *sql.Tx,
+func logicWhenSubmitGlobalTxCompensationFail(ctx context.Context, tx db.StoreTx,
 	globalTx *db.GlobalTxEntity, oldState pb.TxState) (err error) {
 	// 只有各分支事务状态都是已经CompensationFail的全局事务才能标记为CompensationFail
 	xid := globalTx.Xid
-	branches, err := db.FindAllBranchTxsByXid(ctx, dbConn, xid)
+	branches, err := tx.FindAllBranchTxsByXid(ctx, xid)
 	if err != nil {
 		return
 	}
@@ -100,52 +126,76 @@ func logicWhenSubmitGlobalTxCompensationFail(ctx context.Context, dbConn *sql.DB
 }
 
 /**
- * 提交committed的分支事务状态时的回调逻辑
+ * 不再接受新branch的处理中的全局事务，如果各branches都committed了则改成committed
  */
-func logicWhenSubmitBranchTxCommitted(ctx context.Context, dbConn *sql.DB, tx *sql.Tx,
-	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (err error) {
-	if !globalTx.EndBranches {
+func commitGlobalTxIfAllBranchesCommitted(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity) (err error) {
+	if !globalTx.EndBranches || globalTx.State != int(pb.TxState_PROCESSING) {
 		return
 	}
-
-	// 如果这个xid的flag是EndBranches(不再接受新branch)，那么 如果这个xid的其他branches也都committed了，则这个xid要改成committed
-	var otherBranches []*db.BranchTxEntity
-	otherBranches, err = getBranchesOfXidExcept(ctx, dbConn, branchTx)
+	branches, err := tx.FindAllBranchTxsByXid(ctx, globalTx.Xid)
 	if err != nil {
 		return
 	}
-	hasNotCommitted := false
-	for _, b := range otherBranches {
+	for _, b := range branches {
 		if b.State != int(pb.TxState_COMMITTED) {
-			hasNotCommitted = true
-			break
+			return
 		}
 	}
-	if !hasNotCommitted {
-		// 这个xid的各branches都committed了
-		_, err = db.UpdateGlobalTxState(ctx, tx, globalTx.Xid,
-			globalTx.Version, globalTx.State, int(pb.TxState_COMMITTED))
-		if err != nil {
+	for _, progress := range branchGroupsOf(branches) {
+		if !progress.full() {
+			// 并行分支组还有分支没有创建
 			return
 		}
-		globalTx.State = int(pb.TxState_COMMITTED)
 	}
+	if isTccGlobalTx(globalTx) {
+		// TCC模式的各分支都try成功后进入confirm
+		_, err = startConfirmGlobalTx(ctx, tx, globalTx, branches)
+		return
+	}
+	// 这个xid的各branches都committed了
+	rowsChanged, err := tx.UpdateGlobalTxState(ctx, globalTx.Xid,
+		globalTx.Version, globalTx.State, int(pb.TxState_COMMITTED))
+	if err != nil {
+		return
+	}
+	if rowsChanged <= 0 {
+		return
+	}
+	globalTx.State = int(pb.TxState_COMMITTED)
+	globalTx.Version += 1
+	return
+}
+
+/**
+ * 提交committed的分支事务状态时的回调逻辑
+ */
+func logicWhenSubmitBranchTxCommitted(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (err error) {
+	// 全局事务已经在回滚时，迟到提交的分支(比如并行分支组中执行较慢的分支)直接进入补偿
+	if globalTx.State == int(pb.TxState_COMPENSATION_DOING) || globalTx.State == int(pb.TxState_COMPENSATION_ERROR) {
+		_, err = updateBranchTxState(ctx, tx, branchTx, int(pb.TxState_COMPENSATION_DOING))
+		return
+	}
+	// 如果这个xid的flag是EndBranches(不再接受新branch)，那么 如果这个xid的其他branches也都committed了，则这个xid要改成committed
+	err = commitGlobalTxIfAllBranchesCommitted(ctx, tx, globalTx)
 	return
 }
 
 /**
  * 提交补偿失败状态的分支事务状态时的回调逻辑
  */
-func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.DB, tx *sql.Tx,
+func logicWhenSubmitBranchTxCompensationError(ctx context.Context, tx db.StoreTx,
 	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity, jobId string, errorReason string) (err error) {
-	// 如果分支事务补偿任务失败次数超过阈值，则这个branchTx要标记为补偿failed，并且xid也要标记为补偿failed
+	// 如果分支事务补偿任务失败次数达到重试策略的最大次数，则这个branchTx要标记为补偿failed，并且xid也要标记为补偿failed
+	// 没达到最大次数时按重试策略记录下次允许重试的时间
 	// 为了幂等性，每次尝试补偿都要有一个不同的jobId
 	log.Printf("COMPENSATION_ERROR of jobId %s", jobId)
 	xid := globalTx.Xid
 	branchTxId := branchTx.BranchTxId
 	var rowsChanged int64
 	// 补偿失败要记录日志，如果jobId没重复的话
-	compensationFailLog, err := db.FindBranchTxCompensationFailLogByJobId(ctx, tx, jobId)
+	compensationFailLog, err := tx.FindBranchTxCompensationFailLogByJobId(ctx, jobId)
 	if err != nil {
 		return
 	}
@@ -154,7 +204,7 @@ func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.D
 		return
 	}
 	var compensationFailLogId uint64
-	compensationFailLogId, err = db.InsertBranchTxCompensationFailLog(ctx, tx,
+	compensationFailLogId, err = tx.InsertBranchTxCompensationFailLog(ctx,
 		xid, branchTxId, jobId, errorReason)
 	if err != nil {
 		return
@@ -164,8 +214,14 @@ func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.D
 	}
 	// 插入补偿失败日志成功，说明没有并发重复插入
 	branchTx.CompensationFailTimes += 1
-	rowsChanged, err = db.UpdateBranchTxCompensationFailTimes(
-		ctx, tx, branchTx.Id, branchTx.Version, branchTx.CompensationFailTimes)
+	retryPolicy := effectiveRetryPolicy(globalTx, branchTx)
+	var nextRetryAt *time.Time
+	if branchTx.CompensationFailTimes < retryPolicy.MaxAttempts {
+		retryAt := time.Now().Add(retryDelayAfterFailTimes(retryPolicy, branchTx.CompensationFailTimes))
+		nextRetryAt = &retryAt
+	}
+	rowsChanged, err = tx.UpdateBranchTxCompensationFailTimes(
+		ctx, branchTx.Id, branchTx.Version, branchTx.CompensationFailTimes, nextRetryAt)
 	if err != nil {
 		return
 	}
@@ -173,11 +229,12 @@ func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.D
 		return
 	}
 	branchTx.Version += 1
-	if branchTx.CompensationFailTimes <= defaultBranchTxCompensationMaxFailTimes {
+	branchTx.NextRetryAt = nextRetryAt
+	if nextRetryAt != nil {
 		// 还没到允许的最大阈值
 		return
 	}
-	rowsChanged, err = db.UpdateBranchTxState(ctx, tx, xid, branchTxId,
+	rowsChanged, err = tx.UpdateBranchTxState(ctx, xid, branchTxId,
 		branchTx.Version, branchTx.State, int(pb.TxState_COMPENSATION_FAIL))
 	if err != nil {
 		return
@@ -187,7 +244,7 @@ func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.D
 	}
 	branchTx.Version += 1
 	branchTx.State = int(pb.TxState_COMPENSATION_FAIL)
-	rowsChanged, err = db.UpdateGlobalTxState(ctx, tx, xid, globalTx.Version,
+	rowsChanged, err = tx.UpdateGlobalTxState(ctx, xid, globalTx.Version,
 		globalTx.State, int(pb.TxState_COMPENSATION_FAIL))
 	if err != nil {
 		return
@@ -203,12 +260,12 @@ func logicWhenSubmitBranchTxCompensationError(ctx context.Context, dbConn *sql.D
 /**
  * 提交补偿完成状态的分支事务状态时的回调逻辑
  */
-func logicWhenSubmitBranchTxCompensationDone(ctx context.Context, dbConn *sql.DB, tx *sql.Tx,
+func logicWhenSubmitBranchTxCompensationDone(ctx context.Context, tx db.StoreTx,
 	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity) (err error) {
 	// 如果这个xid的其他branches也都补偿done了，则这个xid要改成补偿done
 	xid := globalTx.Xid
 	var otherBranches []*db.BranchTxEntity
-	otherBranches, err = getBranchesOfXidExcept(ctx, dbConn, branchTx)
+	otherBranches, err = getBranchesOfXidExcept(ctx, tx, branchTx)
 	if err != nil {
 		return
 	}
@@ -221,7 +278,7 @@ func logicWhenSubmitBranchTxCompensationDone(ctx context.Context, dbConn *sql.DB
 	}
 	if !hasNotCompensationDone {
 		// 这个xid的各branches都COMPENSATION_DONE了
-		_, err = db.UpdateGlobalTxState(ctx, tx, xid, globalTx.Version, globalTx.State, int(pb.TxState_COMPENSATION_DONE))
+		_, err = tx.UpdateGlobalTxState(ctx, xid, globalTx.Version, globalTx.State, int(pb.TxState_COMPENSATION_DONE))
 		if err != nil {
 			return
 		}
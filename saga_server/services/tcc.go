@@ -0,0 +1,102 @@
+package services
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"log"
+)
+
+func isTccGlobalTx(globalTx *db.GlobalTxEntity) bool {
+	return globalTx.Mode == int(pb.TransactionMode_TCC)
+}
+
+func isValidTransactionMode(mode pb.TransactionMode) bool {
+	_, ok := pb.TransactionMode_name[int32(mode)]
+	return ok
+}
+
+/**
+ * TCC模式的全局事务中是否有分支还没有try成功(COMMITTED)
+ */
+func firstNotTriedBranchTx(branches []*db.BranchTxEntity) *db.BranchTxEntity {
+	for _, b := range branches {
+		if b.State != int(pb.TxState_COMMITTED) {
+			return b
+		}
+	}
+	return nil
+}
+
+/**
+ * TCC模式的全局事务提交时进入CONFIRMING，有confirm的分支改成CONFIRMING等待confirm，没有confirm的分支直接CONFIRMED
+ * 所有分支都CONFIRMED时全局事务改成COMMITTED
+ * 调用方需要保证各分支都已经try成功(COMMITTED)
+ */
+func startConfirmGlobalTx(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branches []*db.BranchTxEntity) (rowsChanged int64, err error) {
+	rowsChanged, err = tx.UpdateGlobalTxState(ctx, globalTx.Xid,
+		globalTx.Version, globalTx.State, int(pb.TxState_CONFIRMING))
+	if err != nil {
+		return
+	}
+	if rowsChanged < 1 {
+		return
+	}
+	globalTx.State = int(pb.TxState_CONFIRMING)
+	globalTx.Version += 1
+	for _, b := range branches {
+		newState := pb.TxState_CONFIRMED
+		if len(b.BranchConfirmServiceKey) > 0 {
+			newState = pb.TxState_CONFIRMING
+		}
+		_, err = updateBranchTxState(ctx, tx, b, int(newState))
+		if err != nil {
+			return
+		}
+	}
+	log.Printf("global tx %s state changed to CONFIRMING\n", globalTx.Xid)
+	err = commitGlobalTxIfAllBranchesConfirmed(ctx, tx, globalTx)
+	return
+}
+
+/**
+ * CONFIRMING的全局事务，如果各分支都CONFIRMED了则改成COMMITTED
+ */
+func commitGlobalTxIfAllBranchesConfirmed(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity) (err error) {
+	if globalTx.State != int(pb.TxState_CONFIRMING) {
+		return
+	}
+	branches, err := tx.FindAllBranchTxsByXid(ctx, globalTx.Xid)
+	if err != nil {
+		return
+	}
+	for _, b := range branches {
+		if b.State != int(pb.TxState_CONFIRMED) {
+			return
+		}
+	}
+	rowsChanged, err := tx.UpdateGlobalTxState(ctx, globalTx.Xid,
+		globalTx.Version, globalTx.State, int(pb.TxState_COMMITTED))
+	if err != nil {
+		return
+	}
+	if rowsChanged <= 0 {
+		return
+	}
+	globalTx.State = int(pb.TxState_COMMITTED)
+	globalTx.Version += 1
+	return
+}
+
+/**
+ * 提交CONFIRMING状态的分支事务状态(confirm执行失败)时的回调逻辑
+ */
+func logicWhenSubmitBranchTxConfirming(ctx context.Context, tx db.StoreTx,
+	globalTx *db.GlobalTxEntity, branchTx *db.BranchTxEntity, jobId string, errorReason string) (err error) {
+	// confirm不能失败，每次失败都记录日志和失败次数，按重试策略的间隔一直重试直到成功
+	log.Printf("CONFIRMING of jobId %s", jobId)
+	err = recordBranchTxRetryFailure(ctx, tx, globalTx, branchTx, jobId, errorReason)
+	return
+}
@@ -0,0 +1,216 @@
+package services
+
+import (
+	"context"
+	pb "github.com/zoowii/saga_server/api"
+	"github.com/zoowii/saga_server/db"
+	"log"
+	"time"
+)
+
+/**
+ * 记录事务中的状态修改，Commit成功后把每次修改的(oldState, state)发布到事件总线
+ * 修改时的旧状态来自修改语句的条件或者修改前在同一个事务中读到的状态，发布时不需要重新读取存储
+ * 同一个xid的提交和发布持有这个xid的锁，订阅方按提交的顺序收到状态变化
+ * 状态变化之后再给有任务可以领取的参与方发布WorkReady事件
+ */
+type txEventRecorder struct {
+	db.StoreTx
+	ctx       context.Context
+	bus       *TxEventBus
+	events    []*TxStateEvent
+	globalTxs map[string]*db.GlobalTxEntity // 事务中用到的全局事务，只用来取发起方
+}
+
+func (r *txEventRecorder) record(event *TxStateEvent) {
+	r.events = append(r.events, event)
+}
+
+func (r *txEventRecorder) findGlobalTx(ctx context.Context, xid string) (*db.GlobalTxEntity, error) {
+	if globalTx, ok := r.globalTxs[xid]; ok {
+		return globalTx, nil
+	}
+	globalTx, err := r.StoreTx.FindGlobalTxByXidOrNull(ctx, xid)
+	if err != nil || globalTx == nil {
+		return nil, err
+	}
+	r.globalTxs[xid] = globalTx
+	return globalTx, nil
+}
+
+func (r *txEventRecorder) CreateGlobalTx(ctx context.Context, record *db.GlobalTxEntity) (xid string, err error) {
+	xid, err = r.StoreTx.CreateGlobalTx(ctx, record)
+	if err != nil {
+		return
+	}
+	state := pb.TxState(record.State)
+	event := globalTxStateEvent(record, state, state, time.Now())
+	event.Xid = xid
+	r.record(event)
+	return
+}
+
+func (r *txEventRecorder) UpdateGlobalTxState(ctx context.Context, xid string,
+	oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	rowsChanged, err = r.StoreTx.UpdateGlobalTxState(ctx, xid, oldVersion, oldState, state)
+	if err != nil || rowsChanged < 1 || oldState == state {
+		return
+	}
+	globalTx, err := r.findGlobalTx(ctx, xid)
+	if err != nil || globalTx == nil {
+		return
+	}
+	event := globalTxStateEvent(globalTx, pb.TxState(oldState), pb.TxState(state), time.Now())
+	event.Version = oldVersion + 1
+	r.record(event)
+	return
+}
+
+func (r *txEventRecorder) CreateBranchTx(ctx context.Context, record *db.BranchTxEntity) (branchTxId string, err error) {
+	branchTxId, err = r.StoreTx.CreateBranchTx(ctx, record)
+	if err != nil {
+		return
+	}
+	state := pb.TxState(record.State)
+	event := branchTxStateEvent(record, state, state, time.Now())
+	event.BranchId = branchTxId
+	r.record(event)
+	return
+}
+
+func (r *txEventRecorder) UpdateBranchTxState(ctx context.Context, xid string,
+	branchTxId string, oldVersion int32, oldState int, state int) (rowsChanged int64, err error) {
+	rowsChanged, err = r.StoreTx.UpdateBranchTxState(ctx, xid, branchTxId, oldVersion, oldState, state)
+	if err != nil || rowsChanged < 1 {
+		return
+	}
+	err = r.recordBranchTxStateChanged(ctx, branchTxId, oldVersion, oldState, state)
+	return
+}
+
+func (r *txEventRecorder) UpdateLeasedBranchTxState(ctx context.Context, xid string, branchTxId string,
+	oldVersion int32, oldState int, state int, leaseId string, now time.Time) (rowsChanged int64, err error) {
+	rowsChanged, err = r.StoreTx.UpdateLeasedBranchTxState(ctx, xid, branchTxId, oldVersion, oldState, state,
+		leaseId, now)
+	if err != nil || rowsChanged < 1 {
+		return
+	}
+	err = r.recordBranchTxStateChanged(ctx, branchTxId, oldVersion, oldState, state)
+	return
+}
+
+func (r *txEventRecorder) recordBranchTxStateChanged(ctx context.Context, branchTxId string,
+	oldVersion int32, oldState int, state int) (err error) {
+	if oldState == state {
+		return
+	}
+	branchTx, err := r.StoreTx.FindBranchTxByBranchTxId(ctx, branchTxId)
+	if err != nil || branchTx == nil {
+		return
+	}
+	event := branchTxStateEvent(branchTx, pb.TxState(oldState), pb.TxState(state), time.Now())
+	event.Version = oldVersion + 1
+	r.record(event)
+	return
+}
+
+func (r *txEventRecorder) UpdateBranchesStateByXid(ctx context.Context, xid string, state int) (rowsChanged int64, err error) {
+	branches, err := r.StoreTx.FindAllBranchTxsByXid(ctx, xid)
+	if err != nil {
+		return
+	}
+	rowsChanged, err = r.StoreTx.UpdateBranchesStateByXid(ctx, xid, state)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for _, branchTx := range branches {
+		// 不论状态是否变化版本号都加1
+		branchTx.Version++
+		if branchTx.State != state {
+			r.record(branchTxStateEvent(branchTx, pb.TxState(branchTx.State), pb.TxState(state), now))
+		}
+	}
+	return
+}
+
+func (r *txEventRecorder) UpdateBranchTxsByXidFromStateToState(ctx context.Context,
+	xid string, oldState int, newState int) (rowsAffected int64, err error) {
+	branches, err := r.StoreTx.FindAllBranchTxsByXid(ctx, xid)
+	if err != nil {
+		return
+	}
+	rowsAffected, err = r.StoreTx.UpdateBranchTxsByXidFromStateToState(ctx, xid, oldState, newState)
+	if err != nil || oldState == newState {
+		return
+	}
+	now := time.Now()
+	for _, branchTx := range branches {
+		if branchTx.State == oldState {
+			branchTx.Version++
+			r.record(branchTxStateEvent(branchTx, pb.TxState(oldState), pb.TxState(newState), now))
+		}
+	}
+	return
+}
+
+/**
+ * 提交事务并发布记录的状态变化，然后发布状态变化的xid中现在可以领取的任务
+ */
+func (r *txEventRecorder) Commit() error {
+	if len(r.events) < 1 {
+		return r.StoreTx.Commit()
+	}
+	if err := r.commitAndDispatch(); err != nil {
+		return err
+	}
+	// 事务已经提交，发布失败只影响推送，参与方定时的一轮处理还能领取到
+	if err := r.bus.publishClaimableWork(r.ctx, transitionXids(r.events)); err != nil {
+		log.Printf("publish claimable work error %s\n", err.Error())
+	}
+	return nil
+}
+
+/**
+ * 提交和发布期间持有涉及的xid的锁
+ */
+func (r *txEventRecorder) commitAndDispatch() error {
+	unlock := r.bus.lockXids(r.events)
+	defer unlock()
+	if err := r.StoreTx.Commit(); err != nil {
+		return err
+	}
+	r.bus.dispatch(orderTxStateEvents(r.events))
+	return nil
+}
+
+/**
+ * 状态有变化(不是新创建)的xid，不重复
+ */
+func transitionXids(events []*TxStateEvent) (xids []string) {
+	found := make(map[string]bool)
+	for _, event := range events {
+		if event.OldState == event.State || found[event.Xid] {
+			continue
+		}
+		found[event.Xid] = true
+		xids = append(xids, event.Xid)
+	}
+	return
+}
+
+/**
+ * 全局事务的结束状态放在最后，订阅方收到全局事务的结束状态时已经收到了各分支的最终状态
+ */
+func orderTxStateEvents(events []*TxStateEvent) []*TxStateEvent {
+	result := make([]*TxStateEvent, 0, len(events))
+	var finished []*TxStateEvent
+	for _, event := range events {
+		if event.isGlobalTxEvent() && isTerminalGlobalTxState(event.State) {
+			finished = append(finished, event)
+			continue
+		}
+		result = append(result, event)
+	}
+	return append(result, finished...)
+}
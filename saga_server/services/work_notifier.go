@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const defaultWorkNotifyInterval = time.Second
+
+/**
+ * 定时扫描重试等待结束或者租约到期的分支，给现在可以领取它们的参与方发布WorkReady事件
+ * 状态变化和释放租约时的任务事件在提交时发布，这里补上只因为时间到了才可以领取的任务
+ */
+type WorkNotifier struct {
+	events     *TxEventBus
+	interval   time.Duration
+	lastScanAt time.Time
+}
+
+func NewWorkNotifier(service *SagaServerService, interval time.Duration) *WorkNotifier {
+	if interval <= 0 {
+		interval = defaultWorkNotifyInterval
+	}
+	return &WorkNotifier{
+		events:     service.events,
+		interval:   interval,
+		lastScanAt: time.Now(),
+	}
+}
+
+/**
+ * 阻塞运行直到ctx结束
+ */
+func (n *WorkNotifier) Run(ctx context.Context) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := n.NotifyOnce(ctx); err != nil {
+				log.Printf("notify claimable work error %s\n", err.Error())
+			}
+		}
+	}
+}
+
+/**
+ * 扫描上次扫描之后到现在到期的分支并发布它们的全局事务中可以领取的任务，返回扫描到的全局事务数量
+ * 出错时不移动扫描的位置，下次扫描时重试
+ */
+func (n *WorkNotifier) NotifyOnce(ctx context.Context) (count int, err error) {
+	now := time.Now()
+	xids, err := n.events.store.FindXidsOfBranchTxsDueBetween(ctx, n.lastScanAt, now)
+	if err != nil {
+		return
+	}
+	if err = n.events.publishClaimableWork(ctx, xids); err != nil {
+		return
+	}
+	n.lastScanAt = now
+	count = len(xids)
+	return
+}
@@ -1,15 +1,22 @@
+-- 当前完整表结构，仅供参考. saga_server启动时(或者执行 saga_server migrate)会通过db/migrations.go中的migrations自动建表和升级
 CREATE TABLE `global_tx` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
   `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   `xid` varchar(50) NOT NULL,
   `state` int(11) NOT NULL,
+  `end_branches` tinyint(1) NOT NULL DEFAULT 0,
+  `mode` int(11) NOT NULL DEFAULT 0,
   `version` int(11) NOT NULL,
   `creator_group` varchar(100) DEFAULT NULL,
   `creator_service` varchar(100) DEFAULT NULL,
   `creator_instance_id` varchar(100) DEFAULT NULL,
   `expire_seconds` int(11) NOT NULL,
   `extra` text,
+  `retry_max_attempts` int(11) NOT NULL DEFAULT 0,
+  `retry_initial_delay_ms` bigint(20) NOT NULL DEFAULT 0,
+  `retry_multiplier` double NOT NULL DEFAULT 0,
+  `retry_max_delay_ms` bigint(20) NOT NULL DEFAULT 0,
   PRIMARY KEY (`id`),
   UNIQUE KEY `global_tx_index_xid` (`xid`),
   KEY `global_tx_index_creator_group_creator_service` (`creator_group`,`creator_service`)
@@ -23,18 +30,37 @@ CREATE TABLE `branch_tx` (
   `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
   `branch_tx_id` varchar(50) NOT NULL,
   `xid` varchar(50) NOT NULL,
+  `parent_branch_tx_id` varchar(50) NOT NULL DEFAULT '',
+  `branch_group` varchar(100) NOT NULL DEFAULT '',
+  `branch_group_size` int(11) NOT NULL DEFAULT 0,
+  `recovery_mode` int(11) NOT NULL DEFAULT 0,
   `state` int(11) NOT NULL,
   `version` int(11) NOT NULL,
   `compensation_fail_times` int(11) NOT NULL,
+  `forward_retry_times` int(11) NOT NULL DEFAULT 0,
   `node_group` varchar(100) DEFAULT NULL,
   `node_service` varchar(100) DEFAULT NULL,
   `node_instance_id` varchar(100) DEFAULT NULL,
   `branch_service_key` varchar(255) DEFAULT NULL,
   `branch_compensation_service_key` varchar(255) DEFAULT NULL,
+  `branch_confirm_service_key` varchar(255) NOT NULL DEFAULT '',
+  `retry_max_attempts` int(11) NOT NULL DEFAULT 0,
+  `retry_initial_delay_ms` bigint(20) NOT NULL DEFAULT 0,
+  `retry_multiplier` double NOT NULL DEFAULT 0,
+  `retry_max_delay_ms` bigint(20) NOT NULL DEFAULT 0,
+  `next_retry_at` timestamp NULL DEFAULT NULL,
+  `lease_id` varchar(50) NOT NULL DEFAULT '',
+  `lease_owner` varchar(100) NOT NULL DEFAULT '',
+  `lease_expire_at` timestamp NULL DEFAULT NULL,
+  `heartbeat_timeout_seconds` int(11) NOT NULL DEFAULT 0,
+  `last_heartbeat_at` timestamp NULL DEFAULT NULL,
+  `orphaned_at` timestamp NULL DEFAULT NULL,
   PRIMARY KEY (`id`),
   UNIQUE KEY `branch_tx_idx_branch_tx_id` (`branch_tx_id`) /*!80000 INVISIBLE */,
   KEY `branch_tx_idx_xid` (`xid`) /*!80000 INVISIBLE */,
-  KEY `branch_tx_node_group_node_service` (`node_group`,`node_service`)
+  KEY `branch_tx_node_group_node_service` (`node_group`,`node_service`),
+  KEY `branch_tx_idx_next_retry_at` (`next_retry_at`),
+  KEY `branch_tx_idx_lease_expire_at` (`lease_expire_at`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
 CREATE TABLE `branch_tx_compensation_fail_log` (
@@ -51,6 +77,20 @@ CREATE TABLE `branch_tx_compensation_fail_log` (
   UNIQUE KEY `branch_tx_compensation_fail_log_idx_job_id` (`job_id`)
 ) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
 
+CREATE TABLE `branch_tx_forward_retry_log` (
+  `id` bigint(20) NOT NULL AUTO_INCREMENT,
+  `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `updated_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+  `xid` varchar(50) NOT NULL,
+  `branch_tx_id` varchar(50) DEFAULT NULL,
+  `job_id` varchar(50) NOT NULL,
+  `reason` text NULL DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  KEY `branch_tx_forward_retry_log_idx_xid_branch_tx_id` (`xid`, `branch_tx_id`),
+  KEY `branch_tx_forward_retry_log_idx_branch_tx_id` (`branch_tx_id`),
+  UNIQUE KEY `branch_tx_forward_retry_log_idx_job_id` (`job_id`)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;
+
 CREATE TABLE `tx_log` (
   `id` bigint(20) NOT NULL AUTO_INCREMENT,
   `created_at` timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
@@ -0,0 +1,130 @@
+-- 当前完整表结构，仅供参考. saga_server启动时(或者执行 saga_server migrate)会通过db/migrations.go中的migrations自动建表和升级
+CREATE OR REPLACE FUNCTION saga_set_updated_at() RETURNS trigger AS $$
+BEGIN
+  NEW.updated_at = CURRENT_TIMESTAMP;
+  RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TABLE global_tx (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  state int NOT NULL,
+  end_branches boolean NOT NULL DEFAULT false,
+  version int NOT NULL,
+  creator_group varchar(100) DEFAULT NULL,
+  creator_service varchar(100) DEFAULT NULL,
+  creator_instance_id varchar(100) DEFAULT NULL,
+  expire_seconds int NOT NULL,
+  extra text,
+  retry_max_attempts integer NOT NULL DEFAULT 0,
+  retry_initial_delay_ms bigint NOT NULL DEFAULT 0,
+  retry_multiplier double precision NOT NULL DEFAULT 0,
+  retry_max_delay_ms bigint NOT NULL DEFAULT 0,
+  mode integer NOT NULL DEFAULT 0
+);
+CREATE UNIQUE INDEX global_tx_index_xid ON global_tx (xid);
+CREATE INDEX global_tx_index_creator_group_creator_service ON global_tx (creator_group, creator_service);
+CREATE TRIGGER global_tx_updated_at BEFORE UPDATE ON global_tx
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();
+
+CREATE TABLE branch_tx (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  branch_tx_id varchar(50) NOT NULL,
+  xid varchar(50) NOT NULL,
+  state int NOT NULL,
+  version int NOT NULL,
+  compensation_fail_times int NOT NULL,
+  node_group varchar(100) DEFAULT NULL,
+  node_service varchar(100) DEFAULT NULL,
+  node_instance_id varchar(100) DEFAULT NULL,
+  branch_service_key varchar(255) DEFAULT NULL,
+  branch_compensation_service_key varchar(255) DEFAULT NULL,
+  retry_max_attempts integer NOT NULL DEFAULT 0,
+  retry_initial_delay_ms bigint NOT NULL DEFAULT 0,
+  retry_multiplier double precision NOT NULL DEFAULT 0,
+  retry_max_delay_ms bigint NOT NULL DEFAULT 0,
+  next_retry_at timestamp NULL DEFAULT NULL,
+  parent_branch_tx_id varchar(50) NOT NULL DEFAULT '',
+  branch_group varchar(100) NOT NULL DEFAULT '',
+  branch_group_size integer NOT NULL DEFAULT 0,
+  recovery_mode integer NOT NULL DEFAULT 0,
+  forward_retry_times integer NOT NULL DEFAULT 0,
+  branch_confirm_service_key varchar(255) NOT NULL DEFAULT '',
+  lease_id varchar(50) NOT NULL DEFAULT '',
+  lease_owner varchar(100) NOT NULL DEFAULT '',
+  lease_expire_at timestamp NULL DEFAULT NULL,
+  heartbeat_timeout_seconds integer NOT NULL DEFAULT 0,
+  last_heartbeat_at timestamp NULL DEFAULT NULL,
+  orphaned_at timestamp NULL DEFAULT NULL
+);
+CREATE UNIQUE INDEX branch_tx_idx_branch_tx_id ON branch_tx (branch_tx_id);
+CREATE INDEX branch_tx_idx_xid ON branch_tx (xid);
+CREATE INDEX branch_tx_node_group_node_service ON branch_tx (node_group, node_service);
+CREATE INDEX branch_tx_idx_next_retry_at ON branch_tx (next_retry_at);
+CREATE INDEX branch_tx_idx_lease_expire_at ON branch_tx (lease_expire_at);
+CREATE TRIGGER branch_tx_updated_at BEFORE UPDATE ON branch_tx
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();
+
+CREATE TABLE branch_tx_compensation_fail_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  job_id varchar(50) NOT NULL,
+  reason text NULL DEFAULT NULL
+);
+CREATE INDEX branch_tx_compensation_fail_log_idx_xid_branch_tx_id ON branch_tx_compensation_fail_log (xid, branch_tx_id);
+CREATE INDEX branch_tx_compensation_fail_log_idx_branch_tx_id ON branch_tx_compensation_fail_log (branch_tx_id);
+CREATE UNIQUE INDEX branch_tx_compensation_fail_log_idx_job_id ON branch_tx_compensation_fail_log (job_id);
+CREATE TRIGGER branch_tx_compensation_fail_log_updated_at BEFORE UPDATE ON branch_tx_compensation_fail_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();
+
+CREATE TABLE branch_tx_forward_retry_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  job_id varchar(50) NOT NULL,
+  reason text NULL DEFAULT NULL
+);
+CREATE INDEX branch_tx_forward_retry_log_idx_xid_branch_tx_id ON branch_tx_forward_retry_log (xid, branch_tx_id);
+CREATE INDEX branch_tx_forward_retry_log_idx_branch_tx_id ON branch_tx_forward_retry_log (branch_tx_id);
+CREATE UNIQUE INDEX branch_tx_forward_retry_log_idx_job_id ON branch_tx_forward_retry_log (job_id);
+CREATE TRIGGER branch_tx_forward_retry_log_updated_at BEFORE UPDATE ON branch_tx_forward_retry_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();
+
+CREATE TABLE tx_log (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(50) NOT NULL,
+  branch_tx_id varchar(50) DEFAULT NULL,
+  operator_group varchar(100) DEFAULT NULL,
+  operator_service varchar(100) DEFAULT NULL,
+  operator_instance_id varchar(100) DEFAULT NULL,
+  log_type varchar(45) DEFAULT NULL,
+  log_params text
+);
+CREATE INDEX tx_log_idx_xid_branch_x_id ON tx_log (xid, branch_tx_id);
+CREATE INDEX tx_log_idx_branch_tx_id ON tx_log (branch_tx_id);
+CREATE TRIGGER tx_log_updated_at BEFORE UPDATE ON tx_log
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();
+
+CREATE TABLE saga_data (
+  id BIGSERIAL PRIMARY KEY,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  updated_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  xid varchar(45) NOT NULL,
+  data bytea NULL,
+  version int NOT NULL
+);
+CREATE UNIQUE INDEX saga_data_unique_idx_xid ON saga_data (xid);
+CREATE TRIGGER saga_data_updated_at BEFORE UPDATE ON saga_data
+  FOR EACH ROW EXECUTE PROCEDURE saga_set_updated_at();